@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cli"
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+)
+
+var (
+	configFile string
+	kubeconfig string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect YAML cloud-config files",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a YAML cloud-config",
+	Long: `Validates a YAML cloud-config the way the cloud provider does when
+VSPHERE_STRICT_CONFIG is true. Unknown keys and values of the wrong type are
+reported with their line, then the constraints between fields are checked. With
+--kubeconfig, the load balancer classes used by the Services of the cluster must be
+defined.
+  `,
+	Example: `	vcpctl config validate --config vsphere.yaml
+
+# Check the load balancer classes used by the Services of the cluster
+	vcpctl config validate --config vsphere.yaml --kubeconfig ~/.kube/config
+`,
+	Run: RunValidate,
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the YAML cloud-config",
+	Run:   RunSchema,
+}
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the environment variables mapped to the YAML cloud-config keys",
+	Long: `Lists the environment variable overriding each key of the YAML cloud-config.
+<NAME> stands for the name of a map entry, such as a vcenter, in upper snake case.
+  `,
+	Run: RunEnv,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Compare two cloud-configs and flag the risky changes",
+	Long: `Parses both cloud-configs with the readers of the cloud provider, ignoring the
+environment variables that override their keys, and prints the settings that differ, then the risky changes: a vCenter removed or moved to another
+TenantRef, a changed zone or region tag category, a disabled load balancer or route,
+and a removed load balancer class. With --kubeconfig, a removed load balancer class
+is only flagged if Services still use it. Credentials are never printed. Exits with
+1 if a change is risky.
+  `,
+	Example: `	vcpctl config diff vsphere.yaml vsphere-next.yaml
+
+# Check the load balancer classes used by the Services of the cluster
+	vcpctl config diff vsphere.yaml vsphere-next.yaml --kubeconfig ~/.kube/config
+`,
+	Args: cobra.ExactArgs(2),
+	Run:  RunDiff,
+}
+
+// AddConfig initializes the "config" command group.
+func AddConfig(cmd *cobra.Command) {
+	validateCmd.Flags().StringVar(&configFile, "config", "", "YAML cloud-config file path")
+	validateCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig file path used to list the Services of the cluster")
+	diffCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Kubeconfig file path used to list the Services of the cluster")
+
+	configCmd.AddCommand(validateCmd)
+	configCmd.AddCommand(schemaCmd)
+	configCmd.AddCommand(envCmd)
+	configCmd.AddCommand(diffCmd)
+	cmd.AddCommand(configCmd)
+}
+
+// RunValidate executes the "config validate" command.
+func RunValidate(cmd *cobra.Command, args []string) {
+	if configFile == "" {
+		fmt.Fprintln(os.Stderr, "error: please specify the YAML cloud-config file, e.g. --config vsphere.yaml")
+		os.Exit(1)
+	}
+	byConfig, err := os.ReadFile(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	errs := []error{ccfg.ValidateStrict(byConfig)}
+	if kubeconfig != "" {
+		services, err := cli.ListServices(context.Background(), kubeconfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		errs = append(errs, ccfg.ValidateLoadBalancerClasses(byConfig, cli.LoadBalancerClassUsers(services)))
+	}
+	err = utilerrors.Flatten(utilerrors.NewAggregate(errs))
+	if err != nil {
+		if agg, ok := err.(utilerrors.Aggregate); ok {
+			for _, e := range agg.Errors() {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", configFile, e)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", configFile, err)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("%s: valid\n", configFile)
+}
+
+// RunSchema executes the "config schema" command.
+func RunSchema(cmd *cobra.Command, args []string) {
+	os.Stdout.Write(ccfg.JSONSchema())
+}
+
+// RunEnv executes the "config env" command.
+func RunEnv(cmd *cobra.Command, args []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VARIABLE\tKEY\tTYPE")
+	for _, envVar := range vcfg.EnvVars(vcfg.EnvPrefix, &ccfg.CloudConfigYAML{}) {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", envVar.Name, envVar.Path, envVar.Type)
+	}
+	w.Flush()
+}
+
+// RunDiff executes the "config diff" command.
+func RunDiff(cmd *cobra.Command, args []string) {
+	var configs [2][]byte
+	for i, file := range args {
+		byConfig, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		configs[i] = byConfig
+	}
+
+	var services []corev1.Service
+	if kubeconfig != "" {
+		var err error
+		services, err = cli.ListServices(context.Background(), kubeconfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	diff, err := cli.DiffConfig(configs[0], configs[1], services)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(diff.String())
+	if len(diff.Risks) > 0 {
+		os.Exit(1)
+	}
+}
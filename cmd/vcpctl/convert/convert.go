@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cloud-provider-vsphere/pkg/cli"
+)
+
+var (
+	configFile string
+	outputFile string
+	strict     bool
+)
+
+var convertConfigCmd = &cobra.Command{
+	Use:   "convert-config",
+	Short: "Convert an INI cloud-config to YAML",
+	Long: `Converts an INI cloud-config, including the LoadBalancer, LoadBalancerClass, NSXT
+and Route sections, to the equivalent YAML cloud-config. Options that have no YAML
+equivalent are reported on stderr.
+  `,
+	Example: `# Print the YAML cloud-config
+	vcpctl convert-config --config vsphere.conf
+
+# Write it to a file and fail if some options cannot be converted
+	vcpctl convert-config --config vsphere.conf --output vsphere.yaml --strict
+`,
+	Run: RunConvertConfig,
+}
+
+// AddConvertConfig initializes the "convert-config" command.
+func AddConvertConfig(cmd *cobra.Command) {
+	convertConfigCmd.Flags().StringVar(&configFile, "config", "", "INI cloud-config file path")
+	convertConfigCmd.Flags().StringVar(&outputFile, "output", "", "YAML cloud-config file path, stdout if not set")
+	convertConfigCmd.Flags().BoolVar(&strict, "strict", false, "Fail if some options have no YAML equivalent")
+
+	cmd.AddCommand(convertConfigCmd)
+}
+
+// RunConvertConfig executes the "convert-config" command.
+func RunConvertConfig(cmd *cobra.Command, args []string) {
+	if configFile == "" {
+		fmt.Fprintln(os.Stderr, "error: please specify the INI cloud-config file, e.g. --config vsphere.conf")
+		os.Exit(1)
+	}
+	byConfig, err := os.ReadFile(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	byYAML, skipped, err := cli.ConvertConfigINIToYAML(byConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, option := range skipped {
+		fmt.Fprintf(os.Stderr, "warning: not converted: %s\n", option)
+	}
+	if strict && len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "error: %d options have no YAML equivalent\n", len(skipped))
+		os.Exit(1)
+	}
+
+	if outputFile == "" {
+		fmt.Print(string(byYAML))
+		return
+	}
+	if err := os.WriteFile(outputFile, byYAML, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cloud-provider-vsphere/pkg/cli"
+)
+
+var (
+	source cli.CredentialsSource
+	output string
+)
+
+var validateCredentialsCmd = &cobra.Command{
+	Use:   "validate-credentials",
+	Short: "Validate the vCenter credentials secret",
+	Long: `Reports, for each vCenter server, which credential keys are present in the secret,
+as well as unknown keys and malformed server_/username_/password_ triplets.
+Credential values are never printed.
+  `,
+	Example: `# Validate a secret manifest
+	vcpctl validate-credentials --secret-file vsphere-cloud-secret.yaml
+
+# Validate the secret used by the CCM
+	vcpctl validate-credentials --secret-name vsphere-cloud-secret --secret-namespace kube-system --output json
+`,
+	Run: RunValidateCredentials,
+}
+
+// AddValidateCredentials initializes the "validate-credentials" command.
+func AddValidateCredentials(cmd *cobra.Command) {
+	validateCredentialsCmd.Flags().StringVar(&source.SecretFile, "secret-file", "", "Secret manifest file path")
+	validateCredentialsCmd.Flags().StringVar(&source.SecretsDirectory, "secrets-directory", "", "Directory with one file per secret key")
+	validateCredentialsCmd.Flags().StringVar(&source.SecretName, "secret-name", "", "Name of the secret in the cluster")
+	validateCredentialsCmd.Flags().StringVar(&source.SecretNamespace, "secret-namespace", "kube-system", "Namespace of the secret in the cluster")
+	validateCredentialsCmd.Flags().StringVar(&source.Kubeconfig, "kubeconfig", "", "Kubeconfig file path used with --secret-name")
+	validateCredentialsCmd.Flags().StringVar(&output, "output", "text", "Output format (text|json)")
+
+	cmd.AddCommand(validateCredentialsCmd)
+}
+
+// RunValidateCredentials executes the "validate-credentials" command.
+func RunValidateCredentials(cmd *cobra.Command, args []string) {
+	report, err := cli.ValidateCredentials(context.Background(), &source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch output {
+	case "json":
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+	case "text":
+		fmt.Print(report.String())
+	default:
+		fmt.Fprintf(os.Stderr, "error: unsupported output format %q\n", output)
+		os.Exit(1)
+	}
+
+	if !report.Valid() {
+		os.Exit(1)
+	}
+}
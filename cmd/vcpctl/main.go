@@ -23,17 +23,22 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"k8s.io/cloud-provider-vsphere/cmd/vcpctl/config"
+	"k8s.io/cloud-provider-vsphere/cmd/vcpctl/convert"
+	"k8s.io/cloud-provider-vsphere/cmd/vcpctl/credentials"
 	"k8s.io/cloud-provider-vsphere/cmd/vcpctl/provision"
 )
 
 func main() {
 
 	provision.AddProvision(cmd)
+	credentials.AddValidateCredentials(cmd)
+	convert.AddConvertConfig(cmd)
+	config.AddConfig(cmd)
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("\nCompleted!\n")
 }
 
 var cmd = &cobra.Command{
@@ -44,6 +49,9 @@ var cmd = &cobra.Command{
 * Create vSphere role with a minimal set of permissioins.
 * Create vSphere solution user, to be used with CCM
 * Convert old in-tree vsphere.conf configuration files to new configMap
+* Validate the vCenter credentials secret
+* Convert INI cloud-config files to YAML
+* Validate YAML cloud-config files against their schema
 
 `,
 
@@ -20,18 +20,21 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"flag"
 	goflag "flag"
 	"fmt"
 	"math/rand"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere"
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
 	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer"
 	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphereparavirtual"
 	"k8s.io/cloud-provider/app"
@@ -97,6 +100,12 @@ func main() {
 		globalflag.Register(namedFlagSets.FlagSet("generic"), "is-legacy-paravirtual")
 	}
 
+	var printEffectiveConfig bool
+	namedFlagSets.FlagSet("vsphere").BoolVar(&printEffectiveConfig, "print-effective-config", false,
+		"Print the YAML cloud-config merged with its environment variables, with the secrets redacted, and exit.")
+	namedFlagSets.FlagSet("vsphere").StringVar(&vsphere.CloudConfigName, "vsphere-cloud-config", "",
+		"Name of a VSphereCloudConfig to merge into the YAML cloud-config, none if empty.")
+
 	for _, f := range namedFlagSets.FlagSets {
 		fs.AddFlagSet(f)
 	}
@@ -155,6 +164,9 @@ func main() {
 
 	innerRun := func(cmd *cobra.Command, args []string) {
 		verflag.PrintAndExitIfRequested()
+		if printEffectiveConfig {
+			printEffectiveCloudConfig(ccmOptions.KubeCloudShared.CloudProvider.CloudConfigFile)
+		}
 		cliflag.PrintFlags(cmd.Flags())
 
 		c, err := ccmOptions.Config(app.ControllerNames(app.DefaultInitFuncConstructors), app.ControllersDisabledByDefault.List(), names.CCMControllerAliases(), app.AllWebhooks, app.DisabledByDefaultWebhooks)
@@ -194,7 +206,17 @@ func main() {
 		if cloudProvider == vsphereparavirtual.RegisteredProviderName {
 			pathsToMonitor = append(pathsToMonitor, SupervisorServiceAccountPath)
 		}
-		watch, stop, err := initializeWatch(completedConfig, pathsToMonitor)
+		stop := make(chan struct{})
+		watch, err := initializeWatch(pathsToMonitor, func(path string) {
+			// a cloud-config directory reports the changes of its fragments
+			if path == cloudConfig || filepath.Dir(path) == filepath.Clean(cloudConfig) {
+				if r, ok := cloud.(reloader); ok {
+					reloadCloudConfig(r, cloudConfig)
+					return
+				}
+			}
+			klog.Fatalf("restarting pod because %s changed\n", path)
+		})
 		if err != nil {
 			klog.Fatalf("fail to initialize watch on config map %s: %v\n", cloudConfig, err)
 		}
@@ -245,6 +267,36 @@ func main() {
 	}
 }
 
+// printEffectiveCloudConfig prints the effective cloud-config and exits. The
+// keys of a cloud-config directory are annotated with their fragment.
+func printEffectiveCloudConfig(path string) {
+	var byConfig []byte
+	var fragments *ccfg.Fragments
+	var err error
+	if ccfg.IsConfigDir(path) {
+		fragments, err = ccfg.ReadConfigDir(path)
+		if fragments != nil {
+			byConfig = fragments.Data
+		}
+	} else {
+		byConfig, err = os.ReadFile(path)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	effective, err := ccfg.EffectiveConfig(byConfig)
+	if err == nil && fragments != nil {
+		effective, err = ccfg.AnnotateOwners(effective, fragments.Owners)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	_, _ = os.Stdout.Write(effective)
+	os.Exit(0)
+}
+
 // shouldEnableRouteController decides whether it should enable the routable pod controller
 // returns true if CPI is running under paravirtual mode and flag value contains `route`
 func shouldEnableRouteController(controllersFlag, cloudProviderFlag *pflag.Value) bool {
@@ -254,11 +306,42 @@ func shouldEnableRouteController(controllersFlag, cloudProviderFlag *pflag.Value
 		vsphereparavirtual.RegisteredProviderName == (*cloudProviderFlag).String()
 }
 
+// reloader is implemented by cloud providers that can apply a changed
+// cloud-config without a restart.
+type reloader interface {
+	Reload(byConfig []byte) error
+}
+
+// readCloudConfig reads the cloud-config file, or merges the fragments of the
+// cloud-config directory.
+func readCloudConfig(path string) ([]byte, error) {
+	if !ccfg.IsConfigDir(path) {
+		return os.ReadFile(path)
+	}
+	fragments, err := ccfg.ReadConfigDir(path)
+	if err != nil {
+		return nil, err
+	}
+	return fragments.Data, nil
+}
+
+// reloadCloudConfig reads the cloud-config and hands it to the cloud provider.
+// The cloud provider keeps its current config if the new one is invalid.
+func reloadCloudConfig(r reloader, path string) {
+	byConfig, err := readCloudConfig(path)
+	if err != nil {
+		klog.Warningf("fail to read cloud config file %s, keeping the current config: %v\n", path, err)
+		return
+	}
+	if err := r.Reload(byConfig); err != nil {
+		klog.Warningf("fail to reload cloud config file %s: %v\n", path, err)
+	}
+}
+
 // set up a filesystem watcher for the mounted files
 // which include cloud-config and projected service account.
-// reboot the app whenever there is an update via the returned stopCh.
-func initializeWatch(_ *appconfig.CompletedConfig, paths []string) (watch *fsnotify.Watcher, stopCh chan struct{}, err error) {
-	stopCh = make(chan struct{})
+// onChange is called with the watched path whenever its content changes.
+func initializeWatch(paths []string, onChange func(path string)) (watch *fsnotify.Watcher, err error) {
 	watch, err = fsnotify.NewWatcher()
 	if err != nil {
 		klog.Fatalln("fail to setup config watcher")
@@ -269,12 +352,19 @@ func initializeWatch(_ *appconfig.CompletedConfig, paths []string) (watch *fsnot
 			case err := <-watch.Errors:
 				klog.Warningf("watcher receives err: %v\n", err)
 			case event := <-watch.Events:
-				if event.Op != fsnotify.Chmod {
-					klog.Fatalf("restarting pod because received event %v\n", event)
-					stopCh <- struct{}{}
-				} else {
+				if event.Op == fsnotify.Chmod {
 					klog.V(5).Infof("watcher receives %s on the mounted file %s\n", event.Op.String(), event.Name)
+					continue
 				}
+				klog.V(2).Infof("watcher receives %s on the mounted file %s\n", event.Op.String(), event.Name)
+				// ConfigMap and Secret volumes replace the file on update, which
+				// removes the watch on the previous one
+				if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && isWatched(paths, event.Name) {
+					if err := watch.Add(event.Name); err != nil {
+						klog.Warningf("fail to watch %s again: %v\n", event.Name, err)
+					}
+				}
+				onChange(event.Name)
 			}
 		}
 	}()
@@ -287,11 +377,33 @@ func initializeWatch(_ *appconfig.CompletedConfig, paths []string) (watch *fsnot
 	return
 }
 
+// isWatched returns true if name is one of the watched paths, rather than a
+// file of a watched directory
+func isWatched(paths []string, name string) bool {
+	for _, p := range paths {
+		if filepath.Clean(p) == filepath.Clean(name) {
+			return true
+		}
+	}
+	return false
+}
+
 func initializeCloud(config *appconfig.CompletedConfig, cloudProvider string) cloudprovider.Interface {
 	cloudConfig := config.ComponentConfig.KubeCloudShared.CloudProvider
 
 	// initialize cloud provider with the cloud provider name and config file provided
-	cloud, err := cloudprovider.InitCloudProvider(cloudProvider, cloudConfig.CloudConfigFile)
+	var cloud cloudprovider.Interface
+	var err error
+	if ccfg.IsConfigDir(cloudConfig.CloudConfigFile) {
+		var byConfig []byte
+		byConfig, err = readCloudConfig(cloudConfig.CloudConfigFile)
+		if err != nil {
+			klog.Fatalf("Cloud config directory %s could not be read: %v", cloudConfig.CloudConfigFile, err)
+		}
+		cloud, err = cloudprovider.GetCloudProvider(cloudProvider, bytes.NewReader(byConfig))
+	} else {
+		cloud, err = cloudprovider.InitCloudProvider(cloudProvider, cloudConfig.CloudConfigFile)
+	}
 	if err != nil {
 		klog.Fatalf("Cloud provider could not be initialized: %v", err)
 	}
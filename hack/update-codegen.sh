@@ -42,3 +42,10 @@ bash "${CODEGEN_PKG}"/generate-groups.sh all \
   "$CUSTOM_RESOURCE_PACKAGE:$CUSTOM_RESOURCE_VERSION" \
   --output-base "$(dirname "${BASH_SOURCE[0]}")/../../.." \
   --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
+
+# the VSphereCloudConfig CRD of the vSphere cloud provider
+bash "${CODEGEN_PKG}"/generate-groups.sh all \
+  k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/client k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/apis \
+  cloudconfig:v1alpha1 \
+  --output-base "$(dirname "${BASH_SOURCE[0]}")/../../.." \
+  --go-header-file "${SCRIPT_ROOT}"/hack/boilerplate.go.txt
@@ -0,0 +1,322 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	ini "gopkg.in/gcfg.v1"
+	"gopkg.in/warnings.v0"
+	"gopkg.in/yaml.v2"
+
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+// cloudConfigINI holds every section read from an INI cloud-config by the
+// config packages of the cloud provider.
+type cloudConfigINI struct {
+	vcfg.CommonConfigINI
+	Nodes ccfg.NodesINI
+	lcfg.LBConfigINI
+	ncfg.NsxtConfigINI
+	rcfg.RouteConfigINI
+}
+
+// UnconvertedOption is an INI option that has no YAML equivalent and was left
+// out of the converted cloud-config.
+type UnconvertedOption struct {
+	// Option identifies the section and variable of the option
+	Option string
+	// Reason explains why the option was not converted
+	Reason string
+}
+
+func (o UnconvertedOption) String() string {
+	return fmt.Sprintf("%s: %s", o.Option, o.Reason)
+}
+
+// ConvertConfigINIToYAML converts an INI cloud-config, including the
+// LoadBalancer, LoadBalancerClass, NSXT and Route sections, to the equivalent
+// YAML cloud-config. Options that cannot be converted are returned alongside.
+// The values are converted as written, defaults are not filled in.
+func ConvertConfigINIToYAML(byConfig []byte) ([]byte, []UnconvertedOption, error) {
+	if len(byConfig) == 0 {
+		return nil, nil, fmt.Errorf("Invalid INI file")
+	}
+
+	cfg := &cloudConfigINI{}
+	err := ini.ReadStringInto(cfg, string(byConfig))
+	if fatal := ini.FatalOnly(err); fatal != nil {
+		return nil, nil, fatal
+	}
+
+	c := &converter{}
+	for _, w := range warnings.WarningsOnly(err) {
+		c.skip(strings.TrimPrefix(w.Error(), "can't store data at "), "unknown option, ignored by the cloud provider")
+	}
+
+	out := yaml.MapSlice{}
+	out = add(out, "global", c.global(&cfg.Global))
+	out = add(out, "vcenter", c.vcenters(cfg.VirtualCenter))
+	out = add(out, "labels", yaml.MapSlice{
+		{Key: "zone", Value: cfg.Labels.Zone},
+		{Key: "region", Value: cfg.Labels.Region},
+	})
+	out = add(out, "nodes", nodes(&cfg.Nodes))
+	out = add(out, "credentialProvider", credentialProvider(&cfg.CredentialProvider))
+	out = add(out, "loadBalancer", c.loadBalancer(&cfg.LoadBalancer))
+	if len(cfg.LoadBalancerClass) > 0 {
+		// not compacted, an empty class selects the defaults of the LoadBalancer section
+		out = append(out, yaml.MapItem{Key: "loadBalancerClass", Value: loadBalancerClasses(cfg.LoadBalancerClass)})
+	}
+	out = add(out, "nsxt", nsxt(&cfg.NSXT))
+	out = add(out, "route", yaml.MapSlice{
+		{Key: "enabled", Value: cfg.Route.Enabled},
+		{Key: "routerPath", Value: cfg.Route.RouterPath},
+	})
+
+	byYAML, err := yaml.Marshal(out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return byYAML, c.skipped, nil
+}
+
+// converter collects the options that cannot be converted
+type converter struct {
+	skipped []UnconvertedOption
+}
+
+func (c *converter) skip(option, reason string) {
+	c.skipped = append(c.skipped, UnconvertedOption{Option: option, Reason: reason})
+}
+
+// port converts a port to the numeric form required by YAML
+func (c *converter) port(section, port string) interface{} {
+	if port == "" {
+		return nil
+	}
+	p, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		c.skip(section+`, variable "port"`, fmt.Sprintf("%q is not a port number", port))
+		return nil
+	}
+	return p
+}
+
+func (c *converter) global(g *vcfg.GlobalINI) yaml.MapSlice {
+	const section = `section "Global"`
+	return yaml.MapSlice{
+		{Key: "user", Value: g.User},
+		{Key: "password", Value: g.Password},
+		{Key: "server", Value: g.VCenterIP},
+		{Key: "port", Value: c.port(section, g.VCenterPort)},
+		{Key: "insecureFlag", Value: g.InsecureFlag},
+		{Key: "datacenters", Value: splitList(g.Datacenters)},
+		{Key: "vmFolders", Value: splitList(g.VMFolders)},
+		{Key: "resourcePools", Value: splitList(g.ResourcePools)},
+		{Key: "soapRoundtripCount", Value: g.RoundTripperCount},
+		{Key: "apiQPS", Value: g.APIQPS},
+		{Key: "apiBurst", Value: g.APIBurst},
+		{Key: "maxConcurrentRequests", Value: g.MaxConcurrentRequests},
+		{Key: "caFile", Value: g.CAFile},
+		{Key: "thumbprint", Value: g.Thumbprint},
+		{Key: "secretName", Value: g.SecretName},
+		{Key: "secretNamespace", Value: g.SecretNamespace},
+		{Key: "secretsDirectory", Value: g.SecretsDirectory},
+		{Key: "apiDisable", Value: g.APIDisable},
+		{Key: "apiBinding", Value: g.APIBinding},
+		{Key: "ipFamily", Value: splitList(g.IPFamily)},
+	}
+}
+
+func (c *converter) vcenters(vcenters map[string]*vcfg.VirtualCenterConfigINI) yaml.MapSlice {
+	names := make([]string, 0, len(vcenters))
+	for name := range vcenters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := yaml.MapSlice{}
+	for _, name := range names {
+		vc := vcenters[name]
+		// in the INI format the section name is the server unless it is set
+		server := vc.VCenterIP
+		if server == "" {
+			server = name
+		}
+		section := fmt.Sprintf("section \"VirtualCenter\", subsection %q", name)
+		out = add(out, name, yaml.MapSlice{
+			{Key: "server", Value: server},
+			{Key: "user", Value: vc.User},
+			{Key: "password", Value: vc.Password},
+			{Key: "port", Value: c.port(section, vc.VCenterPort)},
+			{Key: "insecureFlag", Value: vc.InsecureFlag},
+			{Key: "datacenters", Value: splitList(vc.Datacenters)},
+			{Key: "vmFolders", Value: splitList(vc.VMFolders)},
+			{Key: "resourcePools", Value: splitList(vc.ResourcePools)},
+			{Key: "soapRoundtripCount", Value: vc.RoundTripperCount},
+			{Key: "apiQPS", Value: vc.APIQPS},
+			{Key: "apiBurst", Value: vc.APIBurst},
+			{Key: "maxConcurrentRequests", Value: vc.MaxConcurrentRequests},
+			{Key: "caFile", Value: vc.CAFile},
+			{Key: "thumbprint", Value: vc.Thumbprint},
+			{Key: "secretName", Value: vc.SecretName},
+			{Key: "secretNamespace", Value: vc.SecretNamespace},
+			{Key: "secretKeyPrefix", Value: vc.SecretKeyPrefix},
+			{Key: "ipFamily", Value: splitList(vc.IPFamily)},
+		})
+	}
+	return out
+}
+
+func nodes(n *ccfg.NodesINI) yaml.MapSlice {
+	return yaml.MapSlice{
+		{Key: "internalNetworkSubnetCidr", Value: n.InternalNetworkSubnetCIDR},
+		{Key: "externalNetworkSubnetCidr", Value: n.ExternalNetworkSubnetCIDR},
+		{Key: "internalVmNetworkName", Value: n.InternalVMNetworkName},
+		{Key: "externalVmNetworkName", Value: n.ExternalVMNetworkName},
+		{Key: "excludeInternalNetworkSubnetCidr", Value: n.ExcludeInternalNetworkSubnetCIDR},
+		{Key: "excludeExternalNetworkSubnetCidr", Value: n.ExcludeExternalNetworkSubnetCIDR},
+	}
+}
+
+func credentialProvider(p *vcfg.CredentialProviderINI) yaml.MapSlice {
+	return yaml.MapSlice{
+		{Key: "type", Value: p.Type},
+		{Key: "command", Value: p.Command},
+		{Key: "args", Value: p.Args},
+		{Key: "timeout", Value: p.Timeout},
+		{Key: "usernameFile", Value: p.UsernameFile},
+		{Key: "passwordFile", Value: p.PasswordFile},
+	}
+}
+
+func loadBalancerClass(class *lcfg.LoadBalancerClassConfigINI) yaml.MapSlice {
+	return yaml.MapSlice{
+		{Key: "ipPoolName", Value: class.IPPoolName},
+		{Key: "ipPoolId", Value: class.IPPoolID},
+		{Key: "ipv6PoolName", Value: class.IPv6PoolName},
+		{Key: "ipv6PoolId", Value: class.IPv6PoolID},
+		{Key: "tcpAppProfileName", Value: class.TCPAppProfileName},
+		{Key: "tcpAppProfilePath", Value: class.TCPAppProfilePath},
+		{Key: "udpAppProfileName", Value: class.UDPAppProfileName},
+		{Key: "udpAppProfilePath", Value: class.UDPAppProfilePath},
+	}
+}
+
+func (c *converter) loadBalancer(lb *lcfg.LoadBalancerConfigINI) yaml.MapSlice {
+	var tags map[string]string
+	if lb.RawTags != "" {
+		if err := json.Unmarshal([]byte(lb.RawTags), &tags); err != nil {
+			c.skip(`section "LoadBalancer", variable "tags"`, fmt.Sprintf("tags are not a JSON object of strings: %v", err))
+		}
+	}
+	out := yaml.MapSlice{
+		{Key: "enabled", Value: lb.Enabled},
+		{Key: "size", Value: lb.Size},
+		{Key: "lbServiceId", Value: lb.LBServiceID},
+		{Key: "tier1GatewayPath", Value: lb.Tier1GatewayPath},
+		{Key: "snatDisabled", Value: lb.SnatDisabled},
+		{Key: "tags", Value: tags},
+	}
+	return append(out, loadBalancerClass(&lb.LoadBalancerClassConfigINI)...)
+}
+
+func loadBalancerClasses(classes map[string]*lcfg.LoadBalancerClassConfigINI) yaml.MapSlice {
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := yaml.MapSlice{}
+	for _, name := range names {
+		out = append(out, yaml.MapItem{Key: name, Value: compact(loadBalancerClass(classes[name]))})
+	}
+	return out
+}
+
+func nsxt(n *ncfg.NsxtINI) yaml.MapSlice {
+	return yaml.MapSlice{
+		{Key: "user", Value: n.User},
+		{Key: "password", Value: n.Password},
+		{Key: "host", Value: n.Host},
+		{Key: "insecureFlag", Value: n.InsecureFlag},
+		{Key: "remoteAuth", Value: n.RemoteAuth},
+		{Key: "secretName", Value: n.SecretName},
+		{Key: "secretNamespace", Value: n.SecretNamespace},
+		{Key: "vmcAccessToken", Value: n.VMCAccessToken},
+		{Key: "vmcAuthHost", Value: n.VMCAuthHost},
+		{Key: "clientAuthCertFile", Value: n.ClientAuthCertFile},
+		{Key: "clientAuthKeyFile", Value: n.ClientAuthKeyFile},
+		{Key: "caFile", Value: n.CAFile},
+	}
+}
+
+// add appends the value to the map unless it is unset
+func add(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	if slice, ok := value.(yaml.MapSlice); ok {
+		value = compact(slice)
+	}
+	if isZero(value) {
+		return m
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+// compact removes the unset values of the map
+func compact(m yaml.MapSlice) yaml.MapSlice {
+	out := yaml.MapSlice{}
+	for _, item := range m {
+		out = add(out, item.Key.(string), item.Value)
+	}
+	return out
+}
+
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// splitList splits a comma separated INI value
+func splitList(value string) []string {
+	var out []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
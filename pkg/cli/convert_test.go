@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+const fullConfigINI = `
+[Global]
+port = 443
+insecure-flag = true
+datacenters = "dc1,dc2"
+secret-name = vsphere-creds
+secret-namespace = kube-system
+api-qps = 10
+unknown-option = foo
+
+[VirtualCenter "10.0.0.1"]
+max-concurrent-requests = 5
+
+[VirtualCenter "tenant2"]
+server = 10.0.0.2
+port = 8443
+ip-family = ipv4,ipv6
+secret-key-prefix = vc2
+
+[Labels]
+zone = k8s-zone
+region = k8s-region
+
+[Nodes]
+internal-network-subnet-cidr = 192.0.2.0/24
+exclude-external-network-subnet-cidr = "192.1.2.0/24,fe80::2/128"
+
+[LoadBalancer]
+ip-pool-name = pool1
+size = MEDIUM
+lb-service-id = lbs1
+tcp-app-profile-name = default-tcp
+udp-app-profile-name = default-udp
+tags = {\"owner\": \"team-a\"}
+
+[LoadBalancerClass "public"]
+ip-pool-name = pool2
+
+[LoadBalancerClass "default"]
+
+[NSXT]
+user = admin
+password = secret
+host = nsxt.local
+insecure-flag = true
+
+[Route]
+router-path = /infra/tier-1s/t1
+`
+
+func TestConvertConfigINIToYAML(t *testing.T) {
+	byYAML, skipped, err := ConvertConfigINIToYAML([]byte(fullConfigINI))
+	if err != nil {
+		t.Fatalf("ConvertConfigINIToYAML failed: %v", err)
+	}
+	if len(skipped) != 1 || skipped[0].Option != `section "Global", variable "unknown-option"` {
+		t.Errorf("Expected unknown-option to be reported, got %v", skipped)
+	}
+
+	cpiINI, err := ccfg.ReadCPIConfigINI([]byte(fullConfigINI))
+	if err != nil {
+		t.Fatalf("ReadCPIConfigINI failed: %v", err)
+	}
+	cpiYAML, err := ccfg.ReadCPIConfigYAML(byYAML)
+	if err != nil {
+		t.Fatalf("ReadCPIConfigYAML failed on:\n%s\n%v", byYAML, err)
+	}
+	if !reflect.DeepEqual(cpiINI, cpiYAML) {
+		t.Errorf("CPI config differs:\nINI:  %+v\nYAML: %+v", cpiINI, cpiYAML)
+	}
+
+	lbINI, err := lcfg.ReadConfigINI([]byte(fullConfigINI))
+	if err != nil {
+		t.Fatalf("lb ReadConfigINI failed: %v", err)
+	}
+	lbYAML, err := lcfg.ReadConfigYAML(byYAML)
+	if err != nil {
+		t.Fatalf("lb ReadConfigYAML failed: %v", err)
+	}
+	if !reflect.DeepEqual(lbINI, lbYAML) {
+		t.Errorf("LB config differs:\nINI:  %+v\nYAML: %+v", lbINI, lbYAML)
+	}
+
+	nsxtINI, err := ncfg.ReadConfigINI([]byte(fullConfigINI))
+	if err != nil {
+		t.Fatalf("nsxt ReadConfigINI failed: %v", err)
+	}
+	nsxtYAML, err := ncfg.ReadConfigYAML(byYAML)
+	if err != nil {
+		t.Fatalf("nsxt ReadConfigYAML failed: %v", err)
+	}
+	if !reflect.DeepEqual(nsxtINI, nsxtYAML) {
+		t.Errorf("NSXT config differs:\nINI:  %+v\nYAML: %+v", nsxtINI, nsxtYAML)
+	}
+
+	routeINI, err := rcfg.ReadConfigINI([]byte(fullConfigINI))
+	if err != nil {
+		t.Fatalf("route ReadConfigINI failed: %v", err)
+	}
+	routeYAML, err := rcfg.ReadConfigYAML(byYAML)
+	if err != nil {
+		t.Fatalf("route ReadConfigYAML failed: %v", err)
+	}
+	if !reflect.DeepEqual(routeINI, routeYAML) {
+		t.Errorf("Route config differs:\nINI:  %+v\nYAML: %+v", routeINI, routeYAML)
+	}
+}
+
+func TestConvertConfigINIToYAMLUnconvertible(t *testing.T) {
+	_, skipped, err := ConvertConfigINIToYAML([]byte(`
+[VirtualCenter "10.0.0.1"]
+port = https
+
+[LoadBalancer]
+tags = owner=team-a
+`))
+	if err != nil {
+		t.Fatalf("ConvertConfigINIToYAML failed: %v", err)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("Expected port and tags to be reported, got %v", skipped)
+	}
+
+	if _, _, err := ConvertConfigINIToYAML([]byte("[Global\nuser = x")); err == nil {
+		t.Error("Expected an error on invalid INI")
+	}
+}
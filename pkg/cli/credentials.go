@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	clientset "k8s.io/client-go/kubernetes"
+
+	cm "k8s.io/cloud-provider-vsphere/pkg/common/credentialmanager"
+)
+
+// CredentialsSource selects where the vCenter credentials are read from. Exactly
+// one of SecretFile, SecretsDirectory or SecretName must be set.
+type CredentialsSource struct {
+	// SecretFile is the path of a Secret manifest
+	SecretFile string
+	// SecretsDirectory is a directory with one file per secret key
+	SecretsDirectory string
+	// SecretName and SecretNamespace select a Secret in the cluster
+	SecretName      string
+	SecretNamespace string
+	// Kubeconfig is used to read SecretName, in-cluster config if empty
+	Kubeconfig string
+}
+
+// ReadCredentialsData returns the raw content of the credentials secret.
+func ReadCredentialsData(ctx context.Context, source *CredentialsSource) (map[string][]byte, error) {
+	set := 0
+	for _, s := range []string{source.SecretFile, source.SecretsDirectory, source.SecretName} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one of --secret-file, --secrets-directory or --secret-name must be set")
+	}
+
+	switch {
+	case source.SecretFile != "":
+		return readSecretFile(source.SecretFile)
+	case source.SecretsDirectory != "":
+		return readSecretsDirectory(source.SecretsDirectory)
+	default:
+		return readClusterSecret(ctx, source)
+	}
+}
+
+// ValidateCredentials returns the validation report of the credentials secret.
+// The report contains key and server names only.
+func ValidateCredentials(ctx context.Context, source *CredentialsSource) (*cm.ValidationReport, error) {
+	data, err := ReadCredentialsData(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return cm.ValidateSecretData(data), nil
+}
+
+// readSecretFile decodes a Secret manifest, including its stringData
+func readSecretFile(path string) (map[string][]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{}
+	if err := yaml.Unmarshal(content, secret); err != nil {
+		return nil, fmt.Errorf("failed to decode secret %s: %v", path, err)
+	}
+	data := map[string][]byte{}
+	for key, value := range secret.Data {
+		data[key] = value
+	}
+	for key, value := range secret.StringData {
+		data[key] = []byte(value)
+	}
+	return data, nil
+}
+
+// readSecretsDirectory reads a directory the way it is mounted from a secret
+func readSecretsDirectory(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		data[entry.Name()] = content
+	}
+	return data, nil
+}
+
+// newClientset returns a client of the cluster of kubeconfig, the default
+// kubeconfig or in-cluster config if kubeconfig is empty
+func newClientset(kubeconfig string) (clientset.Interface, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return clientset.NewForConfig(config)
+}
+
+// readClusterSecret reads the secret from the cluster
+func readClusterSecret(ctx context.Context, source *CredentialsSource) (map[string][]byte, error) {
+	client, err := newClientset(source.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	namespace := source.SecretNamespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, source.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateCredentialsSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret.yaml")
+	manifest := `apiVersion: v1
+kind: Secret
+metadata:
+  name: vsphere-cloud-secret
+data:
+  10.0.0.1.username: dXNlcg==
+stringData:
+  10.0.0.1.password: password
+  10.0.0.2.username: user
+`
+	if err := os.WriteFile(secretFile, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ValidateCredentials(context.Background(), &CredentialsSource{SecretFile: secretFile})
+	if err != nil {
+		t.Fatalf("ValidateCredentials failed: %v", err)
+	}
+	if s := report.Server("10.0.0.1"); s == nil || len(s.MissingKeys()) != 0 {
+		t.Errorf("Expected complete credentials for 10.0.0.1, got %+v", s)
+	}
+	if s := report.Server("10.0.0.2"); s == nil || !s.HasUsername || s.HasPassword {
+		t.Errorf("Expected missing password for 10.0.0.2, got %+v", s)
+	}
+	if report.Valid() {
+		t.Error("Expected report to be invalid")
+	}
+}
+
+func TestValidateCredentialsSecretsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for key, value := range map[string]string{"10.0.0.1.username": "user", "10.0.0.1.password": "password"} {
+		if err := os.WriteFile(filepath.Join(dir, key), []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := ValidateCredentials(context.Background(), &CredentialsSource{SecretsDirectory: dir})
+	if err != nil {
+		t.Fatalf("ValidateCredentials failed: %v", err)
+	}
+	if !report.Valid() {
+		t.Errorf("Expected report to be valid, got %s", report)
+	}
+
+	if _, err := ValidateCredentials(context.Background(), &CredentialsSource{SecretsDirectory: dir, SecretFile: "x"}); err == nil {
+		t.Error("Expected error when several sources are set")
+	}
+}
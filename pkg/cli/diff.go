@@ -0,0 +1,333 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer"
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+// secretFields are the config fields holding credentials, their values are
+// never printed
+var secretFields = map[string]bool{
+	"Password":       true,
+	"VMCAccessToken": true,
+}
+
+// ConfigChange is a setting that differs between two cloud-configs
+type ConfigChange struct {
+	// Path is the dotted path of the setting in the parsed config, with the
+	// names of map entries such as vcenters
+	Path string
+	// Old and New are the values, empty if the setting is not set
+	Old string
+	New string
+}
+
+// ConfigRisk is a change that needs care before it is rolled out
+type ConfigRisk struct {
+	// Path is the dotted path of the setting or map entry at risk
+	Path string
+	// Message explains the effect of the change
+	Message string
+}
+
+// ConfigDiff is the semantic difference between two cloud-configs
+type ConfigDiff struct {
+	Changes []ConfigChange
+	Risks   []ConfigRisk
+	// Notes are the sections that can only be read from one of the configs,
+	// or fail with another error. They are compared as if they were not set.
+	Notes []string
+}
+
+// parsedConfig holds a cloud-config as parsed by the cloud provider. The
+// sections that cannot be read are left empty, as the cloud provider disables
+// them, with their error in errs.
+type parsedConfig struct {
+	cpi   *ccfg.CPIConfig
+	lb    *lcfg.LBConfig
+	nsxt  *ncfg.Config
+	route *rcfg.Config
+	errs  map[string]error
+}
+
+// parseConfig reads the cloud-config as written, the environment variables
+// of the process overriding its keys are ignored.
+func parseConfig(byConfig []byte) (*parsedConfig, error) {
+	cfg := &parsedConfig{errs: map[string]error{}}
+	var err error
+	if cfg.cpi, err = ccfg.ReadCPIConfigWithEnv(byConfig, vcfg.NoEnv); err != nil {
+		return nil, err
+	}
+	if cfg.lb, err = lcfg.ReadLBConfigWithEnv(byConfig, vcfg.NoEnv); err != nil {
+		cfg.lb, cfg.errs["loadBalancer"] = &lcfg.LBConfig{}, err
+	}
+	if cfg.nsxt, err = ncfg.ReadNsxtConfigWithEnv(byConfig, vcfg.NoEnv); err != nil {
+		cfg.nsxt, cfg.errs["nsxt"] = &ncfg.Config{}, err
+	}
+	if cfg.route, err = rcfg.ReadRouteConfigWithEnv(byConfig, vcfg.NoEnv); err != nil {
+		cfg.route, cfg.errs["route"] = &rcfg.Config{}, err
+	}
+	return cfg, nil
+}
+
+// settings returns the non-zero settings of the config keyed by their path
+func (cfg *parsedConfig) settings() map[string]string {
+	out := map[string]string{}
+	flatten("", reflect.ValueOf(cfg.cpi), out)
+	flatten("", reflect.ValueOf(cfg.lb), out)
+	flatten("NSXT", reflect.ValueOf(cfg.nsxt), out)
+	flatten("", reflect.ValueOf(cfg.route), out)
+	return out
+}
+
+func flatten(path string, v reflect.Value, out map[string]string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			flatten(path, v.Elem(), out)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			switch {
+			case !field.IsExported():
+			case field.Anonymous:
+				flatten(path, v.Field(i), out)
+			case secretFields[field.Name] && !v.Field(i).IsZero():
+				out[joinPath(path, field.Name)] = vcfg.Redacted
+			default:
+				flatten(joinPath(path, field.Name), v.Field(i), out)
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			flatten(joinPath(path, fmt.Sprint(key.Interface())), v.MapIndex(key), out)
+		}
+	case reflect.Slice:
+		if v.Len() > 0 {
+			items := make([]string, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, fmt.Sprint(v.Index(i).Interface()))
+			}
+			out[path] = strings.Join(items, ",")
+		}
+	default:
+		if !v.IsZero() {
+			out[path] = fmt.Sprint(v.Interface())
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// DiffConfig returns the semantic difference between two cloud-configs, parsed
+// with the readers of the cloud provider. services are the Services of the
+// cluster, used to tell whether a removed load balancer class is still in use.
+// With nil services every removed class is a risk.
+func DiffConfig(oldConfig, newConfig []byte, services []corev1.Service) (*ConfigDiff, error) {
+	oldCfg, err := parseConfig(oldConfig)
+	if err != nil {
+		return nil, fmt.Errorf("old: %v", err)
+	}
+	newCfg, err := parseConfig(newConfig)
+	if err != nil {
+		return nil, fmt.Errorf("new: %v", err)
+	}
+
+	diff := &ConfigDiff{}
+	for _, section := range []string{"loadBalancer", "nsxt", "route"} {
+		oldErr, newErr := oldCfg.errs[section], newCfg.errs[section]
+		switch {
+		case oldErr != nil && (newErr == nil || newErr.Error() != oldErr.Error()):
+			diff.Notes = append(diff.Notes, fmt.Sprintf("old %s not read: %v", section, oldErr))
+			if newErr != nil {
+				diff.Notes = append(diff.Notes, fmt.Sprintf("new %s not read: %v", section, newErr))
+			}
+		case newErr != nil && oldErr == nil:
+			diff.Notes = append(diff.Notes, fmt.Sprintf("new %s not read: %v", section, newErr))
+		}
+	}
+
+	oldSettings, newSettings := oldCfg.settings(), newCfg.settings()
+	paths := map[string]bool{}
+	for path := range oldSettings {
+		paths[path] = true
+	}
+	for path := range newSettings {
+		paths[path] = true
+	}
+	for path := range paths {
+		if oldSettings[path] != newSettings[path] {
+			diff.Changes = append(diff.Changes, ConfigChange{Path: path, Old: oldSettings[path], New: newSettings[path]})
+		}
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Path < diff.Changes[j].Path })
+
+	diff.checkVCenters(oldCfg.cpi, newCfg.cpi)
+	diff.checkLabels(oldCfg.cpi, newCfg.cpi)
+	diff.checkLoadBalancer(oldCfg.lb, newCfg.lb, services)
+	if oldCfg.route.IsEnabled() && !newCfg.route.IsEnabled() {
+		diff.risk("Route", "routes are disabled, the pod routes of the nodes are no longer reconciled")
+	}
+	return diff, nil
+}
+
+func (d *ConfigDiff) risk(path, format string, args ...interface{}) {
+	d.Risks = append(d.Risks, ConfigRisk{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkVCenters flags the vCenters that are removed or moved to another tenant
+func (d *ConfigDiff) checkVCenters(oldCfg, newCfg *ccfg.CPIConfig) {
+	newTenants := map[string]string{}
+	for tenantRef, vc := range newCfg.VirtualCenter {
+		newTenants[vc.VCenterIP] = tenantRef
+	}
+
+	tenantRefs := make([]string, 0, len(oldCfg.VirtualCenter))
+	for tenantRef := range oldCfg.VirtualCenter {
+		tenantRefs = append(tenantRefs, tenantRef)
+	}
+	sort.Strings(tenantRefs)
+	for _, tenantRef := range tenantRefs {
+		server := oldCfg.VirtualCenter[tenantRef].VCenterIP
+		newTenantRef, ok := newTenants[server]
+		switch {
+		case !ok:
+			d.risk(joinPath("VirtualCenter", tenantRef), "vCenter %s is removed, its nodes are no longer found and may be deleted", server)
+		case newTenantRef != tenantRef:
+			d.risk(joinPath("VirtualCenter", newTenantRef), "the TenantRef of vCenter %s changes from %s to %s, which changes how its nodes and credentials are looked up",
+				server, tenantRef, newTenantRef)
+		}
+	}
+}
+
+// checkLabels flags the changed zone and region tag categories
+func (d *ConfigDiff) checkLabels(oldCfg, newCfg *ccfg.CPIConfig) {
+	if oldCfg.Labels.Zone != newCfg.Labels.Zone {
+		d.risk("Labels.Zone", "the zones are read from the tag category %q instead of %q, the topology labels of the registered nodes are not updated",
+			newCfg.Labels.Zone, oldCfg.Labels.Zone)
+	}
+	if oldCfg.Labels.Region != newCfg.Labels.Region {
+		d.risk("Labels.Region", "the regions are read from the tag category %q instead of %q, the topology labels of the registered nodes are not updated",
+			newCfg.Labels.Region, oldCfg.Labels.Region)
+	}
+}
+
+// checkLoadBalancer flags a disabled load balancer and the removed classes
+// still used by Services
+func (d *ConfigDiff) checkLoadBalancer(oldCfg, newCfg *lcfg.LBConfig, services []corev1.Service) {
+	if !oldCfg.IsEnabled() {
+		return
+	}
+	if !newCfg.IsEnabled() {
+		d.risk("LoadBalancer", "the load balancer is disabled, Services of type LoadBalancer are no longer reconciled")
+		return
+	}
+
+	users := LoadBalancerClassUsers(services)
+	classes := make([]string, 0, len(oldCfg.LoadBalancerClass))
+	for name := range oldCfg.LoadBalancerClass {
+		classes = append(classes, name)
+	}
+	sort.Strings(classes)
+	for _, name := range classes {
+		if _, ok := newCfg.LoadBalancerClass[name]; ok {
+			continue
+		}
+		path := joinPath("LoadBalancerClass", name)
+		switch {
+		case services == nil:
+			d.risk(path, "load balancer class %s is removed, the Services using it can no longer be updated", name)
+		case len(users[name]) > 0:
+			d.risk(path, "load balancer class %s is removed but still used by the Services %s", name, strings.Join(users[name], ", "))
+		}
+	}
+}
+
+// LoadBalancerClassUsers returns the Services of type LoadBalancer, as
+// namespace/name, keyed by the load balancer class they use
+func LoadBalancerClassUsers(services []corev1.Service) map[string][]string {
+	users := map[string][]string{}
+	for i := range services {
+		service := &services[i]
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		class := strings.TrimSpace(service.Annotations[loadbalancer.LoadBalancerClassAnnotation])
+		if class == "" {
+			class = lcfg.DefaultLoadBalancerClass
+		}
+		users[class] = append(users[class], service.Namespace+"/"+service.Name)
+	}
+	return users
+}
+
+// ListServices returns the Services of all namespaces of the cluster
+func ListServices(ctx context.Context, kubeconfig string) ([]corev1.Service, error) {
+	client, err := newClientset(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// String returns the changes, then the risks and notes
+func (d *ConfigDiff) String() string {
+	var b strings.Builder
+	for _, change := range d.Changes {
+		switch {
+		case change.Old == "":
+			fmt.Fprintf(&b, "+ %s: %q\n", change.Path, change.New)
+		case change.New == "":
+			fmt.Fprintf(&b, "- %s: %q\n", change.Path, change.Old)
+		default:
+			fmt.Fprintf(&b, "~ %s: %q -> %q\n", change.Path, change.Old, change.New)
+		}
+	}
+	for _, risk := range d.Risks {
+		fmt.Fprintf(&b, "RISK %s: %s\n", risk.Path, risk.Message)
+	}
+	for _, note := range d.Notes {
+		fmt.Fprintf(&b, "NOTE %s\n", note)
+	}
+	return b.String()
+}
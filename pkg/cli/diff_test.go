@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer"
+)
+
+const diffOldConfig = `
+global:
+  port: 443
+  user: user
+  password: old-password
+  insecureFlag: true
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc1
+  tenant-b:
+    server: 10.0.0.2
+    datacenters:
+      - dc2
+labels:
+  zone: k8s-zone
+  region: k8s-region
+loadBalancer:
+  size: SMALL
+  lbServiceId: lbs
+  ipPoolName: pool
+  tcpAppProfileName: default-tcp-lb-app-profile
+  udpAppProfileName: default-udp-lb-app-profile
+loadBalancerClass:
+  public:
+    ipPoolName: public-pool
+  private:
+    ipPoolName: private-pool
+nsxt:
+  host: nsxt.example.com
+  user: admin
+  password: secret
+`
+
+func newDiffConfig() string {
+	config := strings.Replace(diffOldConfig, "old-password", "new-password", 1)
+	config = strings.Replace(config, "  tenant-a:\n", "  site-a:\n", 1)
+	config = strings.Replace(config, "      - dc2\n", "      - dc2\n      - dc3\n", 1)
+	config = strings.Replace(config, "zone: k8s-zone", "zone: k8s-site", 1)
+	return strings.Replace(config, "  private:\n    ipPoolName: private-pool\n", "", 1)
+}
+
+func TestDiffConfig(t *testing.T) {
+	diff, err := DiffConfig([]byte(diffOldConfig), []byte(newDiffConfig()), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changes := map[string]ConfigChange{}
+	for _, change := range diff.Changes {
+		changes[change.Path] = change
+	}
+	if c := changes["VirtualCenter.tenant-b.Datacenters"]; c.Old != "dc2" || c.New != "dc2,dc3" {
+		t.Errorf("unexpected datacenters change: %+v", c)
+	}
+	if c := changes["VirtualCenter.site-a.VCenterIP"]; c.Old != "" || c.New != "10.0.0.1" {
+		t.Errorf("unexpected site-a change: %+v", c)
+	}
+	if c, ok := changes["LoadBalancerClass.private.IPPoolName"]; !ok || c.New != "" {
+		t.Errorf("unexpected private class change: %+v", c)
+	}
+	if _, ok := changes["NSXT.Host"]; ok {
+		t.Errorf("unchanged settings are not listed: %v", diff.Changes)
+	}
+	if strings.Contains(diff.String(), "password") {
+		t.Errorf("credentials are printed:\n%s", diff)
+	}
+
+	risks := map[string]string{}
+	for _, risk := range diff.Risks {
+		risks[risk.Path] = risk.Message
+	}
+	for path, message := range map[string]string{
+		"VirtualCenter.site-a":      "TenantRef of vCenter 10.0.0.1 changes from tenant-a to site-a",
+		"Labels.Zone":               `"k8s-site" instead of "k8s-zone"`,
+		"LoadBalancerClass.private": "Services using it",
+	} {
+		if !strings.Contains(risks[path], message) {
+			t.Errorf("expected the risk %s: %s, got %v", path, message, diff.Risks)
+		}
+	}
+	if len(diff.Risks) != 3 {
+		t.Errorf("expected 3 risks, got %v", diff.Risks)
+	}
+}
+
+func TestDiffConfigIgnoresEnv(t *testing.T) {
+	t.Setenv("VSPHERE_LABEL_ZONE", "env-zone")
+	t.Setenv("VSPHERE_VCENTER_TENANT_A_DATACENTERS", "env-dc")
+	t.Setenv("VSPHERE_VCENTER_ENV", "10.0.0.9")
+
+	diff, err := DiffConfig([]byte(diffOldConfig), []byte(newDiffConfig()), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, change := range diff.Changes {
+		if strings.Contains(change.Old+change.New, "env-") || strings.Contains(change.Path, "10.0.0.9") {
+			t.Errorf("expected the environment to be ignored, got %+v", change)
+		}
+	}
+	for _, risk := range diff.Risks {
+		if risk.Path == "Labels.Zone" && !strings.Contains(risk.Message, `"k8s-site" instead of "k8s-zone"`) {
+			t.Errorf("expected the zone of the configs, got %s", risk.Message)
+		}
+	}
+}
+
+func TestDiffConfigServices(t *testing.T) {
+	services := []corev1.Service{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "frontend", Annotations: map[string]string{
+			loadbalancer.LoadBalancerClassAnnotation: "private",
+		}},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}}
+	newConfig := strings.Replace(diffOldConfig, "  private:\n    ipPoolName: private-pool\n", "", 1)
+
+	diff, err := DiffConfig([]byte(diffOldConfig), []byte(newConfig), services)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.Risks) != 1 || !strings.Contains(diff.Risks[0].Message, "still used by the Services web/frontend") {
+		t.Errorf("expected the private class to be flagged, got %v", diff.Risks)
+	}
+
+	services[0].Spec.Type = corev1.ServiceTypeClusterIP
+	diff, err = DiffConfig([]byte(diffOldConfig), []byte(newConfig), services)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.Risks) != 0 {
+		t.Errorf("expected an unused class to be removed safely, got %v", diff.Risks)
+	}
+
+	if _, err := DiffConfig([]byte(diffOldConfig), []byte("global: ["), nil); err == nil || !strings.HasPrefix(err.Error(), "new: ") {
+		t.Errorf("expected the invalid new config to fail, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VSphereCloudConfig holds the vCenters, node address rules, load balancer
+// classes and route settings of the vSphere cloud provider. It is merged into
+// the cloud-config file, a key can only be set by one of them.
+type VSphereCloudConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VSphereCloudConfigSpec   `json:"spec"`
+	Status VSphereCloudConfigStatus `json:"status,omitempty"`
+}
+
+// VSphereCloudConfigSpec defines the desired cloud-config. The fields have the
+// names and meaning of the keys of the YAML cloud-config.
+type VSphereCloudConfigSpec struct {
+	// VCenters are the vCenters, the entries of the vcenter section.
+	VCenters []VCenter `json:"vcenters,omitempty"`
+	// Nodes are the node address rules, the nodes section.
+	Nodes *Nodes `json:"nodes,omitempty"`
+	// LoadBalancerClasses are the load balancer classes, the entries of the
+	// loadBalancerClass section.
+	LoadBalancerClasses []LoadBalancerClass `json:"loadBalancerClasses,omitempty"`
+	// Route is the route section.
+	Route *Route `json:"route,omitempty"`
+}
+
+// VCenter defines a vCenter, the credentials are read from a Secret.
+type VCenter struct {
+	// Name is the key of the vcenter entry, the tenant of its nodes.
+	Name string `json:"name"`
+	// Server is the IP address or FQDN of the vCenter.
+	Server string `json:"server"`
+	// Port is the port of the vCenter, 443 by default.
+	Port uint `json:"port,omitempty"`
+	// Datacenters are the names, paths or glob patterns of the datacenters
+	// of the nodes.
+	Datacenters []string `json:"datacenters,omitempty"`
+	// VMFolders are the VM folders the nodes are searched in.
+	VMFolders []string `json:"vmFolders,omitempty"`
+	// ResourcePools are the resource pools the nodes are searched in.
+	ResourcePools []string `json:"resourcePools,omitempty"`
+	// InsecureFlag disables the validation of the vCenter certificate.
+	InsecureFlag bool `json:"insecureFlag,omitempty"`
+	// CAFile is the path of the CA certificate of the vCenter.
+	CAFile string `json:"caFile,omitempty"`
+	// Thumbprint is the SHA-1 thumbprint of the vCenter certificate.
+	Thumbprint string `json:"thumbprint,omitempty"`
+	// SecretName is the name of the Secret with the vCenter credentials.
+	SecretName string `json:"secretName,omitempty"`
+	// SecretNamespace is the namespace of the Secret with the vCenter
+	// credentials.
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+	// IPFamily is the IP family priority of the node addresses, ipv4 and
+	// ipv6.
+	IPFamily []string `json:"ipFamily,omitempty"`
+	// Nodes selects the addresses of the nodes of this vCenter, in place of
+	// the global nodes.
+	Nodes *Nodes `json:"nodes,omitempty"`
+}
+
+// Nodes defines how the addresses of the nodes are selected.
+type Nodes struct {
+	InternalNetworkSubnetCIDR        string `json:"internalNetworkSubnetCidr,omitempty"`
+	ExternalNetworkSubnetCIDR        string `json:"externalNetworkSubnetCidr,omitempty"`
+	InternalVMNetworkName            string `json:"internalVmNetworkName,omitempty"`
+	ExternalVMNetworkName            string `json:"externalVmNetworkName,omitempty"`
+	ExcludeInternalNetworkSubnetCIDR string `json:"excludeInternalNetworkSubnetCidr,omitempty"`
+	ExcludeExternalNetworkSubnetCIDR string `json:"excludeExternalNetworkSubnetCidr,omitempty"`
+}
+
+// LoadBalancerClass defines the IP pool and application profiles of a load
+// balancer class.
+type LoadBalancerClass struct {
+	// Name is the name of the class, as used by the
+	// loadbalancer.vmware.io/class annotation of a Service.
+	Name              string `json:"name"`
+	IPPoolName        string `json:"ipPoolName,omitempty"`
+	IPPoolID          string `json:"ipPoolId,omitempty"`
+	IPv6PoolName      string `json:"ipv6PoolName,omitempty"`
+	IPv6PoolID        string `json:"ipv6PoolId,omitempty"`
+	TCPAppProfileName string `json:"tcpAppProfileName,omitempty"`
+	TCPAppProfilePath string `json:"tcpAppProfilePath,omitempty"`
+	UDPAppProfileName string `json:"udpAppProfileName,omitempty"`
+	UDPAppProfilePath string `json:"udpAppProfilePath,omitempty"`
+}
+
+// Route defines the route settings.
+type Route struct {
+	// Enabled explicitly enables or disables the routes.
+	Enabled *bool `json:"enabled,omitempty"`
+	// RouterPath is the NSX-T path of the tier-1 router of the routes.
+	RouterPath string `json:"routerPath,omitempty"`
+}
+
+// VSphereCloudConfigStatus defines the observed state of VSphereCloudConfig.
+type VSphereCloudConfigStatus struct {
+	// ObservedGeneration is the generation of the spec the status is about.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the conditions of the whole config, such as Valid.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// VCenters are the conditions of each vCenter of the spec.
+	VCenters []VCenterStatus `json:"vcenters,omitempty"`
+}
+
+// VCenterStatus defines the observed state of a vCenter.
+type VCenterStatus struct {
+	// Name is the name of the vCenter in the spec.
+	Name string `json:"name"`
+	// Conditions are the Connected, DatacentersFound and
+	// TagCategoriesResolved conditions of the vCenter.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionValid means the config is valid and in effect.
+	ConditionValid = "Valid"
+	// ConditionConnected means the vCenter is connected.
+	ConditionConnected = "Connected"
+	// ConditionDatacentersFound means all the datacenters of the vCenter are
+	// found.
+	ConditionDatacentersFound = "DatacentersFound"
+	// ConditionTagCategoriesResolved means the zone and region tag categories
+	// are found on the vCenter.
+	ConditionTagCategoriesResolved = "TagCategoriesResolved"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VSphereCloudConfigList is a list of VSphereCloudConfig.
+type VSphereCloudConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereCloudConfig `json:"items"`
+}
@@ -74,22 +74,20 @@ func init() {
 		if err != nil {
 			return nil, err
 		}
-		nsxtcfg, err := ncfg.ReadNsxtConfig(byConfig)
+		subsystems, err := readSubsystemConfigs(byConfig)
 		if err != nil {
-			klog.Errorf("ReadNsxtConfig failed: %s", err)
-			nsxtcfg = nil
-		}
-		lbcfg, err := lcfg.ReadLBConfig(byConfig)
-		if err != nil {
-			lbcfg = nil //Error reading LBConfig, explicitly set to nil
+			klog.Errorf("reading the subsystem configs failed: %s", err)
+			return nil, err
 		}
-		routecfg, err := rcfg.ReadRouteConfig(byConfig)
+		subsystems.logStates()
+
+		vs, err := newVSphere(cfg, subsystems.nsxt, subsystems.lb, subsystems.route, true)
 		if err != nil {
-			klog.Errorf("ReadRouteConfig failed: %s", err)
-			routecfg = nil
+			return nil, err
 		}
-
-		return newVSphere(cfg, nsxtcfg, lbcfg, routecfg, true)
+		vs.cfgData = byConfig
+		vs.cfgFile = byConfig
+		return vs, nil
 	})
 }
 
@@ -123,8 +121,9 @@ func (vs *VSphere) Initialize(clientBuilder cloudprovider.ControllerClientBuilde
 		klog.V(1).Info("Kubernetes Client Init Succeeded")
 
 		vs.informMgr = k8s.NewInformer(client, true)
+		vs.eventRecorder, vs.podRef = newPodEventRecorder(client)
 
-		connMgr := cm.NewConnectionManager(&vs.cfg.Config, vs.informMgr, client)
+		connMgr := cm.NewConnectionManager(&vs.config().Config, vs.informMgr, client)
 		vs.connectionManager = connMgr
 		vs.nodeManager.connectionManager = connMgr
 
@@ -146,6 +145,13 @@ func (vs *VSphere) Initialize(clientBuilder cloudprovider.ControllerClientBuilde
 
 		// if running secrets, init them
 		connMgr.InitializeSecretLister()
+
+		// report every vCenter with missing credentials once the secrets are synced
+		go reportCredentials(connMgr, vs.eventRecorder, vs.podRef, stop)
+
+		if CloudConfigName != "" {
+			vs.startCloudConfigController(clientBuilder, stop)
+		}
 	} else {
 		klog.Errorf("Kubernetes Client Init Failed: %v", err)
 	}
@@ -259,6 +265,8 @@ func buildVSphereFromConfig(cfg *ccfg.CPIConfig, nsxtcfg *ncfg.Config, lbcfg *lc
 
 	vs := VSphere{
 		cfg:              cfg,
+		cfgNSXT:          nsxtcfg,
+		cfgRoute:         routecfg,
 		cfgLB:            lbcfg,
 		nodeManager:      nm,
 		nsxtConnectorMgr: ncm,
@@ -0,0 +1,297 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cloudprovider "k8s.io/cloud-provider"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/apis/cloudconfig/v1alpha1"
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/client/clientset/versioned"
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/client/informers/externalversions"
+	listers "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/client/listers/cloudconfig/v1alpha1"
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+)
+
+// CloudConfigName is the name of the VSphereCloudConfig merged into the
+// cloud-config, none if empty.
+var CloudConfigName string
+
+const (
+	// cloudConfigResyncPeriod is the period of the vCenter checks reported in
+	// the status of the VSphereCloudConfig
+	cloudConfigResyncPeriod = 5 * time.Minute
+	// vCenterCheckTimeout bounds the checks of a vCenter
+	vCenterCheckTimeout = time.Minute
+)
+
+// cloudConfigController merges the VSphereCloudConfig named CloudConfigName
+// into the cloud-config and reports the validation and the vCenter checks in
+// its status.
+type cloudConfigController struct {
+	vs        *VSphere
+	client    versioned.Interface
+	factory   externalversions.SharedInformerFactory
+	lister    listers.VSphereCloudConfigLister
+	synced    cache.InformerSynced
+	workqueue workqueue.RateLimitingInterface
+}
+
+// newCloudConfigController returns a controller of the VSphereCloudConfig
+// named CloudConfigName.
+func newCloudConfigController(vs *VSphere, client versioned.Interface) *cloudConfigController {
+	factory := externalversions.NewSharedInformerFactoryWithOptions(client, cloudConfigResyncPeriod,
+		externalversions.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", CloudConfigName).String()
+		}))
+	informer := factory.CloudConfig().V1alpha1().VSphereCloudConfigs()
+
+	c := &cloudConfigController{
+		vs:        vs,
+		client:    client,
+		factory:   factory,
+		lister:    informer.Lister(),
+		synced:    informer.Informer().HasSynced,
+		workqueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "VSphereCloudConfigs"),
+	}
+	_, _ = informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, cur interface{}) { c.enqueue(cur) },
+		DeleteFunc: c.enqueue,
+	})
+	return c
+}
+
+// startCloudConfigController watches the VSphereCloudConfig named
+// CloudConfigName
+func (vs *VSphere) startCloudConfigController(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	restConfig, err := clientBuilder.Config(ClientName)
+	if err != nil {
+		klog.Errorf("VSphereCloudConfig %s is ignored, no client config: %v", CloudConfigName, err)
+		return
+	}
+	client, err := versioned.NewForConfig(restConfig)
+	if err != nil {
+		klog.Errorf("VSphereCloudConfig %s is ignored, no client: %v", CloudConfigName, err)
+		return
+	}
+	klog.Infof("merging VSphereCloudConfig %s into the cloud-config", CloudConfigName)
+	go newCloudConfigController(vs, client).Run(stop)
+}
+
+func (c *cloudConfigController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// Run processes the VSphereCloudConfig until stopCh is closed
+func (c *cloudConfigController) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	c.factory.Start(stopCh)
+	if !cache.WaitForNamedCacheSync("vspherecloudconfig", stopCh, c.synced) {
+		return
+	}
+	// a VSphereCloudConfig that does not exist is reported once
+	if _, err := c.lister.Get(CloudConfigName); apierrors.IsNotFound(err) {
+		klog.Warningf("VSphereCloudConfig %s not found, using the cloud-config file only", CloudConfigName)
+	}
+
+	go wait.Until(c.runWorker, time.Second, stopCh)
+	<-stopCh
+}
+
+func (c *cloudConfigController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *cloudConfigController) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.workqueue.Done(obj)
+
+	key := obj.(string)
+	if err := c.sync(key); err != nil {
+		c.workqueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing VSphereCloudConfig %s: %v, requeuing", key, err))
+		return true
+	}
+	c.workqueue.Forget(obj)
+	return true
+}
+
+// sync merges the VSphereCloudConfig name into the cloud-config, or removes it
+// once deleted, and updates its status. Only the errors of the status update
+// are returned, a rejected config is reported in the status.
+func (c *cloudConfigController) sync(name string) error {
+	cfg, err := c.lister.Get(name)
+	if apierrors.IsNotFound(err) {
+		klog.Infof("VSphereCloudConfig %s deleted, using the cloud-config file only", name)
+		return c.vs.applyCloudConfigFragment(nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	status := cfg.Status.DeepCopy()
+	status.ObservedGeneration = cfg.Generation
+	valid := metav1.Condition{Type: v1alpha1.ConditionValid, Status: metav1.ConditionTrue, Reason: "Applied",
+		Message: "the config is merged into the cloud-config and in effect", ObservedGeneration: cfg.Generation}
+	if err := c.apply(cfg); err != nil {
+		valid.Status, valid.Reason, valid.Message = metav1.ConditionFalse, "Rejected", err.Error()
+	}
+	meta.SetStatusCondition(&status.Conditions, valid)
+	status.VCenters = c.vs.checkVCenters(cfg, cfg.Status.VCenters)
+
+	if equality.Semantic.DeepEqual(status, &cfg.Status) {
+		return nil
+	}
+	update := cfg.DeepCopy()
+	update.Status = *status
+	_, err = c.client.CloudConfigV1alpha1().VSphereCloudConfigs().UpdateStatus(context.Background(), update, metav1.UpdateOptions{})
+	return err
+}
+
+// apply validates the VSphereCloudConfig merged into the cloud-config and
+// swaps it in
+func (c *cloudConfigController) apply(cfg *v1alpha1.VSphereCloudConfig) error {
+	fragment, err := ccfg.CloudConfigFragment(&cfg.Spec)
+	if err != nil {
+		return err
+	}
+	return c.vs.applyCloudConfigFragment(fragment)
+}
+
+// applyCloudConfigFragment merges the YAML fragment of the VSphereCloudConfig
+// into the cloud-config file in effect and applies the result like Reload. The
+// merged config must pass the strict validation. A nil fragment removes the
+// current one.
+func (vs *VSphere) applyCloudConfigFragment(fragment []byte) error {
+	vs.reloadLock.Lock()
+	defer vs.reloadLock.Unlock()
+
+	merged, err := vs.mergeCloudConfig(vs.cfgFile, fragment)
+	if err == nil && fragment != nil {
+		err = ccfg.ValidateStrict(merged)
+	}
+	if err == nil {
+		err = vs.apply(merged)
+	}
+	if err != nil {
+		return vs.rejectConfig(err)
+	}
+	vs.cfgFragment = fragment
+	return nil
+}
+
+// checkVCenters checks the vCenters of the VSphereCloudConfig and returns
+// their status, the conditions of current are updated
+func (vs *VSphere) checkVCenters(cfg *v1alpha1.VSphereCloudConfig, current []v1alpha1.VCenterStatus) []v1alpha1.VCenterStatus {
+	labels := vs.config().Labels
+	var categories []string
+	for _, category := range []string{labels.Zone, labels.Region} {
+		if category != "" {
+			categories = append(categories, category)
+		}
+	}
+
+	var statuses []v1alpha1.VCenterStatus
+	for _, vc := range cfg.Spec.VCenters {
+		status := v1alpha1.VCenterStatus{Name: vc.Name}
+		for i := range current {
+			if current[i].Name == vc.Name {
+				status.Conditions = append(status.Conditions, current[i].Conditions...)
+			}
+		}
+		for _, condition := range vs.checkVCenter(vc.Name, categories) {
+			condition.ObservedGeneration = cfg.Generation
+			meta.SetStatusCondition(&status.Conditions, condition)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkVCenter returns the Connected, DatacentersFound and
+// TagCategoriesResolved conditions of the vCenter tenantRef
+func (vs *VSphere) checkVCenter(tenantRef string, categories []string) []metav1.Condition {
+	conditions := []metav1.Condition{
+		{Type: v1alpha1.ConditionConnected},
+		{Type: v1alpha1.ConditionDatacentersFound},
+		{Type: v1alpha1.ConditionTagCategoriesResolved},
+	}
+	unknown := func(reason, message string) []metav1.Condition {
+		for i := range conditions {
+			conditions[i].Status, conditions[i].Reason, conditions[i].Message = metav1.ConditionUnknown, reason, message
+		}
+		return conditions
+	}
+	if vs.connectionManager == nil {
+		return unknown("NotConnected", "no connection manager, the cloud provider is not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vCenterCheckTimeout)
+	defer cancel()
+	check, err := vs.connectionManager.CheckVCenter(ctx, tenantRef, categories...)
+	if err != nil {
+		return unknown("NotInEffect", "the vCenter is not in the config in effect")
+	}
+	if check.Connect != nil {
+		conditions = unknown("NotConnected", "the vCenter is not connected")
+		setCondition(&conditions[0], check.Connect, "Connected", "ConnectFailed", "connected")
+		return conditions
+	}
+	setCondition(&conditions[0], nil, "Connected", "", "connected")
+	setCondition(&conditions[1], check.Datacenters, "Found", "NotFound", "all the datacenters are found")
+	if len(categories) == 0 {
+		conditions[2].Status, conditions[2].Reason, conditions[2].Message = metav1.ConditionTrue, "NotConfigured", "no zone or region labels are configured"
+	} else {
+		setCondition(&conditions[2], check.TagCategories, "Resolved", "NotFound", "the zone and region tag categories are found")
+	}
+	return conditions
+}
+
+// setCondition sets the condition to True with reason and message if err is
+// nil, otherwise to False with failedReason and the error as message
+func setCondition(condition *metav1.Condition, err error, reason, failedReason, message string) {
+	if err != nil {
+		condition.Status, condition.Reason, condition.Message = metav1.ConditionFalse, failedReason, err.Error()
+		return
+	}
+	condition.Status, condition.Reason, condition.Message = metav1.ConditionTrue, reason, message
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/apis/cloudconfig/v1alpha1"
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/client/clientset/versioned/fake"
+)
+
+func newTestCloudConfig() *v1alpha1.VSphereCloudConfig {
+	return &v1alpha1.VSphereCloudConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Generation: 1},
+		Spec: v1alpha1.VSphereCloudConfigSpec{
+			// nothing listens on port 1, the connection is refused
+			VCenters: []v1alpha1.VCenter{{Name: "tenant-b", Server: "127.0.0.1", Port: 1, Datacenters: []string{"dc2"}}},
+			Nodes:    &v1alpha1.Nodes{ExternalNetworkSubnetCIDR: "198.51.100.0/24"},
+		},
+	}
+}
+
+func syncCloudConfig(t *testing.T, c *cloudConfigController, client *fake.Clientset) *v1alpha1.VSphereCloudConfig {
+	t.Helper()
+	if err := c.sync("cluster"); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	cfg, err := client.CloudConfigV1alpha1().VSphereCloudConfigs().Get(context.Background(), "cluster", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestCloudConfigController(t *testing.T) {
+	CloudConfigName = "cluster"
+	defer func() { CloudConfigName = "" }()
+
+	vs, recorder := newReloadVSphere(t)
+	cfg := newTestCloudConfig()
+	client := fake.NewSimpleClientset(cfg)
+	c := newCloudConfigController(vs, client)
+	indexer := c.factory.CloudConfig().V1alpha1().VSphereCloudConfigs().Informer().GetIndexer()
+	if err := indexer.Add(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = syncCloudConfig(t, c, client)
+	expectEvents(t, recorder, ReasonConfigReloaded)
+	if !meta.IsStatusConditionTrue(cfg.Status.Conditions, v1alpha1.ConditionValid) || cfg.Status.ObservedGeneration != 1 {
+		t.Errorf("expected a valid config, got %+v", cfg.Status)
+	}
+	if vs.connectionManager.VSphereInstances()["tenant-b"] == nil {
+		t.Error("expected tenant-b to be added")
+	}
+	if cidr := vs.nodeManager.config().Nodes.ExternalNetworkSubnetCIDR; cidr != "198.51.100.0/24" {
+		t.Errorf("expected the nodes of the VSphereCloudConfig, got %s", cidr)
+	}
+	if len(cfg.Status.VCenters) != 1 {
+		t.Fatalf("expected the status of tenant-b, got %+v", cfg.Status.VCenters)
+	}
+	conditions := cfg.Status.VCenters[0].Conditions
+	if connected := meta.FindStatusCondition(conditions, v1alpha1.ConditionConnected); connected == nil || connected.Reason != "ConnectFailed" {
+		t.Errorf("expected tenant-b not to be connected, got %+v", conditions)
+	}
+	if dcs := meta.FindStatusCondition(conditions, v1alpha1.ConditionDatacentersFound); dcs == nil || dcs.Status != metav1.ConditionUnknown {
+		t.Errorf("expected the datacenters of tenant-b to be unknown, got %+v", conditions)
+	}
+
+	// the file already sets nodes.internalNetworkSubnetCidr
+	cfg.Generation = 2
+	cfg.Spec.Nodes.InternalNetworkSubnetCIDR = "203.0.113.0/24"
+	if err := indexer.Update(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg = syncCloudConfig(t, c, client)
+	expectEvents(t, recorder, ReasonConfigReloadFailed)
+	valid := meta.FindStatusCondition(cfg.Status.Conditions, v1alpha1.ConditionValid)
+	if valid == nil || valid.Status != metav1.ConditionFalse || valid.ObservedGeneration != 2 {
+		t.Errorf("expected the conflict to be rejected, got %+v", cfg.Status.Conditions)
+	}
+	if vs.connectionManager.VSphereInstances()["tenant-b"] == nil {
+		t.Error("expected the previous VSphereCloudConfig to stay in effect")
+	}
+
+	if err := indexer.Delete(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.sync("cluster"); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	expectEvents(t, recorder, ReasonConfigReloaded)
+	if vs.connectionManager.VSphereInstances()["tenant-b"] != nil {
+		t.Error("expected tenant-b to be removed with the VSphereCloudConfig")
+	}
+}
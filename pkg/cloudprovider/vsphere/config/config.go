@@ -18,9 +18,10 @@ package config
 
 import (
 	"fmt"
-	"os"
 
 	klog "k8s.io/klog/v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
 // FromCPIEnv initializes the provided configuration object with values
@@ -28,21 +29,25 @@ import (
 // for a property that's already initialized, the environment variable's value
 // takes precedence.
 func (cfg *CPIConfig) FromCPIEnv() error {
-	if err := cfg.FromEnv(); err != nil {
+	return cfg.fromCPIEnv(vcfg.OSEnv)
+}
+
+func (cfg *CPIConfig) fromCPIEnv(env vcfg.Env) error {
+	if err := cfg.FromEnvSource(env); err != nil {
 		return err
 	}
 
-	if v := os.Getenv("VSPHERE_NODES_INTERNAL_NETWORK_SUBNET_CIDR"); v != "" {
+	if v := env.Getenv("VSPHERE_NODES_INTERNAL_NETWORK_SUBNET_CIDR"); v != "" {
 		cfg.Nodes.InternalNetworkSubnetCIDR = v
 	}
-	if v := os.Getenv("VSPHERE_NODES_EXTERNAL_NETWORK_SUBNET_CIDR"); v != "" {
+	if v := env.Getenv("VSPHERE_NODES_EXTERNAL_NETWORK_SUBNET_CIDR"); v != "" {
 		cfg.Nodes.ExternalNetworkSubnetCIDR = v
 	}
 
-	if v := os.Getenv("VSPHERE_NODES_INTERNAL_VM_NETWORK_NAME"); v != "" {
+	if v := env.Getenv("VSPHERE_NODES_INTERNAL_VM_NETWORK_NAME"); v != "" {
 		cfg.Nodes.InternalVMNetworkName = v
 	}
-	if v := os.Getenv("VSPHERE_NODES_EXTERNAL_VM_NETWORK_NAME"); v != "" {
+	if v := env.Getenv("VSPHERE_NODES_EXTERNAL_VM_NETWORK_NAME"); v != "" {
 		cfg.Nodes.ExternalVMNetworkName = v
 	}
 
@@ -58,14 +63,31 @@ func (cfg *CPIConfig) FromCPIEnv() error {
 // ReadCPIConfig parses vSphere cloud config file and stores it into CPIConfig.
 // Environment variables are also checked
 func ReadCPIConfig(byConfig []byte) (*CPIConfig, error) {
+	return ReadCPIConfigWithEnv(byConfig, vcfg.OSEnv)
+}
+
+// ReadCPIConfigWithEnv parses the config like ReadCPIConfig, with the variables
+// of env overriding its keys.
+func ReadCPIConfigWithEnv(byConfig []byte, env vcfg.Env) (*CPIConfig, error) {
 	if len(byConfig) == 0 {
 		err := fmt.Errorf("no vSphere cloud provider config file given")
 		klog.Error("config is nil")
 		return nil, err
 	}
 
-	cfg, err := ReadCPIConfigYAML(byConfig)
+	if IsStrict() {
+		if err := ValidateStrict(byConfig); err != nil {
+			klog.Errorf("strict validation of the cloud-config failed: %s", err)
+			return nil, err
+		}
+	}
+
+	cfg, err := readCPIConfigYAML(byConfig, env)
 	if err != nil {
+		if IsStrict() {
+			klog.Errorf("ReadCPIConfigYAML failed: %s", err)
+			return nil, err
+		}
 		klog.Warningf("ReadCPIConfigYAML failed: %s", err)
 
 		cfg, err = ReadCPIConfigINI(byConfig)
@@ -80,7 +102,7 @@ func ReadCPIConfig(byConfig []byte) (*CPIConfig, error) {
 	}
 
 	// Env Vars should override config file entries if present
-	if err := cfg.FromCPIEnv(); err != nil {
+	if err := cfg.fromCPIEnv(env); err != nil {
 		klog.Errorf("FromEnv failed: %s", err)
 		return nil, err
 	}
@@ -88,3 +110,14 @@ func ReadCPIConfig(byConfig []byte) (*CPIConfig, error) {
 	klog.Info("Config initialized")
 	return cfg, nil
 }
+
+// NodesFor returns the nodes section used for the nodes found in the vCenter of
+// tenantRef, its own nodes block if set, the global nodes section otherwise.
+// The nodes block of a vCenter replaces the global section as a whole, it is
+// not merged per field.
+func (cfg *CPIConfig) NodesFor(tenantRef string) *Nodes {
+	if vc, ok := cfg.VirtualCenter[tenantRef]; ok && vc.Nodes != (Nodes{}) {
+		return &vc.Nodes
+	}
+	return &cfg.Nodes
+}
@@ -50,6 +50,10 @@ func (ccy *CPIConfigYAML) CreateConfig() *CPIConfig {
 
 // ReadCPIConfigYAML parses vSphere cloud config file and stores it into CPIConfigYAML.
 func ReadCPIConfigYAML(byConfig []byte) (*CPIConfig, error) {
+	return readCPIConfigYAML(byConfig, vcfg.OSEnv)
+}
+
+func readCPIConfigYAML(byConfig []byte, env vcfg.Env) (*CPIConfig, error) {
 	if len(byConfig) == 0 {
 		return nil, fmt.Errorf("Invalid YAML file")
 	}
@@ -60,9 +64,12 @@ func ReadCPIConfigYAML(byConfig []byte) (*CPIConfig, error) {
 	if err := yaml.Unmarshal(byConfig, cfgOLD); err != nil {
 		return nil, err
 	}
+	if err := env.Apply(vcfg.EnvPrefix+"_NODES", &cfgOLD.Nodes); err != nil {
+		return nil, err
+	}
 
 	// with this so that we can call the validate function within ReadRawConfigINI
-	vCFG, err := vcfg.ReadRawConfigYAML(byConfig)
+	vCFG, err := vcfg.ReadRawConfigYAMLWithEnv(byConfig, env)
 	if err != nil {
 		return nil, err
 	}
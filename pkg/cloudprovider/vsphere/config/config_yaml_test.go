@@ -73,6 +73,30 @@ nodes:
   excludeExternalNetworkSubnetCidr: "192.1.2.0/24,fe80::2/128"
 `
 
+const vcenterNodesYAMLConfig = `
+global:
+  port: 443
+  user: user
+  password: password
+  insecureFlag: true
+
+vcenter:
+  site-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc-a
+    nodes:
+      internalNetworkSubnetCidr: 10.1.0.0/16
+  site-b:
+    server: 10.0.0.2
+    datacenters:
+      - dc-b
+
+nodes:
+  internalNetworkSubnetCidr: 192.0.2.0/24
+  externalNetworkSubnetCidr: 198.51.100.0/24
+`
+
 func TestReadYAMLConfigSubnetCidr(t *testing.T) {
 	_, err := ReadCPIConfigYAML(nil)
 	if err == nil {
@@ -130,3 +154,21 @@ func TestReadYAMLConfigExcludeSubnetCidr(t *testing.T) {
 		t.Errorf("incorrect exclude external network subnet cidrs: %s", cfg.Nodes.ExcludeExternalNetworkSubnetCIDR)
 	}
 }
+
+func TestReadYAMLConfigVCenterNodes(t *testing.T) {
+	cfg, err := ReadCPIConfigYAML([]byte(vcenterNodesYAMLConfig))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	// the nodes block of site-a replaces the global nodes section as a whole
+	nodes := cfg.NodesFor("site-a")
+	if nodes.InternalNetworkSubnetCIDR != "10.1.0.0/16" || nodes.ExternalNetworkSubnetCIDR != "" {
+		t.Errorf("incorrect nodes of site-a: %+v", nodes)
+	}
+	for _, tenantRef := range []string{"site-b", "unknown"} {
+		if nodes := cfg.NodesFor(tenantRef); *nodes != cfg.Nodes {
+			t.Errorf("expected the global nodes for %s, got %+v", tenantRef, nodes)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/apis/cloudconfig/v1alpha1"
+)
+
+// CloudConfigFragment converts the spec of a VSphereCloudConfig into a YAML
+// cloud-config fragment. The fields of the spec have the names of the keys of
+// the cloud-config, the named list entries become the keys of the vcenter and
+// loadBalancerClass sections.
+func CloudConfigFragment(spec *v1alpha1.VSphereCloudConfigSpec) ([]byte, error) {
+	fragment := map[string]interface{}{}
+
+	if len(spec.VCenters) > 0 {
+		vcenters := map[string]interface{}{}
+		for i := range spec.VCenters {
+			vc := spec.VCenters[i]
+			if _, ok := vcenters[vc.Name]; ok {
+				return nil, fmt.Errorf("vcenters: %s is set twice", vc.Name)
+			}
+			name := vc.Name
+			vc.Name = ""
+			value, err := toYAMLValue(&vc)
+			if err != nil {
+				return nil, err
+			}
+			vcenters[name] = value
+		}
+		fragment["vcenter"] = vcenters
+	}
+
+	if len(spec.LoadBalancerClasses) > 0 {
+		classes := map[string]interface{}{}
+		for i := range spec.LoadBalancerClasses {
+			class := spec.LoadBalancerClasses[i]
+			if _, ok := classes[class.Name]; ok {
+				return nil, fmt.Errorf("loadBalancerClasses: %s is set twice", class.Name)
+			}
+			name := class.Name
+			class.Name = ""
+			value, err := toYAMLValue(&class)
+			if err != nil {
+				return nil, err
+			}
+			classes[name] = value
+		}
+		fragment["loadBalancerClass"] = classes
+	}
+
+	for key, section := range map[string]interface{}{"nodes": spec.Nodes, "route": spec.Route} {
+		value, err := toYAMLValue(section)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			fragment[key] = value
+		}
+	}
+
+	return yaml.Marshal(fragment)
+}
+
+// toYAMLValue converts a struct of the spec into a generic value, without its
+// empty fields. nil is returned for a nil or empty struct.
+func toYAMLValue(in interface{}) (interface{}, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	delete(value, "name")
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// MergeCloudConfigFragment merges the fragment of a VSphereCloudConfig, named
+// name, into the YAML cloud-config byConfig. The fragment can add vCenters,
+// load balancer classes and keys, but not change a key of byConfig.
+func MergeCloudConfigFragment(byConfig []byte, name string, fragment []byte) ([]byte, error) {
+	if _, err := ReadCPIConfigYAML(byConfig); err != nil {
+		if _, iniErr := ReadCPIConfigINI(byConfig); iniErr == nil {
+			return nil, fmt.Errorf("a VSphereCloudConfig can only be merged into a YAML cloud-config, convert it with vcpctl convert-config")
+		}
+	}
+	// cloud-config sorts first, so that the keys of the fragment are reported
+	// as the conflicts
+	merged, err := MergeFragments(map[string][]byte{
+		"cloud-config": byConfig,
+		fmt.Sprintf("vspherecloudconfig/%s", name): fragment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged.Data, nil
+}
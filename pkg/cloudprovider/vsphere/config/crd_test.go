@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/apis/cloudconfig/v1alpha1"
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+)
+
+const crdBaseConfig = `
+global:
+  port: 443
+  insecureFlag: true
+  secretName: vsphere-creds
+  secretNamespace: kube-system
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc1
+loadBalancer:
+  size: SMALL
+  lbServiceId: lbs
+  ipPoolName: pool
+  tcpAppProfileName: default-tcp-lb-app-profile
+  udpAppProfileName: default-udp-lb-app-profile
+nsxt:
+  host: nsxt.example.com
+  user: admin
+  password: secret
+`
+
+var crdSpec = v1alpha1.VSphereCloudConfigSpec{
+	VCenters: []v1alpha1.VCenter{{
+		Name:        "tenant-b",
+		Server:      "10.0.0.2",
+		Datacenters: []string{"dc2", "dc3"},
+	}},
+	Nodes: &v1alpha1.Nodes{InternalNetworkSubnetCIDR: "192.0.2.0/24"},
+	LoadBalancerClasses: []v1alpha1.LoadBalancerClass{{
+		Name:       "public",
+		IPPoolName: "public-pool",
+	}},
+}
+
+func TestCloudConfigFragment(t *testing.T) {
+	fragment, err := CloudConfigFragment(&crdSpec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, unexpected := range []string{"name:", "route", "port"} {
+		if strings.Contains(string(fragment), unexpected) {
+			t.Errorf("%q is not set by the spec:\n%s", unexpected, fragment)
+		}
+	}
+
+	merged, err := MergeCloudConfigFragment([]byte(crdBaseConfig), "cluster", fragment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, err := ReadCPIConfig(merged)
+	if err != nil {
+		t.Fatalf("merged config is invalid: %v\n%s", err, merged)
+	}
+	vc := cfg.VirtualCenter["tenant-b"]
+	if len(cfg.VirtualCenter) != 2 || vc == nil {
+		t.Fatalf("vcenters not merged: %v", cfg.VirtualCenter)
+	}
+	if vc.Datacenters != "dc2,dc3" || vc.VCenterPort != "443" || !vc.InsecureFlag {
+		t.Errorf("unexpected tenant-b: %+v", vc)
+	}
+	if cfg.Nodes.InternalNetworkSubnetCIDR != "192.0.2.0/24" {
+		t.Errorf("nodes not merged: %+v", cfg.Nodes)
+	}
+
+	lbcfg, err := lcfg.ReadLBConfig(merged)
+	if err != nil {
+		t.Fatalf("merged load balancer config is invalid: %v", err)
+	}
+	if class := lbcfg.LoadBalancerClass["public"]; class == nil || class.IPPoolName != "public-pool" {
+		t.Errorf("load balancer class not merged: %v", lbcfg.LoadBalancerClass)
+	}
+	if err := ValidateStrict(merged); err != nil {
+		t.Errorf("merged config fails the strict validation: %v", err)
+	}
+}
+
+func TestCloudConfigFragmentDuplicate(t *testing.T) {
+	spec := crdSpec.DeepCopy()
+	spec.VCenters = append(spec.VCenters, spec.VCenters[0])
+	if _, err := CloudConfigFragment(spec); err == nil || !strings.Contains(err.Error(), "tenant-b is set twice") {
+		t.Errorf("expected the duplicate vCenter to fail, got %v", err)
+	}
+}
+
+func TestMergeCloudConfigFragmentConflict(t *testing.T) {
+	spec := crdSpec.DeepCopy()
+	spec.VCenters[0].Name = "tenant-a"
+	fragment, err := CloudConfigFragment(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = MergeCloudConfigFragment([]byte(crdBaseConfig), "cluster", fragment)
+	if err == nil || !strings.Contains(err.Error(), "vspherecloudconfig/cluster: line") ||
+		!strings.Contains(err.Error(), "vcenter.tenant-a.server is already set by cloud-config") {
+		t.Errorf("expected a conflict on vcenter.tenant-a.server, got %v", err)
+	}
+
+	ini := []byte(`
+[Global]
+user = "user"
+password = "password"
+server = "0.0.0.0"
+`)
+	_, err = MergeCloudConfigFragment(ini, "cluster", fragment)
+	if err == nil || !strings.Contains(err.Error(), "only be merged into a YAML cloud-config") {
+		t.Errorf("expected an INI cloud-config to fail, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+// EffectiveConfig returns the YAML cloud-config the cloud provider runs with:
+// the file merged with the environment variables mapped to its keys and the
+// defaults filled in, with the secrets redacted. The sections the cloud
+// provider ignores because they are invalid are replaced by a comment.
+func EffectiveConfig(byConfig []byte) ([]byte, error) {
+	if len(byConfig) == 0 {
+		return nil, fmt.Errorf("no vSphere cloud provider config file given")
+	}
+
+	common, err := vcfg.ReadRawConfigYAML(byConfig)
+	if err != nil {
+		if _, iniErr := ReadCPIConfigINI(byConfig); iniErr == nil {
+			return nil, fmt.Errorf("the effective config is only available for YAML cloud-configs, convert it with vcpctl convert-config")
+		}
+		return nil, err
+	}
+	nodes := &CPIConfigYAML{}
+	if err := yaml.Unmarshal(byConfig, nodes); err != nil {
+		return nil, err
+	}
+	if err := vcfg.ApplyEnv(vcfg.EnvPrefix+"_NODES", &nodes.Nodes); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if legacy := vcfg.LegacyEnvVars(); len(legacy) > 0 {
+		fmt.Fprintf(&out, "# overridden by the legacy environment variables %s\n", strings.Join(legacy, ", "))
+	}
+	sections := []struct {
+		name string
+		read func() (interface{}, error)
+	}{
+		{"global", func() (interface{}, error) { return common, nil }},
+		{"nodes", func() (interface{}, error) {
+			return &struct {
+				Nodes NodesYAML `yaml:"nodes"`
+			}{nodes.Nodes}, nil
+		}},
+		{"loadBalancer", func() (interface{}, error) { return lcfg.ReadRawConfigYAML(byConfig) }},
+		{"nsxt", func() (interface{}, error) { return ncfg.ReadRawConfigYAML(byConfig) }},
+		{"route", func() (interface{}, error) { return rcfg.ReadRawConfigYAML(byConfig) }},
+	}
+	for _, section := range sections {
+		cfg, err := section.read()
+		if err != nil {
+			fmt.Fprintf(&out, "# %s: ignored, %v\n", section.name, err)
+			continue
+		}
+		data, err := vcfg.MarshalRedacted(cfg)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(data)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	t.Setenv("VSPHERE_VCENTER_TENANT_A_DATACENTERS", "dc2")
+	t.Setenv("VSPHERE_NODES_INTERNAL_VM_NETWORK_NAME", "vm-network")
+	t.Setenv("VSPHERE_NSXT_PASSWORD", "nsxt-password")
+	t.Setenv("VSPHERE_NSXT_USER", "admin")
+
+	out, err := EffectiveConfig([]byte(strictYAMLConfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	effective := &CloudConfigYAML{}
+	if err := yaml.Unmarshal(out, effective); err != nil {
+		t.Fatalf("effective config is not YAML: %v\n%s", err, out)
+	}
+	if dcs := effective.Vcenter["tenant-a"].Datacenters; len(dcs) != 1 || dcs[0] != "dc2" {
+		t.Errorf("datacenters = %v, expected the variable", dcs)
+	}
+	if effective.Vcenter["tenant-a"].VCenterPort != 443 || !effective.Vcenter["tenant-a"].InsecureFlag {
+		t.Errorf("tenant-a did not inherit from global: %+v", effective.Vcenter["tenant-a"])
+	}
+	if effective.Nodes.InternalVMNetworkName != "vm-network" {
+		t.Errorf("nodes not overridden: %+v", effective.Nodes)
+	}
+	if effective.NSXT.Password != vcfg.Redacted {
+		t.Errorf("NSX-T password = %q, expected it redacted", effective.NSXT.Password)
+	}
+	if !strings.Contains(string(out), "# route: ignored") {
+		t.Errorf("the invalid route section should be commented:\n%s", out)
+	}
+}
+
+func TestEffectiveConfigINI(t *testing.T) {
+	ini := `
+[Global]
+user = user
+password = password
+port = 443
+
+[VirtualCenter "0.0.0.0"]
+datacenters = dc1
+`
+	_, err := EffectiveConfig([]byte(ini))
+	if err == nil || !strings.Contains(err.Error(), "convert-config") {
+		t.Errorf("expected an error pointing at convert-config, got %v", err)
+	}
+}
+
+// schemaLeaves returns the paths of the settings of the schema, with map
+// entries named vcfg.EnvEntryName
+func schemaLeaves(schema, node *schemaNode, path string) []string {
+	if node.Ref != "" {
+		node = schema.Definitions[strings.TrimPrefix(node.Ref, "#/definitions/")]
+	}
+	if len(node.AdditionalProperties) > 0 {
+		entry := &schemaNode{}
+		if err := json.Unmarshal(node.AdditionalProperties, entry); err == nil && (entry.Ref != "" || len(entry.Properties) > 0) {
+			return schemaLeaves(schema, entry, path+"."+vcfg.EnvEntryName)
+		}
+	}
+	if len(node.Properties) == 0 {
+		return []string{strings.TrimPrefix(path, ".")}
+	}
+	var leaves []string
+	for key, property := range node.Properties {
+		leaves = append(leaves, schemaLeaves(schema, property, path+"."+key)...)
+	}
+	return leaves
+}
+
+func TestEnvVarsCoverSchema(t *testing.T) {
+	schema := &schemaNode{}
+	if err := json.Unmarshal(JSONSchema(), schema); err != nil {
+		t.Fatalf("invalid JSON Schema: %v", err)
+	}
+
+	paths := map[string]bool{}
+	for _, envVar := range vcfg.EnvVars(vcfg.EnvPrefix, &CloudConfigYAML{}) {
+		paths[envVar.Path] = true
+	}
+	leaves := schemaLeaves(schema, schema, "")
+	for _, leaf := range leaves {
+		if !paths[leaf] {
+			t.Errorf("%s has no environment variable", leaf)
+		}
+	}
+	if len(leaves) != len(paths) {
+		t.Errorf("%d schema settings, %d environment variables", len(leaves), len(paths))
+	}
+}
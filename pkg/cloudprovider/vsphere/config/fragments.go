@@ -0,0 +1,223 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Fragments is a YAML cloud-config merged from the fragments of a directory
+type Fragments struct {
+	// Data is the merged cloud-config
+	Data []byte
+	// Owners maps the dotted path of each key set by a fragment to the name
+	// of the fragment
+	Owners map[string]string
+}
+
+// IsConfigDir returns true if path is a directory of cloud-config fragments
+func IsConfigDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ReadConfigDir merges the *.yaml and *.yml fragments of dir. Hidden files,
+// such as the data links of a mounted ConfigMap, are skipped.
+func ReadConfigDir(dir string) (*Fragments, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fragments := map[string][]byte{}
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if strings.HasPrefix(name, ".") || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		fragments[name] = data
+	}
+	if len(fragments) == 0 {
+		return nil, fmt.Errorf("no YAML cloud-config fragment in %s", dir)
+	}
+	return MergeFragments(fragments)
+}
+
+// MergeFragments merges YAML cloud-config fragments, keyed by name, in the
+// lexical order of their names. Mappings such as sections and vcenter entries
+// are merged key by key. A key that is not a mapping can only be set by one
+// fragment, every key set twice is reported as a conflict.
+func MergeFragments(fragments map[string][]byte) (*Fragments, error) {
+	names := make([]string, 0, len(fragments))
+	for name := range fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m := &fragmentMerger{
+		root:   &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		owners: map[string]string{},
+		lines:  map[string]int{},
+	}
+	for _, name := range names {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(fragments[name], &doc); err != nil {
+			m.errs = append(m.errs, fmt.Errorf("%s: %v", name, err))
+			continue
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			m.errs = append(m.errs, fmt.Errorf("%s: line %d: a fragment must be a mapping of cloud-config sections", name, root.Line))
+			continue
+		}
+		m.merge(name, nil, m.root, root)
+	}
+	if len(m.errs) > 0 {
+		return nil, utilerrors.NewAggregate(m.errs)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m.root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return &Fragments{Data: buf.Bytes(), Owners: m.owners}, nil
+}
+
+type fragmentMerger struct {
+	root   *yaml.Node
+	owners map[string]string
+	lines  map[string]int
+	errs   []error
+}
+
+// merge merges the mapping src of the fragment name into dst
+func (m *fragmentMerger) merge(name string, path []string, dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		keyPath := append(append([]string{}, path...), key.Value)
+		dotted := strings.Join(keyPath, ".")
+
+		var existing *yaml.Node
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				existing = dst.Content[j+1]
+				break
+			}
+		}
+		if existing == nil {
+			dst.Content = append(dst.Content, key, value)
+			m.own(name, keyPath, key, value)
+			continue
+		}
+		if existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			m.merge(name, keyPath, existing, value)
+			continue
+		}
+		owner := m.owners[dotted]
+		if owner == "" {
+			owner = m.ownerUnder(dotted)
+		}
+		m.errs = append(m.errs, fmt.Errorf("%s: line %d: %s is already set by %s, line %d",
+			name, key.Line, dotted, owner, m.lines[dotted]))
+	}
+}
+
+// own records name as the owner of the keys set by value
+func (m *fragmentMerger) own(name string, path []string, key, value *yaml.Node) {
+	dotted := strings.Join(path, ".")
+	m.lines[dotted] = key.Line
+	if value.Kind != yaml.MappingNode || len(value.Content) == 0 {
+		m.owners[dotted] = name
+		return
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		m.own(name, append(append([]string{}, path...), value.Content[i].Value), value.Content[i], value.Content[i+1])
+	}
+}
+
+// ownerUnder returns the first fragment owning a key below path
+func (m *fragmentMerger) ownerUnder(path string) string {
+	first := ""
+	for key, owner := range m.owners {
+		if strings.HasPrefix(key, path+".") && (first == "" || owner < first) {
+			first = owner
+		}
+	}
+	return first
+}
+
+// AnnotateOwners adds the fragment owning each key of the YAML cloud-config
+// data as a line comment, e.g. "server: 10.0.0.1 # 20-vcenters.yaml". Keys that
+// are not set by a fragment, such as defaults, are left as they are.
+func AnnotateOwners(data []byte, owners map[string]string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) > 0 {
+		annotateOwners(doc.Content[0], nil, owners)
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func annotateOwners(node *yaml.Node, path []string, owners map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := append(append([]string{}, path...), key.Value)
+		if owner, ok := owners[strings.Join(keyPath, ".")]; ok {
+			if value.Kind == yaml.ScalarNode {
+				value.LineComment = owner
+			} else {
+				key.LineComment = owner
+			}
+			continue
+		}
+		annotateOwners(value, keyPath, owners)
+	}
+}
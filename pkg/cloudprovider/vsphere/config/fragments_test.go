@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFragments = map[string][]byte{
+	"10-global.yaml": []byte(`
+global:
+  port: 443
+  insecureFlag: true
+  secretName: vsphere-creds
+  secretNamespace: kube-system
+`),
+	"20-vcenters.yaml": []byte(`
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc1
+`),
+	"30-more-vcenters.yaml": []byte(`
+vcenter:
+  tenant-b:
+    server: 10.0.0.2
+    datacenters:
+      - dc2
+`),
+}
+
+func TestMergeFragments(t *testing.T) {
+	fragments, err := MergeFragments(testFragments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := ReadCPIConfig(fragments.Data)
+	if err != nil {
+		t.Fatalf("merged config is invalid: %v\n%s", err, fragments.Data)
+	}
+	if len(cfg.VirtualCenter) != 2 || cfg.VirtualCenter["tenant-b"] == nil {
+		t.Errorf("vcenters not merged: %v", cfg.VirtualCenter)
+	}
+
+	for path, owner := range map[string]string{
+		"global.port":                  "10-global.yaml",
+		"vcenter.tenant-a.server":      "20-vcenters.yaml",
+		"vcenter.tenant-b.datacenters": "30-more-vcenters.yaml",
+	} {
+		if fragments.Owners[path] != owner {
+			t.Errorf("%s is owned by %q, expected %s", path, fragments.Owners[path], owner)
+		}
+	}
+}
+
+func TestMergeFragmentsConflict(t *testing.T) {
+	fragments := map[string][]byte{
+		"40-conflict.yaml": []byte(`
+global:
+  port: 8443
+vcenter:
+  tenant-a: 10.0.0.3
+`),
+		"50-list.yaml": []byte(`
+- global
+`),
+	}
+	for name, data := range testFragments {
+		fragments[name] = data
+	}
+
+	_, err := MergeFragments(fragments)
+	if err == nil {
+		t.Fatal("expected conflicts")
+	}
+	for _, expected := range []string{
+		"40-conflict.yaml: line 3: global.port is already set by 10-global.yaml, line 3",
+		"40-conflict.yaml: line 5: vcenter.tenant-a is already set by 20-vcenters.yaml",
+		"50-list.yaml: line 2: a fragment must be a mapping",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q does not contain %q", err, expected)
+		}
+	}
+}
+
+func TestReadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range testFragments {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not fragments
+	for _, name := range []string{"README.md", ".hidden.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("global: {port: 80}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !IsConfigDir(dir) {
+		t.Errorf("%s is a config directory", dir)
+	}
+	fragments, err := ReadConfigDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fragments.Owners["global.port"] != "10-global.yaml" {
+		t.Errorf("global.port is owned by %q", fragments.Owners["global.port"])
+	}
+
+	if _, err := ReadConfigDir(t.TempDir()); err == nil {
+		t.Error("expected an error for an empty directory")
+	}
+}
+
+func TestAnnotateOwners(t *testing.T) {
+	fragments, err := MergeFragments(testFragments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	effective, err := EffectiveConfig(fragments.Data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	annotated, err := AnnotateOwners(effective, fragments.Owners)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := string(annotated)
+	for _, expected := range []string{
+		"port: 443 # 10-global.yaml",
+		"server: 10.0.0.2 # 30-more-vcenters.yaml",
+		"datacenters: # 20-vcenters.yaml",
+		"# route: ignored",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("%q is missing from\n%s", expected, out)
+		}
+	}
+	// the vcenter port is inherited, not set by a fragment
+	if strings.Count(out, "port: 443 #") != 1 {
+		t.Errorf("only global.port is owned:\n%s", out)
+	}
+}
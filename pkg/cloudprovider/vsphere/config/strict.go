@@ -0,0 +1,398 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	_ "embed" // for the JSON Schema
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	pathpkg "path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+// StrictConfigEnv enables the strict validation of the cloud-config when set to
+// true. The cloud-config must then be YAML, without unknown keys.
+const StrictConfigEnv = "VSPHERE_STRICT_CONFIG"
+
+//go:embed cloud-config.schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema of the YAML cloud-config, covering the
+// sections of all the config packages.
+func JSONSchema() []byte {
+	return jsonSchema
+}
+
+// IsStrict returns true if strict validation was enabled through StrictConfigEnv
+func IsStrict() bool {
+	strict, _ := strconv.ParseBool(os.Getenv(StrictConfigEnv))
+	return strict
+}
+
+// CloudConfigYAML holds every section of the YAML cloud-config read by the
+// config packages of the cloud provider.
+type CloudConfigYAML struct {
+	vcfg.CommonConfigYAML `yaml:",inline"`
+	Nodes                 NodesYAML `yaml:"nodes"`
+	lcfg.LBConfigYAML     `yaml:",inline"`
+	ncfg.NsxtConfigYAML   `yaml:",inline"`
+	rcfg.RouteConfigYAML  `yaml:",inline"`
+}
+
+// ValidationError is a strict validation failure, located in the cloud-config
+type ValidationError struct {
+	// Line is the line of the offending key, 0 if unknown
+	Line int
+	// Path is the dotted path of the offending key
+	Path string
+	// Message describes the failure
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	if e.Path != "" {
+		fmt.Fprintf(&b, "%s: ", e.Path)
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+var yamlLineError = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// ValidateStrict validates the YAML cloud-config. It rejects unknown keys and
+// values of the wrong type, then checks the constraints between fields. All
+// failures are returned in an aggregate of *ValidationError.
+func ValidateStrict(byConfig []byte) error {
+	if len(byConfig) == 0 {
+		return fmt.Errorf("no vSphere cloud provider config file given")
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(byConfig, &root); err != nil {
+		return utilerrors.NewAggregate([]error{yamlError(err.Error())})
+	}
+
+	cfg := &CloudConfigYAML{}
+	decoder := yaml.NewDecoder(bytes.NewReader(byConfig))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return utilerrors.NewAggregate([]error{yamlError(err.Error())})
+		}
+		errs := make([]error, 0, len(typeErr.Errors))
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, yamlError(msg))
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+
+	v := &strictValidator{root: &root}
+	v.validate(cfg)
+	return utilerrors.NewAggregate(v.errs)
+}
+
+// yamlError turns a yaml error message into a ValidationError
+func yamlError(msg string) *ValidationError {
+	msg = strings.TrimPrefix(msg, "yaml: ")
+	if m := yamlLineError.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &ValidationError{Line: line, Message: m[2]}
+	}
+	return &ValidationError{Message: msg}
+}
+
+// strictValidator checks the cross-field constraints of the cloud-config
+type strictValidator struct {
+	root *yaml.Node
+	errs []error
+}
+
+func (v *strictValidator) errorf(path []string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Line:    v.line(path),
+		Path:    strings.Join(path, "."),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// line returns the line of the key at path, or of its closest parent
+func (v *strictValidator) line(path []string) int {
+	node := v.root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := 0
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			break
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				line = node.Content[i].Line
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+func (v *strictValidator) validate(cfg *CloudConfigYAML) {
+	v.validateVCenters(cfg)
+	v.validateIPFamily([]string{"global", "ipFamily"}, cfg.Global.IPFamilyPriority)
+	v.validateSearchPatterns([]string{"global"}, cfg.Global.Datacenters, cfg.Global.VMFolders, cfg.Global.ResourcePools)
+	v.validateNodes([]string{"nodes"}, &cfg.Nodes)
+	v.validateLoadBalancer(cfg)
+	v.validateRoute(cfg)
+}
+
+func (v *strictValidator) validateVCenters(cfg *CloudConfigYAML) {
+	if len(cfg.Vcenter) == 0 && cfg.Global.VCenterIP == "" {
+		v.errorf([]string{"vcenter"}, "at least one vCenter is required")
+	}
+
+	tenantRefs := make([]string, 0, len(cfg.Vcenter))
+	for tenantRef := range cfg.Vcenter {
+		tenantRefs = append(tenantRefs, tenantRef)
+	}
+	sort.Strings(tenantRefs)
+
+	// the global server is a tenant of its own unless a vcenter is keyed by it
+	tenants := map[string]string{}
+	if cfg.Global.VCenterIP != "" && cfg.Vcenter[cfg.Global.VCenterIP] == nil {
+		tenants[cfg.Global.VCenterIP] = "global"
+	}
+
+	for _, tenantRef := range tenantRefs {
+		vc := cfg.Vcenter[tenantRef]
+		path := []string{"vcenter", tenantRef}
+		if vc == nil {
+			v.errorf(path, "vCenter settings are empty")
+			continue
+		}
+		if vc.SecretRef != "" {
+			v.errorf(path, "secretref is set from the secret settings")
+		}
+		// the vcenter key is the TenantRef unless it is given explicitly
+		ref := tenantRef
+		if vc.TenantRef != "" {
+			ref = vc.TenantRef
+		}
+		if other, ok := tenants[ref]; ok {
+			v.errorf(path, "TenantRef %s is also used by %s", ref, other)
+		} else {
+			tenants[ref] = "vcenter." + tenantRef
+		}
+		if vc.VCenterIP == "" {
+			v.errorf(path, "server is required")
+		}
+		if (vc.SecretName == "") != (vc.SecretNamespace == "") {
+			v.errorf(path, "secretName and secretNamespace must be set together")
+		}
+		v.validateIPFamily(append(path, "ipFamily"), vc.IPFamilyPriority)
+		v.validateSearchPatterns(path, vc.Datacenters, vc.VMFolders, vc.ResourcePools)
+		nodes := NodesYAML(vc.Nodes)
+		v.validateNodes(append(path, "nodes"), &nodes)
+	}
+}
+
+// validateSearchPatterns checks the glob patterns of the datacenters, VM
+// folders and resource pools
+func (v *strictValidator) validateSearchPatterns(path []string, datacenters, folders, pools []string) {
+	keys := []string{"datacenters", "vmFolders", "resourcePools"}
+	for i, patterns := range [][]string{datacenters, folders, pools} {
+		for _, pattern := range patterns {
+			if _, err := pathpkg.Match(pattern, ""); err != nil {
+				v.errorf(append(append([]string{}, path...), keys[i]), "invalid pattern %q: %v", pattern, err)
+			}
+		}
+	}
+}
+
+func (v *strictValidator) validateIPFamily(path []string, families []string) {
+	seen := map[string]bool{}
+	for _, family := range families {
+		family = strings.ToLower(family)
+		if family != vcfg.IPv4Family && family != vcfg.IPv6Family {
+			v.errorf(path, "invalid IP family %q, must be %s or %s", family, vcfg.IPv4Family, vcfg.IPv6Family)
+			continue
+		}
+		if seen[family] {
+			v.errorf(path, "IP family %s is listed twice", family)
+		}
+		seen[family] = true
+	}
+}
+
+func (v *strictValidator) validateNodes(path []string, nodes *NodesYAML) {
+	for key, cidrs := range map[string]string{
+		"internalNetworkSubnetCidr":        nodes.InternalNetworkSubnetCIDR,
+		"externalNetworkSubnetCidr":        nodes.ExternalNetworkSubnetCIDR,
+		"excludeInternalNetworkSubnetCidr": nodes.ExcludeInternalNetworkSubnetCIDR,
+		"excludeExternalNetworkSubnetCidr": nodes.ExcludeExternalNetworkSubnetCIDR,
+	} {
+		if cidrs == "" {
+			continue
+		}
+		for _, cidr := range strings.Split(cidrs, ",") {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				v.errorf(append(append([]string{}, path...), key), "invalid CIDR %q", cidr)
+			}
+		}
+	}
+}
+
+func (v *strictValidator) validateRoute(cfg *CloudConfigYAML) {
+	route := &cfg.Route
+	routeEnabled := route.RouterPath != ""
+	if route.Enabled != nil {
+		routeEnabled = *route.Enabled
+	}
+	if !routeEnabled {
+		return
+	}
+	if route.RouterPath == "" {
+		v.errorf([]string{"route"}, "routerPath is required")
+	}
+	if cfg.NSXT.Host == "" {
+		v.errorf([]string{"route"}, "route requires the nsxt section")
+	}
+}
+
+// loadBalancerEnabled returns true if the load balancer is enabled explicitly,
+// or implicitly by any of its settings
+func loadBalancerEnabled(cfg *CloudConfigYAML) bool {
+	lb := &cfg.LoadBalancer
+	if lb.Enabled != nil {
+		return *lb.Enabled
+	}
+	return len(cfg.LoadBalancerClass) > 0 || lb.Size != "" || lb.LBServiceID != "" ||
+		lb.Tier1GatewayPath != "" || lb.IPPoolName != "" || lb.IPPoolID != ""
+}
+
+func (v *strictValidator) validateLoadBalancer(cfg *CloudConfigYAML) {
+	if !loadBalancerEnabled(cfg) {
+		return
+	}
+	lb := &cfg.LoadBalancer
+
+	path := []string{"loadBalancer"}
+	if cfg.NSXT.Host == "" {
+		v.errorf(path, "load balancer requires the nsxt section")
+	}
+	if lb.LBServiceID == "" && lb.Tier1GatewayPath == "" {
+		v.errorf(path, "either lbServiceId or tier1GatewayPath is required")
+	}
+	if lb.TCPAppProfileName == "" && lb.TCPAppProfilePath == "" {
+		v.errorf(path, "either tcpAppProfileName or tcpAppProfilePath is required")
+	}
+	if lb.UDPAppProfileName == "" && lb.UDPAppProfilePath == "" {
+		v.errorf(path, "either udpAppProfileName or udpAppProfilePath is required")
+	}
+	if !lcfg.LoadBalancerSizes.Has(lb.Size) {
+		v.errorf(append(path, "size"), "invalid size %q, must be one of %s", lb.Size, strings.Join(lcfg.LoadBalancerSizes.List(), ", "))
+	}
+	if lb.IPPoolName != "" && lb.IPPoolID != "" {
+		v.errorf(path, "ipPoolName and ipPoolId are mutually exclusive")
+	}
+	if lb.IPv6PoolName != "" && lb.IPv6PoolID != "" {
+		v.errorf(path, "ipv6PoolName and ipv6PoolId are mutually exclusive")
+	}
+	if lb.IPPoolName == "" && lb.IPPoolID == "" && cfg.LoadBalancerClass[lcfg.DefaultLoadBalancerClass] == nil {
+		v.errorf(path, "ipPoolName or ipPoolId is required unless the %q load balancer class is defined", lcfg.DefaultLoadBalancerClass)
+	}
+
+	names := make([]string, 0, len(cfg.LoadBalancerClass))
+	for name := range cfg.LoadBalancerClass {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		class := cfg.LoadBalancerClass[name]
+		classPath := []string{"loadBalancerClass", name}
+		if class == nil {
+			class = &lcfg.LoadBalancerClassConfigYAML{}
+		}
+		if class.IPPoolName != "" && class.IPPoolID != "" {
+			v.errorf(classPath, "ipPoolName and ipPoolId are mutually exclusive")
+		}
+		if class.IPv6PoolName != "" && class.IPv6PoolID != "" {
+			v.errorf(classPath, "ipv6PoolName and ipv6PoolId are mutually exclusive")
+		}
+		if class.IPPoolName == "" && class.IPPoolID == "" && lb.IPPoolName == "" && lb.IPPoolID == "" {
+			v.errorf(classPath, "no IP pool, set ipPoolName or ipPoolId in the class or in loadBalancer")
+		}
+	}
+}
+
+// ValidateLoadBalancerClasses checks that the load balancer classes used by
+// Services, as returned by cli.LoadBalancerClassUsers, are defined in the YAML
+// cloud-config. The default class is always defined if the load balancer is
+// enabled, no class is checked otherwise. All failures are returned in an
+// aggregate of *ValidationError.
+func ValidateLoadBalancerClasses(byConfig []byte, users map[string][]string) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(byConfig, &root); err != nil {
+		return utilerrors.NewAggregate([]error{yamlError(err.Error())})
+	}
+	cfg := &CloudConfigYAML{}
+	if err := root.Decode(cfg); err != nil {
+		return utilerrors.NewAggregate([]error{yamlError(err.Error())})
+	}
+	if !loadBalancerEnabled(cfg) {
+		return nil
+	}
+
+	v := &strictValidator{root: &root}
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := cfg.LoadBalancerClass[name]; ok || name == lcfg.DefaultLoadBalancerClass {
+			continue
+		}
+		v.errorf([]string{"loadBalancerClass"}, "load balancer class %s used by the Services %s is not defined",
+			name, strings.Join(users[name], ", "))
+	}
+	return utilerrors.NewAggregate(v.errs)
+}
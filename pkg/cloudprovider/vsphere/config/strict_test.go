@@ -0,0 +1,268 @@
+/*
+Copyright 2019New The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const strictYAMLConfig = `
+global:
+  port: 443
+  insecureFlag: true
+  secretName: vsphere-creds
+  secretNamespace: kube-system
+
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc1
+    ipFamily:
+      - ipv4
+      - ipv6
+
+nodes:
+  internalNetworkSubnetCidr: "192.0.2.0/24,2001:db8::/32"
+
+loadBalancer:
+  size: SMALL
+  tier1GatewayPath: /infra/tier-1s/t1
+  tcpAppProfileName: default-tcp-lb-app-profile
+  udpAppProfileName: default-udp-lb-app-profile
+
+loadBalancerClass:
+  default:
+    ipPoolName: pool-default
+  public:
+    ipPoolId: pool-public
+
+nsxt:
+  host: nsxt.example.com
+  secretName: nsxt-creds
+  secretNamespace: kube-system
+`
+
+func validationErrors(t *testing.T, err error) []*ValidationError {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	var agg utilerrors.Aggregate
+	if !errors.As(err, &agg) {
+		t.Fatalf("expected an aggregate, got %T: %v", err, err)
+	}
+	var errs []*ValidationError
+	for _, e := range agg.Errors() {
+		var verr *ValidationError
+		if !errors.As(e, &verr) {
+			t.Fatalf("expected a ValidationError, got %T: %v", e, e)
+		}
+		errs = append(errs, verr)
+	}
+	return errs
+}
+
+func TestValidateStrictValid(t *testing.T) {
+	if err := ValidateStrict([]byte(strictYAMLConfig)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateStrictUnknownKey(t *testing.T) {
+	config := strings.Replace(strictYAMLConfig, "tier1GatewayPath:", "lbServiceID: lbs\n  tier1GatewayPath:", 1)
+	errs := validationErrors(t, ValidateStrict([]byte(config)))
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if errs[0].Line != 22 || !strings.Contains(errs[0].Message, "lbServiceID") {
+		t.Errorf("unexpected error %q at line %d", errs[0].Message, errs[0].Line)
+	}
+}
+
+func TestValidateStrictTypeError(t *testing.T) {
+	config := strings.Replace(strictYAMLConfig, "port: 443", "port: https", 1)
+	errs := validationErrors(t, ValidateStrict([]byte(config)))
+	if len(errs) != 1 || errs[0].Line != 3 {
+		t.Fatalf("expected 1 error at line 3, got %v", errs)
+	}
+}
+
+func TestValidateStrictCrossField(t *testing.T) {
+	config := strictYAMLConfig + `
+  user: admin
+`
+	config = strings.Replace(config, "vcenter:\n", `vcenter:
+  tenant-b:
+    server: 10.0.0.1
+    port: 443
+    tenantref: tenant-a
+    secretName: creds-b
+    vmFolders:
+      - "k8s/["
+    nodes:
+      externalNetworkSubnetCidr: 198.51.100.0/33
+`, 1)
+	config = strings.Replace(config, "      - ipv6", "      - ipv7", 1)
+	config = strings.Replace(config, "2001:db8::/32", "2001:db8::/129", 1)
+	config = strings.Replace(config, "    ipPoolName: pool-default\n", "", 1)
+	config = strings.Replace(config, "default:", "default: {}", 1)
+
+	errs := validationErrors(t, ValidateStrict([]byte(config)))
+	expected := []struct {
+		path    string
+		message string
+	}{
+		{"vcenter.tenant-b", "TenantRef tenant-a is also used by vcenter.tenant-a"},
+		{"vcenter.tenant-b", "secretName and secretNamespace must be set together"},
+		{"vcenter.tenant-a.ipFamily", `invalid IP family "ipv7"`},
+		{"vcenter.tenant-b.vmFolders", `invalid pattern "k8s/["`},
+		{"nodes.internalNetworkSubnetCidr", `invalid CIDR "2001:db8::/129"`},
+		{"vcenter.tenant-b.nodes.externalNetworkSubnetCidr", `invalid CIDR "198.51.100.0/33"`},
+		{"loadBalancerClass.default", "no IP pool"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for _, exp := range expected {
+		found := false
+		for _, err := range errs {
+			if err.Path == exp.path && strings.Contains(err.Message, exp.message) && err.Line > 0 {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing error %s: %s in %v", exp.path, exp.message, errs)
+		}
+	}
+}
+
+func TestValidateLoadBalancerClasses(t *testing.T) {
+	users := map[string][]string{
+		"default": {"web/frontend"},
+		"unknown": {"web/backend", "db/primary"},
+	}
+	errs := validationErrors(t, ValidateLoadBalancerClasses([]byte(strictYAMLConfig), users))
+	if len(errs) != 1 || errs[0].Line == 0 || !strings.Contains(errs[0].Message, "class unknown used by the Services web/backend, db/primary is not defined") {
+		t.Fatalf("expected the unknown class to be reported, got %v", errs)
+	}
+
+	config := strings.Replace(strictYAMLConfig, "loadBalancer:\n", "loadBalancer:\n  enabled: false\n", 1)
+	if err := ValidateLoadBalancerClasses([]byte(config), users); err != nil {
+		t.Errorf("expected no check with the load balancer disabled, got %v", err)
+	}
+}
+
+func TestValidateStrictReadCPIConfig(t *testing.T) {
+	t.Setenv(StrictConfigEnv, "true")
+	config := strings.Replace(strictYAMLConfig, "insecureFlag:", "insecure:", 1)
+	if _, err := ReadCPIConfig([]byte(config)); err == nil {
+		t.Error("expected unknown key to fail in strict mode")
+	}
+	if _, err := ReadCPIConfig([]byte(strictYAMLConfig)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+}
+
+// yamlKeys returns the yaml keys of a struct, following inline fields
+func yamlKeys(t reflect.Type) map[string]reflect.StructField {
+	keys := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			for key, f := range yamlKeys(field.Type) {
+				keys[key] = f
+			}
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		keys[name] = field
+	}
+	return keys
+}
+
+// compareSchema checks that the schema properties match the yaml keys of t
+func compareSchema(t *testing.T, schema *schemaNode, node *schemaNode, typ reflect.Type, path string) {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Map {
+		if typ.Kind() == reflect.Map {
+			var additional schemaNode
+			if err := json.Unmarshal(node.AdditionalProperties, &additional); err != nil {
+				t.Fatalf("%s: additionalProperties is not a schema: %v", path, err)
+			}
+			node = &additional
+			path += ".*"
+		}
+		typ = typ.Elem()
+	}
+	if node.Ref != "" {
+		node = schema.Definitions[strings.TrimPrefix(node.Ref, "#/definitions/")]
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+
+	keys := yamlKeys(typ)
+	// tenantref and secretref are derived from the config, they are not settings
+	delete(keys, "tenantref")
+	delete(keys, "secretref")
+
+	var yamlNames, schemaNames []string
+	for key := range keys {
+		yamlNames = append(yamlNames, key)
+	}
+	for key := range node.Properties {
+		schemaNames = append(schemaNames, key)
+	}
+	sort.Strings(yamlNames)
+	sort.Strings(schemaNames)
+	if !reflect.DeepEqual(yamlNames, schemaNames) {
+		t.Errorf("%s: schema properties %v do not match yaml keys %v", path, schemaNames, yamlNames)
+		return
+	}
+	for key, field := range keys {
+		compareSchema(t, schema, node.Properties[key], field.Type, path+"."+key)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := &schemaNode{}
+	if err := json.Unmarshal(JSONSchema(), schema); err != nil {
+		t.Fatalf("invalid JSON Schema: %v", err)
+	}
+	compareSchema(t, schema, schema, reflect.TypeOf(CloudConfigYAML{}), "")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"gopkg.in/gcfg.v1"
+	yaml "gopkg.in/yaml.v2"
+	klog "k8s.io/klog/v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+)
+
+// SubsystemFlags holds the explicit enable flags of the optional subsystems,
+// nil when unset.
+type SubsystemFlags struct {
+	LoadBalancer *bool
+	Route        *bool
+}
+
+type subsystemFlagsYAML struct {
+	LoadBalancer struct {
+		Enabled *bool `yaml:"enabled"`
+	} `yaml:"loadBalancer"`
+	Route struct {
+		Enabled *bool `yaml:"enabled"`
+	} `yaml:"route"`
+}
+
+type subsystemFlagsINI struct {
+	LoadBalancer struct {
+		Enabled *bool `gcfg:"enabled"`
+	} `gcfg:"loadbalancer"`
+	Route struct {
+		Enabled *bool `gcfg:"enabled"`
+	} `gcfg:"route"`
+}
+
+// ReadSubsystemFlags reads the enable flags of the YAML or INI cloud-config,
+// including their environment variables. Unlike the configs of the subsystems,
+// the flags are read even if the rest of the section is invalid, so that an
+// enabled subsystem with an invalid config can be told from an unset one.
+func ReadSubsystemFlags(byConfig []byte) SubsystemFlags {
+	cfgYAML := subsystemFlagsYAML{}
+	if err := yaml.Unmarshal(byConfig, &cfgYAML); err == nil {
+		if err := vcfg.ApplyEnv(vcfg.EnvPrefix, &cfgYAML); err != nil {
+			klog.Warningf("ignoring the enable flags variables: %v", err)
+		}
+		return SubsystemFlags{LoadBalancer: cfgYAML.LoadBalancer.Enabled, Route: cfgYAML.Route.Enabled}
+	}
+
+	cfgINI := subsystemFlagsINI{}
+	if err := gcfg.FatalOnly(gcfg.ReadStringInto(&cfgINI, string(byConfig))); err != nil {
+		return SubsystemFlags{}
+	}
+	return SubsystemFlags{LoadBalancer: cfgINI.LoadBalancer.Enabled, Route: cfgINI.Route.Enabled}
+}
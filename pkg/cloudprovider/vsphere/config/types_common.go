@@ -26,24 +26,9 @@ import (
 	the structs in types_yaml.go will be renamed to replace the ones in this file.
 */
 
-// Nodes captures internal/external networks
-type Nodes struct {
-	// IP address on VirtualMachine's network interfaces included in the fields' CIDRs
-	// that will be used in respective status.addresses fields.
-	InternalNetworkSubnetCIDR string
-	ExternalNetworkSubnetCIDR string
-	// IP address on VirtualMachine's VM Network names that will be used to when searching
-	// for status.addresses fields. Note that if InternalNetworkSubnetCIDR and
-	// ExternalNetworkSubnetCIDR are not set, then the vNIC associated to this network must
-	// only have a single IP address assigned to it.
-	InternalVMNetworkName string
-	ExternalVMNetworkName string
-	// IP addresses in these subnet ranges will be excluded when selecting
-	// the IP address from the VirtualMachine's VM for use in the
-	// status.addresses fields.
-	ExcludeInternalNetworkSubnetCIDR string
-	ExcludeExternalNetworkSubnetCIDR string
-}
+// Nodes captures internal/external networks. It is the type of the per-vCenter
+// nodes block as well, so that either can select the node addresses.
+type Nodes = vcfg.Nodes
 
 // CPIConfig is used to read and store information (related only to the CPI) from the cloud configuration file
 type CPIConfig struct {
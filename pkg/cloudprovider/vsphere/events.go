@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+
+	cm "k8s.io/cloud-provider-vsphere/pkg/common/connectionmanager"
+	credentialmanager "k8s.io/cloud-provider-vsphere/pkg/common/credentialmanager"
+)
+
+const (
+	// podNameEnv and podNamespaceEnv identify the pod of the CCM, set through
+	// the downward API, so that startup events can be attached to it.
+	podNameEnv      = "POD_NAME"
+	podNamespaceEnv = "POD_NAMESPACE"
+
+	// ReasonCredentialsValid is the reason of the startup event when all vCenter
+	// credentials are present.
+	ReasonCredentialsValid = "CredentialsValid"
+	// ReasonCredentialsInvalid is the reason of the startup event when vCenter
+	// credentials are missing or malformed.
+	ReasonCredentialsInvalid = "CredentialsInvalid"
+
+	// ReasonConfigReloaded is the reason of the event when a changed cloud-config
+	// was applied without a restart.
+	ReasonConfigReloaded = "ConfigReloaded"
+	// ReasonConfigReloadFailed is the reason of the event when a changed
+	// cloud-config was rejected, the previous config stays in effect.
+	ReasonConfigReloadFailed = "ConfigReloadFailed"
+	// ReasonConfigRestartRequired is the reason of the event when a changed
+	// cloud-config contains changes that only take effect after a restart.
+	ReasonConfigRestartRequired = "ConfigRestartRequired"
+)
+
+// newPodEventRecorder returns an event recorder and a reference to the pod of the
+// CCM, or nil if the pod is not known.
+func newPodEventRecorder(client clientset.Interface) (record.EventRecorder, *v1.ObjectReference) {
+	name, namespace := os.Getenv(podNameEnv), os.Getenv(podNamespaceEnv)
+	if name == "" || namespace == "" {
+		klog.V(2).Infof("%s or %s not set, events are not recorded", podNameEnv, podNamespaceEnv)
+		return nil, nil
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(namespace)})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: ClientName})
+
+	return recorder, &v1.ObjectReference{Kind: "Pod", APIVersion: "v1", Name: name, Namespace: namespace}
+}
+
+// reportCredentials validates the vCenter credentials once the secrets are synced
+// and publishes the result as an event on the pod of the CCM. Only key and server
+// names are reported, credential values are never included.
+func reportCredentials(connMgr *cm.ConnectionManager, recorder record.EventRecorder, pod *v1.ObjectReference, stop <-chan struct{}) {
+	validationErr := connMgr.ValidateCredentials(stop)
+	if validationErr != nil {
+		klog.Errorf("vCenter credentials validation failed: %v", validationErr)
+	}
+	reports := connMgr.CredentialReports()
+
+	if recorder == nil {
+		return
+	}
+
+	message := credentialsSummary(reports, validationErr)
+	if validationErr != nil {
+		recorder.Event(pod, v1.EventTypeWarning, ReasonCredentialsInvalid, message)
+		return
+	}
+	recorder.Event(pod, v1.EventTypeNormal, ReasonCredentialsValid, message)
+}
+
+// credentialsSummary returns a short description of the credential reports
+func credentialsSummary(reports map[string]*credentialmanager.ValidationReport, validationErr error) string {
+	secretRefs := make([]string, 0, len(reports))
+	for secretRef := range reports {
+		secretRefs = append(secretRefs, secretRef)
+	}
+	sort.Strings(secretRefs)
+
+	var parts []string
+	for _, secretRef := range secretRefs {
+		report := reports[secretRef]
+		var servers []string
+		for i := range report.Servers {
+			server := &report.Servers[i]
+			if missing := server.MissingKeys(); len(missing) > 0 {
+				servers = append(servers, fmt.Sprintf("%s missing %s", server.Server, strings.Join(missing, "/")))
+			} else {
+				servers = append(servers, server.Server+" ok")
+			}
+		}
+		part := fmt.Sprintf("%s: %s", secretRef, strings.Join(servers, ", "))
+		if len(report.MalformedTriplets) > 0 {
+			part += fmt.Sprintf("; %d malformed server_/username_/password_ sets", len(report.MalformedTriplets))
+		}
+		if len(report.UnknownKeys) > 0 {
+			part += fmt.Sprintf("; unknown keys %s", strings.Join(report.UnknownKeys, ", "))
+		}
+		parts = append(parts, part)
+	}
+	if validationErr != nil {
+		parts = append(parts, validationErr.Error())
+	}
+	if len(parts) == 0 {
+		return "vCenter credentials are set in the cloud config"
+	}
+	return strings.Join(parts, "; ")
+}
@@ -18,6 +18,8 @@ package loadbalancer
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -43,6 +45,19 @@ const (
 	ScopeIPPoolID = "ippoolid"
 	// ScopeLBClass is the load balancer class scope
 	ScopeLBClass = "lbclass"
+	// ScopeIPFamily is the IP family scope of virtual servers and pools, IPv4 if missing
+	ScopeIPFamily = "ipfamily"
+	// ScopeCertificate is the scope of the checksum of an imported certificate and key
+	ScopeCertificate = "certificate"
+	// ScopeRetainIP marks IP address allocations kept after the deletion of their service
+	ScopeRetainIP = "retainip"
+
+	// HealthCheckPath is the path probed on the health check node port of services with local external traffic policy
+	HealthCheckPath = "/healthz"
+	// HTTPAppProfilePath is the application profile of HTTP and HTTPS virtual servers
+	HTTPAppProfilePath = "/infra/lb-app-profiles/default-http-lb-app-profile"
+	// ClientSSLProfilePath is the client SSL profile of HTTPS virtual servers
+	ClientSSLProfilePath = "/infra/lb-client-ssl-profiles/default-balanced-client-ssl-profile"
 )
 
 type access struct {
@@ -50,6 +65,7 @@ type access struct {
 	config       *config.LBConfig
 	ownerTag     model.Tag
 	standardTags Tags
+	inventory    *inventory
 }
 
 var _ NSXTAccess = &access{}
@@ -67,9 +83,14 @@ func NewNSXTAccess(broker NsxtBroker, config *config.LBConfig) (NSXTAccess, erro
 		config:       config,
 		ownerTag:     standardTags[ScopeOwner],
 		standardTags: standardTags,
+		inventory:    newInventory(broker),
 	}, nil
 }
 
+func (a *access) InvalidateInventory() {
+	a.inventory.invalidate()
+}
+
 func (a *access) FindIPPoolByName(poolName string) (string, error) {
 	list, err := a.broker.ListIPPools()
 	if err != nil {
@@ -208,97 +229,115 @@ func (a *access) GetAppProfilePath(class LBClass, protocol corev1.Protocol) (str
 	return a.findAppProfilePathByName(profileReference.Name, resourceType)
 }
 
-func (a *access) CreateVirtualServer(clusterName string, objectName types.NamespacedName, class LBClass, ipAddress string,
-	mapping Mapping, lbServicePath, applicationProfilePath string, poolPath *string) (*model.LBVirtualServer, error) {
-	allTags := append(class.Tags(), clusterTag(clusterName), serviceTag(objectName), portTag(mapping))
+func (a *access) CreateVirtualServer(clusterName string, objectName types.NamespacedName, class LBClass, mapping Mapping,
+	spec VirtualServerSpec) (*model.LBVirtualServer, error) {
+	allTags := append(class.Tags(mapping.ipFamily()), clusterTag(clusterName), serviceTag(objectName), portTag(mapping), ipFamilyTag(mapping))
 	virtualServer := model.LBVirtualServer{
 		Description: strptr(fmt.Sprintf("virtual server for cluster %s, service %s created by %s",
 			clusterName, objectName, AppName)),
-		DisplayName:            displayNameObject(clusterName, objectName),
-		Tags:                   a.standardTags.Append(allTags...).Normalize(),
-		DefaultPoolMemberPorts: []string{fmt.Sprintf("%d", mapping.NodePort)},
-		Enabled:                boolptr(true),
-		IpAddress:              strptr(ipAddress),
-		ApplicationProfilePath: strptr(applicationProfilePath),
-		PoolPath:               poolPath,
-		Ports:                  []string{fmt.Sprintf("%d", mapping.SourcePort)},
-		LbServicePath:          strptr(lbServicePath),
+		DisplayName:              displayNameObject(clusterName, objectName),
+		Tags:                     a.standardTags.Append(allTags...).Normalize(),
+		DefaultPoolMemberPorts:   []string{fmt.Sprintf("%d", mapping.NodePort)},
+		Enabled:                  boolptr(true),
+		IpAddress:                spec.IPAddress,
+		ApplicationProfilePath:   strptr(spec.ApplicationProfilePath),
+		ClientSslProfileBinding:  spec.ClientSSLBinding,
+		AccessListControl:        spec.AccessListControl,
+		LbPersistenceProfilePath: spec.PersistenceProfilePath,
+		PoolPath:                 spec.PoolPath,
+		Ports:                    []string{fmt.Sprintf("%d", mapping.SourcePort)},
+		LbServicePath:            strptr(spec.LBServicePath),
 	}
 	result, err := a.broker.CreateLoadBalancerVirtualServer(virtualServer)
 	if err != nil {
-		return nil, errors.Wrapf(err, "creating virtual server failed for %s:%s with IP address %s", clusterName, objectName, ipAddress)
+		a.inventory.invalidate()
+		return nil, errors.Wrapf(err, "creating virtual server failed for %s:%s with IP address %s", clusterName, objectName, *spec.IPAddress)
 	}
+	a.inventory.put(resourceTypeVirtualServer, result, model.LBVirtualServerBindingType())
 	return &result, nil
 }
 
 func (a *access) FindVirtualServers(clusterName string, objectName types.NamespacedName) ([]*model.LBVirtualServer, error) {
-	return a.listVirtualServers(a.ownerTag, clusterTag(clusterName), serviceTag(objectName))
+	return a.listVirtualServers(clusterName, a.ownerTag, serviceTag(objectName))
 }
 
 func (a *access) ListVirtualServers(clusterName string) ([]*model.LBVirtualServer, error) {
-	return a.listVirtualServers(a.ownerTag, clusterTag(clusterName))
+	return a.listVirtualServers(clusterName, a.ownerTag)
 }
 
-func (a *access) listVirtualServers(tags ...model.Tag) ([]*model.LBVirtualServer, error) {
-	list, err := a.broker.ListLoadBalancerVirtualServers()
+func (a *access) listVirtualServers(clusterName string, tags ...model.Tag) ([]*model.LBVirtualServer, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeVirtualServer, tags...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "listing virtual servers failed")
 	}
 	var result []*model.LBVirtualServer
+	converter := newNsxtTypeConverter()
 	for _, item := range list {
-		if checkTags(item.Tags, tags...) {
-			itemCopy := item
-			result = append(result, &itemCopy)
+		server, err := converter.convertStructValueToLBVirtualServer(item)
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, &server)
 	}
 	return result, nil
 }
 
 func (a *access) UpdateVirtualServer(server *model.LBVirtualServer) error {
-	_, err := a.broker.UpdateLoadBalancerVirtualServer(*server)
+	result, err := a.broker.UpdateLoadBalancerVirtualServer(*server)
 	if err != nil {
+		a.inventory.invalidate()
 		return errors.Wrapf(err, "updating load balancer virtual server %s (%s) failed", *server.DisplayName, *server.Id)
 	}
+	a.inventory.put(resourceTypeVirtualServer, result, model.LBVirtualServerBindingType())
 	return nil
 }
 
 func (a *access) DeleteVirtualServer(id string) error {
 	err := a.broker.DeleteLoadBalancerVirtualServer(id)
-	if isNotFoundError(err) {
-		return nil
-	}
-	if err != nil {
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
 		return errors.Wrapf(err, "deleting virtual server %s failed", id)
 	}
+	a.inventory.remove(id, resourceTypeVirtualServer)
 	return nil
 }
 
-func (a *access) CreatePool(clusterName string, objectName types.NamespacedName, mapping Mapping, members []model.LBPoolMember, activeMonitorPaths []string) (*model.LBPool, error) {
-	var snatTranslation *data.StructValue
-	var err error
+func (a *access) PoolSnatTranslation() (*data.StructValue, error) {
 	if a.config.LoadBalancer.SnatDisabled {
-		snatTranslation, err = newNsxtTypeConverter().createLBSnatDisabled()
+		snatTranslation, err := newNsxtTypeConverter().createLBSnatDisabled()
 		if err != nil {
-			return nil, errors.Wrapf(err, "creating pool failed on preparing LBSnatDisabled failed")
-		}
-	} else {
-		snatTranslation, err = newNsxtTypeConverter().createLBSnatAutoMap()
-		if err != nil {
-			return nil, errors.Wrapf(err, "creating pool failed on preparing LBSnatAutoMap failed")
+			return nil, errors.Wrapf(err, "preparing LBSnatDisabled failed")
 		}
+		return snatTranslation, nil
+	}
+	snatTranslation, err := newNsxtTypeConverter().createLBSnatAutoMap()
+	if err != nil {
+		return nil, errors.Wrapf(err, "preparing LBSnatAutoMap failed")
+	}
+	return snatTranslation, nil
+}
+
+func (a *access) CreatePool(clusterName string, objectName types.NamespacedName, mapping Mapping, members []model.LBPoolMember,
+	activeMonitorPaths []string, algorithm string) (*model.LBPool, error) {
+	snatTranslation, err := a.PoolSnatTranslation()
+	if err != nil {
+		return nil, errors.Wrapf(err, "creating pool failed")
 	}
 	pool := model.LBPool{
 		Description:        strptr(fmt.Sprintf("pool for cluster %s, service %s created by %s", clusterName, objectName, AppName)),
 		DisplayName:        displayNameObject(clusterName, objectName),
-		Tags:               a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName), portTag(mapping)).Normalize(),
+		Tags:               a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName), portTag(mapping), ipFamilyTag(mapping)).Normalize(),
 		SnatTranslation:    snatTranslation,
 		Members:            members,
 		ActiveMonitorPaths: activeMonitorPaths,
+		Algorithm:          strptr(algorithm),
 	}
 	result, err := a.broker.CreateLoadBalancerPool(pool)
 	if err != nil {
+		a.inventory.invalidate()
 		return nil, errors.Wrapf(err, "creating pool failed for %s:%s", clusterName, objectName)
 	}
+	a.inventory.put(resourceTypePool, result, model.LBPoolBindingType())
 	return &result, nil
 }
 
@@ -311,58 +350,58 @@ func (a *access) GetPool(id string) (*model.LBPool, error) {
 }
 
 func (a *access) FindPool(clusterName string, objectName types.NamespacedName, mapping Mapping) (*model.LBPool, error) {
-	list, err := a.broker.ListLoadBalancerPools()
+	list, err := a.listPools(clusterName, a.ownerTag, serviceTag(objectName), portTag(mapping))
 	if err != nil {
-		return nil, errors.Wrapf(err, "listing load balancer pools failed")
+		return nil, err
 	}
-	tags := []model.Tag{a.ownerTag, clusterTag(clusterName), serviceTag(objectName), portTag(mapping)}
-	for _, item := range list {
-		if checkTags(item.Tags, tags...) {
-			return &item, nil
-		}
+	if len(list) == 0 {
+		return nil, nil
 	}
-	return nil, nil
+	return list[0], nil
 }
 
 func (a *access) FindPools(clusterName string, objectName types.NamespacedName) ([]*model.LBPool, error) {
-	return a.listPools(a.ownerTag, clusterTag(clusterName), serviceTag(objectName))
+	return a.listPools(clusterName, a.ownerTag, serviceTag(objectName))
 }
 
 func (a *access) ListPools(clusterName string) ([]*model.LBPool, error) {
-	return a.listPools(a.ownerTag, clusterTag(clusterName))
+	return a.listPools(clusterName, a.ownerTag)
 }
 
-func (a *access) listPools(tags ...model.Tag) ([]*model.LBPool, error) {
-	list, err := a.broker.ListLoadBalancerPools()
+func (a *access) listPools(clusterName string, tags ...model.Tag) ([]*model.LBPool, error) {
+	list, err := a.inventory.list(clusterName, resourceTypePool, tags...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "listing pools failed")
 	}
 	var result []*model.LBPool
+	converter := newNsxtTypeConverter()
 	for _, item := range list {
-		if checkTags(item.Tags, tags...) {
-			itemCopy := item
-			result = append(result, &itemCopy)
+		pool, err := converter.convertStructValueToLBPool(item)
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, &pool)
 	}
 	return result, nil
 }
 
 func (a *access) UpdatePool(pool *model.LBPool) error {
-	_, err := a.broker.UpdateLoadBalancerPool(*pool)
+	result, err := a.broker.UpdateLoadBalancerPool(*pool)
 	if err != nil {
+		a.inventory.invalidate()
 		return errors.Wrapf(err, "updating load balancer pool %s (%s) failed", *pool.DisplayName, *pool.Id)
 	}
+	a.inventory.put(resourceTypePool, result, model.LBPoolBindingType())
 	return nil
 }
 
 func (a *access) DeletePool(id string) error {
 	err := a.broker.DeleteLoadBalancerPool(id)
-	if isNotFoundError(err) {
-		return nil
-	}
-	if err != nil {
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
 		return errors.Wrapf(err, "deleting load balancer pool %s failed", id)
 	}
+	a.inventory.remove(id, resourceTypePool)
 	return nil
 }
 
@@ -376,8 +415,10 @@ func (a *access) CreateTCPMonitorProfile(clusterName string, objectName types.Na
 	}
 	monitor, err := a.broker.CreateLoadBalancerTCPMonitorProfile(profile)
 	if err != nil {
+		a.inventory.invalidate()
 		return nil, errors.Wrapf(err, "creating tcp monitor failed for %s:%s:%d", clusterName, objectName, mapping.NodePort)
 	}
+	a.inventory.put(resourceTypeTCPMonitorProfile, monitor, model.LBTcpMonitorProfileBindingType())
 	return &monitor, nil
 }
 
@@ -390,104 +431,421 @@ func (a *access) GetTCPMonitorProfile(id string) (*model.LBTcpMonitorProfile, er
 }
 
 func (a *access) FindTCPMonitorProfiles(clusterName string, objectName types.NamespacedName) ([]*model.LBTcpMonitorProfile, error) {
-	return a.listTCPMonitorProfiles(a.ownerTag, clusterTag(clusterName), serviceTag(objectName))
+	return a.listTCPMonitorProfiles(clusterName, a.ownerTag, serviceTag(objectName))
 }
 
 func (a *access) ListTCPMonitorProfiles(clusterName string) ([]*model.LBTcpMonitorProfile, error) {
-	return a.listTCPMonitorProfiles(a.ownerTag, clusterTag(clusterName))
+	return a.listTCPMonitorProfiles(clusterName, a.ownerTag)
 }
 
-func (a *access) listTCPMonitorProfiles(tags ...model.Tag) ([]*model.LBTcpMonitorProfile, error) {
-	list, err := a.broker.ListLoadBalancerMonitorProfiles()
+func (a *access) listTCPMonitorProfiles(clusterName string, tags ...model.Tag) ([]*model.LBTcpMonitorProfile, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeTCPMonitorProfile, tags...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "listing load balancer monitors failed")
 	}
 	result := []*model.LBTcpMonitorProfile{}
 	converter := newNsxtTypeConverter()
 	for _, item := range list {
-		resourceType, err := item.String("resource_type")
-		if err != nil || resourceType != model.LBMonitorProfile_RESOURCE_TYPE_LBTCPMONITORPROFILE {
-			continue
-		}
 		profile, err := converter.convertStructValueToLBTCPMonitorProfile(item)
 		if err != nil {
 			return nil, err
 		}
-		if checkTags(profile.Tags, tags...) {
-			result = append(result, &profile)
-		}
+		result = append(result, &profile)
 	}
 	return result, nil
 }
 
 func (a *access) UpdateTCPMonitorProfile(monitor *model.LBTcpMonitorProfile) error {
-	_, err := a.broker.UpdateLoadBalancerTCPMonitorProfile(*monitor)
+	result, err := a.broker.UpdateLoadBalancerTCPMonitorProfile(*monitor)
 	if err != nil {
+		a.inventory.invalidate()
 		return errors.Wrapf(err, "updating load balancer TCP monitor %s (%s) failed", *monitor.DisplayName, *monitor.Id)
 	}
+	a.inventory.put(resourceTypeTCPMonitorProfile, result, model.LBTcpMonitorProfileBindingType())
 	return nil
 }
 
 func (a *access) DeleteTCPMonitorProfile(id string) error {
+	return a.deleteMonitorProfile(id)
+}
+
+func (a *access) deleteMonitorProfile(id string) error {
 	err := a.broker.DeleteLoadBalancerMonitorProfile(id)
-	if isNotFoundError(err) {
-		return nil
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "deleting monitor %s failed", id)
 	}
+	a.inventory.remove(id, resourceTypeTCPMonitorProfile, resourceTypeHTTPMonitorProfile)
+	return nil
+}
+
+func (a *access) CreateHTTPMonitorProfile(clusterName string, objectName types.NamespacedName, mapping Mapping, healthCheckNodePort int) (*model.LBHttpMonitorProfile, error) {
+	profile := model.LBHttpMonitorProfile{
+		Description: strptr(fmt.Sprintf("http monitor for cluster %s, service %s, port %d created by %s",
+			clusterName, objectName, mapping.NodePort, AppName)),
+		DisplayName:         displayNameMapping(clusterName, objectName, mapping),
+		Tags:                a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName), portTag(mapping)).Normalize(),
+		MonitorPort:         int64ptr(int64(healthCheckNodePort)),
+		RequestMethod:       strptr(model.LBHttpMonitorProfile_REQUEST_METHOD_GET),
+		RequestUrl:          strptr(HealthCheckPath),
+		ResponseStatusCodes: []int64{200},
+	}
+	monitor, err := a.broker.CreateLoadBalancerHTTPMonitorProfile(profile)
 	if err != nil {
-		return errors.Wrapf(err, "deleting monitor %s failed", id)
+		a.inventory.invalidate()
+		return nil, errors.Wrapf(err, "creating http monitor failed for %s:%s:%d", clusterName, objectName, mapping.NodePort)
+	}
+	a.inventory.put(resourceTypeHTTPMonitorProfile, monitor, model.LBHttpMonitorProfileBindingType())
+	return &monitor, nil
+}
+
+func (a *access) FindHTTPMonitorProfiles(clusterName string, objectName types.NamespacedName) ([]*model.LBHttpMonitorProfile, error) {
+	return a.listHTTPMonitorProfiles(clusterName, a.ownerTag, serviceTag(objectName))
+}
+
+func (a *access) ListHTTPMonitorProfiles(clusterName string) ([]*model.LBHttpMonitorProfile, error) {
+	return a.listHTTPMonitorProfiles(clusterName, a.ownerTag)
+}
+
+func (a *access) listHTTPMonitorProfiles(clusterName string, tags ...model.Tag) ([]*model.LBHttpMonitorProfile, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeHTTPMonitorProfile, tags...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing load balancer monitors failed")
+	}
+	result := []*model.LBHttpMonitorProfile{}
+	converter := newNsxtTypeConverter()
+	for _, item := range list {
+		profile, err := converter.convertStructValueToLBHTTPMonitorProfile(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &profile)
+	}
+	return result, nil
+}
+
+func (a *access) UpdateHTTPMonitorProfile(monitor *model.LBHttpMonitorProfile) error {
+	result, err := a.broker.UpdateLoadBalancerHTTPMonitorProfile(*monitor)
+	if err != nil {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "updating load balancer HTTP monitor %s (%s) failed", *monitor.DisplayName, *monitor.Id)
+	}
+	a.inventory.put(resourceTypeHTTPMonitorProfile, result, model.LBHttpMonitorProfileBindingType())
+	return nil
+}
+
+func (a *access) DeleteHTTPMonitorProfile(id string) error {
+	return a.deleteMonitorProfile(id)
+}
+
+func (a *access) CreateSourceIPPersistenceProfile(clusterName string, objectName types.NamespacedName, timeout int64) (*model.LBSourceIpPersistenceProfile, error) {
+	profile := model.LBSourceIpPersistenceProfile{
+		Description: strptr(fmt.Sprintf("source IP persistence profile for cluster %s, service %s created by %s", clusterName, objectName, AppName)),
+		DisplayName: displayNameObject(clusterName, objectName),
+		Tags:        a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName)).Normalize(),
+		Timeout:     int64ptr(timeout),
+	}
+	result, err := a.broker.CreateLoadBalancerSourceIPPersistenceProfile(profile)
+	if err != nil {
+		a.inventory.invalidate()
+		return nil, errors.Wrapf(err, "creating source IP persistence profile failed for %s:%s", clusterName, objectName)
+	}
+	a.inventory.put(resourceTypeSourceIPPersistenceProfile, result, model.LBSourceIpPersistenceProfileBindingType())
+	return &result, nil
+}
+
+func (a *access) FindSourceIPPersistenceProfiles(clusterName string, objectName types.NamespacedName) ([]*model.LBSourceIpPersistenceProfile, error) {
+	return a.listSourceIPPersistenceProfiles(clusterName, a.ownerTag, serviceTag(objectName))
+}
+
+func (a *access) ListSourceIPPersistenceProfiles(clusterName string) ([]*model.LBSourceIpPersistenceProfile, error) {
+	return a.listSourceIPPersistenceProfiles(clusterName, a.ownerTag)
+}
+
+func (a *access) listSourceIPPersistenceProfiles(clusterName string, tags ...model.Tag) ([]*model.LBSourceIpPersistenceProfile, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeSourceIPPersistenceProfile, tags...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing load balancer persistence profiles failed")
+	}
+	result := []*model.LBSourceIpPersistenceProfile{}
+	converter := newNsxtTypeConverter()
+	for _, item := range list {
+		profile, err := converter.convertStructValueToLBSourceIPPersistenceProfile(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &profile)
+	}
+	return result, nil
+}
+
+func (a *access) UpdateSourceIPPersistenceProfile(profile *model.LBSourceIpPersistenceProfile) error {
+	result, err := a.broker.UpdateLoadBalancerSourceIPPersistenceProfile(*profile)
+	if err != nil {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "updating source IP persistence profile %s (%s) failed", *profile.DisplayName, *profile.Id)
+	}
+	a.inventory.put(resourceTypeSourceIPPersistenceProfile, result, model.LBSourceIpPersistenceProfileBindingType())
+	return nil
+}
+
+func (a *access) DeleteSourceIPPersistenceProfile(id string) error {
+	err := a.broker.DeleteLoadBalancerPersistenceProfile(id)
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "deleting persistence profile %s failed", id)
+	}
+	a.inventory.remove(id, resourceTypeSourceIPPersistenceProfile)
+	return nil
+}
+
+func (a *access) CreateCertificate(clusterName string, objectName types.NamespacedName, checksum string, certificatePEM, keyPEM []byte) (*model.TlsCertificate, error) {
+	trustData := model.TlsTrustData{
+		Description: strptr(fmt.Sprintf("certificate for cluster %s, service %s created by %s", clusterName, objectName, AppName)),
+		DisplayName: displayNameObject(clusterName, objectName),
+		Tags:        a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName), newTag(ScopeCertificate, checksum)).Normalize(),
+		PemEncoded:  strptr(string(certificatePEM)),
+		PrivateKey:  strptr(string(keyPEM)),
+	}
+	certificate, err := a.broker.ImportCertificate(trustData)
+	if err != nil {
+		a.inventory.invalidate()
+		return nil, errors.Wrapf(err, "importing certificate failed for %s:%s", clusterName, objectName)
+	}
+	a.inventory.put(resourceTypeCertificate, certificate, model.TlsCertificateBindingType())
+	return &certificate, nil
+}
+
+func (a *access) FindCertificates(clusterName string, objectName types.NamespacedName) ([]*model.TlsCertificate, error) {
+	return a.listCertificates(clusterName, a.ownerTag, serviceTag(objectName))
+}
+
+func (a *access) ListCertificates(clusterName string) ([]*model.TlsCertificate, error) {
+	return a.listCertificates(clusterName, a.ownerTag)
+}
+
+func (a *access) listCertificates(clusterName string, tags ...model.Tag) ([]*model.TlsCertificate, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeCertificate, tags...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing certificates failed")
+	}
+	var result []*model.TlsCertificate
+	converter := newNsxtTypeConverter()
+	for _, item := range list {
+		certificate, err := converter.convertStructValueToTLSCertificate(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &certificate)
+	}
+	return result, nil
+}
+
+func (a *access) DeleteCertificate(id string) error {
+	err := a.broker.DeleteCertificate(id)
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "deleting certificate %s failed", id)
+	}
+	a.inventory.remove(id, resourceTypeCertificate)
+	return nil
+}
+
+func (a *access) CreateSourceRangesGroup(clusterName string, objectName types.NamespacedName, ipAddresses []string) (*model.Group, error) {
+	group := model.Group{
+		Description: strptr(fmt.Sprintf("source ranges for cluster %s, service %s created by %s", clusterName, objectName, AppName)),
+		DisplayName: displayNameObject(clusterName, objectName),
+		Tags:        a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName)).Normalize(),
+	}
+	err := setGroupIPAddresses(&group, ipAddresses)
+	if err != nil {
+		return nil, err
+	}
+	result, err := a.broker.CreateGroup(group)
+	if err != nil {
+		a.inventory.invalidate()
+		return nil, errors.Wrapf(err, "creating source ranges group failed for %s:%s", clusterName, objectName)
+	}
+	a.inventory.put(resourceTypeGroup, result, model.GroupBindingType())
+	return &result, nil
+}
+
+func (a *access) FindSourceRangesGroups(clusterName string, objectName types.NamespacedName) ([]*model.Group, error) {
+	return a.listSourceRangesGroups(clusterName, a.ownerTag, serviceTag(objectName))
+}
+
+func (a *access) ListSourceRangesGroups(clusterName string) ([]*model.Group, error) {
+	return a.listSourceRangesGroups(clusterName, a.ownerTag)
+}
+
+func (a *access) listSourceRangesGroups(clusterName string, tags ...model.Tag) ([]*model.Group, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeGroup, tags...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing groups failed")
+	}
+	var result []*model.Group
+	converter := newNsxtTypeConverter()
+	for _, item := range list {
+		group, err := converter.convertStructValueToGroup(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &group)
+	}
+	return result, nil
+}
+
+func (a *access) UpdateSourceRangesGroup(group *model.Group, ipAddresses []string) error {
+	err := setGroupIPAddresses(group, ipAddresses)
+	if err != nil {
+		return err
+	}
+	result, err := a.broker.UpdateGroup(*group)
+	if err != nil {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "updating source ranges group %s (%s) failed", *group.DisplayName, *group.Id)
+	}
+	a.inventory.put(resourceTypeGroup, result, model.GroupBindingType())
+	return nil
+}
+
+func (a *access) DeleteSourceRangesGroup(id string) error {
+	err := a.broker.DeleteGroup(id)
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "deleting source ranges group %s failed", id)
 	}
+	a.inventory.remove(id, resourceTypeGroup)
 	return nil
 }
 
-func (a *access) AllocateExternalIPAddress(ipPoolID string, clusterName string, objectName types.NamespacedName) (*model.IpAddressAllocation, *string, error) {
+func (a *access) AllocateExternalIPAddress(ipPoolID string, clusterName string, objectName types.NamespacedName, ipAddress *string, retain bool) (*model.IpAddressAllocation, *string, error) {
+	tags := a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName))
+	if retain {
+		tags = tags.Append(retainIPTag())
+	}
 	allocation := model.IpAddressAllocation{
-		Tags: a.standardTags.Append(clusterTag(clusterName), serviceTag(objectName)).Normalize(),
+		AllocationIp: ipAddress,
+		Tags:         tags.Normalize(),
 	}
 	allocated, ipAdress, err := a.broker.AllocateFromIPPool(ipPoolID, allocation)
 	if err != nil {
+		a.inventory.invalidate()
 		return nil, nil, errors.Wrapf(err, "allocating external IP address failed")
 	}
+	a.inventory.put(resourceTypeIPAddressAllocation, allocated, model.IpAddressAllocationBindingType())
 	return &allocated, &ipAdress, nil
 }
 
-func (a *access) FindExternalIPAddressForObject(ipPoolID string, clusterName string, objectName types.NamespacedName) (*model.IpAddressAllocation, *string, error) {
-	results, err := a.findExternalIPAddresses(ipPoolID, a.ownerTag, clusterTag(clusterName), serviceTag(objectName))
+func (a *access) FindExternalIPAddressesForObject(ipPoolID string, clusterName string, objectName types.NamespacedName) ([]*model.IpAddressAllocation, error) {
+	return a.findExternalIPAddresses(ipPoolID, clusterName, a.ownerTag, serviceTag(objectName))
+}
+
+func (a *access) GetExternalIPAddress(ipPoolID string, allocation *model.IpAddressAllocation) (*string, error) {
+	if allocation.AllocationIp != nil {
+		return allocation.AllocationIp, nil
+	}
+	ipAddress, err := a.broker.GetRealizedExternalIPAddress(*allocation.Path, 5*time.Second)
 	if err != nil {
-		return nil, nil, err
+		return nil, errors.Wrapf(err, "GetReleaziedExternalIPAddress failed for allocation %s IP pool %s failed", *allocation.Path, ipPoolID)
 	}
-	if len(results) == 0 {
-		return nil, nil, nil
+	return ipAddress, nil
+}
+
+func (a *access) FindExternalIPAddressAllocation(ipPoolID string, ipAddress string) (*model.IpAddressAllocation, error) {
+	list, err := a.broker.ListIPPoolAllocations(ipPoolID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing IP address allocations from IP pool %s failed", ipPoolID)
 	}
-	if len(results) > 1 {
-		return nil, nil, fmt.Errorf("Multiple IP address allocations")
+	for _, item := range list {
+		if item.AllocationIp != nil && net.ParseIP(*item.AllocationIp).Equal(net.ParseIP(ipAddress)) {
+			return &item, nil
+		}
 	}
+	return nil, nil
+}
 
-	item := results[0]
-	ipAddress := item.AllocationIp
-	if ipAddress == nil {
-		ipAddress, err = a.broker.GetRealizedExternalIPAddress(*item.Path, 5*time.Second)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "GetReleaziedExternalIPAddress failed for allocation %s IP pool %s failed", *item.Path, ipPoolID)
+func (a *access) UpdateExternalIPAddressRetention(ipPoolID string, allocation *model.IpAddressAllocation, retain bool) error {
+	tags := Tags{}
+	for _, tag := range allocation.Tags {
+		if *tag.Scope != ScopeRetainIP {
+			tags = tags.Append(tag)
 		}
 	}
+	if retain {
+		tags = tags.Append(retainIPTag())
+	}
+	allocation.Tags = tags.Normalize()
+	err := a.broker.UpdateIPPoolAllocation(ipPoolID, *allocation)
+	if err != nil {
+		a.inventory.invalidate()
+		return errors.Wrapf(err, "updating IP address allocation %s failed", *allocation.Id)
+	}
+	a.inventory.put(resourceTypeIPAddressAllocation, *allocation, model.IpAddressAllocationBindingType())
+	return nil
+}
 
-	return item, ipAddress, nil
+func (a *access) IPPoolContainsIPAddress(ipPoolID string, ipAddress string) (bool, error) {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false, fmt.Errorf("invalid IP address %q", ipAddress)
+	}
+	list, err := a.broker.ListIPPoolSubnets(ipPoolID)
+	if err != nil {
+		return false, errors.Wrapf(err, "listing subnets of IP pool %s failed", ipPoolID)
+	}
+	converter := newNsxtTypeConverter()
+	for _, item := range list {
+		resourceType, err := item.String("resource_type")
+		if err != nil {
+			continue
+		}
+		switch resourceType {
+		case model.IpAddressPoolSubnet_RESOURCE_TYPE_IPADDRESSPOOLSTATICSUBNET:
+			subnet, err := converter.convertStructValueToIPAddressPoolStaticSubnet(item)
+			if err != nil {
+				return false, err
+			}
+			for _, r := range subnet.AllocationRanges {
+				if r.Start != nil && r.End != nil && ipInRange(ip, *r.Start, *r.End) {
+					return true, nil
+				}
+			}
+		case model.IpAddressPoolSubnet_RESOURCE_TYPE_IPADDRESSPOOLBLOCKSUBNET:
+			subnet, err := converter.convertStructValueToIPAddressPoolBlockSubnet(item)
+			if err != nil {
+				return false, err
+			}
+			if subnet.Cidr == nil {
+				continue
+			}
+			_, cidr, err := net.ParseCIDR(*subnet.Cidr)
+			if err == nil && cidr.Contains(ip) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
 }
 
 func (a *access) ListExternalIPAddresses(ipPoolID string, clusterName string) ([]*model.IpAddressAllocation, error) {
-	return a.findExternalIPAddresses(ipPoolID, a.ownerTag, clusterTag(clusterName))
+	return a.findExternalIPAddresses(ipPoolID, clusterName, a.ownerTag)
 }
 
-func (a *access) findExternalIPAddresses(ipPoolID string, tags ...model.Tag) ([]*model.IpAddressAllocation, error) {
-	list, err := a.broker.ListIPPoolAllocations(ipPoolID)
+func (a *access) findExternalIPAddresses(ipPoolID string, clusterName string, tags ...model.Tag) ([]*model.IpAddressAllocation, error) {
+	list, err := a.inventory.list(clusterName, resourceTypeIPAddressAllocation, tags...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "listing IP address allocations from IP pool %s failed", ipPoolID)
 	}
 	results := []*model.IpAddressAllocation{}
+	converter := newNsxtTypeConverter()
 	for _, item := range list {
-		if checkTags(item.Tags, tags...) {
-			itemCopy := item
-			results = append(results, &itemCopy)
+		allocation, err := converter.convertStructValueToIPAddressAllocation(item)
+		if err != nil {
+			return nil, err
+		}
+		if allocation.Path != nil && strings.HasPrefix(*allocation.Path, ipPoolAllocationsPath(ipPoolID)) {
+			results = append(results, &allocation)
 		}
 	}
 	return results, nil
@@ -495,15 +853,19 @@ func (a *access) findExternalIPAddresses(ipPoolID string, tags ...model.Tag) ([]
 
 func (a *access) ReleaseExternalIPAddress(ipPoolID string, id string) error {
 	err := a.broker.ReleaseFromIPPool(ipPoolID, id)
-	if isNotFoundError(err) {
-		return nil
-	}
-	if err != nil {
+	if err != nil && !isNotFoundError(err) {
+		a.inventory.invalidate()
 		return errors.Wrapf(err, "releasing external IP address allocation id=%s failed", id)
 	}
+	a.inventory.remove(id, resourceTypeIPAddressAllocation)
 	return nil
 }
 
+// ipPoolAllocationsPath is the path prefix of the IP address allocations of an IP pool
+func ipPoolAllocationsPath(ipPoolID string) string {
+	return fmt.Sprintf("/infra/ip-pools/%s/ip-allocations/", ipPoolID)
+}
+
 func displayName(clusterName string) *string {
 	return strptr(fmt.Sprintf("cluster:%s", clusterName))
 }
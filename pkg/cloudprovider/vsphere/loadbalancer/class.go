@@ -35,10 +35,9 @@ type loadBalancerClasses struct {
 type loadBalancerClass struct {
 	className     string
 	ipPool        Reference
+	ipv6Pool      Reference
 	tcpAppProfile Reference
 	udpAppProfile Reference
-
-	tags []model.Tag
 }
 
 func setupClasses(access NSXTAccess, cfg *config.LBConfig) (*loadBalancerClasses, error) {
@@ -125,6 +124,10 @@ func newLBClass(name string, classConfig *config.LoadBalancerClassConfig, defaul
 			Identifier: classConfig.IPPoolID,
 			Name:       classConfig.IPPoolName,
 		},
+		ipv6Pool: Reference{
+			Identifier: classConfig.IPv6PoolID,
+			Name:       classConfig.IPv6PoolName,
+		},
 		tcpAppProfile: Reference{
 			Identifier: classConfig.TCPAppProfilePath,
 			Name:       classConfig.TCPAppProfileName,
@@ -138,6 +141,9 @@ func newLBClass(name string, classConfig *config.LoadBalancerClassConfig, defaul
 		if class.ipPool.IsEmpty() {
 			class.ipPool = defaults.ipPool
 		}
+		if class.ipv6Pool.IsEmpty() {
+			class.ipv6Pool = defaults.ipv6Pool
+		}
 		if class.tcpAppProfile.IsEmpty() {
 			class.tcpAppProfile = defaults.tcpAppProfile
 		}
@@ -150,19 +156,33 @@ func newLBClass(name string, classConfig *config.LoadBalancerClassConfig, defaul
 		if err != nil {
 			return nil, err
 		}
-	} else if class.ipPool.Identifier == "" {
+		if !class.ipv6Pool.IsEmpty() {
+			err = resolver.resolve(&class.ipv6Pool)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else if class.ipPool.Identifier == "" || !class.ipv6Pool.IsEmpty() && class.ipv6Pool.Identifier == "" {
 		return nil, fmt.Errorf("ipPoolResolver needed if IP pool ID not provided")
 	}
-	class.tags = []model.Tag{
-		newTag(ScopeIPPoolID, class.ipPool.Identifier),
-		newTag(ScopeLBClass, class.className),
-	}
 
 	return &class, nil
 }
 
-func (c *loadBalancerClass) Tags() []model.Tag {
-	return c.tags
+// IPPool returns the IP pool of the virtual IP addresses of an IP family, an
+// empty reference if no pool is configured for the family
+func (c *loadBalancerClass) IPPool(family corev1.IPFamily) Reference {
+	if family == corev1.IPv6Protocol {
+		return c.ipv6Pool
+	}
+	return c.ipPool
+}
+
+func (c *loadBalancerClass) Tags(family corev1.IPFamily) []model.Tag {
+	return []model.Tag{
+		newTag(ScopeIPPoolID, c.IPPool(family).Identifier),
+		newTag(ScopeLBClass, c.className),
+	}
 }
 
 func (c *loadBalancerClass) AppProfile(protocol corev1.Protocol) (Reference, error) {
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -45,6 +46,8 @@ const maxPeriod = 30 * time.Minute
 // to identify all elements originally created by this controller. By
 // comparing this set with the actually required objects it is possible
 // to identify those that are orphaned and safely delete them.
+// Afterwards changes made out-of-band to the NSX-T objects of the load
+// balancers of all existing services are reverted.
 func (p *lbProvider) cleanup(clusterName string, client clientcorev1.ServiceInterface, stop <-chan struct{}) {
 	timer := time.NewTimer(1 * time.Second)
 	lastErrNext := 0 * time.Second
@@ -77,6 +80,8 @@ func (p *lbProvider) cleanup(clusterName string, client clientcorev1.ServiceInte
 
 func (p *lbProvider) doCleanupStep(clusterName string, client clientcorev1.ServiceInterface) error {
 	klog.Infof("starting cleanup...")
+	// objects changed or deleted out-of-band are only seen after a new search
+	p.access.InvalidateInventory()
 	list, err := client.List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
 		return err
@@ -89,28 +94,59 @@ func (p *lbProvider) doCleanupStep(clusterName string, client clientcorev1.Servi
 		}
 	}
 
-	return p.CleanupServices(clusterName, services, false)
+	err = p.CleanupServices(clusterName, services, false)
+	if err != nil {
+		return err
+	}
+	p.resyncServices(clusterName, services)
+	return nil
+}
+
+// resyncServices corrects drift of the NSX-T objects of the load balancers of
+// the services. Failures of single services are only logged, they are retried
+// by the next resync.
+func (p *lbProvider) resyncServices(clusterName string, services map[types.NamespacedName]corev1.Service) {
+	klog.Infof("resync: checking %d services", len(services))
+	for name, item := range services {
+		service := item
+		if service.DeletionTimestamp != nil || service.Spec.LoadBalancerClass != nil {
+			continue
+		}
+		if err := p.resyncLoadBalancer(clusterName, &service); err != nil {
+			klog.Warningf("resync of service %s failed: %s", name, err)
+		}
+	}
+}
+
+// resyncLoadBalancer corrects drift of the load balancer of the service, see state.Resync
+func (p *lbProvider) resyncLoadBalancer(clusterName string, service *corev1.Service) error {
+	key := namespacedNameFromService(service).String()
+	p.keyLock.Lock(key)
+	defer p.keyLock.Unlock(key)
+
+	class, err := p.classFromService(service)
+	if err != nil {
+		return err
+	}
+	return newState(p.lbService, p.secrets, p.recorder, clusterName, service, nil).Resync(class)
 }
 
 func (p *lbProvider) CleanupServices(clusterName string, validServices map[types.NamespacedName]corev1.Service, ensureLBServiceDeleted bool) error {
 	ipPoolIds := sets.NewString()
-	for _, name := range p.classes.GetClassNames() {
-		class := p.classes.GetClass(name)
-		ipPoolIds.Insert(class.ipPool.Identifier)
+	classes := p.getClasses()
+	for _, name := range classes.GetClassNames() {
+		class := classes.GetClass(name)
+		ipPoolIds.Insert(class.ipPool.Identifier, class.ipv6Pool.Identifier)
 	}
 
-	lbs := map[types.NamespacedName]struct{}{}
+	var tags [][]model.Tag
 	servers, err := p.access.ListVirtualServers(clusterName)
 	if err != nil {
 		return err
 	}
 	for _, server := range servers {
-		tag := getTag(server.Tags, ScopeService)
-		if tag != "" {
-			lbs[parseNamespacedName(tag)] = struct{}{}
-		}
-		ipPoolID := getTag(server.Tags, ScopeIPPoolID)
-		ipPoolIds.Insert(ipPoolID)
+		tags = append(tags, server.Tags)
+		ipPoolIds.Insert(getTag(server.Tags, ScopeIPPoolID))
 	}
 	ipPoolIds.Delete("")
 
@@ -119,21 +155,47 @@ func (p *lbProvider) CleanupServices(clusterName string, validServices map[types
 		return err
 	}
 	for _, pool := range pools {
-		tag := getTag(pool.Tags, ScopeService)
-		if tag != "" {
-			lbs[parseNamespacedName(tag)] = struct{}{}
-		}
+		tags = append(tags, pool.Tags)
 	}
 
 	monitors, err := p.access.ListTCPMonitorProfiles(clusterName)
 	if err != nil {
 		return err
 	}
-	for _, pool := range monitors {
-		tag := getTag(pool.Tags, ScopeService)
-		if tag != "" {
-			lbs[parseNamespacedName(tag)] = struct{}{}
-		}
+	for _, monitor := range monitors {
+		tags = append(tags, monitor.Tags)
+	}
+
+	persistenceProfiles, err := p.access.ListSourceIPPersistenceProfiles(clusterName)
+	if err != nil {
+		return err
+	}
+	for _, profile := range persistenceProfiles {
+		tags = append(tags, profile.Tags)
+	}
+
+	certificates, err := p.access.ListCertificates(clusterName)
+	if err != nil {
+		return err
+	}
+	for _, certificate := range certificates {
+		tags = append(tags, certificate.Tags)
+	}
+
+	groups, err := p.access.ListSourceRangesGroups(clusterName)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		tags = append(tags, group.Tags)
+	}
+
+	httpMonitors, err := p.access.ListHTTPMonitorProfiles(clusterName)
+	if err != nil {
+		return err
+	}
+	for _, monitor := range httpMonitors {
+		tags = append(tags, monitor.Tags)
 	}
 
 	for ipPoolID := range ipPoolIds {
@@ -142,13 +204,13 @@ func (p *lbProvider) CleanupServices(clusterName string, validServices map[types
 			return err
 		}
 		for _, ipAddressAlloc := range ipAddressAllocs {
-			tag := getTag(ipAddressAlloc.Tags, ScopeService)
-			if tag != "" {
-				lbs[parseNamespacedName(tag)] = struct{}{}
+			if !isRetained(ipAddressAlloc.Tags) {
+				tags = append(tags, ipAddressAlloc.Tags)
 			}
 		}
 	}
 
+	lbs := servicesFromTags(tags...)
 	klog.Infof("cleanup: %d existing services, artefacts for %d services", len(validServices), len(lbs))
 	for lb := range lbs {
 		if svc, ok := validServices[lb]; !ok || svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
@@ -175,3 +237,14 @@ func (p *lbProvider) CleanupServices(clusterName string, validServices map[types
 	}
 	return nil
 }
+
+// servicesFromTags returns the services of the ScopeService tags of the objects
+func servicesFromTags(tags ...[]model.Tag) map[types.NamespacedName]struct{} {
+	lbs := map[types.NamespacedName]struct{}{}
+	for _, objectTags := range tags {
+		if tag := getTag(objectTags, ScopeService); tag != "" {
+			lbs[parseNamespacedName(tag)] = struct{}{}
+		}
+	}
+	return lbs
+}
@@ -0,0 +1,107 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	servicehelper "k8s.io/cloud-provider/service/helpers"
+	klog "k8s.io/klog/v2"
+)
+
+// ConditionLoadBalancerReady is the type of the service condition reporting
+// the result of the last reconciliation of the NSX-T load balancer
+const ConditionLoadBalancerReady = "loadbalancer.vmware.io/Ready"
+
+// reconcileError is an error of the reconciliation of a load balancer with
+// the reason reported in the service condition
+type reconcileError struct {
+	reason string
+	err    error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.err
+}
+
+// withReason returns the error with the reason of the service condition, nil
+// if the error is nil
+func withReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reconcileError{reason: reason, err: err}
+}
+
+// reasonOf returns the reason of the service condition for an error
+func reasonOf(err error) string {
+	var rerr *reconcileError
+	if errors.As(err, &rerr) {
+		return rerr.reason
+	}
+	return ReasonReconcileFailed
+}
+
+// readyCondition returns the service condition for the result of a
+// reconciliation
+func readyCondition(service *corev1.Service, err error) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               ConditionLoadBalancerReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: service.Generation,
+		Reason:             ReasonReconciled,
+		Message:            "NSX-T load balancer is up to date",
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonOf(err)
+		condition.Message = err.Error()
+	}
+	return condition
+}
+
+// setReadyCondition reports the result of a reconciliation in the conditions
+// of the service, the condition is removed if the load balancer is deleted.
+// The status is patched from the given service and only if the condition
+// changes.
+func setReadyCondition(client clientcorev1.CoreV1Interface, service *corev1.Service, err error, deleted bool) {
+	if client == nil {
+		return
+	}
+	updated := service.DeepCopy()
+	var changed bool
+	if deleted {
+		changed = meta.RemoveStatusCondition(&updated.Status.Conditions, ConditionLoadBalancerReady)
+	} else {
+		changed = meta.SetStatusCondition(&updated.Status.Conditions, readyCondition(service, err))
+	}
+	if !changed {
+		return
+	}
+	_, updateErr := servicehelper.PatchService(client, service, updated)
+	if updateErr != nil && !apierrors.IsNotFound(updateErr) {
+		klog.Warningf("updating condition %s of service %s/%s failed: %s", ConditionLoadBalancerReady, service.Namespace, service.Name, updateErr)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 
 	klog "k8s.io/klog/v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
 /*
@@ -28,8 +30,12 @@ import (
 */
 
 // IsEnabled checks whether the load balancer feature is enabled
-// It is enabled if any flavor of the load balancer configuration is given.
+// It is enabled if any flavor of the load balancer configuration is given,
+// unless it is explicitly enabled or disabled.
 func (cfg *LBConfig) IsEnabled() bool {
+	if cfg.LoadBalancer.Enabled != nil {
+		return *cfg.LoadBalancer.Enabled
+	}
 	return len(cfg.LoadBalancerClass) > 0 || !cfg.LoadBalancer.IsEmpty()
 }
 
@@ -49,11 +55,17 @@ func (cfg *LoadBalancerConfig) IsEmpty() bool {
 // ReadLBConfig parses vSphere cloud config file and stores it into VSphereConfig.
 // Environment variables are also checked
 func ReadLBConfig(byConfig []byte) (*LBConfig, error) {
+	return ReadLBConfigWithEnv(byConfig, vcfg.OSEnv)
+}
+
+// ReadLBConfigWithEnv parses the config like ReadLBConfig, with the variables
+// of env overriding its keys.
+func ReadLBConfigWithEnv(byConfig []byte, env vcfg.Env) (*LBConfig, error) {
 	if len(byConfig) == 0 {
 		return nil, fmt.Errorf("Invalid YAML/INI file")
 	}
 
-	cfg, err := ReadConfigYAML(byConfig)
+	cfg, err := readConfigYAML(byConfig, env)
 	if err != nil {
 		klog.Warningf("ReadConfigYAML failed: %s", err)
 
@@ -41,11 +41,14 @@ func (lbc *LBConfigINI) CreateConfig() *LBConfig {
 	//LoadBalancerClassConfig
 	cfg.LoadBalancer.IPPoolName = lbc.LoadBalancer.IPPoolName
 	cfg.LoadBalancer.IPPoolID = lbc.LoadBalancer.IPPoolID
+	cfg.LoadBalancer.IPv6PoolName = lbc.LoadBalancer.IPv6PoolName
+	cfg.LoadBalancer.IPv6PoolID = lbc.LoadBalancer.IPv6PoolID
 	cfg.LoadBalancer.TCPAppProfileName = lbc.LoadBalancer.TCPAppProfileName
 	cfg.LoadBalancer.TCPAppProfilePath = lbc.LoadBalancer.TCPAppProfilePath
 	cfg.LoadBalancer.UDPAppProfileName = lbc.LoadBalancer.UDPAppProfileName
 	cfg.LoadBalancer.UDPAppProfilePath = lbc.LoadBalancer.UDPAppProfilePath
 	//LoadBalancerClassConfig -> LoadBalancerConfig
+	cfg.LoadBalancer.Enabled = lbc.LoadBalancer.Enabled
 	cfg.LoadBalancer.Size = lbc.LoadBalancer.Size
 	cfg.LoadBalancer.LBServiceID = lbc.LoadBalancer.LBServiceID
 	cfg.LoadBalancer.Tier1GatewayPath = lbc.LoadBalancer.Tier1GatewayPath
@@ -57,6 +60,8 @@ func (lbc *LBConfigINI) CreateConfig() *LBConfig {
 		cfg.LoadBalancerClass[key] = &LoadBalancerClassConfig{
 			IPPoolName:        value.IPPoolName,
 			IPPoolID:          value.IPPoolID,
+			IPv6PoolName:      value.IPv6PoolName,
+			IPv6PoolID:        value.IPv6PoolID,
 			TCPAppProfileName: value.TCPAppProfileName,
 			TCPAppProfilePath: value.TCPAppProfilePath,
 			UDPAppProfileName: value.UDPAppProfileName,
@@ -68,6 +73,9 @@ func (lbc *LBConfigINI) CreateConfig() *LBConfig {
 }
 
 func (lbc *LBConfigINI) isEnabled() bool {
+	if lbc.LoadBalancer.Enabled != nil {
+		return *lbc.LoadBalancer.Enabled
+	}
 	return len(lbc.LoadBalancerClass) > 0 || !lbc.LoadBalancer.isEmpty()
 }
 
@@ -22,6 +22,8 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 	klog "k8s.io/klog/v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
 /*
@@ -40,11 +42,14 @@ func (lbc *LBConfigYAML) CreateConfig() *LBConfig {
 	//LoadBalancerClassConfig
 	cfg.LoadBalancer.IPPoolName = lbc.LoadBalancer.IPPoolName
 	cfg.LoadBalancer.IPPoolID = lbc.LoadBalancer.IPPoolID
+	cfg.LoadBalancer.IPv6PoolName = lbc.LoadBalancer.IPv6PoolName
+	cfg.LoadBalancer.IPv6PoolID = lbc.LoadBalancer.IPv6PoolID
 	cfg.LoadBalancer.TCPAppProfileName = lbc.LoadBalancer.TCPAppProfileName
 	cfg.LoadBalancer.TCPAppProfilePath = lbc.LoadBalancer.TCPAppProfilePath
 	cfg.LoadBalancer.UDPAppProfileName = lbc.LoadBalancer.UDPAppProfileName
 	cfg.LoadBalancer.UDPAppProfilePath = lbc.LoadBalancer.UDPAppProfilePath
 	//LoadBalancerClassConfig -> LoadBalancerConfig
+	cfg.LoadBalancer.Enabled = lbc.LoadBalancer.Enabled
 	cfg.LoadBalancer.Size = lbc.LoadBalancer.Size
 	cfg.LoadBalancer.LBServiceID = lbc.LoadBalancer.LBServiceID
 	cfg.LoadBalancer.Tier1GatewayPath = lbc.LoadBalancer.Tier1GatewayPath
@@ -56,6 +61,8 @@ func (lbc *LBConfigYAML) CreateConfig() *LBConfig {
 		cfg.LoadBalancerClass[key] = &LoadBalancerClassConfig{
 			IPPoolName:        value.IPPoolName,
 			IPPoolID:          value.IPPoolID,
+			IPv6PoolName:      value.IPv6PoolName,
+			IPv6PoolID:        value.IPv6PoolID,
 			TCPAppProfileName: value.TCPAppProfileName,
 			TCPAppProfilePath: value.TCPAppProfilePath,
 			UDPAppProfileName: value.UDPAppProfileName,
@@ -66,6 +73,9 @@ func (lbc *LBConfigYAML) CreateConfig() *LBConfig {
 }
 
 func (lbc *LBConfigYAML) isEnabled() bool {
+	if lbc.LoadBalancer.Enabled != nil {
+		return *lbc.LoadBalancer.Enabled
+	}
 	return len(lbc.LoadBalancerClass) > 0 || !lbc.LoadBalancer.isEmpty()
 }
 
@@ -140,6 +150,10 @@ func (lbc *LBConfigYAML) CompleteAndValidate() error {
 
 // ReadRawConfigYAML parses vSphere cloud config file and stores it into ConfigYAML
 func ReadRawConfigYAML(byConfig []byte) (*LBConfigYAML, error) {
+	return readRawConfigYAML(byConfig, vcfg.OSEnv)
+}
+
+func readRawConfigYAML(byConfig []byte, env vcfg.Env) (*LBConfigYAML, error) {
 	if len(byConfig) == 0 {
 		return nil, fmt.Errorf("Invalid YAML file")
 	}
@@ -152,6 +166,10 @@ func ReadRawConfigYAML(byConfig []byte) (*LBConfigYAML, error) {
 		klog.Errorf("Unmarshal failed: %s", err)
 		return nil, err
 	}
+	if err := env.Apply(vcfg.EnvPrefix, &cfg); err != nil {
+		klog.Errorf("ApplyEnv failed: %s", err)
+		return nil, err
+	}
 
 	err := cfg.CompleteAndValidate()
 	if err != nil {
@@ -162,7 +180,11 @@ func ReadRawConfigYAML(byConfig []byte) (*LBConfigYAML, error) {
 
 // ReadConfigYAML parses vSphere cloud config file and stores it into Config
 func ReadConfigYAML(byConfig []byte) (*LBConfig, error) {
-	cfg, err := ReadRawConfigYAML(byConfig)
+	return readConfigYAML(byConfig, vcfg.OSEnv)
+}
+
+func readConfigYAML(byConfig []byte, env vcfg.Env) (*LBConfig, error) {
+	cfg, err := readRawConfigYAML(byConfig, env)
 	if err != nil {
 		return nil, err
 	}
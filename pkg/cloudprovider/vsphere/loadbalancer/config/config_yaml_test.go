@@ -105,3 +105,21 @@ loadBalancer:
 	assertEquals("loadBalancer.udpAppProfilePath", config.LoadBalancer.UDPAppProfilePath, "infra/xxx/udp1234")
 	assert.Equal(t, false, config.LoadBalancer.SnatDisabled)
 }
+
+func TestReadYAMLConfigEnabled(t *testing.T) {
+	contents := `
+loadBalancer:
+  enabled: false
+  ipPoolName: pool1
+`
+	config, err := ReadConfigYAML([]byte(contents))
+	assert.Nil(t, err, "a disabled load balancer is not validated")
+	assert.False(t, config.IsEnabled())
+
+	contents = `
+loadBalancer:
+  enabled: true
+`
+	_, err = ReadConfigYAML([]byte(contents))
+	assert.NotNil(t, err, "an enabled load balancer is validated")
+}
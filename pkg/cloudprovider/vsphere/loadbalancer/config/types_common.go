@@ -25,6 +25,7 @@ type LBConfig struct {
 // LoadBalancerConfig contains the configuration for the load balancer itself
 type LoadBalancerConfig struct {
 	LoadBalancerClassConfig
+	Enabled          *bool
 	Size             string
 	LBServiceID      string
 	Tier1GatewayPath string
@@ -36,6 +37,8 @@ type LoadBalancerConfig struct {
 type LoadBalancerClassConfig struct {
 	IPPoolName        string
 	IPPoolID          string
+	IPv6PoolName      string
+	IPv6PoolID        string
 	TCPAppProfileName string
 	TCPAppProfilePath string
 	UDPAppProfileName string
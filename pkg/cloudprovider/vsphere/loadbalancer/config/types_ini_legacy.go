@@ -25,6 +25,7 @@ type LBConfigINI struct {
 // LoadBalancerConfigINI contains the configuration for the load balancer itself
 type LoadBalancerConfigINI struct {
 	LoadBalancerClassConfigINI
+	Enabled          *bool  `gcfg:"enabled"`
 	Size             string `gcfg:"size"`
 	LBServiceID      string `gcfg:"lb-service-id"`
 	Tier1GatewayPath string `gcfg:"tier1-gateway-path"`
@@ -37,6 +38,8 @@ type LoadBalancerConfigINI struct {
 type LoadBalancerClassConfigINI struct {
 	IPPoolName        string `gcfg:"ip-pool-name"`
 	IPPoolID          string `gcfg:"ip-pool-id"`
+	IPv6PoolName      string `gcfg:"ipv6-pool-name"`
+	IPv6PoolID        string `gcfg:"ipv6-pool-id"`
 	TCPAppProfileName string `gcfg:"tcp-app-profile-name"`
 	TCPAppProfilePath string `gcfg:"tcp-app-profile-path"`
 	UDPAppProfileName string `gcfg:"udp-app-profile-name"`
@@ -34,6 +34,9 @@ type LBConfigYAML struct {
 
 // LoadBalancerConfigYAML contains the configuration for the load balancer itself
 type LoadBalancerConfigYAML struct {
+	// Enabled explicitly enables or disables the load balancer support, it is
+	// enabled if any of its settings is given when unset
+	Enabled          *bool             `yaml:"enabled"`
 	Size             string            `yaml:"size"`
 	LBServiceID      string            `yaml:"lbServiceId"`
 	Tier1GatewayPath string            `yaml:"tier1GatewayPath"`
@@ -44,6 +47,8 @@ type LoadBalancerConfigYAML struct {
 	// wasnt able to indirectly parse inherited fields
 	IPPoolName        string `yaml:"ipPoolName"`
 	IPPoolID          string `yaml:"ipPoolId"`
+	IPv6PoolName      string `yaml:"ipv6PoolName"`
+	IPv6PoolID        string `yaml:"ipv6PoolId"`
 	TCPAppProfileName string `yaml:"tcpAppProfileName"`
 	TCPAppProfilePath string `yaml:"tcpAppProfilePath"`
 	UDPAppProfileName string `yaml:"udpAppProfileName"`
@@ -54,6 +59,8 @@ type LoadBalancerConfigYAML struct {
 type LoadBalancerClassConfigYAML struct {
 	IPPoolName        string `yaml:"ipPoolName"`
 	IPPoolID          string `yaml:"ipPoolId"`
+	IPv6PoolName      string `yaml:"ipv6PoolName"`
+	IPv6PoolID        string `yaml:"ipv6PoolId"`
 	TCPAppProfileName string `yaml:"tcpAppProfileName"`
 	TCPAppProfilePath string `yaml:"tcpAppProfilePath"`
 	UDPAppProfileName string `yaml:"udpAppProfileName"`
@@ -0,0 +1,119 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// Kinds of NSX-T objects reported in the drift metric
+const (
+	kindVirtualServer = "virtual_server"
+	kindPool          = "pool"
+	kindTCPMonitor    = "tcp_monitor"
+	kindHTTPMonitor   = "http_monitor"
+)
+
+var (
+	driftCorrectionsMetric = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Name:           "cloudprovider_vsphere_nsxt_lb_drift_corrections_total",
+			Help:           "Number of fields of NSX-T load balancer objects updated because they differed from the desired state",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"kind", "field"},
+	)
+
+	registerDriftMetrics sync.Once
+)
+
+// registerMetrics registers the metrics of the load balancer
+func registerMetrics() {
+	registerDriftMetrics.Do(func() {
+		legacyregistry.MustRegister(driftCorrectionsMetric)
+	})
+}
+
+// drift collects the fields of an NSX-T object differing from the desired
+// state
+type drift []string
+
+// check adds the field if the current and the desired value differ and
+// returns true in this case
+func (d *drift) check(field string, equal bool) bool {
+	if !equal {
+		*d = append(*d, field)
+	}
+	return !equal
+}
+
+// record counts the corrected fields in the drift metric
+func (d drift) record(kind string) {
+	for _, field := range d {
+		driftCorrectionsMetric.WithLabelValues(kind, field).Inc()
+	}
+}
+
+func (d drift) String() string {
+	return strings.Join(d, ",")
+}
+
+// equalSnatTranslations compares the SNAT translation types and IP addresses
+// of two pools
+func equalSnatTranslations(a, b *data.StructValue) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return structString(a, "type") == structString(b, "type") &&
+		equalStrings(snatIPAddresses(a), snatIPAddresses(b))
+}
+
+// snatIPAddresses returns the sorted IP addresses and ranges of a SNAT
+// translation of type LBSnatIpPool, with the prefix length if set
+func snatIPAddresses(snatTranslation *data.StructValue) []string {
+	list, ok := structField(snatTranslation, "ip_addresses").(*data.ListValue)
+	if !ok {
+		return nil
+	}
+	var ipAddresses []string
+	for _, item := range list.List() {
+		element, ok := item.(*data.StructValue)
+		if !ok {
+			continue
+		}
+		ipAddress := structString(element, "ip_address")
+		if prefixLength, ok := structField(element, "prefix_length").(*data.IntegerValue); ok {
+			ipAddress = fmt.Sprintf("%s/%d", ipAddress, prefixLength.Value())
+		}
+		ipAddresses = append(ipAddresses, ipAddress)
+	}
+	sort.Strings(ipAddresses)
+	return ipAddresses
+}
+
+// equalStrings compares two string slices, nil and empty ones are equal
+func equalStrings(a, b []string) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
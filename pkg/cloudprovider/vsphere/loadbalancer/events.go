@@ -0,0 +1,90 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// ReasonIPAddressAllocated is the reason of the event when a virtual IP
+	// address was allocated for a service.
+	ReasonIPAddressAllocated = "IPAddressAllocated"
+	// ReasonPoolCreated is the reason of the event when a pool was created.
+	ReasonPoolCreated = "PoolCreated"
+	// ReasonPoolUpdated is the reason of the event when the members, monitors or
+	// the algorithm of a pool were updated.
+	ReasonPoolUpdated = "PoolUpdated"
+	// ReasonVirtualServerCreated is the reason of the event when a virtual
+	// server was created.
+	ReasonVirtualServerCreated = "VirtualServerCreated"
+	// ReasonVirtualServerUpdated is the reason of the event when a virtual
+	// server was updated.
+	ReasonVirtualServerUpdated = "VirtualServerUpdated"
+	// ReasonOrphansDeleted is the reason of the event when NSX-T objects not
+	// needed by the service anymore were deleted.
+	ReasonOrphansDeleted = "OrphansDeleted"
+
+	// ReasonReconciled is the reason of the ready condition of a service whose
+	// load balancer is up to date.
+	ReasonReconciled = "Reconciled"
+	// ReasonReconcileFailed is the reason of the ready condition of a service
+	// whose load balancer failed for no more specific reason.
+	ReasonReconcileFailed = "ReconcileFailed"
+	// ReasonInvalidLoadBalancerClass is the reason of the ready condition of a
+	// service with an unknown load balancer class or a class without the IP pool
+	// of an IP family of the service.
+	ReasonInvalidLoadBalancerClass = "InvalidLoadBalancerClass"
+	// ReasonIPAllocationFailed is the reason of the ready condition of a service
+	// whose virtual IP address could not be allocated, e.g. because the IP pool
+	// is exhausted.
+	ReasonIPAllocationFailed = "IPAllocationFailed"
+	// ReasonIPAddressNotRealized is the reason of the ready condition of a
+	// service whose IP address allocation was not realized by NSX-T in time.
+	ReasonIPAddressNotRealized = "IPAddressNotRealized"
+	// ReasonAppProfileNotFound is the reason of the ready condition of a service
+	// whose application profile could not be found.
+	ReasonAppProfileNotFound = "AppProfileNotFound"
+	// ReasonLoadBalancerServiceFailed is the reason of the ready condition of a
+	// service when the NSX-T load balancer service could not be found or created.
+	ReasonLoadBalancerServiceFailed = "LoadBalancerServiceFailed"
+
+	// ReasonLoadBalancerIPInvalid is the reason of the event and the ready
+	// condition when the requested virtual IP addresses of a service cannot be
+	// parsed or do not match its IP families.
+	ReasonLoadBalancerIPInvalid = "LoadBalancerIPInvalid"
+	// ReasonLoadBalancerIPNotInPool is the reason of the event and the ready
+	// condition when a requested virtual IP address is not part of the IP pool of
+	// the load balancer class.
+	ReasonLoadBalancerIPNotInPool = "LoadBalancerIPNotInPool"
+	// ReasonLoadBalancerIPConflict is the reason of the event and the ready
+	// condition when a requested virtual IP address is already allocated by
+	// someone else.
+	ReasonLoadBalancerIPConflict = "LoadBalancerIPConflict"
+)
+
+// newServiceEventRecorder returns an event recorder for events on the services
+// of the cluster
+func newServiceEventRecorder(client clientset.Interface) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: AppName})
+}
@@ -17,12 +17,27 @@
 package loadbalancer
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	servicehelper "k8s.io/cloud-provider/service/helpers"
+	klog "k8s.io/klog/v2"
 
 	vapi_errors "github.com/vmware/vsphere-automation-sdk-go/lib/vapi/std/errors"
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
 )
 
 func namespacedNameFromService(service *corev1.Service) types.NamespacedName {
@@ -34,12 +49,22 @@ func parseNamespacedName(name string) types.NamespacedName {
 	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}
 }
 
-func collectNodeInternalAddresses(nodes []*corev1.Node) map[string]string {
-	set := map[string]string{}
+// collectPoolMemberNodes returns the nodes used as pool members by their internal
+// IP address of the IP family. Nodes labeled to be excluded from external load
+// balancers, nodes not matching the selector and nodes without an internal IP
+// address of the family are skipped.
+func collectPoolMemberNodes(nodes []*corev1.Node, selector labels.Selector, family corev1.IPFamily) map[string]*corev1.Node {
+	set := map[string]*corev1.Node{}
 	for _, node := range nodes {
+		if _, ok := node.Labels[corev1.LabelNodeExcludeBalancers]; ok {
+			continue
+		}
+		if !selector.Matches(labels.Set(node.Labels)) {
+			continue
+		}
 		for _, addr := range node.Status.Addresses {
-			if addr.Type == corev1.NodeInternalIP {
-				set[addr.Address] = node.Name
+			if addr.Type == corev1.NodeInternalIP && ipFamilyOf(addr.Address) == family {
+				set[addr.Address] = node
 				break
 			}
 		}
@@ -47,6 +72,294 @@ func collectNodeInternalAddresses(nodes []*corev1.Node) map[string]string {
 	return set
 }
 
+// ipFamilyOf returns the IP family of an IP address, empty if it is invalid
+func ipFamilyOf(address string) corev1.IPFamily {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return corev1.IPv4Protocol
+	default:
+		return corev1.IPv6Protocol
+	}
+}
+
+// ipFamiliesFromService returns the IP families of the service, IPv4 only if
+// none are set
+func ipFamiliesFromService(service *corev1.Service) []corev1.IPFamily {
+	if len(service.Spec.IPFamilies) == 0 {
+		return []corev1.IPFamily{corev1.IPv4Protocol}
+	}
+	return service.Spec.IPFamilies
+}
+
+func containsIPFamily(families []corev1.IPFamily, family corev1.IPFamily) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// requestedIPAddresses returns the virtual IP addresses requested for the
+// service by IP family. The annotation takes precedence over the deprecated
+// loadBalancerIP field.
+func requestedIPAddresses(service *corev1.Service) (map[corev1.IPFamily]string, error) {
+	value := strings.TrimSpace(service.GetAnnotations()[LoadBalancerIPsAnnotation])
+	source := LoadBalancerIPsAnnotation
+	if value == "" {
+		value = strings.TrimSpace(service.Spec.LoadBalancerIP)
+		source = "loadBalancerIP"
+	}
+	if value == "" {
+		return nil, nil
+	}
+	result := map[corev1.IPFamily]string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		family := ipFamilyOf(item)
+		if family == "" {
+			return nil, fmt.Errorf("invalid %s: %q is no IP address", source, item)
+		}
+		if _, ok := result[family]; ok {
+			return nil, fmt.Errorf("invalid %s: multiple %s addresses", source, family)
+		}
+		result[family] = net.ParseIP(item).String()
+	}
+	return result, nil
+}
+
+// retainIPFromService returns true if the virtual IP addresses of the service
+// should be kept after its deletion
+func retainIPFromService(service *corev1.Service) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(service.GetAnnotations()[RetainIPAnnotation]))
+	return err == nil && value
+}
+
+// ipInRange checks whether the IP address is in the range from start to end
+func ipInRange(ip net.IP, start, end string) bool {
+	first, last := net.ParseIP(start), net.ParseIP(end)
+	if first == nil || last == nil || (ip.To4() == nil) != (first.To4() == nil) {
+		return false
+	}
+	return bytes.Compare(ip.To16(), first.To16()) >= 0 && bytes.Compare(ip.To16(), last.To16()) <= 0
+}
+
+// poolMemberAdminState returns the admin state of the pool member of a node.
+// Cordoned nodes are drained gracefully, unready nodes are disabled.
+func poolMemberAdminState(node *corev1.Node) string {
+	if node.Spec.Unschedulable {
+		return model.LBPoolMember_ADMIN_STATE_GRACEFUL_DISABLED
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue {
+			return model.LBPoolMember_ADMIN_STATE_DISABLED
+		}
+	}
+	return model.LBPoolMember_ADMIN_STATE_ENABLED
+}
+
+// poolMemberWeight returns the weight annotated at the node, 1 if there is no
+// valid weight between 1 and 256. Invalid weights are only logged at V(4),
+// they are seen on every reconciliation of every service.
+func poolMemberWeight(node *corev1.Node) int64 {
+	value, ok := node.GetAnnotations()[PoolMemberWeightAnnotation]
+	if !ok {
+		return 1
+	}
+	weight, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || weight < 1 || weight > 256 {
+		klog.V(4).Infof("node %s: ignoring invalid annotation %s=%q", node.Name, PoolMemberWeightAnnotation, value)
+		return 1
+	}
+	return weight
+}
+
+var poolAlgorithms = sets.NewString(
+	model.LBPool_ALGORITHM_ROUND_ROBIN,
+	model.LBPool_ALGORITHM_WEIGHTED_ROUND_ROBIN,
+	model.LBPool_ALGORITHM_LEAST_CONNECTION,
+	model.LBPool_ALGORITHM_WEIGHTED_LEAST_CONNECTION,
+	model.LBPool_ALGORITHM_IP_HASH,
+)
+
+// poolAlgorithmFromService returns the pool algorithm annotated at the service,
+// ROUND_ROBIN if there is none.
+func poolAlgorithmFromService(service *corev1.Service) (string, error) {
+	value := strings.ToUpper(strings.TrimSpace(service.GetAnnotations()[PoolAlgorithmAnnotation]))
+	if value == "" {
+		return model.LBPool_ALGORITHM_ROUND_ROBIN, nil
+	}
+	if !poolAlgorithms.Has(value) {
+		return "", fmt.Errorf("invalid annotation %s: algorithm must be one of %v", PoolAlgorithmAnnotation, poolAlgorithms.List())
+	}
+	return value, nil
+}
+
+// sessionAffinityTimeout returns the timeout in seconds of the client IP session affinity of the service
+func sessionAffinityTimeout(service *corev1.Service) int64 {
+	config := service.Spec.SessionAffinityConfig
+	if config != nil && config.ClientIP != nil && config.ClientIP.TimeoutSeconds != nil {
+		return int64(*config.ClientIP.TimeoutSeconds)
+	}
+	return int64(corev1.DefaultClientIPServiceAffinitySeconds)
+}
+
+// nodeSelectorFromService returns the node selector annotated at the service,
+// selecting all nodes if there is none.
+func nodeSelectorFromService(service *corev1.Service) (labels.Selector, error) {
+	value := strings.TrimSpace(service.GetAnnotations()[NodeSelectorAnnotation])
+	if value == "" {
+		return labels.Everything(), nil
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid annotation %s", NodeSelectorAnnotation)
+	}
+	return selector, nil
+}
+
+const (
+	appProtocolHTTP  = "HTTP"
+	appProtocolHTTPS = "HTTPS"
+)
+
+// appProtocolFromService returns the application protocol annotated at the
+// service for a service port, HTTP or HTTPS for L7 virtual servers, or an
+// empty string for L4 ones.
+func appProtocolFromService(service *corev1.Service, servicePort corev1.ServicePort) (string, error) {
+	appProtocol := ""
+	for annotation, protocol := range map[string]string{HTTPPortsAnnotation: appProtocolHTTP, HTTPSPortsAnnotation: appProtocolHTTPS} {
+		for _, value := range strings.Split(service.GetAnnotations()[annotation], ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return "", fmt.Errorf("invalid port %q in annotation %s", value, annotation)
+			}
+			if port != int(servicePort.Port) {
+				continue
+			}
+			if appProtocol != "" && appProtocol != protocol {
+				return "", fmt.Errorf("port %d is annotated both in %s and %s", port, HTTPPortsAnnotation, HTTPSPortsAnnotation)
+			}
+			appProtocol = protocol
+		}
+	}
+	if appProtocol != "" && servicePort.Protocol != corev1.ProtocolTCP {
+		return "", fmt.Errorf("%s port %d must use protocol TCP", appProtocol, servicePort.Port)
+	}
+	return appProtocol, nil
+}
+
+// tlsSecretData returns the PEM encoded certificate and private key of a TLS
+// secret together with their checksum
+func tlsSecretData(secret *corev1.Secret) (certificatePEM, keyPEM []byte, checksum string, err error) {
+	if secret.Type != corev1.SecretTypeTLS {
+		return nil, nil, "", fmt.Errorf("secret %s/%s is not of type %s", secret.Namespace, secret.Name, corev1.SecretTypeTLS)
+	}
+	certificatePEM = secret.Data[corev1.TLSCertKey]
+	keyPEM = secret.Data[corev1.TLSPrivateKeyKey]
+	if _, err := tls.X509KeyPair(certificatePEM, keyPEM); err != nil {
+		return nil, nil, "", errors.Wrapf(err, "invalid certificate in secret %s/%s", secret.Namespace, secret.Name)
+	}
+	hash := sha256.New()
+	hash.Write(certificatePEM)
+	hash.Write(keyPEM)
+	return certificatePEM, keyPEM, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// certificatePath returns the path of the certificate bound to a virtual server,
+// or an empty string if the virtual server does not terminate TLS
+func certificatePath(server *model.LBVirtualServer) string {
+	if server.ClientSslProfileBinding == nil || server.ClientSslProfileBinding.DefaultCertificatePath == nil {
+		return ""
+	}
+	return *server.ClientSslProfileBinding.DefaultCertificatePath
+}
+
+// referencedPaths returns the paths of the certificate and the persistence
+// profile referenced by a virtual server
+func referencedPaths(server *model.LBVirtualServer) []string {
+	var paths []string
+	if path := certificatePath(server); path != "" {
+		paths = append(paths, path)
+	}
+	if server.LbPersistenceProfilePath != nil {
+		paths = append(paths, *server.LbPersistenceProfilePath)
+	}
+	if server.AccessListControl != nil && server.AccessListControl.GroupPath != nil {
+		paths = append(paths, *server.AccessListControl.GroupPath)
+	}
+	return paths
+}
+
+func equalAccessListControls(a, b *model.LBAccessListControl) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return safeEquals(a.Action, b.Action) && safeEquals(a.GroupPath, b.GroupPath) &&
+		(a.Enabled != nil && *a.Enabled) == (b.Enabled != nil && *b.Enabled)
+}
+
+// sourceRangesFromService returns the sorted client IP ranges allowed to
+// access the load balancer, nil if access is not restricted.
+func sourceRangesFromService(service *corev1.Service) ([]string, error) {
+	ipnets, err := servicehelper.GetLoadBalancerSourceRanges(service)
+	if err != nil {
+		return nil, err
+	}
+	if servicehelper.IsAllowAll(ipnets) || ipnets.Has(&net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}) {
+		return nil, nil
+	}
+	ranges := ipnets.StringSlice()
+	sort.Strings(ranges)
+	return ranges, nil
+}
+
+// groupIPAddresses returns the IP addresses of the IP address expressions of a group
+func groupIPAddresses(group *model.Group) []string {
+	converter := newNsxtTypeConverter()
+	var ipAddresses []string
+	for _, value := range group.Expression {
+		resourceType, err := value.String("resource_type")
+		if err != nil || resourceType != model.Expression_RESOURCE_TYPE_IPADDRESSEXPRESSION {
+			continue
+		}
+		expression, err := converter.convertStructValueToIPAddressExpression(value)
+		if err != nil {
+			continue
+		}
+		ipAddresses = append(ipAddresses, expression.IpAddresses...)
+	}
+	sort.Strings(ipAddresses)
+	return ipAddresses
+}
+
+func setGroupIPAddresses(group *model.Group, ipAddresses []string) error {
+	expression := model.IPAddressExpression{
+		ResourceType: model.Expression_RESOURCE_TYPE_IPADDRESSEXPRESSION,
+		IpAddresses:  ipAddresses,
+	}
+	value, err := newNsxtTypeConverter().convertIPAddressExpressionToStructValue(expression)
+	if err != nil {
+		return errors.Wrapf(err, "converting IPAddressExpression failed")
+	}
+	group.Expression = []*data.StructValue{value}
+	return nil
+}
+
+func equalClientSSLBindings(a, b *model.LBClientSslProfileBinding) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return safeEquals(a.SslProfilePath, b.SslProfilePath) && safeEquals(a.DefaultCertificatePath, b.DefaultCertificatePath)
+}
+
 func strptr(s string) *string {
 	return &s
 }
@@ -22,7 +22,10 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	cloudprovider "k8s.io/cloud-provider"
 
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
 )
 
 // LBProvider is the interface used call the load balancer functionality
@@ -31,9 +34,24 @@ import (
 type LBProvider interface {
 	cloudprovider.LoadBalancer
 	Initialize(clusterName string, client clientset.Interface, stop <-chan struct{})
+	// Reload replaces the load balancer classes by the ones of the config
+	Reload(cfg *config.LBConfig) error
 	CleanupServices(clusterName string, services map[types.NamespacedName]corev1.Service, ensureLBServiceDeleted bool) error
 }
 
+// VirtualServerSpec holds the settings of a virtual server derived from the
+// service, they are used to create a virtual server and to correct the drift
+// of an existing one.
+type VirtualServerSpec struct {
+	IPAddress              *string
+	LBServicePath          string
+	ApplicationProfilePath string
+	ClientSSLBinding       *model.LBClientSslProfileBinding
+	AccessListControl      *model.LBAccessListControl
+	PersistenceProfilePath *string
+	PoolPath               *string
+}
+
 // NSXTAccess provides methods for dealing with NSX-T objects
 type NSXTAccess interface {
 	// CreateLoadBalancerService creates a LbService
@@ -45,9 +63,9 @@ type NSXTAccess interface {
 	// DeleteLoadBalancerService deletes a LbService by id
 	DeleteLoadBalancerService(id string) error
 
-	// CreateVirtualServer creates a virtual server
-	CreateVirtualServer(clusterName string, objectName types.NamespacedName, class LBClass, ipAddress string, mapping Mapping,
-		lbServicePath, applicationProfilePath string, poolPath *string) (*model.LBVirtualServer, error)
+	// CreateVirtualServer creates a virtual server for the mapping with the settings of the spec
+	CreateVirtualServer(clusterName string, objectName types.NamespacedName, class LBClass, mapping Mapping,
+		spec VirtualServerSpec) (*model.LBVirtualServer, error)
 	// FindVirtualServers finds a virtual server by cluster and object name
 	FindVirtualServers(clusterName string, objectName types.NamespacedName) ([]*model.LBVirtualServer, error)
 	// ListVirtualServers finds all virtual servers for a cluster
@@ -57,9 +75,11 @@ type NSXTAccess interface {
 	// DeleteVirtualServer deletes a virtual server by id
 	DeleteVirtualServer(id string) error
 
+	// PoolSnatTranslation returns the SNAT translation of the pools
+	PoolSnatTranslation() (*data.StructValue, error)
 	// CreatePool creates a LbPool
 	CreatePool(clusterName string, objectName types.NamespacedName, mapping Mapping, members []model.LBPoolMember,
-		activeMonitorPaths []string) (*model.LBPool, error)
+		activeMonitorPaths []string, algorithm string) (*model.LBPool, error)
 	// GetPool gets a LbPool by id
 	GetPool(id string) (*model.LBPool, error)
 	// FindPool finds a LbPool for a mapping
@@ -79,12 +99,20 @@ type NSXTAccess interface {
 	// GetAppProfilePath gets the application profile for given loadbalancer class and protocol
 	GetAppProfilePath(class LBClass, protocol corev1.Protocol) (string, error)
 
-	// AllocateExternalIPAddress allocates an IP address from the given IP pool
-	AllocateExternalIPAddress(ipPoolID string, clusterName string, objectName types.NamespacedName) (allocation *model.IpAddressAllocation, ipAddress *string, err error)
+	// AllocateExternalIPAddress allocates an IP address from the given IP pool, the given IP address if not nil
+	AllocateExternalIPAddress(ipPoolID string, clusterName string, objectName types.NamespacedName, ipAddress *string, retain bool) (allocation *model.IpAddressAllocation, allocatedIPAddress *string, err error)
 	// ListExternalIPAddresses finds all IP addresses belonging to a clusterName from the given IP pool
 	ListExternalIPAddresses(ipPoolID string, clusterName string) ([]*model.IpAddressAllocation, error)
-	// FindExternalIPAddressForObject finds an IP address belonging to an object
-	FindExternalIPAddressForObject(ipPoolID string, clusterName string, objectName types.NamespacedName) (allocation *model.IpAddressAllocation, ipAddress *string, err error)
+	// FindExternalIPAddressesForObject finds the IP address allocations belonging to an object
+	FindExternalIPAddressesForObject(ipPoolID string, clusterName string, objectName types.NamespacedName) ([]*model.IpAddressAllocation, error)
+	// GetExternalIPAddress gets the IP address of an allocation
+	GetExternalIPAddress(ipPoolID string, allocation *model.IpAddressAllocation) (*string, error)
+	// FindExternalIPAddressAllocation finds the allocation of an IP address in the given IP pool regardless of its owner
+	FindExternalIPAddressAllocation(ipPoolID string, ipAddress string) (*model.IpAddressAllocation, error)
+	// UpdateExternalIPAddressRetention sets or removes the retain tag of an IP address allocation
+	UpdateExternalIPAddressRetention(ipPoolID string, allocation *model.IpAddressAllocation, retain bool) error
+	// IPPoolContainsIPAddress checks whether an IP address belongs to a subnet of the given IP pool
+	IPPoolContainsIPAddress(ipPoolID string, ipAddress string) (bool, error)
 	// ReleaseExternalIPAddress releases an allocated IP address
 	ReleaseExternalIPAddress(ipPoolID string, id string) error
 
@@ -98,6 +126,51 @@ type NSXTAccess interface {
 	UpdateTCPMonitorProfile(monitor *model.LBTcpMonitorProfile) error
 	// DeleteTCPMonitorProfile deletes a LBTcpMonitorProfile by id
 	DeleteTCPMonitorProfile(id string) error
+
+	// CreateHTTPMonitorProfile creates a LBHttpMonitorProfile probing the health check node port
+	CreateHTTPMonitorProfile(clusterName string, objectName types.NamespacedName, mapping Mapping, healthCheckNodePort int) (*model.LBHttpMonitorProfile, error)
+	// FindHTTPMonitorProfiles finds a LBHttpMonitorProfile by cluster and object name
+	FindHTTPMonitorProfiles(clusterName string, objectName types.NamespacedName) ([]*model.LBHttpMonitorProfile, error)
+	// ListHTTPMonitorProfiles lists LBHttpMonitorProfile by cluster
+	ListHTTPMonitorProfiles(clusterName string) ([]*model.LBHttpMonitorProfile, error)
+	// UpdateHTTPMonitorProfile updates a LBHttpMonitorProfile
+	UpdateHTTPMonitorProfile(monitor *model.LBHttpMonitorProfile) error
+	// DeleteHTTPMonitorProfile deletes a LBHttpMonitorProfile by id
+	DeleteHTTPMonitorProfile(id string) error
+
+	// CreateSourceIPPersistenceProfile creates a LBSourceIpPersistenceProfile
+	CreateSourceIPPersistenceProfile(clusterName string, objectName types.NamespacedName, timeout int64) (*model.LBSourceIpPersistenceProfile, error)
+	// FindSourceIPPersistenceProfiles finds a LBSourceIpPersistenceProfile by cluster and object name
+	FindSourceIPPersistenceProfiles(clusterName string, objectName types.NamespacedName) ([]*model.LBSourceIpPersistenceProfile, error)
+	// ListSourceIPPersistenceProfiles lists LBSourceIpPersistenceProfile by cluster
+	ListSourceIPPersistenceProfiles(clusterName string) ([]*model.LBSourceIpPersistenceProfile, error)
+	// UpdateSourceIPPersistenceProfile updates a LBSourceIpPersistenceProfile
+	UpdateSourceIPPersistenceProfile(profile *model.LBSourceIpPersistenceProfile) error
+	// DeleteSourceIPPersistenceProfile deletes a LBSourceIpPersistenceProfile by id
+	DeleteSourceIPPersistenceProfile(id string) error
+
+	// CreateCertificate imports the certificate and private key of a TLS secret
+	CreateCertificate(clusterName string, objectName types.NamespacedName, checksum string, certificatePEM, keyPEM []byte) (*model.TlsCertificate, error)
+	// FindCertificates finds the imported certificates by cluster and object name
+	FindCertificates(clusterName string, objectName types.NamespacedName) ([]*model.TlsCertificate, error)
+	// ListCertificates lists the imported certificates by cluster
+	ListCertificates(clusterName string) ([]*model.TlsCertificate, error)
+	// DeleteCertificate deletes an imported certificate by id
+	DeleteCertificate(id string) error
+
+	// CreateSourceRangesGroup creates a group with the source ranges of a service
+	CreateSourceRangesGroup(clusterName string, objectName types.NamespacedName, ipAddresses []string) (*model.Group, error)
+	// FindSourceRangesGroups finds the source ranges groups by cluster and object name
+	FindSourceRangesGroups(clusterName string, objectName types.NamespacedName) ([]*model.Group, error)
+	// ListSourceRangesGroups lists the source ranges groups by cluster
+	ListSourceRangesGroups(clusterName string) ([]*model.Group, error)
+	// UpdateSourceRangesGroup updates the IP addresses of a source ranges group
+	UpdateSourceRangesGroup(group *model.Group, ipAddresses []string) error
+	// DeleteSourceRangesGroup deletes a source ranges group by id
+	DeleteSourceRangesGroup(id string) error
+
+	// InvalidateInventory forces a search for the cached objects on the next access
+	InvalidateInventory()
 }
 
 // Reference references an object either by identifier or name
@@ -113,8 +186,8 @@ func (r *Reference) IsEmpty() bool {
 
 // LBClass is an interface to retrieve settings of load balancer class.
 type LBClass interface {
-	// Tags retrieves tags of an object
-	Tags() []model.Tag
+	// Tags retrieves the tags of an object with a virtual IP address of the IP family
+	Tags(family corev1.IPFamily) []model.Tag
 	// AppProfile retrieves application profile either by path (stored in Reference.Identifier) or by name
 	AppProfile(protocol corev1.Protocol) (Reference, error)
 }
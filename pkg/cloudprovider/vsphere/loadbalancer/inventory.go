@@ -0,0 +1,289 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/bindings"
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
+	"k8s.io/klog/v2"
+)
+
+// Resource types of the NSX-T objects kept in the inventory
+const (
+	resourceTypeVirtualServer              = "LBVirtualServer"
+	resourceTypePool                       = "LBPool"
+	resourceTypeTCPMonitorProfile          = model.LBMonitorProfile_RESOURCE_TYPE_LBTCPMONITORPROFILE
+	resourceTypeHTTPMonitorProfile         = model.LBMonitorProfile_RESOURCE_TYPE_LBHTTPMONITORPROFILE
+	resourceTypeSourceIPPersistenceProfile = model.LBPersistenceProfile_RESOURCE_TYPE_LBSOURCEIPPERSISTENCEPROFILE
+	resourceTypeCertificate                = "TlsCertificate"
+	resourceTypeGroup                      = "Group"
+	resourceTypeIPAddressAllocation        = "IpAddressAllocation"
+)
+
+var inventoryResourceTypes = []string{
+	resourceTypeVirtualServer,
+	resourceTypePool,
+	resourceTypeTCPMonitorProfile,
+	resourceTypeHTTPMonitorProfile,
+	resourceTypeSourceIPPersistenceProfile,
+	resourceTypeCertificate,
+	resourceTypeGroup,
+	resourceTypeIPAddressAllocation,
+}
+
+const (
+	// inventoryMaxAge is the time after which the inventory of a cluster is searched again
+	inventoryMaxAge = 5 * time.Minute
+	// searchIndexDelay is the time the search index of NSX-T may lag behind writes
+	searchIndexDelay = 1 * time.Minute
+)
+
+// inventory is an informer-like cache of the NSX-T objects created by the
+// controller. For every cluster it is filled by a single search for all
+// objects with the cluster tag, instead of paging through all objects of the
+// NSX-T manager on every reconciliation. Writes of the controller are put
+// into the inventory directly, failed writes invalidate it. As the search
+// index is updated asynchronously, writes younger than searchIndexDelay
+// take precedence over the search results.
+type inventory struct {
+	broker   NsxtBroker
+	now      func() time.Time
+	lock     sync.Mutex
+	clusters map[string]*clusterInventory
+}
+
+type clusterInventory struct {
+	// objects are the objects by resource type and id, nil if the inventory
+	// has to be searched
+	objects   map[string]map[string]inventoryObject
+	refreshed time.Time
+	writes    map[inventoryKey]inventoryWrite
+}
+
+type inventoryKey struct {
+	resourceType string
+	id           string
+}
+
+type inventoryObject struct {
+	tags  []model.Tag
+	value *data.StructValue
+}
+
+// inventoryWrite is a write of the controller, the object is nil for a deletion
+type inventoryWrite struct {
+	time   time.Time
+	object *inventoryObject
+}
+
+func newInventory(broker NsxtBroker) *inventory {
+	return &inventory{
+		broker:   broker,
+		now:      time.Now,
+		clusters: map[string]*clusterInventory{},
+	}
+}
+
+// list returns the objects of the resource type of the cluster with all the
+// tags ordered by id
+func (i *inventory) list(clusterName string, resourceType string, tags ...model.Tag) ([]*data.StructValue, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	cluster := i.cluster(clusterName)
+	if cluster.objects == nil || i.now().Sub(cluster.refreshed) >= inventoryMaxAge {
+		err := i.refresh(clusterName, cluster)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var ids []string
+	for id, object := range cluster.objects[resourceType] {
+		if checkTags(object.tags, tags...) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	result := make([]*data.StructValue, len(ids))
+	for n, id := range ids {
+		result[n] = cluster.objects[resourceType][id].value
+	}
+	return result, nil
+}
+
+func (i *inventory) cluster(clusterName string) *clusterInventory {
+	cluster := i.clusters[clusterName]
+	if cluster == nil {
+		cluster = &clusterInventory{writes: map[inventoryKey]inventoryWrite{}}
+		i.clusters[clusterName] = cluster
+	}
+	return cluster
+}
+
+func (i *inventory) refresh(clusterName string, cluster *clusterInventory) error {
+	now := i.now()
+	list, err := i.broker.SearchTaggedObjects(inventoryResourceTypes, clusterTag(clusterName))
+	if err != nil {
+		return err
+	}
+	objects := map[string]map[string]inventoryObject{}
+	for _, resourceType := range inventoryResourceTypes {
+		objects[resourceType] = map[string]inventoryObject{}
+	}
+	for _, value := range list {
+		if structBool(value, "marked_for_delete") {
+			continue
+		}
+		key, object, err := newInventoryObject(structString(value, "resource_type"), value)
+		if err != nil {
+			return err
+		}
+		if objects[key.resourceType] != nil {
+			objects[key.resourceType][key.id] = object
+		}
+	}
+	for key, write := range cluster.writes {
+		if now.Sub(write.time) > searchIndexDelay {
+			delete(cluster.writes, key)
+			continue
+		}
+		if write.object == nil {
+			delete(objects[key.resourceType], key.id)
+		} else {
+			objects[key.resourceType][key.id] = *write.object
+		}
+	}
+	klog.V(4).Infof("inventory: found %d objects of cluster %s", len(list), clusterName)
+	cluster.objects = objects
+	cluster.refreshed = now
+	return nil
+}
+
+// put stores a created or updated object of the resource type
+func (i *inventory) put(resourceType string, obj interface{}, bindingType bindings.BindingType) {
+	dataValue, errs := newNsxtTypeConverter().ConvertToVapi(obj, bindingType)
+	if errs != nil {
+		klog.Warningf("inventory: converting %s failed: %s", resourceType, errs[0])
+		i.invalidate()
+		return
+	}
+	key, object, err := newInventoryObject(resourceType, dataValue.(*data.StructValue))
+	if err != nil {
+		klog.Warningf("inventory: %s", err)
+		i.invalidate()
+		return
+	}
+	clusterName := getTag(object.tags, ScopeCluster)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	cluster := i.cluster(clusterName)
+	if cluster.objects != nil {
+		cluster.objects[resourceType][key.id] = object
+	}
+	cluster.writes[key] = inventoryWrite{time: i.now(), object: &object}
+}
+
+// remove deletes the object with the id, which may have any of the resource types
+func (i *inventory) remove(id string, resourceTypes ...string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for _, cluster := range i.clusters {
+		for _, resourceType := range resourceTypes {
+			if cluster.objects != nil {
+				delete(cluster.objects[resourceType], id)
+			}
+			cluster.writes[inventoryKey{resourceType: resourceType, id: id}] = inventoryWrite{time: i.now()}
+		}
+	}
+}
+
+// invalidate forces a search on the next access to the inventory of any cluster
+func (i *inventory) invalidate() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for _, cluster := range i.clusters {
+		cluster.objects = nil
+	}
+}
+
+func newInventoryObject(resourceType string, value *data.StructValue) (inventoryKey, inventoryObject, error) {
+	key := inventoryKey{resourceType: resourceType, id: structString(value, "id")}
+	object := inventoryObject{value: value}
+	field := structField(value, "tags")
+	if field == nil {
+		return key, object, nil
+	}
+	list, ok := field.(*data.ListValue)
+	if !ok {
+		return key, object, errors.Errorf("tags of %s %s are no list", resourceType, key.id)
+	}
+	converter := newNsxtTypeConverter()
+	for _, item := range list.List() {
+		itemValue, ok := item.(*data.StructValue)
+		if !ok {
+			return key, object, errors.Errorf("tag of %s %s is no struct", resourceType, key.id)
+		}
+		tag, err := converter.convertStructValueToTag(itemValue)
+		if err != nil {
+			return key, object, errors.Wrapf(err, "converting tag of %s %s failed", resourceType, key.id)
+		}
+		if tag.Scope != nil && tag.Tag != nil {
+			object.tags = append(object.tags, tag)
+		}
+	}
+	return key, object, nil
+}
+
+// structField returns the value of an optional or required field of a struct
+// value, or nil if it is not set
+func structField(value *data.StructValue, name string) data.DataValue {
+	field, err := value.Field(name)
+	if err != nil {
+		return nil
+	}
+	if optional, ok := field.(*data.OptionalValue); ok {
+		if !optional.IsSet() {
+			return nil
+		}
+		return optional.Value()
+	}
+	return field
+}
+
+func structString(value *data.StructValue, name string) string {
+	if field, ok := structField(value, name).(*data.StringValue); ok {
+		return field.Value()
+	}
+	return ""
+}
+
+func structBool(value *data.StructValue, name string) bool {
+	if field, ok := structField(value, name).(*data.BooleanValue); ok {
+		return field.Value()
+	}
+	return false
+}
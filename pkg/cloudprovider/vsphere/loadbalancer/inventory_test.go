@@ -0,0 +1,119 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestInventory(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	inventory := provider.access.(*access).inventory
+	now := time.Now()
+	inventory.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	var services []*corev1.Service
+	for i := 0; i < 20; i++ {
+		service := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: int32(30000 + i)})
+		service.Name = fmt.Sprintf("web%d", i)
+		services = append(services, service)
+	}
+	for i := 0; i < 2; i++ {
+		for _, service := range services {
+			if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+				t.Fatalf("EnsureLoadBalancer failed: %v", err)
+			}
+		}
+	}
+	if broker.searches != 1 {
+		t.Errorf("expected a single search for all reconciliations, got %d", broker.searches)
+	}
+	if len(broker.servers) != 20 || len(broker.pools) != 20 || len(broker.monitors) != 20 || len(broker.ipAllocations) != 20 {
+		t.Errorf("expected 20 virtual servers, pools, monitors and IP allocations, got %d, %d, %d and %d",
+			len(broker.servers), len(broker.pools), len(broker.monitors), len(broker.ipAllocations))
+	}
+
+	// a failed write invalidates the inventory
+	other := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 31000})
+	other.Name = "other"
+	broker.allocationErr = fmt.Errorf("IP pool exhausted")
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, other, nodes); err == nil {
+		t.Fatalf("expected EnsureLoadBalancer to fail")
+	}
+	broker.allocationErr = nil
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, other, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if broker.searches != 2 {
+		t.Errorf("expected a search after the failed write, got %d searches", broker.searches)
+	}
+
+	// objects written recently are kept, even if the search index misses them
+	objectName := types.NamespacedName{Namespace: other.Namespace, Name: other.Name}
+	pool, err := provider.access.FindPool(testClusterName, objectName, NewMapping(other.Spec.Ports[0]))
+	if err != nil || pool == nil {
+		t.Fatalf("FindPool failed: %v, %v", pool, err)
+	}
+	hidden := broker.pools[*pool.Id]
+	delete(broker.pools, *pool.Id)
+	provider.access.InvalidateInventory()
+	if pools, err := provider.access.FindPools(testClusterName, objectName); err != nil || len(pools) != 1 {
+		t.Errorf("expected recently created pool to be found, got %d: %v", len(pools), err)
+	}
+	now = now.Add(2 * searchIndexDelay)
+	provider.access.InvalidateInventory()
+	if pools, err := provider.access.FindPools(testClusterName, objectName); err != nil || len(pools) != 0 {
+		t.Errorf("expected pool deleted out-of-band not to be found, got %d: %v", len(pools), err)
+	}
+	broker.pools[*pool.Id] = hidden
+
+	// the inventory is searched again after its maximum age
+	searches := broker.searches
+	now = now.Add(inventoryMaxAge)
+	if _, err := provider.access.ListPools(testClusterName); err != nil {
+		t.Fatalf("ListPools failed: %v", err)
+	}
+	if broker.searches != searches+1 {
+		t.Errorf("expected a search after the maximum age, got %d searches", broker.searches-searches)
+	}
+
+	for _, service := range append(services, other) {
+		if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, service); err != nil {
+			t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+		}
+	}
+	if len(broker.servers) != 0 || len(broker.pools) != 0 || len(broker.monitors) != 0 || len(broker.ipAllocations) != 0 {
+		t.Errorf("expected all objects to be deleted, got %d, %d, %d and %d",
+			len(broker.servers), len(broker.pools), len(broker.monitors), len(broker.ipAllocations))
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	query := searchQuery([]string{resourceTypeVirtualServer, resourceTypePool}, newTag(ScopeCluster, "my-cluster/a:b"))
+	expected := `resource_type:(LBVirtualServer OR LBPool) AND tags.scope:cluster AND tags.tag:my\-cluster\/a\:b`
+	if query != expected {
+		t.Errorf("expected query %s, got %s", expected, query)
+	}
+}
@@ -20,11 +20,15 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/vsphere-automation-sdk-go/runtime/protocol/client"
 	corev1 "k8s.io/api/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
+	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
 )
@@ -32,6 +36,24 @@ import (
 const (
 	// LoadBalancerClassAnnotation is the optional class annotation at the service
 	LoadBalancerClassAnnotation = "loadbalancer.vmware.io/class"
+	// NodeSelectorAnnotation is the optional label selector at the service restricting the nodes used as pool members
+	NodeSelectorAnnotation = "loadbalancer.vmware.io/node-selector"
+	// HTTPPortsAnnotation is the optional comma separated list of service ports served by HTTP virtual servers
+	HTTPPortsAnnotation = "loadbalancer.vmware.io/http-ports"
+	// HTTPSPortsAnnotation is the optional comma separated list of service ports served by HTTPS virtual servers
+	HTTPSPortsAnnotation = "loadbalancer.vmware.io/https-ports"
+	// TLSSecretAnnotation is the name of the kubernetes.io/tls secret in the namespace of the service
+	// holding the certificate of the HTTPS virtual servers
+	TLSSecretAnnotation = "loadbalancer.vmware.io/tls-secret"
+	// PoolAlgorithmAnnotation is the optional load balancing algorithm of the pools of the service
+	PoolAlgorithmAnnotation = "loadbalancer.vmware.io/pool-algorithm"
+	// PoolMemberWeightAnnotation is the optional weight of the pool members of a node for weighted algorithms
+	PoolMemberWeightAnnotation = "loadbalancer.vmware.io/pool-member-weight"
+	// LoadBalancerIPsAnnotation is the optional comma separated list of requested virtual IP addresses,
+	// at most one per IP family. It takes precedence over the loadBalancerIP field of the service.
+	LoadBalancerIPsAnnotation = "loadbalancer.vmware.io/load-balancer-ips"
+	// RetainIPAnnotation keeps the virtual IP addresses allocated after the deletion of the service if set to true
+	RetainIPAnnotation = "loadbalancer.vmware.io/retain-ip"
 )
 
 var (
@@ -43,8 +65,13 @@ var (
 
 type lbProvider struct {
 	*lbService
-	classes *loadBalancerClasses
-	keyLock *keyLock
+	classesLock sync.RWMutex
+	classes     *loadBalancerClasses
+	keyLock     *keyLock
+	secrets     corelisters.SecretLister
+	tlsSecrets  *tlsSecrets
+	recorder    record.EventRecorder
+	services    clientcorev1.CoreV1Interface
 }
 
 // ClusterName contains the cluster-name flag injected from main, needed for cleanup
@@ -73,6 +100,7 @@ func NewLBProvider(cfg *config.LBConfig, connector client.Connector) (LBProvider
 	if err != nil {
 		return nil, errors.Wrap(err, "creating load balancer classes failed")
 	}
+	registerMetrics()
 	return &lbProvider{
 		lbService: newLbService(access, cfg.LoadBalancer.LBServiceID),
 		classes:   classes,
@@ -80,7 +108,30 @@ func NewLBProvider(cfg *config.LBConfig, connector client.Connector) (LBProvider
 	}, nil
 }
 
+// Reload replaces the load balancer classes by the ones of the config. The
+// classes are resolved before they are swapped, on error the current classes
+// are kept. The size, the load balancer service and the tags are not reloaded.
+func (p *lbProvider) Reload(cfg *config.LBConfig) error {
+	classes, err := setupClasses(p.access, cfg)
+	if err != nil {
+		return errors.Wrap(err, "creating load balancer classes failed")
+	}
+	p.classesLock.Lock()
+	p.classes = classes
+	p.classesLock.Unlock()
+	return nil
+}
+
+func (p *lbProvider) getClasses() *loadBalancerClasses {
+	p.classesLock.RLock()
+	defer p.classesLock.RUnlock()
+	return p.classes
+}
+
 func (p *lbProvider) Initialize(clusterName string, client clientset.Interface, stop <-chan struct{}) {
+	p.recorder = newServiceEventRecorder(client)
+	p.services = client.CoreV1()
+	p.watchTLSSecrets(clusterName, client, stop)
 	if clusterName != "" {
 		go p.cleanup(clusterName, client.CoreV1().Services(""), stop)
 	}
@@ -97,15 +148,32 @@ func (p *lbProvider) GetLoadBalancer(_ context.Context, clusterName string, serv
 	if len(servers) == 0 {
 		return nil, false, nil
 	}
-	return newLoadBalancerStatus(servers[0].IpAddress), true, nil
+	ipAddresses := map[corev1.IPFamily]*string{}
+	for _, server := range servers {
+		family := ipFamilyFromTags(server.Tags)
+		if ipAddresses[family] == nil {
+			ipAddresses[family] = server.IpAddress
+		}
+	}
+	var ordered []*string
+	for _, family := range ipFamiliesFromService(service) {
+		if ipAddress, ok := ipAddresses[family]; ok {
+			ordered = append(ordered, ipAddress)
+		}
+	}
+	return newLoadBalancerStatus(ordered...), true, nil
 }
 
-func newLoadBalancerStatus(ipAddress *string) *corev1.LoadBalancerStatus {
+// newLoadBalancerStatus returns the status with the virtual IP addresses of
+// the IP families of the service, the primary family first
+func newLoadBalancerStatus(ipAddresses ...*string) *corev1.LoadBalancerStatus {
 	status := &corev1.LoadBalancerStatus{
 		Ingress: []corev1.LoadBalancerIngress{},
 	}
-	if ipAddress != nil {
-		status.Ingress = append(status.Ingress, corev1.LoadBalancerIngress{IP: *ipAddress})
+	for _, ipAddress := range ipAddresses {
+		if ipAddress != nil {
+			status.Ingress = append(status.Ingress, corev1.LoadBalancerIngress{IP: *ipAddress})
+		}
 	}
 	return status
 }
@@ -121,16 +189,25 @@ func (p *lbProvider) GetLoadBalancerName(_ context.Context, clusterName string,
 // parameters as read-only and not modify them.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
 func (p *lbProvider) EnsureLoadBalancer(_ context.Context, clusterName string, service *corev1.Service, nodes []*corev1.Node) (*corev1.LoadBalancerStatus, error) {
+	status, err := p.ensureLoadBalancer(clusterName, service, nodes)
+	setReadyCondition(p.services, service, err, false)
+	return status, err
+}
+
+func (p *lbProvider) ensureLoadBalancer(clusterName string, service *corev1.Service, nodes []*corev1.Node) (*corev1.LoadBalancerStatus, error) {
 	key := namespacedNameFromService(service).String()
 	p.keyLock.Lock(key)
 	defer p.keyLock.Unlock(key)
 
+	if err := p.tlsSecrets.track(service); err != nil {
+		return nil, err
+	}
 	class, err := p.classFromService(service)
 	if err != nil {
 		return nil, err
 	}
 
-	state := newState(p.lbService, clusterName, service, nodes)
+	state := newState(p.lbService, p.secrets, p.recorder, clusterName, service, nodes)
 	err = state.Process(class)
 	status, err2 := state.Finish()
 	if err != nil {
@@ -150,9 +227,9 @@ func (p *lbProvider) classFromService(service *corev1.Service) (*loadBalancerCla
 		name = config.DefaultLoadBalancerClass
 	}
 
-	class := p.classes.GetClass(name)
+	class := p.getClasses().GetClass(name)
 	if class == nil {
-		return nil, fmt.Errorf("invalid load balancer class %s", name)
+		return nil, withReason(ReasonInvalidLoadBalancerClass, fmt.Errorf("invalid load balancer class %s", name))
 	}
 	return class, nil
 }
@@ -166,9 +243,10 @@ func (p *lbProvider) UpdateLoadBalancer(_ context.Context, clusterName string, s
 	p.keyLock.Lock(key)
 	defer p.keyLock.Unlock(key)
 
-	state := newState(p.lbService, clusterName, service, nodes)
-
-	return state.UpdatePoolMembers()
+	state := newState(p.lbService, nil, p.recorder, clusterName, service, nodes)
+	err := state.UpdatePoolMembers()
+	setReadyCondition(p.services, service, err, false)
+	return err
 }
 
 // EnsureLoadBalancerDeleted deletes the specified load balancer if it
@@ -179,9 +257,10 @@ func (p *lbProvider) UpdateLoadBalancer(_ context.Context, clusterName string, s
 // doesn't exist even if some part of it is still laying around.
 // Implementations must treat the *corev1.Service parameter as read-only and not modify it.
 // Parameter 'clusterName' is the name of the cluster as presented to kube-controller-manager
-func (p *lbProvider) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string, service *corev1.Service) error {
+func (p *lbProvider) EnsureLoadBalancerDeleted(_ context.Context, clusterName string, service *corev1.Service) error {
 	emptyService := service.DeepCopy()
 	emptyService.Spec.Ports = nil
-	_, err := p.EnsureLoadBalancer(ctx, clusterName, emptyService, nil)
+	_, err := p.ensureLoadBalancer(clusterName, emptyService, nil)
+	setReadyCondition(p.services, service, err, err == nil)
 	return err
 }
@@ -0,0 +1,973 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
+
+	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+)
+
+const testClusterName = "test-cluster"
+
+func newFakeLBProvider(t *testing.T) (*lbProvider, *fakeBroker) {
+	t.Helper()
+	cfg := &config.LBConfig{
+		LoadBalancer: config.LoadBalancerConfig{
+			Size: model.LBService_SIZE_SMALL,
+			LoadBalancerClassConfig: config.LoadBalancerClassConfig{
+				IPPoolID:          "pool-default",
+				TCPAppProfilePath: "/infra/lb-app-profiles/default-tcp-lb-app-profile",
+				UDPAppProfilePath: "/infra/lb-app-profiles/default-udp-lb-app-profile",
+			},
+		},
+		LoadBalancerClass: map[string]*config.LoadBalancerClassConfig{
+			"dualstack": {IPv6PoolID: "pool-v6"},
+		},
+	}
+	broker := newFakeBroker(
+		model.IpAddressPool{Id: strptr("pool-default"), DisplayName: strptr("default")},
+		model.IpAddressPool{Id: strptr("pool-v6"), DisplayName: strptr("v6")},
+	)
+	access, err := NewNSXTAccess(broker, cfg)
+	if err != nil {
+		t.Fatalf("NewNSXTAccess failed: %v", err)
+	}
+	classes, err := setupClasses(access, cfg)
+	if err != nil {
+		t.Fatalf("setupClasses failed: %v", err)
+	}
+	return &lbProvider{
+		lbService: newLbService(access, ""),
+		classes:   classes,
+		keyLock:   newKeyLock(),
+	}, broker
+}
+
+func newTestService(ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: ports,
+		},
+	}
+}
+
+func newTestNode(name, internalIP string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: internalIP}},
+		},
+	}
+}
+
+// monitorsByPool returns the active monitor of the pool of each service port,
+// the node port for TCP monitors and the request URL and port for HTTP monitors
+func monitorsByPool(t *testing.T, broker *fakeBroker) map[string]string {
+	t.Helper()
+	converter := newNsxtTypeConverter()
+	result := map[string]string{}
+	for _, pool := range broker.pools {
+		port := getTag(pool.Tags, ScopePort)
+		if len(pool.ActiveMonitorPaths) == 0 {
+			result[port] = ""
+			continue
+		}
+		for _, value := range broker.monitors {
+			resourceType, _ := value.String("resource_type")
+			switch resourceType {
+			case model.LBMonitorProfile_RESOURCE_TYPE_LBTCPMONITORPROFILE:
+				monitor, _ := converter.convertStructValueToLBTCPMonitorProfile(value)
+				if *monitor.Path == pool.ActiveMonitorPaths[0] {
+					result[port] = formatPort(int(*monitor.MonitorPort))
+				}
+			case model.LBMonitorProfile_RESOURCE_TYPE_LBHTTPMONITORPROFILE:
+				monitor, _ := converter.convertStructValueToLBHTTPMonitorProfile(value)
+				if *monitor.Path == pool.ActiveMonitorPaths[0] {
+					result[port] = *monitor.RequestUrl + "@" + formatPort(int(*monitor.MonitorPort))
+				}
+			}
+		}
+	}
+	return result
+}
+
+func TestEnsureLoadBalancerExternalTrafficPolicyLocal(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1"), newTestNode("node2", "10.0.0.2")}
+	service := newTestService(
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+		corev1.ServicePort{Protocol: corev1.ProtocolUDP, Port: 53, NodePort: 30053},
+	)
+	service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+	service.Spec.HealthCheckNodePort = 32000
+
+	steps := []struct {
+		name     string
+		update   func()
+		expected map[string]string
+	}{
+		{
+			name:     "local",
+			expected: map[string]string{"TCP/80": "/healthz@32000", "UDP/53": "/healthz@32000"},
+		},
+		{
+			name:     "health check node port changed",
+			update:   func() { service.Spec.HealthCheckNodePort = 32001 },
+			expected: map[string]string{"TCP/80": "/healthz@32001", "UDP/53": "/healthz@32001"},
+		},
+		{
+			name: "cluster",
+			update: func() {
+				service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyCluster
+				service.Spec.HealthCheckNodePort = 0
+			},
+			expected: map[string]string{"TCP/80": "30080", "UDP/53": ""},
+		},
+		{
+			name: "local again",
+			update: func() {
+				service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+				service.Spec.HealthCheckNodePort = 32002
+			},
+			expected: map[string]string{"TCP/80": "/healthz@32002", "UDP/53": "/healthz@32002"},
+		},
+	}
+	for _, step := range steps {
+		if step.update != nil {
+			step.update()
+		}
+		if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+			t.Fatalf("%s: EnsureLoadBalancer failed: %v", step.name, err)
+		}
+		monitors := monitorsByPool(t, broker)
+		if len(monitors) != len(step.expected) {
+			t.Errorf("%s: expected pools %v, got %v", step.name, step.expected, monitors)
+		}
+		for port, monitor := range step.expected {
+			if monitors[port] != monitor {
+				t.Errorf("%s: expected monitor %q for %s, got %q", step.name, monitor, port, monitors[port])
+			}
+		}
+		expectedMonitors := 0
+		for _, monitor := range step.expected {
+			if monitor != "" {
+				expectedMonitors++
+			}
+		}
+		if len(broker.monitors) != expectedMonitors {
+			t.Errorf("%s: expected %d monitors, orphans are left: %d", step.name, expectedMonitors, len(broker.monitors))
+		}
+	}
+
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+	if len(broker.servers)+len(broker.pools)+len(broker.monitors)+len(broker.ipAllocations) != 0 {
+		t.Errorf("expected all objects to be deleted, got servers=%d pools=%d monitors=%d allocations=%d",
+			len(broker.servers), len(broker.pools), len(broker.monitors), len(broker.ipAllocations))
+	}
+}
+
+func poolMemberStates(broker *fakeBroker) map[string]string {
+	states := map[string]string{}
+	for _, pool := range broker.pools {
+		for _, member := range pool.Members {
+			states[*member.DisplayName] = *member.AdminState
+		}
+	}
+	return states
+}
+
+func TestPoolMembers(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+
+	excluded := newTestNode("excluded", "10.0.0.2")
+	excluded.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: ""}
+	cordoned := newTestNode("cordoned", "10.0.0.3")
+	cordoned.Spec.Unschedulable = true
+	unready := newTestNode("unready", "10.0.0.4")
+	unready.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}}
+	edge := newTestNode("edge", "10.0.0.5")
+	edge.Labels = map[string]string{"role": "edge"}
+	nodes := []*corev1.Node{newTestNode("ready", "10.0.0.1"), excluded, cordoned, unready, edge}
+	service := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080})
+
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	expected := map[string]string{
+		testClusterName + ":ready":    model.LBPoolMember_ADMIN_STATE_ENABLED,
+		testClusterName + ":cordoned": model.LBPoolMember_ADMIN_STATE_GRACEFUL_DISABLED,
+		testClusterName + ":unready":  model.LBPoolMember_ADMIN_STATE_DISABLED,
+		testClusterName + ":edge":     model.LBPoolMember_ADMIN_STATE_ENABLED,
+	}
+	if states := poolMemberStates(broker); !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected members %v, got %v", expected, states)
+	}
+
+	cordoned.Spec.Unschedulable = false
+	if err := provider.UpdateLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("UpdateLoadBalancer failed: %v", err)
+	}
+	expected[testClusterName+":cordoned"] = model.LBPoolMember_ADMIN_STATE_ENABLED
+	if states := poolMemberStates(broker); !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected uncordoned member to be enabled, got %v", states)
+	}
+
+	service.Annotations = map[string]string{NodeSelectorAnnotation: "role=edge"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	expected = map[string]string{testClusterName + ":edge": model.LBPoolMember_ADMIN_STATE_ENABLED}
+	if states := poolMemberStates(broker); !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected selected members %v, got %v", expected, states)
+	}
+
+	service.Annotations[NodeSelectorAnnotation] = "role in (edge"
+	if err := provider.UpdateLoadBalancer(ctx, testClusterName, service, nodes); err == nil {
+		t.Error("expected invalid node selector to fail")
+	}
+}
+
+func persistenceTimeouts(t *testing.T, broker *fakeBroker) []int64 {
+	t.Helper()
+	var timeouts []int64
+	for _, id := range sortedKeys(broker.persistence) {
+		profile, err := newNsxtTypeConverter().convertStructValueToLBSourceIPPersistenceProfile(broker.persistence[id])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, server := range broker.servers {
+			if !safeEquals(server.LbPersistenceProfilePath, profile.Path) {
+				t.Errorf("expected virtual server %s to use persistence profile %s", *server.Id, *profile.Path)
+			}
+		}
+		timeouts = append(timeouts, *profile.Timeout)
+	}
+	return timeouts
+}
+
+func TestSessionAffinityAndPoolAlgorithm(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+
+	weighted := newTestNode("weighted", "10.0.0.2")
+	weighted.Annotations = map[string]string{PoolMemberWeightAnnotation: "3"}
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1"), weighted}
+	service := newTestService(
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 443, NodePort: 30443},
+	)
+	service.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if timeouts := persistenceTimeouts(t, broker); !reflect.DeepEqual(timeouts, []int64{int64(corev1.DefaultClientIPServiceAffinitySeconds)}) {
+		t.Errorf("expected one persistence profile with default timeout, got %v", timeouts)
+	}
+	for _, pool := range broker.pools {
+		if *pool.Algorithm != model.LBPool_ALGORITHM_ROUND_ROBIN {
+			t.Errorf("expected default algorithm, got %s", *pool.Algorithm)
+		}
+		for _, member := range pool.Members {
+			expected := int64(1)
+			if *member.DisplayName == testClusterName+":weighted" {
+				expected = 3
+			}
+			if *member.Weight != expected {
+				t.Errorf("expected weight %d for member %s, got %d", expected, *member.DisplayName, *member.Weight)
+			}
+		}
+	}
+
+	timeout := int32(600)
+	service.Spec.SessionAffinityConfig = &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &timeout}}
+	service.Annotations = map[string]string{PoolAlgorithmAnnotation: "weighted_least_connection"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if timeouts := persistenceTimeouts(t, broker); !reflect.DeepEqual(timeouts, []int64{600}) {
+		t.Errorf("expected persistence profile timeout to be updated, got %v", timeouts)
+	}
+	for _, pool := range broker.pools {
+		if *pool.Algorithm != model.LBPool_ALGORITHM_WEIGHTED_LEAST_CONNECTION {
+			t.Errorf("expected algorithm to be updated, got %s", *pool.Algorithm)
+		}
+	}
+
+	service.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if len(broker.persistence) != 0 {
+		t.Errorf("expected persistence profile to be deleted, got %d", len(broker.persistence))
+	}
+	for _, server := range broker.servers {
+		if server.LbPersistenceProfilePath != nil {
+			t.Errorf("expected virtual server %s without persistence profile", *server.Id)
+		}
+	}
+
+	service.Annotations[PoolAlgorithmAnnotation] = "RANDOM"
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err == nil {
+		t.Error("expected invalid pool algorithm to fail")
+	}
+}
+
+// sourceRanges returns the source ranges allowed by the access list of each virtual server
+func sourceRanges(broker *fakeBroker) map[string][]string {
+	result := map[string][]string{}
+	for _, server := range broker.servers {
+		var ranges []string
+		if control := server.AccessListControl; control != nil {
+			group := broker.groups[strings.TrimPrefix(*control.GroupPath, "/infra/domains/default/groups/")]
+			ranges = groupIPAddresses(&group)
+		}
+		result[getTag(server.Tags, ScopePort)] = ranges
+	}
+	return result
+}
+
+func TestLoadBalancerSourceRanges(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	service := newTestService(
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+		corev1.ServicePort{Protocol: corev1.ProtocolUDP, Port: 53, NodePort: 30053},
+	)
+	service.Spec.LoadBalancerSourceRanges = []string{"10.1.0.0/16", "192.0.2.0/24"}
+
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	expected := map[string][]string{
+		"TCP/80": {"10.1.0.0/16", "192.0.2.0/24"},
+		"UDP/53": {"10.1.0.0/16", "192.0.2.0/24"},
+	}
+	if ranges := sourceRanges(broker); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected source ranges %v, got %v", expected, ranges)
+	}
+	if len(broker.groups) != 1 {
+		t.Errorf("expected one group shared by all virtual servers, got %d", len(broker.groups))
+	}
+
+	service.Spec.LoadBalancerSourceRanges = []string{"2001:db8::/32"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	expected = map[string][]string{"TCP/80": {"2001:db8::/32"}, "UDP/53": {"2001:db8::/32"}}
+	if ranges := sourceRanges(broker); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected updated source ranges %v, got %v", expected, ranges)
+	}
+
+	service.Spec.LoadBalancerSourceRanges = []string{"10.1.0.0/16", "0.0.0.0/0"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	expected = map[string][]string{"TCP/80": nil, "UDP/53": nil}
+	if ranges := sourceRanges(broker); !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected unrestricted access, got %v", ranges)
+	}
+	if len(broker.groups) != 0 {
+		t.Errorf("expected group to be deleted, got %d", len(broker.groups))
+	}
+
+	service.Spec.LoadBalancerSourceRanges = []string{"10.1.0.0"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err == nil {
+		t.Error("expected invalid source range to fail")
+	}
+
+	service.Spec.LoadBalancerSourceRanges = []string{"192.0.2.0/24"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if err := provider.CleanupServices(testClusterName, nil, false); err != nil {
+		t.Fatalf("CleanupServices failed: %v", err)
+	}
+	if len(broker.groups) != 0 || len(broker.servers) != 0 {
+		t.Errorf("expected cleanup to delete %d groups and %d virtual servers", len(broker.groups), len(broker.servers))
+	}
+}
+
+// poolMembersByFamily returns the member IP addresses of the pools of each IP family
+func poolMembersByFamily(broker *fakeBroker) map[corev1.IPFamily][]string {
+	result := map[corev1.IPFamily][]string{}
+	for _, id := range sortedKeys(broker.pools) {
+		pool := broker.pools[id]
+		family := ipFamilyFromTags(pool.Tags)
+		for _, member := range pool.Members {
+			result[family] = append(result[family], *member.IpAddress)
+		}
+	}
+	return result
+}
+
+func ingressIPs(status *corev1.LoadBalancerStatus) []string {
+	var ips []string
+	for _, ingress := range status.Ingress {
+		ips = append(ips, ingress.IP)
+	}
+	return ips
+}
+
+func TestDualStack(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+
+	dual := newTestNode("dual", "10.0.0.1")
+	dual.Status.Addresses = append(dual.Status.Addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "fd00::1"})
+	nodes := []*corev1.Node{dual, newTestNode("ipv4", "10.0.0.2")}
+	service := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080})
+	service.Annotations = map[string]string{LoadBalancerClassAnnotation: "dualstack"}
+	requireDualStack := corev1.IPFamilyPolicyRequireDualStack
+	service.Spec.IPFamilyPolicy = &requireDualStack
+	service.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}
+
+	status, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	ips := ingressIPs(status)
+	if len(ips) != 2 || ipFamilyOf(ips[0]) != corev1.IPv6Protocol || ipFamilyOf(ips[1]) != corev1.IPv4Protocol {
+		t.Fatalf("expected IPv6 and IPv4 ingress, got %v", ips)
+	}
+	if len(broker.servers) != 2 || len(broker.ipAllocations) != 2 {
+		t.Errorf("expected a virtual server and an IP address per family, got %d and %d", len(broker.servers), len(broker.ipAllocations))
+	}
+	for _, server := range broker.servers {
+		family := ipFamilyFromTags(server.Tags)
+		if ipFamilyOf(*server.IpAddress) != family {
+			t.Errorf("expected %s virtual server to have an %s address, got %s", family, family, *server.IpAddress)
+		}
+		if expected := map[corev1.IPFamily]string{corev1.IPv4Protocol: "pool-default", corev1.IPv6Protocol: "pool-v6"}[family]; getTag(server.Tags, ScopeIPPoolID) != expected {
+			t.Errorf("expected %s virtual server to be tagged with pool %s, got %v", family, expected, server.Tags)
+		}
+	}
+	expected := map[corev1.IPFamily][]string{
+		corev1.IPv4Protocol: {"10.0.0.1", "10.0.0.2"},
+		corev1.IPv6Protocol: {"fd00::1"},
+	}
+	members := poolMembersByFamily(broker)
+	for family := range members {
+		sort.Strings(members[family])
+	}
+	if !reflect.DeepEqual(members, expected) {
+		t.Errorf("expected family matched pool members %v, got %v", expected, members)
+	}
+	current, exists, err := provider.GetLoadBalancer(ctx, testClusterName, service)
+	if err != nil || !exists || !reflect.DeepEqual(ingressIPs(current), ips) {
+		t.Errorf("expected GetLoadBalancer to report %v, got %v (%t, %v)", ips, current, exists, err)
+	}
+
+	singleStack := corev1.IPFamilyPolicySingleStack
+	service.Spec.IPFamilyPolicy = &singleStack
+	service.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv4Protocol}
+	status, err = provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if !reflect.DeepEqual(ingressIPs(status), ips[1:]) {
+		t.Errorf("expected IPv4 ingress %v, got %v", ips[1:], ingressIPs(status))
+	}
+	if len(broker.servers) != 1 || len(broker.pools) != 1 || len(broker.ipAllocations) != 1 {
+		t.Errorf("expected IPv6 objects to be deleted, got %d servers, %d pools, %d IP addresses",
+			len(broker.servers), len(broker.pools), len(broker.ipAllocations))
+	}
+
+	service.Annotations = nil
+	service.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv6Protocol}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err == nil {
+		t.Error("expected IPv6 service to fail for class without IPv6 pool")
+	}
+	preferDualStack := corev1.IPFamilyPolicyPreferDualStack
+	service.Spec.IPFamilyPolicy = &preferDualStack
+	service.Spec.IPFamilies = []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}
+	status, err = provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if !reflect.DeepEqual(ingressIPs(status), ips[1:]) {
+		t.Errorf("expected IPv4 ingress only for class without IPv6 pool, got %v", ingressIPs(status))
+	}
+
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+	if len(broker.servers) != 0 || len(broker.ipAllocations) != 0 {
+		t.Errorf("expected all objects to be deleted, got %d servers, %d IP addresses", len(broker.servers), len(broker.ipAllocations))
+	}
+}
+
+// recordedEvents drains the events recorded so far
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+// hasEvent checks whether one of the events starts with the type and reason
+func hasEvent(events []string, eventType, reason string) bool {
+	for _, event := range events {
+		if strings.HasPrefix(event, eventType+" "+reason+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoadBalancerIP(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	recorder := record.NewFakeRecorder(100)
+	provider.recorder = recorder
+	ctx := context.Background()
+
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	service := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080})
+	service.Spec.LoadBalancerIP = "192.0.2.200"
+	status, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if ips := ingressIPs(status); !reflect.DeepEqual(ips, []string{"192.0.2.200"}) {
+		t.Errorf("expected requested ingress 192.0.2.200, got %v", ips)
+	}
+
+	service.Annotations = map[string]string{LoadBalancerIPsAnnotation: "192.0.2.201"}
+	status, err = provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if ips := ingressIPs(status); !reflect.DeepEqual(ips, []string{"192.0.2.201"}) {
+		t.Errorf("expected annotated ingress 192.0.2.201, got %v", ips)
+	}
+	for _, server := range broker.servers {
+		if *server.IpAddress != "192.0.2.201" {
+			t.Errorf("expected virtual server to be moved to 192.0.2.201, got %s", *server.IpAddress)
+		}
+	}
+	if len(broker.ipAllocations) != 1 {
+		t.Errorf("expected the previous IP address to be released, got %d allocations", len(broker.ipAllocations))
+	}
+
+	other := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 31080})
+	other.Name = "other"
+	other.Spec.LoadBalancerIP = "192.0.2.201"
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, other, nodes); err == nil {
+		t.Error("expected conflicting IP address to fail")
+	}
+	if events := recordedEvents(recorder); !hasEvent(events, corev1.EventTypeWarning, ReasonLoadBalancerIPConflict) {
+		t.Errorf("expected conflict event, got %q", events)
+	}
+	other.Spec.LoadBalancerIP = "198.51.100.1"
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, other, nodes); err == nil {
+		t.Error("expected IP address outside of the pool to fail")
+	}
+	if events := recordedEvents(recorder); !hasEvent(events, corev1.EventTypeWarning, ReasonLoadBalancerIPNotInPool) {
+		t.Errorf("expected not in pool event, got %q", events)
+	}
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, other); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+
+	service.Annotations[RetainIPAnnotation] = "true"
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+	if err := provider.CleanupServices(testClusterName, map[types.NamespacedName]corev1.Service{}, false); err != nil {
+		t.Fatalf("CleanupServices failed: %v", err)
+	}
+	if len(broker.servers) != 0 || len(broker.ipAllocations) != 1 {
+		t.Fatalf("expected only the retained IP address to be kept, got %d servers, %d IP addresses", len(broker.servers), len(broker.ipAllocations))
+	}
+
+	service.Annotations = map[string]string{RetainIPAnnotation: "true"}
+	service.Spec.LoadBalancerIP = ""
+	status, err = provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes)
+	if err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if ips := ingressIPs(status); !reflect.DeepEqual(ips, []string{"192.0.2.201"}) {
+		t.Errorf("expected recreated service to get retained ingress 192.0.2.201, got %v", ips)
+	}
+
+	service.Annotations = nil
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+	if len(broker.ipAllocations) != 0 {
+		t.Errorf("expected IP address to be released without retain annotation, got %d", len(broker.ipAllocations))
+	}
+}
+
+func TestRequestedIPAddresses(t *testing.T) {
+	tests := []struct {
+		annotation     string
+		loadBalancerIP string
+		expected       map[corev1.IPFamily]string
+		err            bool
+	}{
+		{},
+		{loadBalancerIP: "192.0.2.1", expected: map[corev1.IPFamily]string{corev1.IPv4Protocol: "192.0.2.1"}},
+		{annotation: "2001:db8::0:1, 192.0.2.2", loadBalancerIP: "192.0.2.1",
+			expected: map[corev1.IPFamily]string{corev1.IPv4Protocol: "192.0.2.2", corev1.IPv6Protocol: "2001:db8::1"}},
+		{annotation: "192.0.2.1,192.0.2.2", err: true},
+		{annotation: "web.example.com", err: true},
+	}
+	for _, test := range tests {
+		service := newTestService()
+		service.Annotations = map[string]string{LoadBalancerIPsAnnotation: test.annotation}
+		service.Spec.LoadBalancerIP = test.loadBalancerIP
+		requested, err := requestedIPAddresses(service)
+		if (err != nil) != test.err || !test.err && !reflect.DeepEqual(requested, test.expected) {
+			t.Errorf("%q/%q: expected %v (error %t), got %v (%v)", test.annotation, test.loadBalancerIP, test.expected, test.err, requested, err)
+		}
+	}
+}
+
+// serviceReadyCondition returns the ready condition of the service in the client
+func serviceReadyCondition(t *testing.T, client *fake.Clientset, service *corev1.Service) *metav1.Condition {
+	t.Helper()
+	current, err := client.CoreV1().Services(service.Namespace).Get(context.Background(), service.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meta.FindStatusCondition(current.Status.Conditions, ConditionLoadBalancerReady)
+}
+
+func TestEventsAndConditions(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	recorder := record.NewFakeRecorder(100)
+	provider.recorder = recorder
+	ctx := context.Background()
+
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	service := newTestService(
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 443, NodePort: 30443},
+	)
+	client := fake.NewSimpleClientset(service)
+	provider.services = client.CoreV1()
+
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	events := recordedEvents(recorder)
+	for _, reason := range []string{ReasonIPAddressAllocated, ReasonPoolCreated, ReasonVirtualServerCreated} {
+		if !hasEvent(events, corev1.EventTypeNormal, reason) {
+			t.Errorf("expected %s event, got %q", reason, events)
+		}
+	}
+	if condition := serviceReadyCondition(t, client, service); condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != ReasonReconciled {
+		t.Errorf("expected ready condition, got %v", condition)
+	}
+
+	// the service controller passes the service of its informer, the
+	// unchanged condition is not patched again
+	service, err := client.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ClearActions()
+	service.Spec.Ports = service.Spec.Ports[:1]
+	nodes = append(nodes, newTestNode("node2", "10.0.0.2"))
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if actions := client.Actions(); len(actions) != 0 {
+		t.Errorf("expected no service requests for an unchanged condition, got %v", actions)
+	}
+	events = recordedEvents(recorder)
+	for _, reason := range []string{ReasonPoolUpdated, ReasonOrphansDeleted} {
+		if !hasEvent(events, corev1.EventTypeNormal, reason) {
+			t.Errorf("expected %s event, got %q", reason, events)
+		}
+	}
+
+	tests := []struct {
+		annotations   map[string]string
+		allocationErr error
+		reason        string
+	}{
+		{annotations: map[string]string{LoadBalancerClassAnnotation: "unknown"}, reason: ReasonInvalidLoadBalancerClass},
+		{allocationErr: fmt.Errorf("IP pool exhausted"), reason: ReasonIPAllocationFailed},
+		{allocationErr: errIPAddressNotRealized, reason: ReasonIPAddressNotRealized},
+	}
+	other := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 31080})
+	other.Name = "other"
+	if _, err := client.CoreV1().Services(other.Namespace).Create(ctx, other, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		other.Annotations = test.annotations
+		broker.allocationErr = test.allocationErr
+		if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, other, nodes); err == nil {
+			t.Errorf("%s: expected EnsureLoadBalancer to fail", test.reason)
+		}
+		condition := serviceReadyCondition(t, client, other)
+		if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != test.reason {
+			t.Errorf("expected failed condition with reason %s, got %v", test.reason, condition)
+		}
+	}
+
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, service); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+	if condition := serviceReadyCondition(t, client, service); condition != nil {
+		t.Errorf("expected ready condition to be removed, got %v", condition)
+	}
+}
+
+func driftCorrections(t *testing.T, kind, field string) float64 {
+	t.Helper()
+	value, err := testutil.GetCounterMetricValue(driftCorrectionsMetric.WithLabelValues(kind, field))
+	if err != nil {
+		t.Fatalf("reading drift metric failed: %v", err)
+	}
+	return value
+}
+
+func TestDriftCorrection(t *testing.T) {
+	registerMetrics()
+	provider, broker := newFakeLBProvider(t)
+	recorder := record.NewFakeRecorder(100)
+	provider.recorder = recorder
+	ctx := context.Background()
+
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	service := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080})
+	client := fake.NewSimpleClientset(service)
+	provider.services = client.CoreV1()
+
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if len(broker.servers) != 1 || len(broker.pools) != 1 {
+		t.Fatalf("expected one virtual server and pool, got %d and %d", len(broker.servers), len(broker.pools))
+	}
+	var server model.LBVirtualServer
+	for _, item := range broker.servers {
+		server = item
+	}
+	var pool model.LBPool
+	for _, item := range broker.pools {
+		pool = item
+	}
+	expectedServer, expectedPool := server, pool
+	recordedEvents(recorder)
+
+	fields := []struct{ kind, field string }{
+		{kindVirtualServer, "applicationProfilePath"},
+		{kindVirtualServer, "lbServicePath"},
+		{kindVirtualServer, "enabled"},
+		{kindPool, "snatTranslation"},
+		{kindPool, "algorithm"},
+		{kindTCPMonitor, "monitorPort"},
+	}
+	before := map[string]float64{}
+	for _, f := range fields {
+		before[f.kind+"/"+f.field] = driftCorrections(t, f.kind, f.field)
+	}
+
+	// out-of-band changes are seen once the search index has caught up with the writes
+	inventory := provider.access.(*access).inventory
+	inventory.now = func() time.Time { return time.Now().Add(2 * searchIndexDelay) }
+
+	server.ApplicationProfilePath = strptr("/infra/lb-app-profiles/other")
+	server.LbServicePath = strptr("/infra/lb-services/other")
+	server.Enabled = boolptr(false)
+	broker.servers[*server.Id] = server
+	pool.SnatTranslation = nil
+	pool.Algorithm = strptr(model.LBPool_ALGORITHM_LEAST_CONNECTION)
+	broker.pools[*pool.Id] = pool
+	monitorID := pool.ActiveMonitorPaths[0][strings.LastIndex(pool.ActiveMonitorPaths[0], "/")+1:]
+	monitor, err := broker.ReadLoadBalancerTCPMonitorProfile(monitorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitor.MonitorPort = int64ptr(1234)
+	if _, err := broker.UpdateLoadBalancerTCPMonitorProfile(monitor); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.doCleanupStep(testClusterName, client.CoreV1().Services("")); err != nil {
+		t.Fatalf("doCleanupStep failed: %v", err)
+	}
+	if !reflect.DeepEqual(broker.servers[*server.Id], expectedServer) {
+		t.Errorf("expected virtual server to be reverted to %v, got %v", expectedServer, broker.servers[*server.Id])
+	}
+	if !reflect.DeepEqual(broker.pools[*pool.Id], expectedPool) {
+		t.Errorf("expected pool to be reverted to %v, got %v", expectedPool, broker.pools[*pool.Id])
+	}
+	if members := poolMemberStates(broker); !reflect.DeepEqual(members, map[string]string{testClusterName + ":node1": model.LBPoolMember_ADMIN_STATE_ENABLED}) {
+		t.Errorf("expected pool members to be kept, got %v", members)
+	}
+	if monitors := monitorsByPool(t, broker); monitors["TCP/80"] != "30080" {
+		t.Errorf("expected monitor port to be reverted, got %v", monitors)
+	}
+	for _, f := range fields {
+		if value := driftCorrections(t, f.kind, f.field); value != before[f.kind+"/"+f.field]+1 {
+			t.Errorf("expected one drift correction of %s %s, got %v", f.kind, f.field, value-before[f.kind+"/"+f.field])
+		}
+	}
+	events := recordedEvents(recorder)
+	for _, reason := range []string{ReasonPoolUpdated, ReasonVirtualServerUpdated} {
+		if !hasEvent(events, corev1.EventTypeNormal, reason) {
+			t.Errorf("expected %s event, got %q", reason, events)
+		}
+	}
+
+	if err := provider.doCleanupStep(testClusterName, client.CoreV1().Services("")); err != nil {
+		t.Fatalf("doCleanupStep failed: %v", err)
+	}
+	if events := recordedEvents(recorder); len(events) != 0 {
+		t.Errorf("expected no events without drift, got %q", events)
+	}
+
+	// objects deleted out-of-band are left to the service controller
+	delete(broker.servers, *server.Id)
+	allocations := len(broker.ipAllocations)
+	if err := provider.doCleanupStep(testClusterName, client.CoreV1().Services("")); err != nil {
+		t.Fatalf("doCleanupStep failed: %v", err)
+	}
+	if len(broker.servers) != 0 || len(broker.ipAllocations) != allocations {
+		t.Errorf("expected resync not to create virtual servers or allocate IP addresses, got %d and %d",
+			len(broker.servers), len(broker.ipAllocations))
+	}
+}
+
+func TestResyncMissingObjects(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+	secret := newTLSSecret(t, "default", "web-tls", "web.example.com")
+	client := fake.NewSimpleClientset(secret)
+	stop := make(chan struct{})
+	defer close(stop)
+	provider.tlsSecrets = newTLSSecrets(client, stop)
+	provider.secrets = provider.tlsSecrets
+
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	service := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 443, NodePort: 30443})
+	service.Annotations = map[string]string{HTTPSPortsAnnotation: "443", TLSSecretAnnotation: "web-tls"}
+	service.Spec.LoadBalancerSourceRanges = []string{"10.1.0.0/16"}
+	service.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if len(broker.groups) != 1 || len(broker.persistence) != 1 || len(broker.certificates) != 1 {
+		t.Fatalf("expected a group, a persistence profile and a certificate, got %d, %d and %d",
+			len(broker.groups), len(broker.persistence), len(broker.certificates))
+	}
+
+	// out-of-band changes are seen once the search index has caught up with the writes
+	inventory := provider.access.(*access).inventory
+	inventory.now = func() time.Time { return time.Now().Add(2 * searchIndexDelay) }
+
+	// the objects are removed in the reverse order they are looked up in
+	tests := []struct {
+		kind  string
+		clear func()
+		count func() int
+	}{
+		{"persistence profile", func() { broker.persistence = map[string]*data.StructValue{} }, func() int { return len(broker.persistence) }},
+		{"source ranges group", func() { broker.groups = map[string]model.Group{} }, func() int { return len(broker.groups) }},
+		{"certificate", func() { broker.certificates = map[string]model.TlsCertificate{} }, func() int { return len(broker.certificates) }},
+	}
+	for _, test := range tests {
+		test.clear()
+		provider.access.InvalidateInventory()
+		err := provider.resyncLoadBalancer(testClusterName, service)
+		if err == nil || !strings.Contains(err.Error(), test.kind+" for TCP/443") {
+			t.Errorf("expected resync to report the missing %s, got %v", test.kind, err)
+		}
+		if count := test.count(); count != 0 {
+			t.Errorf("expected resync not to create a %s, got %d", test.kind, count)
+		}
+	}
+}
+
+func TestEqualSnatTranslations(t *testing.T) {
+	converter := newNsxtTypeConverter()
+	autoMap, err := converter.createLBSnatAutoMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipPool := func(ipAddresses ...string) *data.StructValue {
+		snatTranslation := model.LBSnatIpPool{Type_: model.LBSnatIpPool__TYPE_IDENTIFIER}
+		for _, ipAddress := range ipAddresses {
+			snatTranslation.IpAddresses = append(snatTranslation.IpAddresses, model.LBSnatIpElement{IpAddress: strptr(ipAddress)})
+		}
+		value, errs := converter.ConvertToVapi(snatTranslation, model.LBSnatIpPoolBindingType())
+		if errs != nil {
+			t.Fatal(errs[0])
+		}
+		return value.(*data.StructValue)
+	}
+
+	tests := []struct {
+		a, b     *data.StructValue
+		expected bool
+	}{
+		{a: autoMap, b: autoMap, expected: true},
+		{a: autoMap, b: nil, expected: false},
+		{a: autoMap, b: ipPool("10.0.0.1"), expected: false},
+		{a: ipPool("10.0.0.1", "10.0.0.2"), b: ipPool("10.0.0.2", "10.0.0.1"), expected: true},
+		{a: ipPool("10.0.0.1"), b: ipPool("10.0.0.2"), expected: false},
+		{a: ipPool("10.0.0.1"), b: ipPool("10.0.0.1", "10.0.0.2"), expected: false},
+	}
+	for i, test := range tests {
+		if equal := equalSnatTranslations(test.a, test.b); equal != test.expected {
+			t.Errorf("%d: expected %t, got %t", i, test.expected, equal)
+		}
+	}
+}
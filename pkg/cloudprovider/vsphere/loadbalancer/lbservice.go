@@ -78,6 +78,8 @@ func (s *lbService) removeLoadBalancerServiceIfUnused(clusterName string) error
 		if err != nil {
 			return err
 		}
+		// a managed service is created again by the next virtual server
+		s.lbServiceID = ""
 	}
 	return nil
 }
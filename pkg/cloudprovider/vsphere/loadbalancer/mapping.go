@@ -33,6 +33,10 @@ type Mapping struct {
 	NodePort int
 	// Protoocl is the protocol on the service port
 	Protocol corev1.Protocol
+	// AppProtocol is HTTP or HTTPS for L7 virtual servers and empty for L4 ones
+	AppProtocol string
+	// IPFamily is the IP family of the virtual server and its pool members, IPv4 if empty
+	IPFamily corev1.IPFamily
 }
 
 // NewMapping creates a new Mapping for the given service port
@@ -45,17 +49,28 @@ func NewMapping(servicePort corev1.ServicePort) Mapping {
 }
 
 func (m Mapping) String() string {
+	if m.ipFamily() == corev1.IPv6Protocol {
+		return fmt.Sprintf("%s/%d->%d (IPv6)", m.Protocol, m.SourcePort, m.NodePort)
+	}
 	return fmt.Sprintf("%s/%d->%d", m.Protocol, m.SourcePort, m.NodePort)
 }
 
-// MatchVirtualServer returns true if source port is matching
+func (m Mapping) ipFamily() corev1.IPFamily {
+	if m.IPFamily == "" {
+		return corev1.IPv4Protocol
+	}
+	return m.IPFamily
+}
+
+// MatchVirtualServer returns true if source port and IP family are matching
 func (m Mapping) MatchVirtualServer(server *model.LBVirtualServer) bool {
-	return len(server.Ports) == 1 && server.Ports[0] == formatPort(m.SourcePort) && checkTags(server.Tags, portTag(m))
+	return len(server.Ports) == 1 && server.Ports[0] == formatPort(m.SourcePort) && checkTags(server.Tags, portTag(m)) &&
+		ipFamilyFromTags(server.Tags) == m.ipFamily()
 }
 
-// MatchPool returns true if the pool has the correct port tag
+// MatchPool returns true if the pool has the correct port tag and IP family
 func (m Mapping) MatchPool(pool *model.LBPool) bool {
-	return checkTags(pool.Tags, portTag(m))
+	return checkTags(pool.Tags, portTag(m)) && ipFamilyFromTags(pool.Tags) == m.ipFamily()
 }
 
 // MatchTCPMonitor returns true if the monitor has the correct port tag
@@ -63,6 +78,11 @@ func (m Mapping) MatchTCPMonitor(monitor *model.LBTcpMonitorProfile) bool {
 	return checkTags(monitor.Tags, portTag(m))
 }
 
+// MatchHTTPMonitor returns true if the monitor has the correct port tag
+func (m Mapping) MatchHTTPMonitor(monitor *model.LBHttpMonitorProfile) bool {
+	return checkTags(monitor.Tags, portTag(m))
+}
+
 // MatchNodePort returns true if the server pool member port is equal to the mapping's node port
 func (m Mapping) MatchNodePort(server *model.LBVirtualServer) bool {
 	return len(server.DefaultPoolMemberPorts) == 1 && server.DefaultPoolMemberPorts[0] == formatPort(m.NodePort)
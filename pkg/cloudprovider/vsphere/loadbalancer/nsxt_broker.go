@@ -18,6 +18,7 @@ package loadbalancer
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,9 +30,11 @@ import (
 	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
 	"github.com/vmware/vsphere-automation-sdk-go/runtime/protocol/client"
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/infra"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/infra/domains"
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/infra/ip_pools"
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/infra/realized_state"
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/search"
 )
 
 // NsxtBroker is an internal interface to enable mocking the nsxt backend
@@ -42,37 +45,63 @@ type NsxtBroker interface {
 	UpdateLoadBalancerService(service model.LBService) (model.LBService, error)
 	DeleteLoadBalancerService(id string) error
 	CreateLoadBalancerVirtualServer(server model.LBVirtualServer) (model.LBVirtualServer, error)
-	ListLoadBalancerVirtualServers() ([]model.LBVirtualServer, error)
 	UpdateLoadBalancerVirtualServer(server model.LBVirtualServer) (model.LBVirtualServer, error)
 	DeleteLoadBalancerVirtualServer(id string) error
 	CreateLoadBalancerPool(pool model.LBPool) (model.LBPool, error)
 	ReadLoadBalancerPool(id string) (model.LBPool, error)
-	ListLoadBalancerPools() ([]model.LBPool, error)
 	UpdateLoadBalancerPool(pool model.LBPool) (model.LBPool, error)
 	DeleteLoadBalancerPool(id string) error
 	ListIPPools() ([]model.IpAddressPool, error)
 	AllocateFromIPPool(ipPoolID string, allocation model.IpAddressAllocation) (model.IpAddressAllocation, string, error)
 	ListIPPoolAllocations(ipPoolID string) ([]model.IpAddressAllocation, error)
+	UpdateIPPoolAllocation(ipPoolID string, allocation model.IpAddressAllocation) error
 	ReleaseFromIPPool(ipPoolID, ipAllocationID string) error
+	ListIPPoolSubnets(ipPoolID string) ([]*data.StructValue, error)
 	GetRealizedExternalIPAddress(ipAllocationPath string, timeout time.Duration) (*string, error)
 	ListAppProfiles() ([]*data.StructValue, error)
 
 	CreateLoadBalancerTCPMonitorProfile(monitor model.LBTcpMonitorProfile) (model.LBTcpMonitorProfile, error)
-	ListLoadBalancerMonitorProfiles() ([]*data.StructValue, error)
 	ReadLoadBalancerTCPMonitorProfile(id string) (model.LBTcpMonitorProfile, error)
 	UpdateLoadBalancerTCPMonitorProfile(monitor model.LBTcpMonitorProfile) (model.LBTcpMonitorProfile, error)
+	CreateLoadBalancerHTTPMonitorProfile(monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error)
+	ReadLoadBalancerHTTPMonitorProfile(id string) (model.LBHttpMonitorProfile, error)
+	UpdateLoadBalancerHTTPMonitorProfile(monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error)
 	DeleteLoadBalancerMonitorProfile(id string) error
+
+	CreateLoadBalancerSourceIPPersistenceProfile(profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error)
+	UpdateLoadBalancerSourceIPPersistenceProfile(profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error)
+	DeleteLoadBalancerPersistenceProfile(id string) error
+
+	ImportCertificate(certificate model.TlsTrustData) (model.TlsCertificate, error)
+	DeleteCertificate(id string) error
+
+	CreateGroup(group model.Group) (model.Group, error)
+	UpdateGroup(group model.Group) (model.Group, error)
+	DeleteGroup(id string) error
+
+	SearchTaggedObjects(resourceTypes []string, tag model.Tag) ([]*data.StructValue, error)
 }
 
+// errIPAddressNotRealized is returned if NSX-T has not realized an IP address allocation
+var errIPAddressNotRealized = errors.New("IP address allocation not realized")
+
+// defaultDomain is the policy domain of the groups used by the load balancer
+const defaultDomain = "default"
+
 type nsxtBroker struct {
-	lbServicesClient        infra.LbServicesClient
-	lbVirtServersClient     infra.LbVirtualServersClient
-	lbPoolsClient           infra.LbPoolsClient
-	ipPoolsClient           infra.IpPoolsClient
-	ipAllocationsClient     ip_pools.IpAllocationsClient
-	lbAppProfilesClient     infra.LbAppProfilesClient
-	lbMonitorProfilesClient infra.LbMonitorProfilesClient
-	realizedEntitiesClient  realized_state.RealizedEntitiesClient
+	lbServicesClient            infra.LbServicesClient
+	lbVirtServersClient         infra.LbVirtualServersClient
+	lbPoolsClient               infra.LbPoolsClient
+	ipPoolsClient               infra.IpPoolsClient
+	ipAllocationsClient         ip_pools.IpAllocationsClient
+	ipSubnetsClient             ip_pools.IpSubnetsClient
+	lbAppProfilesClient         infra.LbAppProfilesClient
+	lbMonitorProfilesClient     infra.LbMonitorProfilesClient
+	lbPersistenceProfilesClient infra.LbPersistenceProfilesClient
+	realizedEntitiesClient      realized_state.RealizedEntitiesClient
+	certificatesClient          infra.CertificatesClient
+	groupsClient                domains.GroupsClient
+	searchClient                search.QueryClient
 }
 
 // NewNsxtBroker creates a new NsxtBroker using the configuration
@@ -88,14 +117,19 @@ func NewNsxtBroker(connector client.Connector) (NsxtBroker, error) {
 // NewNsxtBrokerFromConnector creates a new NsxtBroker to the real API
 func NewNsxtBrokerFromConnector(connector client.Connector) NsxtBroker {
 	return &nsxtBroker{
-		lbServicesClient:        infra.NewLbServicesClient(connector),
-		lbVirtServersClient:     infra.NewLbVirtualServersClient(connector),
-		lbPoolsClient:           infra.NewLbPoolsClient(connector),
-		ipPoolsClient:           infra.NewIpPoolsClient(connector),
-		ipAllocationsClient:     ip_pools.NewIpAllocationsClient(connector),
-		lbAppProfilesClient:     infra.NewLbAppProfilesClient(connector),
-		lbMonitorProfilesClient: infra.NewLbMonitorProfilesClient(connector),
-		realizedEntitiesClient:  realized_state.NewRealizedEntitiesClient(connector),
+		lbServicesClient:            infra.NewLbServicesClient(connector),
+		lbVirtServersClient:         infra.NewLbVirtualServersClient(connector),
+		lbPoolsClient:               infra.NewLbPoolsClient(connector),
+		ipPoolsClient:               infra.NewIpPoolsClient(connector),
+		ipAllocationsClient:         ip_pools.NewIpAllocationsClient(connector),
+		ipSubnetsClient:             ip_pools.NewIpSubnetsClient(connector),
+		lbAppProfilesClient:         infra.NewLbAppProfilesClient(connector),
+		lbMonitorProfilesClient:     infra.NewLbMonitorProfilesClient(connector),
+		lbPersistenceProfilesClient: infra.NewLbPersistenceProfilesClient(connector),
+		realizedEntitiesClient:      realized_state.NewRealizedEntitiesClient(connector),
+		certificatesClient:          infra.NewCertificatesClient(connector),
+		groupsClient:                domains.NewGroupsClient(connector),
+		searchClient:                search.NewQueryClient(connector),
 	}
 }
 
@@ -142,23 +176,6 @@ func (b *nsxtBroker) CreateLoadBalancerVirtualServer(server model.LBVirtualServe
 	return result, nicerVAPIError(err)
 }
 
-func (b *nsxtBroker) ListLoadBalancerVirtualServers() ([]model.LBVirtualServer, error) {
-	result, err := b.lbVirtServersClient.List(nil, nil, nil, nil, nil, nil)
-	if err != nil {
-		return nil, nicerVAPIError(err)
-	}
-	list := result.Results
-	count := int(*result.ResultCount)
-	for len(list) < count {
-		result, err = b.lbVirtServersClient.List(result.Cursor, nil, nil, nil, nil, nil)
-		if err != nil {
-			return nil, nicerVAPIError(err)
-		}
-		list = append(list, result.Results...)
-	}
-	return list, nil
-}
-
 func (b *nsxtBroker) UpdateLoadBalancerVirtualServer(server model.LBVirtualServer) (model.LBVirtualServer, error) {
 	result, err := b.lbVirtServersClient.Update(*server.Id, server)
 	return result, nicerVAPIError(err)
@@ -180,23 +197,6 @@ func (b *nsxtBroker) ReadLoadBalancerPool(id string) (model.LBPool, error) {
 	return result, nicerVAPIError(err)
 }
 
-func (b *nsxtBroker) ListLoadBalancerPools() ([]model.LBPool, error) {
-	result, err := b.lbPoolsClient.List(nil, nil, nil, nil, nil, nil)
-	if err != nil {
-		return nil, nicerVAPIError(err)
-	}
-	list := result.Results
-	count := int(*result.ResultCount)
-	for len(list) < count {
-		result, err = b.lbPoolsClient.List(result.Cursor, nil, nil, nil, nil, nil)
-		if err != nil {
-			return nil, nicerVAPIError(err)
-		}
-		list = append(list, result.Results...)
-	}
-	return list, nil
-}
-
 func (b *nsxtBroker) UpdateLoadBalancerPool(pool model.LBPool) (model.LBPool, error) {
 	result, err := b.lbPoolsClient.Update(*pool.Id, pool)
 	return result, nicerVAPIError(err)
@@ -244,23 +244,6 @@ func (b *nsxtBroker) createOrUpdateLoadBalancerTCPMonitorProfile(id string, moni
 	return converter.convertStructValueToLBTCPMonitorProfile(result)
 }
 
-func (b *nsxtBroker) ListLoadBalancerMonitorProfiles() ([]*data.StructValue, error) {
-	result, err := b.lbMonitorProfilesClient.List(nil, nil, nil, nil, nil, nil)
-	if err != nil {
-		return nil, nicerVAPIError(err)
-	}
-	list := result.Results
-	count := int(*result.ResultCount)
-	for len(list) < count {
-		result, err = b.lbMonitorProfilesClient.List(result.Cursor, nil, nil, nil, nil, nil)
-		if err != nil {
-			return nil, nicerVAPIError(err)
-		}
-		list = append(list, result.Results...)
-	}
-	return list, nil
-}
-
 func (b *nsxtBroker) ReadLoadBalancerTCPMonitorProfile(id string) (model.LBTcpMonitorProfile, error) {
 	itf, err := b.lbMonitorProfilesClient.Get(id)
 	if err != nil {
@@ -274,11 +257,140 @@ func (b *nsxtBroker) UpdateLoadBalancerTCPMonitorProfile(monitor model.LBTcpMoni
 	return result, nicerVAPIError(err)
 }
 
+func (b *nsxtBroker) CreateLoadBalancerHTTPMonitorProfile(monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error) {
+	id := uuid.New().String()
+	result, err := b.createOrUpdateLoadBalancerHTTPMonitorProfile(id, monitor)
+	return result, nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) createOrUpdateLoadBalancerHTTPMonitorProfile(id string, monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error) {
+	monitor.ResourceType = model.LBMonitorProfile_RESOURCE_TYPE_LBHTTPMONITORPROFILE
+	converter := newNsxtTypeConverter()
+	value, err := converter.convertLBHTTPMonitorProfileToStructValue(monitor)
+	if err != nil {
+		return model.LBHttpMonitorProfile{}, errors.Wrapf(err, "converting LBHttpMonitorProfile failed")
+	}
+	result, err := b.lbMonitorProfilesClient.Update(id, value)
+	if err != nil {
+		return model.LBHttpMonitorProfile{}, nicerVAPIError(err)
+	}
+	return converter.convertStructValueToLBHTTPMonitorProfile(result)
+}
+
+func (b *nsxtBroker) ReadLoadBalancerHTTPMonitorProfile(id string) (model.LBHttpMonitorProfile, error) {
+	itf, err := b.lbMonitorProfilesClient.Get(id)
+	if err != nil {
+		return model.LBHttpMonitorProfile{}, errors.Wrapf(nicerVAPIError(err), "getting LBHttpMonitorProfile %s failed", id)
+	}
+	return newNsxtTypeConverter().convertStructValueToLBHTTPMonitorProfile(itf)
+}
+
+func (b *nsxtBroker) UpdateLoadBalancerHTTPMonitorProfile(monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error) {
+	result, err := b.createOrUpdateLoadBalancerHTTPMonitorProfile(*monitor.Id, monitor)
+	return result, nicerVAPIError(err)
+}
+
 func (b *nsxtBroker) DeleteLoadBalancerMonitorProfile(id string) error {
 	err := b.lbMonitorProfilesClient.Delete(id, nil)
 	return nicerVAPIError(err)
 }
 
+func (b *nsxtBroker) CreateLoadBalancerSourceIPPersistenceProfile(profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error) {
+	id := uuid.New().String()
+	result, err := b.createOrUpdateLoadBalancerSourceIPPersistenceProfile(id, profile)
+	return result, nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) createOrUpdateLoadBalancerSourceIPPersistenceProfile(id string, profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error) {
+	profile.ResourceType = model.LBPersistenceProfile_RESOURCE_TYPE_LBSOURCEIPPERSISTENCEPROFILE
+	converter := newNsxtTypeConverter()
+	value, err := converter.convertLBSourceIPPersistenceProfileToStructValue(profile)
+	if err != nil {
+		return model.LBSourceIpPersistenceProfile{}, errors.Wrapf(err, "converting LBSourceIpPersistenceProfile failed")
+	}
+	result, err := b.lbPersistenceProfilesClient.Update(id, value)
+	if err != nil {
+		return model.LBSourceIpPersistenceProfile{}, nicerVAPIError(err)
+	}
+	return converter.convertStructValueToLBSourceIPPersistenceProfile(result)
+}
+
+func (b *nsxtBroker) UpdateLoadBalancerSourceIPPersistenceProfile(profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error) {
+	result, err := b.createOrUpdateLoadBalancerSourceIPPersistenceProfile(*profile.Id, profile)
+	return result, nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) DeleteLoadBalancerPersistenceProfile(id string) error {
+	err := b.lbPersistenceProfilesClient.Delete(id, nil)
+	return nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) ImportCertificate(certificate model.TlsTrustData) (model.TlsCertificate, error) {
+	id := uuid.New().String()
+	result, err := b.certificatesClient.Update(id, certificate)
+	return result, nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) DeleteCertificate(id string) error {
+	err := b.certificatesClient.Delete(id)
+	return nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) CreateGroup(group model.Group) (model.Group, error) {
+	id := uuid.New().String()
+	result, err := b.groupsClient.Update(defaultDomain, id, group)
+	return result, nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) UpdateGroup(group model.Group) (model.Group, error) {
+	result, err := b.groupsClient.Update(defaultDomain, *group.Id, group)
+	return result, nicerVAPIError(err)
+}
+
+func (b *nsxtBroker) DeleteGroup(id string) error {
+	err := b.groupsClient.Delete(defaultDomain, id, nil, nil)
+	return nicerVAPIError(err)
+}
+
+// SearchTaggedObjects returns all objects of the resource types with the tag
+// using the policy search API
+func (b *nsxtBroker) SearchTaggedObjects(resourceTypes []string, tag model.Tag) ([]*data.StructValue, error) {
+	query := searchQuery(resourceTypes, tag)
+	result, err := b.searchClient.List(query, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, nicerVAPIError(err)
+	}
+	list := result.Results
+	// the result count is only set on the first page
+	count := int(*result.ResultCount)
+	for len(list) < count && result.Cursor != nil {
+		result, err = b.searchClient.List(query, result.Cursor, nil, nil, nil, nil)
+		if err != nil {
+			return nil, nicerVAPIError(err)
+		}
+		list = append(list, result.Results...)
+	}
+	return list, nil
+}
+
+// searchQuery builds the search query for objects of the resource types with the tag
+func searchQuery(resourceTypes []string, tag model.Tag) string {
+	return fmt.Sprintf("resource_type:(%s) AND tags.scope:%s AND tags.tag:%s",
+		strings.Join(resourceTypes, " OR "), escapeSearchTerm(*tag.Scope), escapeSearchTerm(*tag.Tag))
+}
+
+// escapeSearchTerm escapes the characters with special meaning in search queries
+func escapeSearchTerm(term string) string {
+	var sb strings.Builder
+	for _, c := range term {
+		if strings.ContainsRune(`+-&|!(){}[]^"~*?:\/ `, c) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
 func (b *nsxtBroker) ListIPPools() ([]model.IpAddressPool, error) {
 	result, err := b.ipPoolsClient.List(nil, nil, nil, nil, nil, nil)
 	if err != nil {
@@ -311,7 +423,7 @@ func (b *nsxtBroker) AllocateFromIPPool(ipPoolID string, allocation model.IpAddr
 		return allocated, "", nicerVAPIError(err)
 	}
 	if ipAddress == nil {
-		return allocated, "", fmt.Errorf("no IP address allocated for %s", *allocated.Path)
+		return allocated, "", errors.Wrapf(errIPAddressNotRealized, "no IP address allocated for %s", *allocated.Path)
 	}
 	return allocated, *ipAddress, nil
 }
@@ -333,11 +445,33 @@ func (b *nsxtBroker) ListIPPoolAllocations(ipPoolID string) ([]model.IpAddressAl
 	return list, nil
 }
 
+func (b *nsxtBroker) UpdateIPPoolAllocation(ipPoolID string, allocation model.IpAddressAllocation) error {
+	err := b.ipAllocationsClient.Patch(ipPoolID, *allocation.Id, allocation)
+	return nicerVAPIError(err)
+}
+
 func (b *nsxtBroker) ReleaseFromIPPool(ipPoolID, ipAllocationID string) error {
 	err := b.ipAllocationsClient.Delete(ipPoolID, ipAllocationID)
 	return nicerVAPIError(err)
 }
 
+func (b *nsxtBroker) ListIPPoolSubnets(ipPoolID string) ([]*data.StructValue, error) {
+	result, err := b.ipSubnetsClient.List(ipPoolID, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, nicerVAPIError(err)
+	}
+	list := result.Results
+	count := int(*result.ResultCount)
+	for len(list) < count {
+		result, err = b.ipSubnetsClient.List(ipPoolID, result.Cursor, nil, nil, nil, nil, nil)
+		if err != nil {
+			return nil, nicerVAPIError(err)
+		}
+		list = append(list, result.Results...)
+	}
+	return list, nil
+}
+
 func (b *nsxtBroker) GetRealizedExternalIPAddress(ipAllocationPath string, timeout time.Duration) (*string, error) {
 	// wait for realized state
 	limit := time.Now().Add(timeout)
@@ -362,7 +496,7 @@ func (b *nsxtBroker) GetRealizedExternalIPAddress(ipAllocationPath string, timeo
 			}
 		}
 	}
-	return nil, fmt.Errorf("Timeout of wait for realized state of IP allocation")
+	return nil, errors.Wrapf(errIPAddressNotRealized, "Timeout of wait for realized state of IP allocation %s", ipAllocationPath)
 }
 
 func nicerVAPIError(err error) error {
@@ -0,0 +1,469 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	vapi_errors "github.com/vmware/vsphere-automation-sdk-go/lib/vapi/std/errors"
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/bindings"
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/data"
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
+)
+
+// fakeBroker is an in-memory NsxtBroker. Like NSX-T it refuses to delete
+// monitors, pools, certificates, persistence profiles and groups which are
+// still referenced.
+type fakeBroker struct {
+	nextID        int
+	lbServices    map[string]model.LBService
+	servers       map[string]model.LBVirtualServer
+	pools         map[string]model.LBPool
+	monitors      map[string]*data.StructValue
+	certificates  map[string]model.TlsCertificate
+	persistence   map[string]*data.StructValue
+	groups        map[string]model.Group
+	ipPools       []model.IpAddressPool
+	ipAllocations map[string]model.IpAddressAllocation
+	// allocationErr is returned by AllocateFromIPPool if set
+	allocationErr error
+	// searches counts the calls of SearchTaggedObjects
+	searches int
+}
+
+var _ NsxtBroker = &fakeBroker{}
+
+func newFakeBroker(ipPools ...model.IpAddressPool) *fakeBroker {
+	return &fakeBroker{
+		lbServices:    map[string]model.LBService{},
+		servers:       map[string]model.LBVirtualServer{},
+		pools:         map[string]model.LBPool{},
+		monitors:      map[string]*data.StructValue{},
+		certificates:  map[string]model.TlsCertificate{},
+		persistence:   map[string]*data.StructValue{},
+		groups:        map[string]model.Group{},
+		ipPools:       ipPools,
+		ipAllocations: map[string]model.IpAddressAllocation{},
+	}
+}
+
+func (b *fakeBroker) newID(kind string) (*string, *string) {
+	b.nextID++
+	id := fmt.Sprintf("%s-%d", kind, b.nextID)
+	return strptr(id), strptr(fmt.Sprintf("/infra/%s/%s", kind, id))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (b *fakeBroker) ReadLoadBalancerService(id string) (model.LBService, error) {
+	service, ok := b.lbServices[id]
+	if !ok {
+		return model.LBService{}, vapi_errors.NotFound{}
+	}
+	return service, nil
+}
+
+func (b *fakeBroker) CreateLoadBalancerService(service model.LBService) (model.LBService, error) {
+	service.Id, service.Path = b.newID("lb-services")
+	b.lbServices[*service.Id] = service
+	return service, nil
+}
+
+func (b *fakeBroker) ListLoadBalancerServices() ([]model.LBService, error) {
+	var list []model.LBService
+	for _, id := range sortedKeys(b.lbServices) {
+		list = append(list, b.lbServices[id])
+	}
+	return list, nil
+}
+
+func (b *fakeBroker) UpdateLoadBalancerService(service model.LBService) (model.LBService, error) {
+	if _, ok := b.lbServices[*service.Id]; !ok {
+		return model.LBService{}, vapi_errors.NotFound{}
+	}
+	b.lbServices[*service.Id] = service
+	return service, nil
+}
+
+func (b *fakeBroker) DeleteLoadBalancerService(id string) error {
+	if _, ok := b.lbServices[id]; !ok {
+		return vapi_errors.NotFound{}
+	}
+	delete(b.lbServices, id)
+	return nil
+}
+
+func (b *fakeBroker) CreateLoadBalancerVirtualServer(server model.LBVirtualServer) (model.LBVirtualServer, error) {
+	server.Id, server.Path = b.newID("lb-virtual-servers")
+	server.ResourceType = strptr(resourceTypeVirtualServer)
+	b.servers[*server.Id] = server
+	return server, nil
+}
+
+func (b *fakeBroker) UpdateLoadBalancerVirtualServer(server model.LBVirtualServer) (model.LBVirtualServer, error) {
+	if _, ok := b.servers[*server.Id]; !ok {
+		return model.LBVirtualServer{}, vapi_errors.NotFound{}
+	}
+	b.servers[*server.Id] = server
+	return server, nil
+}
+
+func (b *fakeBroker) DeleteLoadBalancerVirtualServer(id string) error {
+	if _, ok := b.servers[id]; !ok {
+		return vapi_errors.NotFound{}
+	}
+	delete(b.servers, id)
+	return nil
+}
+
+func (b *fakeBroker) CreateLoadBalancerPool(pool model.LBPool) (model.LBPool, error) {
+	pool.Id, pool.Path = b.newID("lb-pools")
+	pool.ResourceType = strptr(resourceTypePool)
+	b.pools[*pool.Id] = pool
+	return pool, nil
+}
+
+func (b *fakeBroker) ReadLoadBalancerPool(id string) (model.LBPool, error) {
+	pool, ok := b.pools[id]
+	if !ok {
+		return model.LBPool{}, vapi_errors.NotFound{}
+	}
+	return pool, nil
+}
+
+func (b *fakeBroker) UpdateLoadBalancerPool(pool model.LBPool) (model.LBPool, error) {
+	if _, ok := b.pools[*pool.Id]; !ok {
+		return model.LBPool{}, vapi_errors.NotFound{}
+	}
+	b.pools[*pool.Id] = pool
+	return pool, nil
+}
+
+func (b *fakeBroker) DeleteLoadBalancerPool(id string) error {
+	pool, ok := b.pools[id]
+	if !ok {
+		return vapi_errors.NotFound{}
+	}
+	for _, server := range b.servers {
+		if safeEquals(server.PoolPath, pool.Path) {
+			return fmt.Errorf("pool %s is still used by virtual server %s", id, *server.Id)
+		}
+	}
+	delete(b.pools, id)
+	return nil
+}
+
+func (b *fakeBroker) ListIPPools() ([]model.IpAddressPool, error) {
+	return b.ipPools, nil
+}
+
+func (b *fakeBroker) AllocateFromIPPool(ipPoolID string, allocation model.IpAddressAllocation) (model.IpAddressAllocation, string, error) {
+	if b.allocationErr != nil {
+		return allocation, "", b.allocationErr
+	}
+	allocation.Id, allocation.Path = b.newID("ip-pools/" + ipPoolID + "/ip-allocations")
+	if allocation.AllocationIp == nil {
+		allocation.AllocationIp = strptr(fmt.Sprintf("192.0.2.%d", b.nextID))
+		if strings.HasSuffix(ipPoolID, "-v6") {
+			allocation.AllocationIp = strptr(fmt.Sprintf("2001:db8::%d", b.nextID))
+		}
+	}
+	b.ipAllocations[*allocation.Id] = allocation
+	return allocation, *allocation.AllocationIp, nil
+}
+
+func (b *fakeBroker) ListIPPoolAllocations(ipPoolID string) ([]model.IpAddressAllocation, error) {
+	var list []model.IpAddressAllocation
+	for _, id := range sortedKeys(b.ipAllocations) {
+		if strings.HasPrefix(id, "ip-pools/"+ipPoolID+"/") {
+			list = append(list, b.ipAllocations[id])
+		}
+	}
+	return list, nil
+}
+
+func (b *fakeBroker) UpdateIPPoolAllocation(ipPoolID string, allocation model.IpAddressAllocation) error {
+	if _, ok := b.ipAllocations[*allocation.Id]; !ok {
+		return vapi_errors.NotFound{}
+	}
+	b.ipAllocations[*allocation.Id] = allocation
+	return nil
+}
+
+func (b *fakeBroker) ReleaseFromIPPool(ipPoolID, ipAllocationID string) error {
+	if _, ok := b.ipAllocations[ipAllocationID]; !ok {
+		return vapi_errors.NotFound{}
+	}
+	delete(b.ipAllocations, ipAllocationID)
+	return nil
+}
+
+// ListIPPoolSubnets returns the static subnet 192.0.2.0/24 for IPv4 pools and
+// the block subnet 2001:db8::/64 for IPv6 pools
+func (b *fakeBroker) ListIPPoolSubnets(ipPoolID string) ([]*data.StructValue, error) {
+	converter := newNsxtTypeConverter()
+	var subnet *data.StructValue
+	var err error
+	if strings.HasSuffix(ipPoolID, "-v6") {
+		subnet, err = converter.convertIPAddressPoolBlockSubnetToStructValue(model.IpAddressPoolBlockSubnet{
+			Cidr:         strptr("2001:db8::/64"),
+			ResourceType: model.IpAddressPoolSubnet_RESOURCE_TYPE_IPADDRESSPOOLBLOCKSUBNET,
+		})
+	} else {
+		subnet, err = converter.convertIPAddressPoolStaticSubnetToStructValue(model.IpAddressPoolStaticSubnet{
+			Cidr:             strptr("192.0.2.0/24"),
+			AllocationRanges: []model.IpPoolRange{{Start: strptr("192.0.2.1"), End: strptr("192.0.2.254")}},
+			ResourceType:     model.IpAddressPoolSubnet_RESOURCE_TYPE_IPADDRESSPOOLSTATICSUBNET,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []*data.StructValue{subnet}, nil
+}
+
+func (b *fakeBroker) GetRealizedExternalIPAddress(ipAllocationPath string, timeout time.Duration) (*string, error) {
+	for _, allocation := range b.ipAllocations {
+		if *allocation.Path == ipAllocationPath {
+			return allocation.AllocationIp, nil
+		}
+	}
+	return nil, vapi_errors.NotFound{}
+}
+
+func (b *fakeBroker) ListAppProfiles() ([]*data.StructValue, error) {
+	return nil, nil
+}
+
+func (b *fakeBroker) CreateLoadBalancerTCPMonitorProfile(monitor model.LBTcpMonitorProfile) (model.LBTcpMonitorProfile, error) {
+	monitor.Id, monitor.Path = b.newID("lb-monitor-profiles")
+	return b.UpdateLoadBalancerTCPMonitorProfile(monitor)
+}
+
+func (b *fakeBroker) ReadLoadBalancerTCPMonitorProfile(id string) (model.LBTcpMonitorProfile, error) {
+	value, ok := b.monitors[id]
+	if !ok {
+		return model.LBTcpMonitorProfile{}, vapi_errors.NotFound{}
+	}
+	return newNsxtTypeConverter().convertStructValueToLBTCPMonitorProfile(value)
+}
+
+func (b *fakeBroker) UpdateLoadBalancerTCPMonitorProfile(monitor model.LBTcpMonitorProfile) (model.LBTcpMonitorProfile, error) {
+	monitor.ResourceType = model.LBMonitorProfile_RESOURCE_TYPE_LBTCPMONITORPROFILE
+	value, err := newNsxtTypeConverter().convertLBTCPMonitorProfileToStructValue(monitor)
+	if err != nil {
+		return model.LBTcpMonitorProfile{}, err
+	}
+	b.monitors[*monitor.Id] = value
+	return monitor, nil
+}
+
+func (b *fakeBroker) CreateLoadBalancerHTTPMonitorProfile(monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error) {
+	monitor.Id, monitor.Path = b.newID("lb-monitor-profiles")
+	return b.UpdateLoadBalancerHTTPMonitorProfile(monitor)
+}
+
+func (b *fakeBroker) ReadLoadBalancerHTTPMonitorProfile(id string) (model.LBHttpMonitorProfile, error) {
+	value, ok := b.monitors[id]
+	if !ok {
+		return model.LBHttpMonitorProfile{}, vapi_errors.NotFound{}
+	}
+	return newNsxtTypeConverter().convertStructValueToLBHTTPMonitorProfile(value)
+}
+
+func (b *fakeBroker) UpdateLoadBalancerHTTPMonitorProfile(monitor model.LBHttpMonitorProfile) (model.LBHttpMonitorProfile, error) {
+	monitor.ResourceType = model.LBMonitorProfile_RESOURCE_TYPE_LBHTTPMONITORPROFILE
+	value, err := newNsxtTypeConverter().convertLBHTTPMonitorProfileToStructValue(monitor)
+	if err != nil {
+		return model.LBHttpMonitorProfile{}, err
+	}
+	b.monitors[*monitor.Id] = value
+	return monitor, nil
+}
+
+func (b *fakeBroker) DeleteLoadBalancerMonitorProfile(id string) error {
+	if _, ok := b.monitors[id]; !ok {
+		return vapi_errors.NotFound{}
+	}
+	for _, pool := range b.pools {
+		for _, path := range pool.ActiveMonitorPaths {
+			if path == "/infra/lb-monitor-profiles/"+id {
+				return fmt.Errorf("monitor %s is still used by pool %s", id, *pool.Id)
+			}
+		}
+	}
+	delete(b.monitors, id)
+	return nil
+}
+
+func (b *fakeBroker) ImportCertificate(certificate model.TlsTrustData) (model.TlsCertificate, error) {
+	result := model.TlsCertificate{
+		Description: certificate.Description,
+		DisplayName: certificate.DisplayName,
+		Tags:        certificate.Tags,
+		PemEncoded:  certificate.PemEncoded,
+	}
+	result.Id, result.Path = b.newID("certificates")
+	b.certificates[*result.Id] = result
+	return result, nil
+}
+
+func (b *fakeBroker) DeleteCertificate(id string) error {
+	certificate, ok := b.certificates[id]
+	if !ok {
+		return vapi_errors.NotFound{}
+	}
+	for _, server := range b.servers {
+		if certificatePath(&server) == *certificate.Path {
+			return fmt.Errorf("certificate %s is still used by virtual server %s", id, *server.Id)
+		}
+	}
+	delete(b.certificates, id)
+	return nil
+}
+
+func (b *fakeBroker) CreateLoadBalancerSourceIPPersistenceProfile(profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error) {
+	profile.Id, profile.Path = b.newID("lb-persistence-profiles")
+	return b.UpdateLoadBalancerSourceIPPersistenceProfile(profile)
+}
+
+func (b *fakeBroker) UpdateLoadBalancerSourceIPPersistenceProfile(profile model.LBSourceIpPersistenceProfile) (model.LBSourceIpPersistenceProfile, error) {
+	profile.ResourceType = model.LBPersistenceProfile_RESOURCE_TYPE_LBSOURCEIPPERSISTENCEPROFILE
+	value, err := newNsxtTypeConverter().convertLBSourceIPPersistenceProfileToStructValue(profile)
+	if err != nil {
+		return model.LBSourceIpPersistenceProfile{}, err
+	}
+	b.persistence[*profile.Id] = value
+	return profile, nil
+}
+
+func (b *fakeBroker) DeleteLoadBalancerPersistenceProfile(id string) error {
+	if _, ok := b.persistence[id]; !ok {
+		return vapi_errors.NotFound{}
+	}
+	for _, server := range b.servers {
+		if safeEquals(server.LbPersistenceProfilePath, strptr("/infra/lb-persistence-profiles/"+id)) {
+			return fmt.Errorf("persistence profile %s is still used by virtual server %s", id, *server.Id)
+		}
+	}
+	delete(b.persistence, id)
+	return nil
+}
+
+func (b *fakeBroker) CreateGroup(group model.Group) (model.Group, error) {
+	group.Id, group.Path = b.newID("domains/" + defaultDomain + "/groups")
+	b.groups[*group.Id] = group
+	return group, nil
+}
+
+func (b *fakeBroker) UpdateGroup(group model.Group) (model.Group, error) {
+	if _, ok := b.groups[*group.Id]; !ok {
+		return model.Group{}, vapi_errors.NotFound{}
+	}
+	b.groups[*group.Id] = group
+	return group, nil
+}
+
+func (b *fakeBroker) DeleteGroup(id string) error {
+	group, ok := b.groups[id]
+	if !ok {
+		return vapi_errors.NotFound{}
+	}
+	for _, server := range b.servers {
+		if server.AccessListControl != nil && safeEquals(server.AccessListControl.GroupPath, group.Path) {
+			return fmt.Errorf("group %s is still used by virtual server %s", id, *server.Id)
+		}
+	}
+	delete(b.groups, id)
+	return nil
+}
+
+// SearchTaggedObjects returns the objects of the resource types with the tag,
+// as struct values with their resource type like the policy search API
+func (b *fakeBroker) SearchTaggedObjects(resourceTypes []string, tag model.Tag) ([]*data.StructValue, error) {
+	b.searches++
+	converter := newNsxtTypeConverter()
+	var list []*data.StructValue
+	add := func(resourceType string, tags []model.Tag, obj interface{}, bindingType bindings.BindingType) error {
+		if !checkTags(tags, tag) {
+			return nil
+		}
+		for _, t := range resourceTypes {
+			if t == resourceType {
+				value, errs := converter.ConvertToVapi(obj, bindingType)
+				if errs != nil {
+					return errs[0]
+				}
+				structValue := value.(*data.StructValue)
+				structValue.SetStringField("resource_type", resourceType)
+				list = append(list, structValue)
+			}
+		}
+		return nil
+	}
+	for _, id := range sortedKeys(b.servers) {
+		if err := add(resourceTypeVirtualServer, b.servers[id].Tags, b.servers[id], model.LBVirtualServerBindingType()); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range sortedKeys(b.pools) {
+		if err := add(resourceTypePool, b.pools[id].Tags, b.pools[id], model.LBPoolBindingType()); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range sortedKeys(b.certificates) {
+		if err := add(resourceTypeCertificate, b.certificates[id].Tags, b.certificates[id], model.TlsCertificateBindingType()); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range sortedKeys(b.groups) {
+		if err := add(resourceTypeGroup, b.groups[id].Tags, b.groups[id], model.GroupBindingType()); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range sortedKeys(b.ipAllocations) {
+		if err := add(resourceTypeIPAddressAllocation, b.ipAllocations[id].Tags, b.ipAllocations[id], model.IpAddressAllocationBindingType()); err != nil {
+			return nil, err
+		}
+	}
+	for _, values := range []map[string]*data.StructValue{b.monitors, b.persistence} {
+		for _, id := range sortedKeys(values) {
+			resourceType := structString(values[id], "resource_type")
+			for _, t := range resourceTypes {
+				if t == resourceType && checkTags(structTags(t, values[id]), tag) {
+					list = append(list, values[id])
+				}
+			}
+		}
+	}
+	return list, nil
+}
+
+func structTags(resourceType string, value *data.StructValue) []model.Tag {
+	_, object, _ := newInventoryObject(resourceType, value)
+	return object.tags
+}
@@ -80,3 +80,197 @@ func (c *nsxtTypeConverter) convertStructValueToLBTCPMonitorProfile(dataValue *d
 	}
 	return profile, nil
 }
+
+func (c *nsxtTypeConverter) convertLBHTTPMonitorProfileToStructValue(monitor model.LBHttpMonitorProfile) (*data.StructValue, error) {
+	dataValue, errs := c.ConvertToVapi(monitor, model.LBHttpMonitorProfileBindingType())
+	if errs != nil {
+		return nil, errs[0]
+	}
+
+	return dataValue.(*data.StructValue), nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToLBHTTPMonitorProfile(dataValue *data.StructValue) (model.LBHttpMonitorProfile, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.LBHttpMonitorProfileBindingType())
+	if errs != nil {
+		return model.LBHttpMonitorProfile{}, errs[0]
+	}
+
+	profile, ok := itf.(model.LBHttpMonitorProfile)
+	if !ok {
+		return model.LBHttpMonitorProfile{}, fmt.Errorf("converting struct value to LBHttpMonitorProfile failed")
+	}
+	return profile, nil
+}
+
+func (c *nsxtTypeConverter) convertLBSourceIPPersistenceProfileToStructValue(profile model.LBSourceIpPersistenceProfile) (*data.StructValue, error) {
+	dataValue, errs := c.ConvertToVapi(profile, model.LBSourceIpPersistenceProfileBindingType())
+	if errs != nil {
+		return nil, errs[0]
+	}
+
+	return dataValue.(*data.StructValue), nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToLBSourceIPPersistenceProfile(dataValue *data.StructValue) (model.LBSourceIpPersistenceProfile, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.LBSourceIpPersistenceProfileBindingType())
+	if errs != nil {
+		return model.LBSourceIpPersistenceProfile{}, errs[0]
+	}
+
+	profile, ok := itf.(model.LBSourceIpPersistenceProfile)
+	if !ok {
+		return model.LBSourceIpPersistenceProfile{}, fmt.Errorf("converting struct value to LBSourceIpPersistenceProfile failed")
+	}
+	return profile, nil
+}
+
+func (c *nsxtTypeConverter) convertIPAddressExpressionToStructValue(expression model.IPAddressExpression) (*data.StructValue, error) {
+	dataValue, errs := c.ConvertToVapi(expression, model.IPAddressExpressionBindingType())
+	if errs != nil {
+		return nil, errs[0]
+	}
+
+	return dataValue.(*data.StructValue), nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToIPAddressExpression(dataValue *data.StructValue) (model.IPAddressExpression, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.IPAddressExpressionBindingType())
+	if errs != nil {
+		return model.IPAddressExpression{}, errs[0]
+	}
+
+	expression, ok := itf.(model.IPAddressExpression)
+	if !ok {
+		return model.IPAddressExpression{}, fmt.Errorf("converting struct value to IPAddressExpression failed")
+	}
+	return expression, nil
+}
+
+func (c *nsxtTypeConverter) convertIPAddressPoolStaticSubnetToStructValue(subnet model.IpAddressPoolStaticSubnet) (*data.StructValue, error) {
+	dataValue, errs := c.ConvertToVapi(subnet, model.IpAddressPoolStaticSubnetBindingType())
+	if errs != nil {
+		return nil, errs[0]
+	}
+
+	return dataValue.(*data.StructValue), nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToIPAddressPoolStaticSubnet(dataValue *data.StructValue) (model.IpAddressPoolStaticSubnet, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.IpAddressPoolStaticSubnetBindingType())
+	if errs != nil {
+		return model.IpAddressPoolStaticSubnet{}, errs[0]
+	}
+
+	subnet, ok := itf.(model.IpAddressPoolStaticSubnet)
+	if !ok {
+		return model.IpAddressPoolStaticSubnet{}, fmt.Errorf("converting struct value to IpAddressPoolStaticSubnet failed")
+	}
+	return subnet, nil
+}
+
+func (c *nsxtTypeConverter) convertIPAddressPoolBlockSubnetToStructValue(subnet model.IpAddressPoolBlockSubnet) (*data.StructValue, error) {
+	dataValue, errs := c.ConvertToVapi(subnet, model.IpAddressPoolBlockSubnetBindingType())
+	if errs != nil {
+		return nil, errs[0]
+	}
+
+	return dataValue.(*data.StructValue), nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToIPAddressPoolBlockSubnet(dataValue *data.StructValue) (model.IpAddressPoolBlockSubnet, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.IpAddressPoolBlockSubnetBindingType())
+	if errs != nil {
+		return model.IpAddressPoolBlockSubnet{}, errs[0]
+	}
+
+	subnet, ok := itf.(model.IpAddressPoolBlockSubnet)
+	if !ok {
+		return model.IpAddressPoolBlockSubnet{}, fmt.Errorf("converting struct value to IpAddressPoolBlockSubnet failed")
+	}
+	return subnet, nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToLBVirtualServer(dataValue *data.StructValue) (model.LBVirtualServer, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.LBVirtualServerBindingType())
+	if errs != nil {
+		return model.LBVirtualServer{}, errs[0]
+	}
+
+	server, ok := itf.(model.LBVirtualServer)
+	if !ok {
+		return model.LBVirtualServer{}, fmt.Errorf("converting struct value to LBVirtualServer failed")
+	}
+
+	return server, nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToLBPool(dataValue *data.StructValue) (model.LBPool, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.LBPoolBindingType())
+	if errs != nil {
+		return model.LBPool{}, errs[0]
+	}
+
+	pool, ok := itf.(model.LBPool)
+	if !ok {
+		return model.LBPool{}, fmt.Errorf("converting struct value to LBPool failed")
+	}
+
+	return pool, nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToTLSCertificate(dataValue *data.StructValue) (model.TlsCertificate, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.TlsCertificateBindingType())
+	if errs != nil {
+		return model.TlsCertificate{}, errs[0]
+	}
+
+	certificate, ok := itf.(model.TlsCertificate)
+	if !ok {
+		return model.TlsCertificate{}, fmt.Errorf("converting struct value to TlsCertificate failed")
+	}
+
+	return certificate, nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToGroup(dataValue *data.StructValue) (model.Group, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.GroupBindingType())
+	if errs != nil {
+		return model.Group{}, errs[0]
+	}
+
+	group, ok := itf.(model.Group)
+	if !ok {
+		return model.Group{}, fmt.Errorf("converting struct value to Group failed")
+	}
+
+	return group, nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToIPAddressAllocation(dataValue *data.StructValue) (model.IpAddressAllocation, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.IpAddressAllocationBindingType())
+	if errs != nil {
+		return model.IpAddressAllocation{}, errs[0]
+	}
+
+	allocation, ok := itf.(model.IpAddressAllocation)
+	if !ok {
+		return model.IpAddressAllocation{}, fmt.Errorf("converting struct value to IpAddressAllocation failed")
+	}
+
+	return allocation, nil
+}
+
+func (c *nsxtTypeConverter) convertStructValueToTag(dataValue *data.StructValue) (model.Tag, error) {
+	itf, errs := c.ConvertToGolang(dataValue, model.TagBindingType())
+	if errs != nil {
+		return model.Tag{}, errs[0]
+	}
+
+	tag, ok := itf.(model.Tag)
+	if !ok {
+		return model.Tag{}, fmt.Errorf("converting struct value to Tag failed")
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,292 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	klog "k8s.io/klog/v2"
+)
+
+// tlsSecrets watches the TLS secrets referenced by services with the
+// TLSSecretAnnotation. Every referenced secret has its own informer, selected
+// by namespace and name, so only these secrets are cached and the service
+// account does not need to list all secrets of the cluster. The informer of a
+// secret is started when the first service references it and stopped when the
+// last one is gone. Changed secrets are queued and their certificates imported
+// by a worker instead of the informer handler.
+type tlsSecrets struct {
+	client clientset.Interface
+	stop   <-chan struct{}
+	queue  workqueue.RateLimitingInterface
+
+	lock sync.Mutex
+	// services are the services by the secret of their TLSSecretAnnotation
+	services map[types.NamespacedName]map[types.NamespacedName]*corev1.Service
+	// informers are the informers of the secrets in services
+	informers map[types.NamespacedName]*secretInformer
+}
+
+// secretInformer caches a single secret until it is cancelled
+type secretInformer struct {
+	informer cache.SharedIndexInformer
+	lister   corelisters.SecretLister
+	cancel   context.CancelFunc
+}
+
+var _ corelisters.SecretLister = &tlsSecrets{}
+
+func newTLSSecrets(client clientset.Interface, stop <-chan struct{}) *tlsSecrets {
+	return &tlsSecrets{
+		client:    client,
+		stop:      stop,
+		queue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "TLSSecrets"),
+		services:  map[types.NamespacedName]map[types.NamespacedName]*corev1.Service{},
+		informers: map[types.NamespacedName]*secretInformer{},
+	}
+}
+
+// startInformer starts the informer of the secret, it runs until it is
+// cancelled or the provider is stopped
+func (t *tlsSecrets) startInformer(key types.NamespacedName) *secretInformer {
+	factory := informers.NewSharedInformerFactoryWithOptions(t.client, 0,
+		informers.WithNamespace(key.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", key.Name).String()
+		}))
+	informer := factory.Core().V1().Secrets()
+	s := &secretInformer{
+		informer: informer.Informer(),
+		lister:   informer.Lister(),
+	}
+	s.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: t.secretUpdated,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go func() {
+		select {
+		case <-t.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	klog.Infof("starting informer for TLS secret %s", key)
+	factory.Start(ctx.Done())
+	return s
+}
+
+// secretUpdated queues a secret used by a service if its data changed
+func (t *tlsSecrets) secretUpdated(oldObj, newObj interface{}) {
+	oldSecret, ok := oldObj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+	newSecret, ok := newObj.(*corev1.Secret)
+	if !ok || reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+		return
+	}
+	key := types.NamespacedName{Namespace: newSecret.Namespace, Name: newSecret.Name}
+	if len(t.servicesUsing(key)) > 0 {
+		t.queue.Add(key)
+	}
+}
+
+// track records the secret referenced by the service, a service without ports
+// is removed. The informer of a secret is started and synced on its first
+// reference and stopped when the last service referencing it is removed.
+func (t *tlsSecrets) track(service *corev1.Service) error {
+	if t == nil {
+		return nil
+	}
+	serviceKey := namespacedNameFromService(service)
+	name := strings.TrimSpace(service.Annotations[TLSSecretAnnotation])
+	if len(service.Spec.Ports) == 0 {
+		name = ""
+	}
+
+	t.lock.Lock()
+	for secretKey, services := range t.services {
+		delete(services, serviceKey)
+		if len(services) == 0 {
+			delete(t.services, secretKey)
+		}
+	}
+	var informer *secretInformer
+	if name != "" {
+		secretKey := types.NamespacedName{Namespace: service.Namespace, Name: name}
+		if t.services[secretKey] == nil {
+			t.services[secretKey] = map[types.NamespacedName]*corev1.Service{}
+		}
+		t.services[secretKey][serviceKey] = service.DeepCopy()
+		if t.informers[secretKey] == nil {
+			t.informers[secretKey] = t.startInformer(secretKey)
+		}
+		informer = t.informers[secretKey]
+	}
+	for secretKey, secretInformer := range t.informers {
+		if t.services[secretKey] == nil {
+			klog.Infof("stopping informer for TLS secret %s", secretKey)
+			secretInformer.cancel()
+			delete(t.informers, secretKey)
+		}
+	}
+	t.lock.Unlock()
+
+	if informer == nil {
+		return nil
+	}
+	if !cache.WaitForNamedCacheSync("TLS secret", t.stop, informer.informer.HasSynced) {
+		return errors.New("TLS secret informer not synced")
+	}
+	return nil
+}
+
+// List returns the cached secrets matching the selector
+func (t *tlsSecrets) List(selector labels.Selector) ([]*corev1.Secret, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var secrets []*corev1.Secret
+	for _, informer := range t.informers {
+		list, err := informer.lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, list...)
+	}
+	return secrets, nil
+}
+
+// Secrets returns a lister for the cached secrets of the namespace
+func (t *tlsSecrets) Secrets(namespace string) corelisters.SecretNamespaceLister {
+	return &tlsSecretsNamespace{secrets: t, namespace: namespace}
+}
+
+// tlsSecretsNamespace lists the cached secrets of a namespace
+type tlsSecretsNamespace struct {
+	secrets   *tlsSecrets
+	namespace string
+}
+
+// List returns the cached secrets of the namespace matching the selector
+func (n *tlsSecretsNamespace) List(selector labels.Selector) ([]*corev1.Secret, error) {
+	list, err := n.secrets.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	var secrets []*corev1.Secret
+	for _, secret := range list {
+		if secret.Namespace == n.namespace {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+// Get returns the secret if it is referenced by a service and cached
+func (n *tlsSecretsNamespace) Get(name string) (*corev1.Secret, error) {
+	n.secrets.lock.Lock()
+	informer := n.secrets.informers[types.NamespacedName{Namespace: n.namespace, Name: name}]
+	n.secrets.lock.Unlock()
+	if informer == nil {
+		return nil, apierrors.NewNotFound(corev1.Resource("secret"), name)
+	}
+	return informer.lister.Secrets(n.namespace).Get(name)
+}
+
+// servicesUsing returns the services referencing the secret
+func (t *tlsSecrets) servicesUsing(key types.NamespacedName) []*corev1.Service {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var services []*corev1.Service
+	for _, service := range t.services[key] {
+		services = append(services, service)
+	}
+	return services
+}
+
+// watchTLSSecrets prepares the informers for the TLS secrets and runs the
+// worker updating the certificates of HTTPS virtual servers whenever their
+// secret changes.
+func (p *lbProvider) watchTLSSecrets(clusterName string, client clientset.Interface, stop <-chan struct{}) {
+	p.tlsSecrets = newTLSSecrets(client, stop)
+	p.secrets = p.tlsSecrets
+	go func() {
+		defer utilruntime.HandleCrash()
+		defer p.tlsSecrets.queue.ShutDown()
+
+		go wait.Until(func() {
+			for p.processNextTLSSecret(clusterName) {
+			}
+		}, time.Second, stop)
+		<-stop
+	}()
+}
+
+// processNextTLSSecret updates the certificates of all load balancers using
+// the next queued secret, it is queued again if one of the updates failed
+func (p *lbProvider) processNextTLSSecret(clusterName string) bool {
+	obj, shutdown := p.tlsSecrets.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer p.tlsSecrets.queue.Done(obj)
+
+	key := obj.(types.NamespacedName)
+	failed := false
+	for _, service := range p.tlsSecrets.servicesUsing(key) {
+		klog.Infof("secret %s changed, updating certificate of service %s", key, service.Name)
+		if err := p.updateCertificates(clusterName, service); err != nil {
+			klog.Warningf("updating certificate of service %s/%s failed: %s", service.Namespace, service.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		p.tlsSecrets.queue.AddRateLimited(key)
+		return true
+	}
+	p.tlsSecrets.queue.Forget(obj)
+	return true
+}
+
+func (p *lbProvider) updateCertificates(clusterName string, service *corev1.Service) error {
+	key := namespacedNameFromService(service).String()
+	p.keyLock.Lock(key)
+	defer p.keyLock.Unlock(key)
+
+	state := newState(p.lbService, p.secrets, p.recorder, clusterName, service, nil)
+	return state.UpdateCertificates()
+}
@@ -0,0 +1,217 @@
+/*
+ Copyright 2024 The Kubernetes Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
+)
+
+func newTLSSecret(t *testing.T, namespace, name, commonName string) *corev1.Secret {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+// virtualServersByPort returns the application profile and bound certificate of the virtual server of each port
+func virtualServersByPort(broker *fakeBroker) map[string][2]string {
+	result := map[string][2]string{}
+	for _, server := range broker.servers {
+		result[getTag(server.Tags, ScopePort)] = [2]string{*server.ApplicationProfilePath, certificatePath(&server)}
+	}
+	return result
+}
+
+func TestHTTPSVirtualServers(t *testing.T) {
+	provider, broker := newFakeLBProvider(t)
+	ctx := context.Background()
+	secret := newTLSSecret(t, "default", "web-tls", "web.example.com")
+	client := fake.NewSimpleClientset(secret)
+	stop := make(chan struct{})
+	defer close(stop)
+	provider.tlsSecrets = newTLSSecrets(client, stop)
+	provider.secrets = provider.tlsSecrets
+
+	// services without TLS secret do not start the informer
+	nodes := []*corev1.Node{newTestNode("node1", "10.0.0.1")}
+	plain := newTestService(corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30081})
+	plain.Name = "plain"
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, plain, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if actions := client.Actions(); len(actions) != 0 {
+		t.Errorf("expected no secrets to be read, got %v", actions)
+	}
+	if err := provider.EnsureLoadBalancerDeleted(ctx, testClusterName, plain); err != nil {
+		t.Fatalf("EnsureLoadBalancerDeleted failed: %v", err)
+	}
+
+	service := newTestService(
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 443, NodePort: 30443},
+		corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 8080, NodePort: 30880},
+	)
+	service.Annotations = map[string]string{
+		HTTPPortsAnnotation:  "80",
+		HTTPSPortsAnnotation: "443",
+		TLSSecretAnnotation:  "web-tls",
+	}
+	tcpProfile := "/infra/lb-app-profiles/default-tcp-lb-app-profile"
+
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if len(broker.certificates) != 1 {
+		t.Fatalf("expected 1 certificate, got %d", len(broker.certificates))
+	}
+	listed := false
+	for _, action := range client.Actions() {
+		list, ok := action.(k8stesting.ListAction)
+		if !ok {
+			continue
+		}
+		listed = true
+		if list.GetNamespace() != "default" || list.GetListRestrictions().Fields.String() != "metadata.name=web-tls" {
+			t.Errorf("expected only the secret web-tls to be listed, got %s %s", list.GetNamespace(), list.GetListRestrictions().Fields)
+		}
+	}
+	if !listed {
+		t.Error("expected the secret web-tls to be listed")
+	}
+	var certificate model.TlsCertificate
+	for _, c := range broker.certificates {
+		certificate = c
+	}
+	servers := virtualServersByPort(broker)
+	expected := map[string][2]string{
+		"TCP/80":   {HTTPAppProfilePath, ""},
+		"TCP/443":  {HTTPAppProfilePath, *certificate.Path},
+		"TCP/8080": {tcpProfile, ""},
+	}
+	for port, exp := range expected {
+		if servers[port] != exp {
+			t.Errorf("expected virtual server %v for %s, got %v", exp, port, servers[port])
+		}
+	}
+
+	rotated := newTLSSecret(t, "default", "web-tls", "web.example.com")
+	if _, err := client.CoreV1().Secrets("default").Update(ctx, rotated, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		return provider.tlsSecrets.queue.Len() == 1, nil
+	})
+	if err != nil {
+		t.Fatalf("expected the rotated secret to be queued: %v", err)
+	}
+	provider.processNextTLSSecret(testClusterName)
+	if len(broker.certificates) != 1 {
+		t.Fatalf("expected the old certificate to be deleted, got %d", len(broker.certificates))
+	}
+	for _, c := range broker.certificates {
+		if *c.Id == *certificate.Id || virtualServersByPort(broker)["TCP/443"][1] != *c.Path {
+			t.Errorf("expected the virtual server to be bound to the rotated certificate, got %v", virtualServersByPort(broker))
+		}
+	}
+
+	service.Annotations = map[string]string{HTTPSPortsAnnotation: "443"}
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err == nil {
+		t.Error("expected missing TLS secret annotation to fail")
+	}
+
+	service.Annotations = nil
+	if _, err := provider.EnsureLoadBalancer(ctx, testClusterName, service, nodes); err != nil {
+		t.Fatalf("EnsureLoadBalancer failed: %v", err)
+	}
+	if services := provider.tlsSecrets.servicesUsing(types.NamespacedName{Namespace: "default", Name: "web-tls"}); len(services) != 0 {
+		t.Errorf("expected the service not to use the secret anymore, got %d", len(services))
+	}
+	if len(provider.tlsSecrets.informers) != 0 {
+		t.Errorf("expected the informer of the secret to be stopped, got %d informers", len(provider.tlsSecrets.informers))
+	}
+	for port, server := range virtualServersByPort(broker) {
+		if server != [2]string{tcpProfile, ""} {
+			t.Errorf("expected L4 virtual server for %s, got %v", port, server)
+		}
+	}
+	if len(broker.certificates) != 0 {
+		t.Errorf("expected certificates to be deleted, got %d", len(broker.certificates))
+	}
+}
+
+func TestAppProtocolFromService(t *testing.T) {
+	service := newTestService()
+	service.Annotations = map[string]string{HTTPPortsAnnotation: "80, 8080", HTTPSPortsAnnotation: "443,8080"}
+	tests := []struct {
+		port     corev1.ServicePort
+		expected string
+		err      bool
+	}{
+		{port: corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 80}, expected: appProtocolHTTP},
+		{port: corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 443}, expected: appProtocolHTTPS},
+		{port: corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 22}},
+		{port: corev1.ServicePort{Protocol: corev1.ProtocolTCP, Port: 8080}, err: true},
+		{port: corev1.ServicePort{Protocol: corev1.ProtocolUDP, Port: 443}, err: true},
+	}
+	for _, test := range tests {
+		appProtocol, err := appProtocolFromService(service, test.port)
+		if (err != nil) != test.err || appProtocol != test.expected && !test.err {
+			t.Errorf("port %s/%d: expected %q (error %t), got %q (%v)", test.port.Protocol, test.port.Port, test.expected, test.err, appProtocol, err)
+		}
+	}
+}
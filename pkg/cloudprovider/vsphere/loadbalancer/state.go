@@ -19,11 +19,14 @@ package loadbalancer
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	klog "k8s.io/klog/v2"
 
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
@@ -33,25 +36,47 @@ import (
 
 type state struct {
 	*lbService
-	clusterName    string
-	objectName     types.NamespacedName
-	service        *corev1.Service
-	nodes          []*corev1.Node
-	servers        []*model.LBVirtualServer
-	pools          []*model.LBPool
-	tcpMonitors    []*model.LBTcpMonitorProfile
-	ipAddressAlloc *model.IpAddressAllocation
-	ipAddress      *string
-	class          *loadBalancerClass
-}
-
-func newState(lbService *lbService, clusterName string, service *corev1.Service, nodes []*corev1.Node) *state {
+	clusterName          string
+	objectName           types.NamespacedName
+	service              *corev1.Service
+	nodes                []*corev1.Node
+	servers              []*model.LBVirtualServer
+	pools                []*model.LBPool
+	tcpMonitors          []*model.LBTcpMonitorProfile
+	httpMonitors         []*model.LBHttpMonitorProfile
+	certificates         []*model.TlsCertificate
+	certificate          *model.TlsCertificate
+	persistenceProfiles  []*model.LBSourceIpPersistenceProfile
+	persistenceProfile   *model.LBSourceIpPersistenceProfile
+	groups               []*model.Group
+	group                *model.Group
+	ipAddressAllocs      map[corev1.IPFamily]*model.IpAddressAllocation
+	ipAddresses          map[corev1.IPFamily]*string
+	staleIPAddressAllocs map[corev1.IPFamily][]*model.IpAddressAllocation
+	requestedIPAddresses map[corev1.IPFamily]string
+	families             []corev1.IPFamily
+	class                *loadBalancerClass
+	secrets              corelisters.SecretLister
+	recorder             record.EventRecorder
+	deletedOrphans       []string
+	lbServicePath        string
+	// resync only corrects drift of the existing objects, see Resync
+	resync bool
+}
+
+func newState(lbService *lbService, secrets corelisters.SecretLister, recorder record.EventRecorder, clusterName string,
+	service *corev1.Service, nodes []*corev1.Node) *state {
 	return &state{
-		lbService:   lbService,
-		clusterName: clusterName,
-		service:     service,
-		nodes:       nodes,
-		objectName:  namespacedNameFromService(service),
+		lbService:            lbService,
+		secrets:              secrets,
+		recorder:             recorder,
+		clusterName:          clusterName,
+		service:              service,
+		nodes:                nodes,
+		objectName:           namespacedNameFromService(service),
+		ipAddressAllocs:      map[corev1.IPFamily]*model.IpAddressAllocation{},
+		ipAddresses:          map[corev1.IPFamily]*string{},
+		staleIPAddressAllocs: map[corev1.IPFamily][]*model.IpAddressAllocation{},
 	}
 }
 
@@ -60,13 +85,31 @@ func (s *state) CtxInfof(format string, args ...interface{}) {
 	klog.V(2).Infof("%s: %s", s.objectName, fmt.Sprintf(format, args...))
 }
 
+// eventf records a normal event at the service
+func (s *state) eventf(reason, format string, args ...interface{}) {
+	if s.recorder != nil {
+		s.recorder.Eventf(s.service, corev1.EventTypeNormal, reason, format, args...)
+	}
+}
+
+// warningf records a warning event at the service and returns it as error
+// with the reason for the service condition
+func (s *state) warningf(reason, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	if s.recorder != nil {
+		s.recorder.Event(s.service, corev1.EventTypeWarning, reason, message)
+	}
+	return withReason(reason, errors.New(message))
+}
+
+// orphanDeleted remembers a deleted object for the orphans deleted event
+func (s *state) orphanDeleted(kind, id string) {
+	s.deletedOrphans = append(s.deletedOrphans, kind+" "+id)
+}
+
 // Process processes a load balancer and ensures that all needed objects are existing
 func (s *state) Process(class *loadBalancerClass) error {
 	var err error
-	s.ipAddressAlloc, s.ipAddress, err = s.access.FindExternalIPAddressForObject(class.ipPool.Identifier, s.clusterName, s.objectName)
-	if err != nil {
-		return err
-	}
 	s.servers, err = s.access.FindVirtualServers(s.clusterName, s.objectName)
 	if err != nil {
 		return err
@@ -79,90 +122,468 @@ func (s *state) Process(class *loadBalancerClass) error {
 	if err != nil {
 		return err
 	}
+	s.httpMonitors, err = s.access.FindHTTPMonitorProfiles(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	s.certificates, err = s.access.FindCertificates(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	s.persistenceProfiles, err = s.access.FindSourceIPPersistenceProfiles(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	s.groups, err = s.access.FindSourceRangesGroups(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
 	if len(s.servers) > 0 {
-		className := getTag(s.servers[0].Tags, ScopeLBClass)
-		ipPoolID := getTag(s.servers[0].Tags, ScopeIPPoolID)
-		if class.className != className || class.ipPool.Identifier != ipPoolID {
-			classConfig := &config.LoadBalancerClassConfig{
-				IPPoolID: ipPoolID,
-			}
-			class, err = newLBClass(className, classConfig, class, nil)
-			if err != nil {
-				return err
-			}
+		class, err = s.classOfVirtualServers(class)
+		if err != nil {
+			return withReason(ReasonInvalidLoadBalancerClass, err)
 		}
 	}
 	s.class = class
-
-	for _, servicePort := range s.service.Spec.Ports {
-		mapping := NewMapping(servicePort)
-
-		monitor, err := s.getTCPMonitor(mapping)
+	if len(s.service.Spec.Ports) > 0 {
+		s.families, err = s.ipFamilies()
 		if err != nil {
-			return err
+			return withReason(ReasonInvalidLoadBalancerClass, err)
 		}
-		pool, err := s.getPool(mapping, monitor)
+		s.requestedIPAddresses, err = s.requestedIPs()
 		if err != nil {
 			return err
 		}
-		_, err = s.getVirtualServer(mapping, pool.Path)
-		if err != nil {
-			return err
+	}
+	err = s.findExternalIPAddresses()
+	if err != nil {
+		return err
+	}
+
+	for _, family := range s.families {
+		for _, servicePort := range s.service.Spec.Ports {
+			mapping, err := s.newMapping(servicePort, family)
+			if err != nil {
+				return err
+			}
+
+			monitorPath, err := s.getMonitor(mapping)
+			if err != nil {
+				return err
+			}
+			pool, err := s.getPool(mapping, monitorPath)
+			if err != nil {
+				return err
+			}
+			_, err = s.getVirtualServer(mapping, pool.Path)
+			if err != nil {
+				return err
+			}
 		}
 	}
-	validPoolPaths, err := s.deleteOrphanVirtualServers()
+	validPoolPaths, validReferencedPaths, err := s.deleteOrphanVirtualServers()
 	if err != nil {
 		return err
 	}
 	s.CtxInfof("validPoolPaths: %v", validPoolPaths.List())
-	validTCPMonitorPaths, err := s.deleteOrphanPools(validPoolPaths)
+	validMonitorPaths, err := s.deleteOrphanPools(validPoolPaths)
 	if err != nil {
 		return err
 	}
-	s.CtxInfof("validTCPMonitorPaths: %v", validTCPMonitorPaths.List())
-	err = s.deleteOrphanTCPMonitors(validTCPMonitorPaths)
+	s.CtxInfof("validMonitorPaths: %v", validMonitorPaths.List())
+	err = s.deleteOrphanTCPMonitors(validMonitorPaths)
 	if err != nil {
 		return err
 	}
+	err = s.deleteOrphanHTTPMonitors(validMonitorPaths)
+	if err != nil {
+		return err
+	}
+	err = s.deleteOrphanCertificates(validReferencedPaths)
+	if err != nil {
+		return err
+	}
+	err = s.deleteOrphanPersistenceProfiles(validReferencedPaths)
+	if err != nil {
+		return err
+	}
+	err = s.deleteOrphanGroups(validReferencedPaths)
+	if err != nil {
+		return err
+	}
+	if len(s.deletedOrphans) > 0 {
+		s.eventf(ReasonOrphansDeleted, "deleted %s", strings.Join(s.deletedOrphans, ", "))
+	}
+	if len(s.service.Spec.Ports) > 0 {
+		return s.releaseUnusedIPAddresses()
+	}
 	return nil
 }
 
-func (s *state) deleteOrphanVirtualServers() (sets.String, error) {
+// Resync corrects drift of the existing objects of a load balancer. The pool
+// members are kept, as they are selected from the nodes by the service
+// controller. Missing objects are not created, because the status of the
+// service would change, they are left to the next reconciliation by the
+// service controller.
+func (s *state) Resync(class *loadBalancerClass) error {
+	s.resync = true
+	return s.Process(class)
+}
+
+// missingObject returns the error for an object missing on resync
+func (s *state) missingObject(kind string, mapping Mapping) error {
+	return errors.Errorf("%s for %s is missing, it is created on the next update of the service", kind, mapping)
+}
+
+// requestedIPs returns the requested virtual IP addresses of the service,
+// which must belong to the IP families of its virtual servers
+func (s *state) requestedIPs() (map[corev1.IPFamily]string, error) {
+	requested, err := requestedIPAddresses(s.service)
+	if err != nil {
+		return nil, s.warningf(ReasonLoadBalancerIPInvalid, "%s", err)
+	}
+	for _, family := range allIPFamilies {
+		if ipAddress, ok := requested[family]; ok && !containsIPFamily(s.families, family) {
+			return nil, s.warningf(ReasonLoadBalancerIPInvalid, "requested IP address %s does not match the IP families %v of the load balancer",
+				ipAddress, s.families)
+		}
+	}
+	return requested, nil
+}
+
+// classOfVirtualServers returns the class of the existing virtual servers,
+// which keep their IP pools even if the class configuration has changed
+func (s *state) classOfVirtualServers(class *loadBalancerClass) (*loadBalancerClass, error) {
+	className := getTag(s.servers[0].Tags, ScopeLBClass)
+	classConfig := &config.LoadBalancerClassConfig{}
+	for _, server := range s.servers {
+		if ipFamilyFromTags(server.Tags) == corev1.IPv6Protocol {
+			classConfig.IPv6PoolID = getTag(server.Tags, ScopeIPPoolID)
+		} else {
+			classConfig.IPPoolID = getTag(server.Tags, ScopeIPPoolID)
+		}
+	}
+	if class.className == className &&
+		(classConfig.IPPoolID == "" || classConfig.IPPoolID == class.ipPool.Identifier) &&
+		(classConfig.IPv6PoolID == "" || classConfig.IPv6PoolID == class.ipv6Pool.Identifier) {
+		return class, nil
+	}
+	return newLBClass(className, classConfig, class, nil)
+}
+
+// ipFamilies returns the IP families of the virtual servers of the service.
+// The family without IP pool in the class is skipped for services preferring
+// dual-stack.
+func (s *state) ipFamilies() ([]corev1.IPFamily, error) {
+	var families []corev1.IPFamily
+	for _, family := range ipFamiliesFromService(s.service) {
+		if s.class.IPPool(family).Identifier == "" {
+			policy := s.service.Spec.IPFamilyPolicy
+			if len(s.service.Spec.IPFamilies) > 1 && policy != nil && *policy == corev1.IPFamilyPolicyPreferDualStack {
+				s.CtxInfof("skipping %s virtual servers, load balancer class %s has no %s pool", family, s.class.className, family)
+				continue
+			}
+			return nil, fmt.Errorf("load balancer class %s has no %s pool", s.class.className, family)
+		}
+		families = append(families, family)
+	}
+	return families, nil
+}
+
+// newMapping returns the mapping of a service port and IP family including
+// the application protocol annotated at the service
+func (s *state) newMapping(servicePort corev1.ServicePort, family corev1.IPFamily) (Mapping, error) {
+	mapping := NewMapping(servicePort)
+	mapping.IPFamily = family
+	appProtocol, err := appProtocolFromService(s.service, servicePort)
+	mapping.AppProtocol = appProtocol
+	return mapping, err
+}
+
+// deleteOrphanVirtualServers deletes the virtual servers of removed service
+// ports. It returns the pool paths and the paths of the certificates and
+// persistence profiles referenced by the remaining virtual servers.
+func (s *state) deleteOrphanVirtualServers() (sets.String, sets.String, error) {
 	validPoolPaths := sets.String{}
+	validReferencedPaths := sets.String{}
 	for _, server := range s.servers {
 		found := false
-		for _, servicePort := range s.service.Spec.Ports {
-			mapping := NewMapping(servicePort)
-			if mapping.MatchVirtualServer(server) {
-				if server.PoolPath != nil {
-					validPoolPaths.Insert(*server.PoolPath)
+		for _, family := range s.families {
+			for _, servicePort := range s.service.Spec.Ports {
+				mapping := NewMapping(servicePort)
+				mapping.IPFamily = family
+				if mapping.MatchVirtualServer(server) {
+					if server.PoolPath != nil {
+						validPoolPaths.Insert(*server.PoolPath)
+					}
+					validReferencedPaths.Insert(referencedPaths(server)...)
+					found = true
+					break
 				}
-				found = true
-				break
 			}
 		}
 		if !found {
 			err := s.deleteVirtualServer(server)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return validPoolPaths, validReferencedPaths, nil
+}
+
+func (s *state) deleteOrphanCertificates(validReferencedPaths sets.String) error {
+	for _, certificate := range s.certificates {
+		if certificate.Path != nil && validReferencedPaths.Has(*certificate.Path) {
+			continue
+		}
+		s.CtxInfof("deleting certificate %s", *certificate.Id)
+		err := s.access.DeleteCertificate(*certificate.Id)
+		if err != nil {
+			return err
+		}
+		s.orphanDeleted("certificate", *certificate.Id)
+	}
+	return nil
+}
+
+// UpdateCertificates imports the current certificate of the TLS secret and
+// binds the HTTPS virtual servers to it. The pools are left untouched.
+func (s *state) UpdateCertificates() error {
+	var err error
+	s.servers, err = s.access.FindVirtualServers(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	s.certificates, err = s.access.FindCertificates(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	s.persistenceProfiles, err = s.access.FindSourceIPPersistenceProfiles(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	s.groups, err = s.access.FindSourceRangesGroups(s.clusterName, s.objectName)
+	if err != nil {
+		return err
+	}
+	for _, family := range ipFamiliesFromService(s.service) {
+		for _, servicePort := range s.service.Spec.Ports {
+			mapping, err := s.newMapping(servicePort, family)
+			if err != nil {
+				return err
+			}
+			if mapping.AppProtocol != appProtocolHTTPS {
+				continue
+			}
+			for _, server := range s.servers {
+				if mapping.MatchVirtualServer(server) {
+					spec, err := s.virtualServerSpec(mapping, server.PoolPath)
+					if err != nil {
+						return err
+					}
+					err = s.updateVirtualServer(server, mapping, spec)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	validReferencedPaths := sets.String{}
+	for _, server := range s.servers {
+		validReferencedPaths.Insert(referencedPaths(server)...)
+	}
+	return s.deleteOrphanCertificates(validReferencedPaths)
+}
+
+func (s *state) deleteOrphanPersistenceProfiles(validReferencedPaths sets.String) error {
+	for _, profile := range s.persistenceProfiles {
+		if profile.Path != nil && validReferencedPaths.Has(*profile.Path) {
+			continue
+		}
+		s.CtxInfof("deleting LbSourceIpPersistenceProfile %s", *profile.Id)
+		err := s.access.DeleteSourceIPPersistenceProfile(*profile.Id)
+		if err != nil {
+			return err
+		}
+		s.orphanDeleted("persistence profile", *profile.Id)
+	}
+	return nil
+}
+
+func (s *state) deleteOrphanGroups(validReferencedPaths sets.String) error {
+	for _, group := range s.groups {
+		if group.Path != nil && validReferencedPaths.Has(*group.Path) {
+			continue
+		}
+		s.CtxInfof("deleting Group %s", *group.Id)
+		err := s.access.DeleteSourceRangesGroup(*group.Id)
+		if err != nil {
+			return err
+		}
+		s.orphanDeleted("group", *group.Id)
+	}
+	return nil
+}
+
+// accessListControl returns the access list allowing the source ranges of the
+// service, nil if access to the service is not restricted
+func (s *state) accessListControl(mapping Mapping) (*model.LBAccessListControl, error) {
+	ranges, err := sourceRangesFromService(s.service)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	if s.group == nil && len(s.groups) > 0 {
+		group := s.groups[0]
+		if !reflect.DeepEqual(groupIPAddresses(group), ranges) {
+			s.CtxInfof("updating Group %s, source ranges=%v", *group.Id, ranges)
+			err := s.access.UpdateSourceRangesGroup(group, ranges)
 			if err != nil {
 				return nil, err
 			}
 		}
+		s.group = group
 	}
-	return validPoolPaths, nil
+	if s.group == nil {
+		if s.resync {
+			return nil, s.missingObject("source ranges group", mapping)
+		}
+		group, err := s.access.CreateSourceRangesGroup(s.clusterName, s.objectName, ranges)
+		if err != nil {
+			return nil, err
+		}
+		s.CtxInfof("created Group %s, source ranges=%v", *group.Id, ranges)
+		s.groups = append(s.groups, group)
+		s.group = group
+	}
+	return &model.LBAccessListControl{
+		Action:    strptr(model.LBAccessListControl_ACTION_ALLOW),
+		Enabled:   boolptr(true),
+		GroupPath: s.group.Path,
+	}, nil
+}
+
+// persistenceProfilePath returns the path of the source IP persistence profile
+// of services with client IP session affinity, nil for other services
+func (s *state) persistenceProfilePath(mapping Mapping) (*string, error) {
+	if s.service.Spec.SessionAffinity != corev1.ServiceAffinityClientIP {
+		return nil, nil
+	}
+	timeout := sessionAffinityTimeout(s.service)
+	if s.persistenceProfile == nil && len(s.persistenceProfiles) > 0 {
+		profile := s.persistenceProfiles[0]
+		if profile.Timeout == nil || *profile.Timeout != timeout {
+			profile.Timeout = int64ptr(timeout)
+			s.CtxInfof("updating LbSourceIpPersistenceProfile %s, timeout=%d", *profile.Id, timeout)
+			err := s.access.UpdateSourceIPPersistenceProfile(profile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		s.persistenceProfile = profile
+	}
+	if s.persistenceProfile == nil {
+		if s.resync {
+			return nil, s.missingObject("persistence profile", mapping)
+		}
+		profile, err := s.access.CreateSourceIPPersistenceProfile(s.clusterName, s.objectName, timeout)
+		if err != nil {
+			return nil, err
+		}
+		s.CtxInfof("created LbSourceIpPersistenceProfile %s, timeout=%d", *profile.Id, timeout)
+		s.persistenceProfiles = append(s.persistenceProfiles, profile)
+		s.persistenceProfile = profile
+	}
+	return s.persistenceProfile.Path, nil
+}
+
+// getCertificate returns the imported certificate of the TLS secret annotated
+// at the service. The certificate is imported again if the secret has changed.
+func (s *state) getCertificate(mapping Mapping) (*model.TlsCertificate, error) {
+	if s.certificate != nil {
+		return s.certificate, nil
+	}
+	secretName := strings.TrimSpace(s.service.GetAnnotations()[TLSSecretAnnotation])
+	if secretName == "" {
+		return nil, fmt.Errorf("annotation %s is required for HTTPS ports", TLSSecretAnnotation)
+	}
+	if s.secrets == nil {
+		return nil, fmt.Errorf("TLS secrets are not available before the load balancer is initialized")
+	}
+	secret, err := s.secrets.Secrets(s.service.Namespace).Get(secretName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting secret %s of type %s failed", secretName, corev1.SecretTypeTLS)
+	}
+	certificatePEM, keyPEM, checksum, err := tlsSecretData(secret)
+	if err != nil {
+		return nil, err
+	}
+	for _, certificate := range s.certificates {
+		if getTag(certificate.Tags, ScopeCertificate) == checksum {
+			s.certificate = certificate
+			return certificate, nil
+		}
+	}
+	if s.resync {
+		return nil, s.missingObject("certificate", mapping)
+	}
+	certificate, err := s.access.CreateCertificate(s.clusterName, s.objectName, checksum, certificatePEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	s.CtxInfof("imported certificate %s of secret %s", *certificate.Id, secretName)
+	s.certificates = append(s.certificates, certificate)
+	s.certificate = certificate
+	return certificate, nil
+}
+
+// clientSSLBinding returns the client SSL profile binding of the virtual server
+// of a mapping, nil if TLS is not terminated by the virtual server
+func (s *state) clientSSLBinding(mapping Mapping) (*model.LBClientSslProfileBinding, error) {
+	if mapping.AppProtocol != appProtocolHTTPS {
+		return nil, nil
+	}
+	certificate, err := s.getCertificate(mapping)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LBClientSslProfileBinding{
+		SslProfilePath:         strptr(ClientSSLProfilePath),
+		DefaultCertificatePath: certificate.Path,
+	}, nil
+}
+
+// appProfilePath returns the application profile of the virtual server of a mapping
+func (s *state) appProfilePath(mapping Mapping) (string, error) {
+	if mapping.AppProtocol != "" {
+		return HTTPAppProfilePath, nil
+	}
+	path, err := s.access.GetAppProfilePath(s.class, mapping.Protocol)
+	if err != nil {
+		return "", withReason(ReasonAppProfileNotFound, errors.Wrapf(err, "Lookup of application profile failed for %s", mapping.Protocol))
+	}
+	return path, nil
 }
 
 func (s *state) deleteOrphanPools(validPoolPaths sets.String) (sets.String, error) {
-	validTCPMonitorPaths := sets.String{}
+	validMonitorPaths := sets.String{}
 	for _, pool := range s.pools {
 		found := false
-		for _, servicePort := range s.service.Spec.Ports {
-			mapping := NewMapping(servicePort)
-			if mapping.MatchPool(pool) && validPoolPaths.Has(*pool.Path) {
-				if len(pool.ActiveMonitorPaths) > 0 {
-					validTCPMonitorPaths.Insert(pool.ActiveMonitorPaths...)
+		for _, family := range s.families {
+			for _, servicePort := range s.service.Spec.Ports {
+				mapping := NewMapping(servicePort)
+				mapping.IPFamily = family
+				if mapping.MatchPool(pool) && validPoolPaths.Has(*pool.Path) {
+					if len(pool.ActiveMonitorPaths) > 0 {
+						validMonitorPaths.Insert(pool.ActiveMonitorPaths...)
+					}
+					found = true
+					break
 				}
-				found = true
-				break
 			}
 		}
 		if !found {
@@ -172,15 +593,15 @@ func (s *state) deleteOrphanPools(validPoolPaths sets.String) (sets.String, erro
 			}
 		}
 	}
-	return validTCPMonitorPaths, nil
+	return validMonitorPaths, nil
 }
 
-func (s *state) deleteOrphanTCPMonitors(validTCPMonitorPaths sets.String) error {
+func (s *state) deleteOrphanTCPMonitors(validMonitorPaths sets.String) error {
 	for _, monitor := range s.tcpMonitors {
 		found := false
 		for _, servicePort := range s.service.Spec.Ports {
 			mapping := NewMapping(servicePort)
-			if mapping.MatchTCPMonitor(monitor) && monitor.Path != nil && validTCPMonitorPaths.Has(*monitor.Path) {
+			if mapping.MatchTCPMonitor(monitor) && monitor.Path != nil && validMonitorPaths.Has(*monitor.Path) {
 				found = true
 				break
 			}
@@ -195,50 +616,240 @@ func (s *state) deleteOrphanTCPMonitors(validTCPMonitorPaths sets.String) error
 	return nil
 }
 
-func (s *state) allocateResources() (allocated bool, err error) {
-	if s.ipAddressAlloc == nil {
-		ipPoolID := s.class.ipPool.Identifier
-		s.ipAddressAlloc, s.ipAddress, err = s.access.AllocateExternalIPAddress(ipPoolID, s.clusterName, s.objectName)
+func (s *state) deleteOrphanHTTPMonitors(validMonitorPaths sets.String) error {
+	for _, monitor := range s.httpMonitors {
+		found := false
+		for _, servicePort := range s.service.Spec.Ports {
+			mapping := NewMapping(servicePort)
+			if mapping.MatchHTTPMonitor(monitor) && monitor.Path != nil && validMonitorPaths.Has(*monitor.Path) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err := s.deleteHTTPMonitor(monitor)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allIPFamilies are the IP families of the virtual IP addresses in the order they are released
+var allIPFamilies = []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}
+
+// findExternalIPAddresses finds the virtual IP addresses of the service in
+// the IP pools of the class. Allocations of other addresses than the requested
+// one are stale and released after the virtual servers have been updated. The
+// retain tag of the allocations follows the annotation of the service, on
+// deletion it is only ever added.
+func (s *state) findExternalIPAddresses() error {
+	retain := retainIPFromService(s.service)
+	for _, family := range allIPFamilies {
+		ipPoolID := s.class.IPPool(family).Identifier
+		if ipPoolID == "" {
+			continue
+		}
+		ipAddressAllocs, err := s.access.FindExternalIPAddressesForObject(ipPoolID, s.clusterName, s.objectName)
 		if err != nil {
-			return
+			return err
+		}
+		requested, isRequested := s.requestedIPAddresses[family]
+		for _, ipAddressAlloc := range ipAddressAllocs {
+			ipAddress, err := s.access.GetExternalIPAddress(ipPoolID, ipAddressAlloc)
+			if errors.Is(err, errIPAddressNotRealized) {
+				return withReason(ReasonIPAddressNotRealized, err)
+			}
+			if err != nil {
+				return err
+			}
+			if s.ipAddressAllocs[family] != nil || (isRequested && (ipAddress == nil || *ipAddress != requested)) {
+				s.staleIPAddressAllocs[family] = append(s.staleIPAddressAllocs[family], ipAddressAlloc)
+				continue
+			}
+			s.ipAddressAllocs[family] = ipAddressAlloc
+			s.ipAddresses[family] = ipAddress
+			retained := isRetained(ipAddressAlloc.Tags)
+			if retained != retain && (retain || len(s.service.Spec.Ports) > 0) {
+				s.CtxInfof("updating retention of IP address allocation %s to %t", *ipAddressAlloc.Id, retain)
+				err = s.access.UpdateExternalIPAddressRetention(ipPoolID, ipAddressAlloc, retain)
+				if err != nil {
+					return err
+				}
+			}
 		}
+	}
+	return nil
+}
+
+func (s *state) allocateResources(family corev1.IPFamily) (allocated bool, err error) {
+	if s.ipAddressAllocs[family] == nil {
+		if s.resync {
+			return false, errors.Errorf("IP address allocation of family %s is missing, it is created on the next update of the service", family)
+		}
+		ipPoolID := s.class.IPPool(family).Identifier
+		var requested *string
+		if ipAddress, ok := s.requestedIPAddresses[family]; ok {
+			err = s.checkRequestedIPAddress(ipPoolID, ipAddress)
+			if err != nil {
+				return false, err
+			}
+			requested = &ipAddress
+		}
+		ipAddressAlloc, ipAddress, err := s.access.AllocateExternalIPAddress(ipPoolID, s.clusterName, s.objectName, requested, retainIPFromService(s.service))
+		if errors.Is(err, errIPAddressNotRealized) {
+			return false, withReason(ReasonIPAddressNotRealized, err)
+		}
+		if err != nil {
+			return false, withReason(ReasonIPAllocationFailed, err)
+		}
+		s.ipAddressAllocs[family] = ipAddressAlloc
+		s.ipAddresses[family] = ipAddress
 		allocated = true
-		s.CtxInfof("allocated IP address %s from pool %s", *s.ipAddress, ipPoolID)
+		s.CtxInfof("allocated IP address %s from pool %s", *ipAddress, ipPoolID)
+		s.eventf(ReasonIPAddressAllocated, "allocated IP address %s from pool %s", *ipAddress, ipPoolID)
 	}
 	return
 }
 
-func (s *state) releaseResources() error {
-	if s.ipAddressAlloc != nil {
-		ipPoolID := s.class.ipPool.Identifier
-		err := s.access.ReleaseExternalIPAddress(ipPoolID, *s.ipAddressAlloc.Id)
+// checkRequestedIPAddress checks that a requested IP address belongs to the IP
+// pool and is not allocated by another service
+func (s *state) checkRequestedIPAddress(ipPoolID, ipAddress string) error {
+	contained, err := s.access.IPPoolContainsIPAddress(ipPoolID, ipAddress)
+	if err != nil {
+		return err
+	}
+	if !contained {
+		return s.warningf(ReasonLoadBalancerIPNotInPool, "requested IP address %s is not part of IP pool %s of load balancer class %s",
+			ipAddress, ipPoolID, s.class.className)
+	}
+	ipAddressAlloc, err := s.access.FindExternalIPAddressAllocation(ipPoolID, ipAddress)
+	if err != nil {
+		return err
+	}
+	if ipAddressAlloc != nil {
+		owner := getTag(ipAddressAlloc.Tags, ScopeService)
+		if owner == "" {
+			owner = "another user"
+		} else {
+			owner = fmt.Sprintf("service %s of cluster %s", owner, getTag(ipAddressAlloc.Tags, ScopeCluster))
+		}
+		return s.warningf(ReasonLoadBalancerIPConflict, "requested IP address %s is already allocated in IP pool %s by %s",
+			ipAddress, ipPoolID, owner)
+	}
+	return nil
+}
+
+// releaseResources releases the virtual IP address of the IP family and its
+// stale allocations
+func (s *state) releaseResources(family corev1.IPFamily) error {
+	if ipAddressAlloc := s.ipAddressAllocs[family]; ipAddressAlloc != nil {
+		ipPoolID := s.class.IPPool(family).Identifier
+		err := s.access.ReleaseExternalIPAddress(ipPoolID, *ipAddressAlloc.Id)
 		if err != nil {
 			return err
 		}
-		s.ipAddressAlloc = nil
-		s.ipAddress = nil
+		delete(s.ipAddressAllocs, family)
+		delete(s.ipAddresses, family)
 	}
-	return nil
+	return s.releaseStaleIPAddresses(family)
 }
 
-func (s *state) loggedReleaseResources() {
-	ipAddress := s.ipAddress
-	err := s.releaseResources()
+func (s *state) loggedReleaseResources(family corev1.IPFamily) {
+	ipAddress := s.ipAddresses[family]
+	err := s.releaseResources(family)
 	if err != nil {
-		s.CtxInfof("failed to release IP address %s to pool %s", *ipAddress, s.class.ipPool.Identifier)
+		s.CtxInfof("failed to release IP address %s to pool %s", *ipAddress, s.class.IPPool(family).Identifier)
 	}
 }
 
+// releaseStaleIPAddresses releases the allocations of the IP family not used
+// by the virtual servers anymore
+func (s *state) releaseStaleIPAddresses(family corev1.IPFamily) error {
+	ipPoolID := s.class.IPPool(family).Identifier
+	for len(s.staleIPAddressAllocs[family]) > 0 {
+		ipAddressAlloc := s.staleIPAddressAllocs[family][0]
+		s.CtxInfof("releasing stale IP address allocation %s from pool %s", *ipAddressAlloc.Id, ipPoolID)
+		err := s.access.ReleaseExternalIPAddress(ipPoolID, *ipAddressAlloc.Id)
+		if err != nil {
+			return err
+		}
+		s.staleIPAddressAllocs[family] = s.staleIPAddressAllocs[family][1:]
+	}
+	return nil
+}
+
+// releaseUnusedIPAddresses releases the virtual IP addresses of the IP
+// families the service has no virtual servers for anymore and the stale
+// allocations of the others
+func (s *state) releaseUnusedIPAddresses() error {
+	for _, family := range allIPFamilies {
+		var err error
+		if containsIPFamily(s.families, family) {
+			err = s.releaseStaleIPAddresses(family)
+		} else {
+			err = s.releaseResources(family)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Finish performs cleanup after Process
 func (s *state) Finish() (*corev1.LoadBalancerStatus, error) {
 	if len(s.service.Spec.Ports) == 0 {
-		err := s.releaseResources()
+		for _, family := range allIPFamilies {
+			if ipAddressAlloc := s.ipAddressAllocs[family]; ipAddressAlloc != nil && isRetained(ipAddressAlloc.Tags) {
+				s.CtxInfof("retaining IP address %s in pool %s", *s.ipAddresses[family], s.class.IPPool(family).Identifier)
+				err := s.releaseStaleIPAddresses(family)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			err := s.releaseResources(family)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+	var ipAddresses []*string
+	for _, family := range s.families {
+		ipAddresses = append(ipAddresses, s.ipAddresses[family])
+	}
+	return newLoadBalancerStatus(ipAddresses...), nil
+}
+
+// healthCheckNodePort returns the port kube-proxy reports the local endpoints
+// on for services with local external traffic policy, or 0 otherwise.
+func (s *state) healthCheckNodePort() int {
+	if s.service.Spec.ExternalTrafficPolicy != corev1.ServiceExternalTrafficPolicyLocal {
+		return 0
+	}
+	return int(s.service.Spec.HealthCheckNodePort)
+}
+
+// getMonitor returns the path of the active monitor of the pool. Services with
+// local external traffic policy are probed on their health check node port so
+// that only nodes with endpoints receive traffic, other TCP services on their
+// node port.
+func (s *state) getMonitor(mapping Mapping) (*string, error) {
+	if port := s.healthCheckNodePort(); port != 0 {
+		monitor, err := s.getHTTPMonitor(mapping, port)
 		if err != nil {
 			return nil, err
 		}
-		return nil, nil
+		return monitor.Path, nil
 	}
-	return newLoadBalancerStatus(s.ipAddress), nil
+	monitor, err := s.getTCPMonitor(mapping)
+	if err != nil || monitor == nil {
+		return nil, err
+	}
+	return monitor.Path, nil
 }
 
 func (s *state) getTCPMonitor(mapping Mapping) (*model.LBTcpMonitorProfile, error) {
@@ -258,6 +869,9 @@ func (s *state) getTCPMonitor(mapping Mapping) (*model.LBTcpMonitorProfile, erro
 }
 
 func (s *state) createTCPMonitor(mapping Mapping) (*model.LBTcpMonitorProfile, error) {
+	if s.resync {
+		return nil, s.missingObject("TCP monitor", mapping)
+	}
 	monitor, err := s.access.CreateTCPMonitorProfile(s.clusterName, s.objectName, mapping)
 	if err == nil {
 		s.CtxInfof("created LbTcpMonitor %s for %s", *monitor.Id, mapping)
@@ -267,23 +881,103 @@ func (s *state) createTCPMonitor(mapping Mapping) (*model.LBTcpMonitorProfile, e
 }
 
 func (s *state) updateTCPMonitor(monitor *model.LBTcpMonitorProfile, mapping Mapping) error {
-	if monitor.MonitorPort != nil && *monitor.MonitorPort == int64(mapping.NodePort) {
+	var d drift
+	if d.check("monitorPort", monitor.MonitorPort != nil && *monitor.MonitorPort == int64(mapping.NodePort)) {
+		monitor.MonitorPort = int64ptr(int64(mapping.NodePort))
+	}
+	if d.check("displayName", safeEquals(monitor.DisplayName, displayNameMapping(s.clusterName, s.objectName, mapping))) {
+		monitor.DisplayName = displayNameMapping(s.clusterName, s.objectName, mapping)
+	}
+	if len(d) == 0 {
 		return nil
 	}
-	monitor.MonitorPort = int64ptr(int64(mapping.NodePort))
-	s.CtxInfof("updating LbTcpMonitor %s for %s", *monitor.Id, mapping)
-	return s.access.UpdateTCPMonitorProfile(monitor)
+	s.CtxInfof("updating LbTcpMonitor %s for %s, changed %s", *monitor.Id, mapping, d)
+	err := s.access.UpdateTCPMonitorProfile(monitor)
+	if err != nil {
+		return err
+	}
+	d.record(kindTCPMonitor)
+	return nil
 }
 
 func (s *state) deleteTCPMonitor(monitor *model.LBTcpMonitorProfile) error {
 	s.CtxInfof("deleting LbTcpMonitor %s for %s", *monitor.Id, getTag(monitor.Tags, ScopePort))
-	return s.access.DeleteTCPMonitorProfile(*monitor.Id)
+	err := s.access.DeleteTCPMonitorProfile(*monitor.Id)
+	if err != nil {
+		return err
+	}
+	s.orphanDeleted("monitor", *monitor.Id)
+	return nil
+}
+
+func (s *state) getHTTPMonitor(mapping Mapping, healthCheckNodePort int) (*model.LBHttpMonitorProfile, error) {
+	for _, m := range s.httpMonitors {
+		if mapping.MatchHTTPMonitor(m) {
+			err := s.updateHTTPMonitor(m, mapping, healthCheckNodePort)
+			if err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+	}
+	return s.createHTTPMonitor(mapping, healthCheckNodePort)
+}
+
+func (s *state) createHTTPMonitor(mapping Mapping, healthCheckNodePort int) (*model.LBHttpMonitorProfile, error) {
+	if s.resync {
+		return nil, s.missingObject("HTTP monitor", mapping)
+	}
+	monitor, err := s.access.CreateHTTPMonitorProfile(s.clusterName, s.objectName, mapping, healthCheckNodePort)
+	if err == nil {
+		s.CtxInfof("created LbHttpMonitor %s for %s on port %d", *monitor.Id, mapping, healthCheckNodePort)
+		s.httpMonitors = append(s.httpMonitors, monitor)
+	}
+	return monitor, err
+}
+
+func (s *state) updateHTTPMonitor(monitor *model.LBHttpMonitorProfile, mapping Mapping, healthCheckNodePort int) error {
+	var d drift
+	if d.check("monitorPort", monitor.MonitorPort != nil && *monitor.MonitorPort == int64(healthCheckNodePort)) {
+		monitor.MonitorPort = int64ptr(int64(healthCheckNodePort))
+	}
+	if d.check("requestUrl", safeEquals(monitor.RequestUrl, strptr(HealthCheckPath))) {
+		monitor.RequestUrl = strptr(HealthCheckPath)
+	}
+	if d.check("requestMethod", safeEquals(monitor.RequestMethod, strptr(model.LBHttpMonitorProfile_REQUEST_METHOD_GET))) {
+		monitor.RequestMethod = strptr(model.LBHttpMonitorProfile_REQUEST_METHOD_GET)
+	}
+	if d.check("responseStatusCodes", reflect.DeepEqual(monitor.ResponseStatusCodes, []int64{200})) {
+		monitor.ResponseStatusCodes = []int64{200}
+	}
+	if d.check("displayName", safeEquals(monitor.DisplayName, displayNameMapping(s.clusterName, s.objectName, mapping))) {
+		monitor.DisplayName = displayNameMapping(s.clusterName, s.objectName, mapping)
+	}
+	if len(d) == 0 {
+		return nil
+	}
+	s.CtxInfof("updating LbHttpMonitor %s for %s on port %d, changed %s", *monitor.Id, mapping, healthCheckNodePort, d)
+	err := s.access.UpdateHTTPMonitorProfile(monitor)
+	if err != nil {
+		return err
+	}
+	d.record(kindHTTPMonitor)
+	return nil
+}
+
+func (s *state) deleteHTTPMonitor(monitor *model.LBHttpMonitorProfile) error {
+	s.CtxInfof("deleting LbHttpMonitor %s for %s", *monitor.Id, getTag(monitor.Tags, ScopePort))
+	err := s.access.DeleteHTTPMonitorProfile(*monitor.Id)
+	if err != nil {
+		return err
+	}
+	s.orphanDeleted("monitor", *monitor.Id)
+	return nil
 }
 
-func (s *state) getPool(mapping Mapping, monitor *model.LBTcpMonitorProfile) (*model.LBPool, error) {
+func (s *state) getPool(mapping Mapping, monitorPath *string) (*model.LBPool, error) {
 	var activeMonitorPaths []string
-	if monitor != nil {
-		activeMonitorPaths = []string{*monitor.Path}
+	if monitorPath != nil {
+		activeMonitorPaths = []string{*monitorPath}
 	}
 	for _, pool := range s.pools {
 		if mapping.MatchPool(pool) {
@@ -295,10 +989,21 @@ func (s *state) getPool(mapping Mapping, monitor *model.LBTcpMonitorProfile) (*m
 }
 
 func (s *state) createPool(mapping Mapping, activeMonitorIds []string) (*model.LBPool, error) {
-	members, _ := s.updatedPoolMembers(nil)
-	pool, err := s.access.CreatePool(s.clusterName, s.objectName, mapping, members, activeMonitorIds)
+	if s.resync {
+		return nil, s.missingObject("pool", mapping)
+	}
+	members, _, err := s.updatedPoolMembers(mapping.ipFamily(), nil)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := poolAlgorithmFromService(s.service)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := s.access.CreatePool(s.clusterName, s.objectName, mapping, members, activeMonitorIds, algorithm)
 	if err == nil {
 		s.CtxInfof("created LbPool %s for %s", *pool.Id, mapping)
+		s.eventf(ReasonPoolCreated, "created pool %s for %s with %d members", *pool.Id, mapping, len(pool.Members))
 		s.pools = append(s.pools, pool)
 	}
 	return pool, err
@@ -309,13 +1014,16 @@ func (s *state) UpdatePoolMembers() error {
 	if err != nil {
 		return err
 	}
-	for _, servicePort := range s.service.Spec.Ports {
-		mapping := NewMapping(servicePort)
-		for _, pool := range pools {
-			if mapping.MatchPool(pool) {
-				err = s.updatePool(pool, mapping, pool.ActiveMonitorPaths)
-				if err != nil {
-					return err
+	for _, family := range ipFamiliesFromService(s.service) {
+		for _, servicePort := range s.service.Spec.Ports {
+			mapping := NewMapping(servicePort)
+			mapping.IPFamily = family
+			for _, pool := range pools {
+				if mapping.MatchPool(pool) {
+					err = s.updatePool(pool, mapping, pool.ActiveMonitorPaths)
+					if err != nil {
+						return err
+					}
 				}
 			}
 		}
@@ -324,35 +1032,82 @@ func (s *state) UpdatePoolMembers() error {
 }
 
 func (s *state) updatePool(pool *model.LBPool, mapping Mapping, activeMonitorPaths []string) error {
-	newMembers, modified := s.updatedPoolMembers(pool.Members)
-	if modified || !reflect.DeepEqual(activeMonitorPaths, pool.ActiveMonitorPaths) {
+	newMembers, modified, err := s.updatedPoolMembers(mapping.ipFamily(), pool.Members)
+	if err != nil {
+		return err
+	}
+	algorithm, err := poolAlgorithmFromService(s.service)
+	if err != nil {
+		return err
+	}
+	snatTranslation, err := s.access.PoolSnatTranslation()
+	if err != nil {
+		return err
+	}
+	currentAlgorithm := model.LBPool_ALGORITHM_ROUND_ROBIN
+	if pool.Algorithm != nil {
+		currentAlgorithm = *pool.Algorithm
+	}
+	var d drift
+	if d.check("members", !modified) {
 		pool.Members = newMembers
+	}
+	if d.check("activeMonitorPaths", equalStrings(activeMonitorPaths, pool.ActiveMonitorPaths)) {
 		pool.ActiveMonitorPaths = activeMonitorPaths
-		s.CtxInfof("updating LbPool %s for %s, #members=%d", *pool.Id, mapping, len(pool.Members))
-		err := s.access.UpdatePool(pool)
-		if err != nil {
-			return err
-		}
 	}
+	if d.check("algorithm", currentAlgorithm == algorithm) {
+		pool.Algorithm = strptr(algorithm)
+	}
+	if d.check("snatTranslation", equalSnatTranslations(pool.SnatTranslation, snatTranslation)) {
+		pool.SnatTranslation = snatTranslation
+	}
+	if d.check("displayName", safeEquals(pool.DisplayName, displayNameObject(s.clusterName, s.objectName))) {
+		pool.DisplayName = displayNameObject(s.clusterName, s.objectName)
+	}
+	if len(d) == 0 {
+		return nil
+	}
+	s.CtxInfof("updating LbPool %s for %s, #members=%d, changed %s", *pool.Id, mapping, len(pool.Members), d)
+	err = s.access.UpdatePool(pool)
+	if err != nil {
+		return err
+	}
+	d.record(kindPool)
+	s.eventf(ReasonPoolUpdated, "updated pool %s for %s with %d members, changed %s", *pool.Id, mapping, len(pool.Members), d)
 	return nil
 }
 
-func (s *state) updatedPoolMembers(oldMembers []model.LBPoolMember) ([]model.LBPoolMember, bool) {
+func (s *state) updatedPoolMembers(family corev1.IPFamily, oldMembers []model.LBPoolMember) ([]model.LBPoolMember, bool, error) {
+	if s.resync {
+		return oldMembers, false, nil
+	}
+	selector, err := nodeSelectorFromService(s.service)
+	if err != nil {
+		return nil, false, err
+	}
 	modified := false
-	nodeIPAddresses := collectNodeInternalAddresses(s.nodes)
+	memberNodes := collectPoolMemberNodes(s.nodes, selector, family)
 	newMembers := []model.LBPoolMember{}
 	for _, member := range oldMembers {
 		if member.IpAddress == nil {
 			continue
 		}
-		if _, ok := nodeIPAddresses[*member.IpAddress]; ok {
+		if node, ok := memberNodes[*member.IpAddress]; ok {
+			if adminState := poolMemberAdminState(node); !safeEquals(member.AdminState, &adminState) {
+				member.AdminState = strptr(adminState)
+				modified = true
+			}
+			if weight := poolMemberWeight(node); member.Weight == nil && weight != 1 || member.Weight != nil && *member.Weight != weight {
+				member.Weight = int64ptr(weight)
+				modified = true
+			}
 			newMembers = append(newMembers, member)
 		} else {
 			modified = true
 		}
 	}
-	if len(nodeIPAddresses) > len(newMembers) {
-		for nodeIPAddress, nodeName := range nodeIPAddresses {
+	if len(memberNodes) > len(newMembers) {
+		for nodeIPAddress, node := range memberNodes {
 			found := false
 			for _, member := range oldMembers {
 				if member.IpAddress != nil && *member.IpAddress == nodeIPAddress {
@@ -362,27 +1117,41 @@ func (s *state) updatedPoolMembers(oldMembers []model.LBPoolMember) ([]model.LBP
 			}
 			if !found {
 				member := model.LBPoolMember{
-					AdminState:  strptr("ENABLED"),
-					DisplayName: strptr(fmt.Sprintf("%s:%s", s.clusterName, nodeName)),
+					AdminState:  strptr(poolMemberAdminState(node)),
+					DisplayName: strptr(fmt.Sprintf("%s:%s", s.clusterName, node.Name)),
 					IpAddress:   strptr(nodeIPAddress),
+					Weight:      int64ptr(poolMemberWeight(node)),
 				}
 				newMembers = append(newMembers, member)
 				modified = true
 			}
 		}
 	}
-	return newMembers, modified
+	return newMembers, modified, nil
 }
 
 func (s *state) deletePool(pool *model.LBPool) error {
 	s.CtxInfof("deleting LbPool %s for %s", *pool.Id, getTag(pool.Tags, ScopePort))
-	return s.access.DeletePool(*pool.Id)
+	err := s.access.DeletePool(*pool.Id)
+	if err != nil {
+		return err
+	}
+	s.orphanDeleted("pool", *pool.Id)
+	return nil
 }
 
 func (s *state) getVirtualServer(mapping Mapping, poolPath *string) (*model.LBVirtualServer, error) {
 	for _, server := range s.servers {
 		if mapping.MatchVirtualServer(server) {
-			err := s.updateVirtualServer(server, mapping, poolPath)
+			_, err := s.allocateResources(mapping.ipFamily())
+			if err != nil {
+				return nil, err
+			}
+			spec, err := s.virtualServerSpec(mapping, poolPath)
+			if err != nil {
+				return nil, err
+			}
+			err = s.updateVirtualServer(server, mapping, spec)
 			if err != nil {
 				return nil, err
 			}
@@ -393,51 +1162,132 @@ func (s *state) getVirtualServer(mapping Mapping, poolPath *string) (*model.LBVi
 	return s.createVirtualServer(mapping, poolPath)
 }
 
-func (s *state) createVirtualServer(mapping Mapping, poolPath *string) (*model.LBVirtualServer, error) {
-	allocated, err := s.allocateResources()
+// virtualServerSpec returns the settings of the virtual server of a mapping,
+// the objects it refers to are created if needed
+func (s *state) virtualServerSpec(mapping Mapping, poolPath *string) (VirtualServerSpec, error) {
+	applicationProfilePath, err := s.appProfilePath(mapping)
 	if err != nil {
-		return nil, err
+		return VirtualServerSpec{}, err
+	}
+	clientSSLBinding, err := s.clientSSLBinding(mapping)
+	if err != nil {
+		return VirtualServerSpec{}, err
+	}
+	accessListControl, err := s.accessListControl(mapping)
+	if err != nil {
+		return VirtualServerSpec{}, err
+	}
+	persistenceProfilePath, err := s.persistenceProfilePath(mapping)
+	if err != nil {
+		return VirtualServerSpec{}, err
+	}
+	lbServicePath, err := s.loadBalancerServicePath()
+	if err != nil {
+		return VirtualServerSpec{}, err
 	}
+	return VirtualServerSpec{
+		IPAddress:              s.ipAddresses[mapping.ipFamily()],
+		LBServicePath:          lbServicePath,
+		ApplicationProfilePath: applicationProfilePath,
+		ClientSSLBinding:       clientSSLBinding,
+		AccessListControl:      accessListControl,
+		PersistenceProfilePath: persistenceProfilePath,
+		PoolPath:               poolPath,
+	}, nil
+}
 
-	lbServicePath, err := s.lbService.getOrCreateLoadBalancerService(s.clusterName)
+func (s *state) createVirtualServer(mapping Mapping, poolPath *string) (*model.LBVirtualServer, error) {
+	if s.resync {
+		return nil, s.missingObject("virtual server", mapping)
+	}
+	family := mapping.ipFamily()
+	allocated, err := s.allocateResources(family)
 	if err != nil {
-		return nil, errors.Wrapf(err, "get or create LBService failed")
+		return nil, err
 	}
 
-	applicationProfilePath, err := s.access.GetAppProfilePath(s.class, mapping.Protocol)
+	spec, err := s.virtualServerSpec(mapping, poolPath)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Lookup of application profile failed for %s", mapping.Protocol)
+		return nil, err
 	}
 
-	server, err := s.access.CreateVirtualServer(s.clusterName, s.objectName, s.class, *s.ipAddress, mapping,
-		lbServicePath, applicationProfilePath, poolPath)
+	server, err := s.access.CreateVirtualServer(s.clusterName, s.objectName, s.class, mapping, spec)
 	if err != nil {
 		if allocated {
-			s.loggedReleaseResources()
+			s.loggedReleaseResources(family)
 		}
 		return nil, err
 	}
 	s.CtxInfof("created LBVirtualServer %s for %s", *server.Id, mapping)
+	s.eventf(ReasonVirtualServerCreated, "created virtual server %s for %s on %s", *server.Id, mapping, *server.IpAddress)
 	s.servers = append(s.servers, server)
 	return server, nil
 }
 
-func (s *state) updateVirtualServer(server *model.LBVirtualServer, mapping Mapping, poolPath *string) error {
-	applicationProfilePath, err := s.access.GetAppProfilePath(s.class, mapping.Protocol)
-	if err != nil {
-		return errors.Wrapf(err, "Lookup of application profile failed for %s", mapping.Protocol)
+func (s *state) updateVirtualServer(server *model.LBVirtualServer, mapping Mapping, spec VirtualServerSpec) error {
+	ipAddress := server.IpAddress
+	if spec.IPAddress != nil {
+		ipAddress = spec.IPAddress
+	}
+	ports := []string{formatPort(mapping.SourcePort)}
+	var d drift
+	if d.check("ipAddress", safeEquals(server.IpAddress, ipAddress)) {
+		server.IpAddress = ipAddress
 	}
-	if !mapping.MatchNodePort(server) || !safeEquals(server.PoolPath, poolPath) || !safeEquals(server.ApplicationProfilePath, &applicationProfilePath) {
-		server.ApplicationProfilePath = strptr(applicationProfilePath)
+	if d.check("ports", reflect.DeepEqual(server.Ports, ports)) {
+		server.Ports = ports
+	}
+	if d.check("defaultPoolMemberPorts", mapping.MatchNodePort(server)) {
 		server.DefaultPoolMemberPorts = []string{formatPort(mapping.NodePort)}
-		server.PoolPath = poolPath
-		s.CtxInfof("updating LbVirtualServer %s for %s", *server.Id, mapping)
-		err = s.access.UpdateVirtualServer(server)
+	}
+	if d.check("poolPath", safeEquals(server.PoolPath, spec.PoolPath)) {
+		server.PoolPath = spec.PoolPath
+	}
+	if d.check("applicationProfilePath", safeEquals(server.ApplicationProfilePath, &spec.ApplicationProfilePath)) {
+		server.ApplicationProfilePath = strptr(spec.ApplicationProfilePath)
+	}
+	if d.check("clientSslProfileBinding", equalClientSSLBindings(server.ClientSslProfileBinding, spec.ClientSSLBinding)) {
+		server.ClientSslProfileBinding = spec.ClientSSLBinding
+	}
+	if d.check("lbPersistenceProfilePath", safeEquals(server.LbPersistenceProfilePath, spec.PersistenceProfilePath)) {
+		server.LbPersistenceProfilePath = spec.PersistenceProfilePath
+	}
+	if d.check("accessListControl", equalAccessListControls(server.AccessListControl, spec.AccessListControl)) {
+		server.AccessListControl = spec.AccessListControl
+	}
+	if d.check("lbServicePath", safeEquals(server.LbServicePath, &spec.LBServicePath)) {
+		server.LbServicePath = strptr(spec.LBServicePath)
+	}
+	if d.check("enabled", server.Enabled != nil && *server.Enabled) {
+		server.Enabled = boolptr(true)
+	}
+	if d.check("displayName", safeEquals(server.DisplayName, displayNameObject(s.clusterName, s.objectName))) {
+		server.DisplayName = displayNameObject(s.clusterName, s.objectName)
+	}
+	if len(d) == 0 {
+		return nil
+	}
+	s.CtxInfof("updating LbVirtualServer %s for %s, changed %s", *server.Id, mapping, d)
+	err := s.access.UpdateVirtualServer(server)
+	if err != nil {
+		return err
+	}
+	d.record(kindVirtualServer)
+	s.eventf(ReasonVirtualServerUpdated, "updated virtual server %s for %s on %s, changed %s", *server.Id, mapping, *server.IpAddress, d)
+	return nil
+}
+
+// loadBalancerServicePath returns the path of the NSX-T load balancer service
+// of the virtual servers, which is created if needed
+func (s *state) loadBalancerServicePath() (string, error) {
+	if s.lbServicePath == "" {
+		path, err := s.lbService.getOrCreateLoadBalancerService(s.clusterName)
 		if err != nil {
-			return err
+			return "", withReason(ReasonLoadBalancerServiceFailed, errors.Wrapf(err, "get or create LBService failed"))
 		}
+		s.lbServicePath = path
 	}
-	return nil
+	return s.lbServicePath, nil
 }
 
 func (s *state) deleteVirtualServer(server *model.LBVirtualServer) error {
@@ -450,5 +1300,6 @@ func (s *state) deleteVirtualServer(server *model.LBVirtualServer) error {
 	if err != nil {
 		return err
 	}
+	s.orphanDeleted("virtual server", *server.Id)
 	return s.lbService.removeLoadBalancerServiceIfUnused(s.clusterName)
 }
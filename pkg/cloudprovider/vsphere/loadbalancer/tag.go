@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/vmware/vsphere-automation-sdk-go/services/nsxt/model"
@@ -76,6 +77,28 @@ func portTag(mapping Mapping) model.Tag {
 	return newTag(ScopePort, fmt.Sprintf("%s/%d", mapping.Protocol, mapping.SourcePort))
 }
 
+func ipFamilyTag(mapping Mapping) model.Tag {
+	return newTag(ScopeIPFamily, string(mapping.ipFamily()))
+}
+
+func retainIPTag() model.Tag {
+	return newTag(ScopeRetainIP, "true")
+}
+
+// isRetained checks whether an IP address allocation is kept after the deletion of its service
+func isRetained(tags []model.Tag) bool {
+	return getTag(tags, ScopeRetainIP) == "true"
+}
+
+// ipFamilyFromTags returns the IP family of a virtual server or pool, objects
+// created before dual-stack support have no IP family tag and are IPv4 ones
+func ipFamilyFromTags(tags []model.Tag) corev1.IPFamily {
+	if family := getTag(tags, ScopeIPFamily); family != "" {
+		return corev1.IPFamily(family)
+	}
+	return corev1.IPv4Protocol
+}
+
 func checkTags(tags []model.Tag, required ...model.Tag) bool {
 outer:
 	for _, req := range required {
@@ -81,6 +81,20 @@ func newNodeManager(cfg *ccfg.CPIConfig, cm *cm.ConnectionManager) *NodeManager
 	}
 }
 
+// config returns the CPI config used to discover the node addresses
+func (nm *NodeManager) config() *ccfg.CPIConfig {
+	nm.cfgLock.RLock()
+	defer nm.cfgLock.RUnlock()
+	return nm.cfg
+}
+
+// setConfig replaces the CPI config used to discover the node addresses
+func (nm *NodeManager) setConfig(cfg *ccfg.CPIConfig) {
+	nm.cfgLock.Lock()
+	defer nm.cfgLock.Unlock()
+	nm.cfg = cfg
+}
+
 // RegisterNode is the handler for when a node is added to a K8s cluster.
 func (nm *NodeManager) RegisterNode(node *v1.Node) {
 	klog.V(4).Info("RegisterNode ENTER: ", node.Name)
@@ -236,7 +250,7 @@ func (nm *NodeManager) DiscoverNode(nodeID string, searchBy cm.FindVM) error {
 	if vmDI.TenantRef != "" {
 		tenantRef = vmDI.TenantRef
 	}
-	vcInstance := nm.connectionManager.VsphereInstanceMap[tenantRef]
+	vcInstance := nm.connectionManager.VSphereInstances()[tenantRef]
 
 	ipFamilies := []string{vcfg.DefaultIPFamily}
 	if vcInstance != nil {
@@ -252,25 +266,26 @@ func (nm *NodeManager) DiscoverNode(nodeID string, searchBy cm.FindVM) error {
 	var internalVMNetworkName string
 	var externalVMNetworkName string
 
-	if nm.cfg != nil {
-		internalNetworkSubnets, err = parseCIDRs(nm.cfg.Nodes.InternalNetworkSubnetCIDR)
+	if cfg := nm.config(); cfg != nil {
+		nodes := cfg.NodesFor(tenantRef)
+		internalNetworkSubnets, err = parseCIDRs(nodes.InternalNetworkSubnetCIDR)
 		if err != nil {
 			return err
 		}
-		externalNetworkSubnets, err = parseCIDRs(nm.cfg.Nodes.ExternalNetworkSubnetCIDR)
+		externalNetworkSubnets, err = parseCIDRs(nodes.ExternalNetworkSubnetCIDR)
 		if err != nil {
 			return err
 		}
-		excludeInternalNetworkSubnets, err = parseCIDRs(nm.cfg.Nodes.ExcludeInternalNetworkSubnetCIDR)
+		excludeInternalNetworkSubnets, err = parseCIDRs(nodes.ExcludeInternalNetworkSubnetCIDR)
 		if err != nil {
 			return err
 		}
-		excludeExternalNetworkSubnets, err = parseCIDRs(nm.cfg.Nodes.ExcludeExternalNetworkSubnetCIDR)
+		excludeExternalNetworkSubnets, err = parseCIDRs(nodes.ExcludeExternalNetworkSubnetCIDR)
 		if err != nil {
 			return err
 		}
-		internalVMNetworkName = nm.cfg.Nodes.InternalVMNetworkName
-		externalVMNetworkName = nm.cfg.Nodes.ExternalVMNetworkName
+		internalVMNetworkName = nodes.InternalVMNetworkName
+		externalVMNetworkName = nodes.ExternalVMNetworkName
 	}
 
 	addrs := []v1.NodeAddress{}
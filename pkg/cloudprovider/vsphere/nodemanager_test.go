@@ -33,6 +33,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 	cm "k8s.io/cloud-provider-vsphere/pkg/common/connectionmanager"
 	"k8s.io/cloud-provider-vsphere/pkg/common/vclib"
 )
@@ -233,6 +234,7 @@ func TestDiscoverNodeIPs(t *testing.T) {
 	type testSetup struct {
 		ipFamilyPriority []string
 		cpiConfig        *ccfg.CPIConfig
+		vcenterNodes     *vcfg.Nodes
 		networks         []vimtypes.GuestNicInfo
 		guestinfo        string
 	}
@@ -1820,6 +1822,37 @@ func TestDiscoverNodeIPs(t *testing.T) {
 			},
 			expectedErrorSubstring: "cannot unmarshal",
 		},
+		{
+			testName: "ByVCenterNodes_replacingTheGlobalNodes",
+			setup: testSetup{
+				ipFamilyPriority: []string{"ipv4"},
+				cpiConfig: &ccfg.CPIConfig{
+					Nodes: ccfg.Nodes{
+						InternalNetworkSubnetCIDR: "10.10.0.0/16",
+						ExternalNetworkSubnetCIDR: "172.15.0.0/16",
+					},
+				},
+				vcenterNodes: &vcfg.Nodes{
+					InternalNetworkSubnetCIDR: "10.20.0.0/16",
+					ExternalNetworkSubnetCIDR: "172.25.0.0/16",
+				},
+				networks: []vimtypes.GuestNicInfo{
+					{
+						Network: "net_123abc",
+						IpAddress: []string{
+							"10.10.1.22",
+							"10.20.1.22",
+							"172.15.108.10",
+							"172.25.108.10",
+						},
+					},
+				},
+			},
+			expectedIPs: []v1.NodeAddress{
+				{Type: "InternalIP", Address: "10.20.1.22"},
+				{Type: "ExternalIP", Address: "172.25.108.10"},
+			},
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -1831,6 +1864,11 @@ func TestDiscoverNodeIPs(t *testing.T) {
 			connMgr := cm.NewConnectionManager(cfg, nil, nil)
 			defer connMgr.Logout()
 
+			if testcase.setup.vcenterNodes != nil {
+				testcase.setup.cpiConfig.VirtualCenter = map[string]*vcfg.VirtualCenterConfig{
+					cfg.Global.VCenterIP: {Nodes: *testcase.setup.vcenterNodes},
+				}
+			}
 			nm := newNodeManager(testcase.setup.cpiConfig, connMgr)
 
 			vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
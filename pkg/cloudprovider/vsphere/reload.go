@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	klog "k8s.io/klog/v2"
+
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+// Reload applies a changed cloud-config without a restart. The config is read
+// and validated first, an invalid config is rejected and the current one stays
+// in effect. Otherwise the NSX-T connection settings, the load balancer classes,
+// the vCenter instances and the node address rules are swapped in. Changes that
+// cannot be applied at runtime are reported in a ConfigRestartRequired event.
+// The VSphereCloudConfig in effect, if any, is merged into the cloud-config.
+func (vs *VSphere) Reload(byConfig []byte) error {
+	vs.reloadLock.Lock()
+	defer vs.reloadLock.Unlock()
+
+	merged, err := vs.mergeCloudConfig(byConfig, vs.cfgFragment)
+	if err == nil {
+		err = vs.apply(merged)
+	}
+	if err != nil {
+		return vs.rejectConfig(err)
+	}
+	vs.cfgFile = byConfig
+	return nil
+}
+
+// mergeCloudConfig merges the VSphereCloudConfig fragment, if any, into the
+// cloud-config file
+func (vs *VSphere) mergeCloudConfig(byConfig, fragment []byte) ([]byte, error) {
+	if fragment == nil {
+		return byConfig, nil
+	}
+	return ccfg.MergeCloudConfigFragment(byConfig, CloudConfigName, fragment)
+}
+
+// apply swaps in the merged cloud-config byConfig if it changed
+func (vs *VSphere) apply(byConfig []byte) error {
+	if bytes.Equal(byConfig, vs.cfgData) {
+		klog.V(4).Info("cloud-config unchanged, nothing to reload")
+		return nil
+	}
+
+	restartRequired, err := vs.reload(byConfig)
+	if err != nil {
+		return err
+	}
+	vs.cfgData = byConfig
+
+	klog.Info("cloud-config reloaded")
+	vs.recordEvent(v1.EventTypeNormal, ReasonConfigReloaded, "cloud-config reloaded")
+	if len(restartRequired) > 0 {
+		message := fmt.Sprintf("changes of %s take effect after a restart", strings.Join(restartRequired, ", "))
+		klog.Warning(message)
+		vs.recordEvent(v1.EventTypeWarning, ReasonConfigRestartRequired, message)
+	}
+	return nil
+}
+
+// rejectConfig reports a rejected cloud-config and returns err
+func (vs *VSphere) rejectConfig(err error) error {
+	klog.Errorf("cloud-config rejected, keeping the current config: %v", err)
+	vs.recordEvent(v1.EventTypeWarning, ReasonConfigReloadFailed,
+		fmt.Sprintf("cloud-config rejected, keeping the current config: %v", err))
+	return err
+}
+
+// reload reads byConfig and swaps in the parts that can change at runtime. It
+// returns the changed settings that require a restart.
+func (vs *VSphere) reload(byConfig []byte) ([]string, error) {
+	cfg, err := ccfg.ReadCPIConfig(byConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDualStack(cfg); err != nil {
+		return nil, err
+	}
+	// same as at startup, an enabled subsystem with an invalid config is rejected
+	subsystems, err := readSubsystemConfigs(byConfig)
+	if err != nil {
+		return nil, err
+	}
+	nsxtcfg, lbcfg, routecfg := subsystems.nsxt, subsystems.lb, subsystems.route
+
+	restartRequired := vs.restartRequired(cfg, nsxtcfg, lbcfg, routecfg)
+	_, currentNSXT, currentLB := vs.configs()
+	nsxtReloadable := (currentNSXT == nil) == (nsxtcfg == nil) &&
+		(nsxtcfg == nil || nsxtcfg.Host == currentNSXT.Host)
+
+	// NSX-T first so that the load balancer classes are resolved with the new
+	// connection settings, it is restored if the classes cannot be resolved
+	restoreNSXT := func() {}
+	if nsxtReloadable && vs.nsxtConnectorMgr != nil {
+		restoreNSXT, err = vs.nsxtConnectorMgr.Reload(nsxtcfg)
+		if err != nil {
+			return nil, fmt.Errorf("NSX-T config: %w", err)
+		}
+	}
+	if vs.isLoadBalancerSupportEnabled() && lbcfg != nil && lbcfg.IsEnabled() {
+		if err := vs.loadbalancer.Reload(lbcfg); err != nil {
+			restoreNSXT()
+			return nil, fmt.Errorf("load balancer config: %w", err)
+		}
+		currentLB = lbcfg
+	}
+	if nsxtReloadable {
+		currentNSXT = nsxtcfg
+	}
+
+	if vs.connectionManager != nil {
+		vs.connectionManager.Reload(&cfg.Config)
+	}
+	vs.nodeManager.setConfig(cfg)
+	vs.setConfigs(cfg, currentNSXT, currentLB)
+
+	return restartRequired, nil
+}
+
+// configs returns the CPI, NSX-T and load balancer configs in effect
+func (vs *VSphere) configs() (*ccfg.CPIConfig, *ncfg.Config, *lcfg.LBConfig) {
+	vs.cfgLock.RLock()
+	defer vs.cfgLock.RUnlock()
+	return vs.cfg, vs.cfgNSXT, vs.cfgLB
+}
+
+// config returns the CPI config in effect
+func (vs *VSphere) config() *ccfg.CPIConfig {
+	cfg, _, _ := vs.configs()
+	return cfg
+}
+
+// setConfigs replaces the CPI, NSX-T and load balancer configs in one step
+func (vs *VSphere) setConfigs(cfg *ccfg.CPIConfig, nsxtcfg *ncfg.Config, lbcfg *lcfg.LBConfig) {
+	vs.cfgLock.Lock()
+	defer vs.cfgLock.Unlock()
+	vs.cfg, vs.cfgNSXT, vs.cfgLB = cfg, nsxtcfg, lbcfg
+}
+
+// restartRequired returns the changed settings that are not reloaded
+func (vs *VSphere) restartRequired(cfg *ccfg.CPIConfig, nsxtcfg *ncfg.Config, lbcfg *lcfg.LBConfig, routecfg *rcfg.Config) []string {
+	var changed []string
+	currentCfg, currentNSXT, currentLB := vs.configs()
+
+	if cfg.Labels != currentCfg.Labels {
+		changed = append(changed, "labels")
+	}
+	if cfg.Global.SecretName != currentCfg.Global.SecretName || cfg.Global.SecretNamespace != currentCfg.Global.SecretNamespace ||
+		cfg.Global.SecretsDirectory != currentCfg.Global.SecretsDirectory ||
+		!reflect.DeepEqual(cfg.CredentialProvider, currentCfg.CredentialProvider) {
+		changed = append(changed, "the global credentials source")
+	}
+
+	if (nsxtcfg == nil) != (currentNSXT == nil) {
+		changed = append(changed, "nsxt enablement")
+	} else if nsxtcfg != nil && nsxtcfg.Host != currentNSXT.Host {
+		changed = append(changed, "nsxt.host")
+	}
+
+	lbEnabled := lbcfg != nil && lbcfg.IsEnabled()
+	if lbEnabled != vs.isLoadBalancerSupportEnabled() {
+		changed = append(changed, "loadBalancer enablement")
+	} else if lbEnabled && currentLB != nil {
+		current, next := &currentLB.LoadBalancer, &lbcfg.LoadBalancer
+		if next.Size != current.Size || next.LBServiceID != current.LBServiceID ||
+			next.Tier1GatewayPath != current.Tier1GatewayPath || next.SnatDisabled != current.SnatDisabled ||
+			!reflect.DeepEqual(next.AdditionalTags, current.AdditionalTags) {
+			changed = append(changed, "loadBalancer size, lbServiceId, tier1GatewayPath, snatDisabled or tags")
+		}
+	}
+
+	var routerPath, currentRouterPath string
+	if routecfg != nil {
+		routerPath = routecfg.Route.RouterPath
+	}
+	if vs.cfgRoute != nil {
+		currentRouterPath = vs.cfgRoute.Route.RouterPath
+	}
+	if routerPath != currentRouterPath {
+		changed = append(changed, "route.routerPath")
+	}
+
+	return changed
+}
+
+// recordEvent records an event on the pod of the CCM if it is known
+func (vs *VSphere) recordEvent(eventType, reason, message string) {
+	if vs.eventRecorder == nil {
+		return
+	}
+	vs.eventRecorder.Event(vs.podRef, eventType, reason, message)
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	cm "k8s.io/cloud-provider-vsphere/pkg/common/connectionmanager"
+)
+
+const reloadConfig = `
+global:
+  port: 443
+  user: user
+  password: password
+  insecureFlag: true
+
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc1
+
+nodes:
+  internalNetworkSubnetCidr: 192.0.2.0/24
+`
+
+func newReloadVSphere(t *testing.T) (*VSphere, *record.FakeRecorder) {
+	cfg, err := ccfg.ReadCPIConfig([]byte(reloadConfig))
+	if err != nil {
+		t.Fatalf("ReadCPIConfig failed: %v", err)
+	}
+	vs, err := newVSphere(cfg, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("newVSphere failed: %v", err)
+	}
+	vs.cfgData = []byte(reloadConfig)
+	vs.cfgFile = []byte(reloadConfig)
+	vs.connectionManager = cm.NewConnectionManager(&cfg.Config, nil, nil)
+	vs.nodeManager.connectionManager = vs.connectionManager
+
+	recorder := record.NewFakeRecorder(10)
+	vs.eventRecorder = recorder
+	return vs, recorder
+}
+
+func expectEvents(t *testing.T, recorder *record.FakeRecorder, reasons ...string) {
+	t.Helper()
+	for _, reason := range reasons {
+		select {
+		case event := <-recorder.Events:
+			if !strings.Contains(event, " "+reason+" ") {
+				t.Errorf("expected %s event, got %q", reason, event)
+			}
+		default:
+			t.Errorf("expected %s event, got none", reason)
+		}
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event %q", event)
+	default:
+	}
+}
+
+const reloadedConfig = `
+global:
+  port: 443
+  user: user
+  password: password
+  insecureFlag: true
+
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+    datacenters:
+      - dc1
+  tenant-b:
+    server: 10.0.0.2
+    datacenters:
+      - dc2
+
+nodes:
+  internalNetworkSubnetCidr: 198.51.100.0/24
+`
+
+func TestReload(t *testing.T) {
+	vs, recorder := newReloadVSphere(t)
+	tenantA := vs.connectionManager.VSphereInstances()["tenant-a"]
+
+	if err := vs.Reload([]byte(reloadedConfig)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	expectEvents(t, recorder, ReasonConfigReloaded)
+
+	instances := vs.connectionManager.VSphereInstances()
+	if len(instances) != 2 || instances["tenant-b"] == nil {
+		t.Errorf("expected tenant-b to be added, got %v", instances)
+	}
+	if instances["tenant-a"] != tenantA {
+		t.Error("expected the unchanged tenant-a instance to be kept")
+	}
+	if cidr := vs.nodeManager.config().Nodes.InternalNetworkSubnetCIDR; cidr != "198.51.100.0/24" {
+		t.Errorf("expected the node address rules to be reloaded, got %s", cidr)
+	}
+
+	// the same config again is not reloaded
+	if err := vs.Reload([]byte(reloadedConfig)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	expectEvents(t, recorder)
+}
+
+func TestReloadInvalid(t *testing.T) {
+	vs, recorder := newReloadVSphere(t)
+	cfg := vs.nodeManager.config()
+	instances := vs.connectionManager.VSphereInstances()
+
+	if err := vs.Reload([]byte("not a cloud-config")); err == nil {
+		t.Fatal("expected an invalid config to be rejected")
+	}
+	expectEvents(t, recorder, ReasonConfigReloadFailed)
+
+	if vs.nodeManager.config() != cfg {
+		t.Error("expected the current config to be kept")
+	}
+	if len(vs.connectionManager.VSphereInstances()) != len(instances) {
+		t.Error("expected the current vCenter instances to be kept")
+	}
+}
+
+func TestReloadRestartRequired(t *testing.T) {
+	vs, recorder := newReloadVSphere(t)
+
+	config := reloadConfig + `
+labels:
+  zone: k8s-zone
+  region: k8s-region
+`
+	if err := vs.Reload([]byte(config)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	expectEvents(t, recorder, ReasonConfigReloaded, ReasonConfigRestartRequired)
+}
@@ -20,8 +20,19 @@ import (
 	"fmt"
 
 	klog "k8s.io/klog/v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
+// IsEnabled checks whether the routes are enabled. They are enabled if the
+// router path is given, unless they are explicitly enabled or disabled.
+func (cfg *Config) IsEnabled() bool {
+	if cfg.Route.Enabled != nil {
+		return *cfg.Route.Enabled
+	}
+	return cfg.Route.RouterPath != ""
+}
+
 /*
 	TODO:
 	When the INI based cloud-config is deprecated, the references to the
@@ -31,11 +42,17 @@ import (
 // ReadRouteConfig parses vSphere cloud config file and stores it into VSphereConfig.
 // Environment variables are also checked
 func ReadRouteConfig(configData []byte) (*Config, error) {
+	return ReadRouteConfigWithEnv(configData, vcfg.OSEnv)
+}
+
+// ReadRouteConfigWithEnv parses the config like ReadRouteConfig, with the variables
+// of env overriding its keys.
+func ReadRouteConfigWithEnv(configData []byte, env vcfg.Env) (*Config, error) {
 	if len(configData) == 0 {
 		return nil, fmt.Errorf("Invalid YAML/INI file")
 	}
 
-	cfg, err := ReadConfigYAML(configData)
+	cfg, err := readConfigYAML(configData, env)
 	if err != nil {
 		cfg, err = ReadConfigINI(configData)
 		if err != nil {
@@ -32,11 +32,15 @@ import (
 // are already dependent upon in other packages.
 func (rci *RouteConfigINI) CreateConfig() *Config {
 	cfg := &Config{}
+	cfg.Route.Enabled = rci.Route.Enabled
 	cfg.Route.RouterPath = rci.Route.RouterPath
 	return cfg
 }
 
 func (rci *RouteConfigINI) validateConfig() error {
+	if rci.Route.Enabled != nil && !*rci.Route.Enabled {
+		return nil
+	}
 	if rci.Route.RouterPath == "" {
 		return errors.New("router path is required")
 	}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 
 	yaml "gopkg.in/yaml.v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
 /*
@@ -33,11 +35,15 @@ import (
 // are already dependent upon in other packages.
 func (rcy *RouteConfigYAML) CreateConfig() *Config {
 	cfg := &Config{}
+	cfg.Route.Enabled = rcy.Route.Enabled
 	cfg.Route.RouterPath = rcy.Route.RouterPath
 	return cfg
 }
 
 func (rcy *RouteConfigYAML) validateConfig() error {
+	if rcy.Route.Enabled != nil && !*rcy.Route.Enabled {
+		return nil
+	}
 	if rcy.Route.RouterPath == "" {
 		return errors.New("router path is required")
 	}
@@ -51,6 +57,10 @@ func (rcy *RouteConfigYAML) CompleteAndValidate() error {
 
 // ReadRawConfigYAML parses vSphere cloud config file and stores it into ConfigYAML
 func ReadRawConfigYAML(configData []byte) (*RouteConfigYAML, error) {
+	return readRawConfigYAML(configData, vcfg.OSEnv)
+}
+
+func readRawConfigYAML(configData []byte, env vcfg.Env) (*RouteConfigYAML, error) {
 	if len(configData) == 0 {
 		return nil, fmt.Errorf("Invalid YAML file")
 	}
@@ -60,6 +70,9 @@ func ReadRawConfigYAML(configData []byte) (*RouteConfigYAML, error) {
 	if err := yaml.Unmarshal(configData, &cfg); err != nil {
 		return nil, err
 	}
+	if err := env.Apply(vcfg.EnvPrefix, &cfg); err != nil {
+		return nil, err
+	}
 
 	err := cfg.CompleteAndValidate()
 	if err != nil {
@@ -70,7 +83,11 @@ func ReadRawConfigYAML(configData []byte) (*RouteConfigYAML, error) {
 
 // ReadConfigYAML parses vSphere cloud config file and stores it into Config
 func ReadConfigYAML(configData []byte) (*Config, error) {
-	cfg, err := ReadRawConfigYAML(configData)
+	return readConfigYAML(configData, vcfg.OSEnv)
+}
+
+func readConfigYAML(configData []byte, env vcfg.Env) (*Config, error) {
+	cfg, err := readRawConfigYAML(configData, env)
 	if err != nil {
 		return nil, err
 	}
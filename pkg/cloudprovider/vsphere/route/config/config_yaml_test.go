@@ -43,3 +43,16 @@ route:
 	}
 	assertEquals("route.routerPath", config.Route.RouterPath, "/infra/tier-1s/test-router")
 }
+
+func TestReadYAMLConfigEnabled(t *testing.T) {
+	config, err := ReadConfigYAML([]byte("route:\n  enabled: false\n"))
+	if err != nil {
+		t.Errorf("a disabled route is not validated: %v", err)
+	} else if config.IsEnabled() {
+		t.Error("route is disabled")
+	}
+
+	if _, err := ReadConfigYAML([]byte("route:\n  enabled: true\n")); err == nil {
+		t.Error("an enabled route requires a router path")
+	}
+}
@@ -23,5 +23,6 @@ type Config struct {
 
 // RouteConfig contains the configuration for the route itself
 type RouteConfig struct {
+	Enabled    *bool
 	RouterPath string
 }
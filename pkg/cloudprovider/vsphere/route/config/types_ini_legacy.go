@@ -23,5 +23,6 @@ type RouteConfigINI struct {
 
 // RouteINI contains the configuration for route
 type RouteINI struct {
+	Enabled    *bool  `gcfg:"enabled"`
 	RouterPath string `gcfg:"router-path"`
 }
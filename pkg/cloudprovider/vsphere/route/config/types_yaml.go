@@ -31,5 +31,8 @@ type RouteConfigYAML struct {
 
 // RouteYAML contains the configuration for route
 type RouteYAML struct {
+	// Enabled explicitly enables or disables the routes, they are enabled if
+	// routerPath is given when unset
+	Enabled    *bool  `yaml:"enabled"`
 	RouterPath string `yaml:"routerPath"`
 }
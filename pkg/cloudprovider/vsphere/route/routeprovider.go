@@ -55,7 +55,7 @@ var _ RoutesProvider = &routeProvider{}
 
 // NewRouteProvider creates a new RouteProvider
 func NewRouteProvider(cfg *config.Config, connector client.Connector) (RoutesProvider, error) {
-	if cfg == nil || cfg.Route.RouterPath == "" {
+	if cfg == nil || !cfg.IsEnabled() {
 		return nil, nil
 	}
 	nsxtbroker, err := NewNsxtBroker(connector)
@@ -0,0 +1,123 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"fmt"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	klog "k8s.io/klog/v2"
+
+	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
+	lcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
+)
+
+// subsystemConfigs holds the configs of the optional NSX-T based subsystems,
+// nil for the disabled ones.
+type subsystemConfigs struct {
+	nsxt  *ncfg.Config
+	lb    *lcfg.LBConfig
+	route *rcfg.Config
+	// states describes the resolved state of each subsystem
+	states []string
+}
+
+// readSubsystemConfigs reads the NSX-T, load balancer and route configs. A
+// subsystem that is not explicitly enabled is disabled if its config is
+// invalid, as it always was. A subsystem explicitly enabled through its enabled
+// flag must have a valid config, as well as NSX-T. All the failures of the
+// enabled subsystems are returned in an aggregate.
+func readSubsystemConfigs(byConfig []byte) (*subsystemConfigs, error) {
+	flags := ccfg.ReadSubsystemFlags(byConfig)
+	var errs []error
+	configs := &subsystemConfigs{}
+
+	lbcfg, lbErr := lcfg.ReadLBConfig(byConfig)
+	switch {
+	case isFalse(flags.LoadBalancer):
+		configs.states = append(configs.states, "loadBalancer: disabled by loadBalancer.enabled")
+	case lbErr != nil && isTrue(flags.LoadBalancer):
+		errs = append(errs, fmt.Errorf("loadBalancer is enabled but its config is invalid: %w", lbErr))
+	case lbErr != nil:
+		klog.Warningf("loadBalancer config is invalid, load balancers are disabled: %v", lbErr)
+		configs.states = append(configs.states, fmt.Sprintf("loadBalancer: disabled, invalid config: %v", lbErr))
+	case lbcfg.IsEnabled():
+		configs.lb = lbcfg
+		configs.states = append(configs.states, "loadBalancer: enabled")
+	default:
+		configs.states = append(configs.states, "loadBalancer: disabled, not configured")
+	}
+
+	routecfg, routeErr := rcfg.ReadRouteConfig(byConfig)
+	switch {
+	case isFalse(flags.Route):
+		configs.states = append(configs.states, "route: disabled by route.enabled")
+	case routeErr != nil && isTrue(flags.Route):
+		errs = append(errs, fmt.Errorf("route is enabled but its config is invalid: %w", routeErr))
+	case routeErr != nil:
+		// the route section is optional, a missing router path is the usual case
+		klog.V(2).Infof("route config is invalid, routes are disabled: %v", routeErr)
+		configs.states = append(configs.states, fmt.Sprintf("route: disabled, %v", routeErr))
+	case routecfg.IsEnabled():
+		configs.route = routecfg
+		configs.states = append(configs.states, "route: enabled")
+	default:
+		configs.states = append(configs.states, "route: disabled, not configured")
+	}
+
+	nsxtcfg, nsxtErr := ncfg.ReadNsxtConfig(byConfig)
+	var requiredBy []string
+	if isTrue(flags.LoadBalancer) {
+		requiredBy = append(requiredBy, "loadBalancer")
+	}
+	if isTrue(flags.Route) {
+		requiredBy = append(requiredBy, "route")
+	}
+	switch {
+	case nsxtErr != nil && len(requiredBy) > 0:
+		errs = append(errs, fmt.Errorf("nsxt is required by %s but its config is invalid: %w", strings.Join(requiredBy, " and "), nsxtErr))
+	case nsxtErr != nil:
+		klog.Errorf("ReadNsxtConfig failed: %s", nsxtErr)
+		configs.states = append(configs.states, fmt.Sprintf("nsxt: disabled, %v", nsxtErr))
+	default:
+		configs.nsxt = nsxtcfg
+		configs.states = append(configs.states, "nsxt: enabled")
+	}
+
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return configs, nil
+}
+
+// logStates reports the resolved state of the subsystems
+func (c *subsystemConfigs) logStates() {
+	for _, state := range c.states {
+		klog.Infof("subsystem %s", state)
+	}
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+func isFalse(b *bool) bool {
+	return b != nil && !*b
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vsphere
+
+import (
+	"strings"
+	"testing"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// the load balancer section has a typo, lbServiceID instead of lbServiceId
+const subsystemsConfig = `
+global:
+  server: 0.0.0.0
+  user: user
+  password: password
+nsxt:
+  host: nsxt.example.com
+  user: admin
+  password: secret
+loadBalancer:
+  %s
+  ipPoolName: pool
+  lbServiceID: lbs
+  tcpAppProfileName: default-tcp-lb-app-profile
+  udpAppProfileName: default-udp-lb-app-profile
+route:
+  %s
+`
+
+func subsystemsYAML(lb, route string) []byte {
+	return []byte(strings.Replace(strings.Replace(subsystemsConfig, "%s", lb, 1), "%s", route, 1))
+}
+
+func hasState(configs *subsystemConfigs, prefix string) bool {
+	for _, state := range configs.states {
+		if strings.HasPrefix(state, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReadSubsystemConfigsImplicit(t *testing.T) {
+	configs, err := readSubsystemConfigs(subsystemsYAML("", ""))
+	if err != nil {
+		t.Fatalf("an invalid section that is not enabled must not fail: %v", err)
+	}
+	if configs.lb != nil || configs.route != nil || configs.nsxt == nil {
+		t.Errorf("unexpected configs: %+v", configs)
+	}
+	for _, state := range []string{"loadBalancer: disabled, invalid config", "route: disabled", "nsxt: enabled"} {
+		if !hasState(configs, state) {
+			t.Errorf("state %q missing from %v", state, configs.states)
+		}
+	}
+}
+
+func TestReadSubsystemConfigsDisabled(t *testing.T) {
+	configs, err := readSubsystemConfigs(subsystemsYAML("enabled: false", "enabled: false"))
+	if err != nil {
+		t.Fatalf("a disabled subsystem must not fail: %v", err)
+	}
+	if configs.lb != nil || configs.route != nil {
+		t.Errorf("unexpected configs: %+v", configs)
+	}
+	if !hasState(configs, "loadBalancer: disabled by loadBalancer.enabled") || !hasState(configs, "route: disabled by route.enabled") {
+		t.Errorf("unexpected states %v", configs.states)
+	}
+}
+
+func TestReadSubsystemConfigsEnabledInvalid(t *testing.T) {
+	_, err := readSubsystemConfigs(subsystemsYAML("enabled: true", "enabled: true"))
+	if err == nil {
+		t.Fatal("an enabled subsystem with an invalid config must fail")
+	}
+	agg, ok := err.(utilerrors.Aggregate)
+	if !ok || len(agg.Errors()) != 2 {
+		t.Fatalf("expected an aggregate of 2 errors, got %v", err)
+	}
+	for _, expected := range []string{"loadBalancer is enabled but its config is invalid", "route is enabled but its config is invalid"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q does not contain %q", err, expected)
+		}
+	}
+}
+
+func TestReadSubsystemConfigsEnabledFromEnv(t *testing.T) {
+	t.Setenv("VSPHERE_LOAD_BALANCER_ENABLED", "true")
+	t.Setenv("VSPHERE_NSXT_HOST", "")
+
+	byConfig := []byte(`
+global:
+  server: 0.0.0.0
+loadBalancer:
+  lbServiceId: lbs
+  ipPoolName: pool
+  tcpAppProfileName: default-tcp-lb-app-profile
+  udpAppProfileName: default-udp-lb-app-profile
+`)
+	_, err := readSubsystemConfigs(byConfig)
+	if err == nil || !strings.Contains(err.Error(), "nsxt is required by loadBalancer") {
+		t.Errorf("expected the missing nsxt section to fail, got %v", err)
+	}
+}
@@ -20,22 +20,33 @@ import (
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
 
 	ccfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/config"
 	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer"
 	lbcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/loadbalancer/config"
 	"k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route"
+	rcfg "k8s.io/cloud-provider-vsphere/pkg/cloudprovider/vsphere/route/config"
 	cm "k8s.io/cloud-provider-vsphere/pkg/common/connectionmanager"
 	k8s "k8s.io/cloud-provider-vsphere/pkg/common/kubernetes"
 	"k8s.io/cloud-provider-vsphere/pkg/common/vclib"
 	"k8s.io/cloud-provider-vsphere/pkg/nsxt"
+	ncfg "k8s.io/cloud-provider-vsphere/pkg/nsxt/config"
 )
 
 // VSphere is an implementation of cloud provider Interface for VSphere.
 type VSphere struct {
 	// input (aka configs) and output (aka interfaces)
-	cfg *ccfg.CPIConfig
+	cfg      *ccfg.CPIConfig
+	cfgNSXT  *ncfg.Config
+	cfgRoute *rcfg.Config
+	// raw cloud-config the configs were read from
+	cfgData []byte
+	// raw cloud-config file, cfgData without the VSphereCloudConfig
+	cfgFile []byte
+	// YAML fragment of the VSphereCloudConfig in effect, nil if none
+	cfgFragment []byte
 
 	/*
 		Interfaces start
@@ -57,6 +68,13 @@ type VSphere struct {
 	nodeManager       *NodeManager
 	informMgr         *k8s.InformerManager
 	nsxtConnectorMgr  *nsxt.ConnectorManager
+	reloadLock        sync.Mutex
+	// cfgLock guards cfg, cfgNSXT and cfgLB, which are swapped by a reload
+	cfgLock sync.RWMutex
+
+	// events on the pod of the CCM, nil if the pod is not known
+	eventRecorder record.EventRecorder
+	podRef        *v1.ObjectReference
 }
 
 // NodeInfo is information about a Kubernetes node.
@@ -96,12 +114,13 @@ type NodeManager struct {
 	// ConnectionManager
 	connectionManager *cm.ConnectionManager
 
-	// Reference to CPI-specific configuration
+	// Reference to CPI-specific configuration, replaced on reload
 	cfg *ccfg.CPIConfig
 
 	// Mutexes
 	nodeInfoLock    sync.RWMutex
 	nodeRegInfoLock sync.RWMutex
+	cfgLock         sync.RWMutex
 }
 
 type instances struct {
@@ -21,6 +21,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	klog "k8s.io/klog/v2"
 )
@@ -30,8 +32,8 @@ import (
 	When the INI based cloud-config is deprecated, this functions below should be preserved
 */
 
-func getEnvKeyValue(match string, partial bool) (string, string, error) {
-	for _, e := range os.Environ() {
+func getEnvKeyValue(env Env, match string, partial bool) (string, string, error) {
+	for _, e := range env.Environ() {
 		pair := strings.Split(e, "=")
 		if len(pair) != 2 {
 			continue
@@ -57,11 +59,61 @@ func getEnvKeyValue(match string, partial bool) (string, string, error) {
 	return "", "", fmt.Errorf("Failed to find %s with %s", matchType, match)
 }
 
+// validateCredentialProvider checks that the credential provider type is known
+// and that the settings it requires are present and valid, so that the
+// connection manager can create the provider.
+func validateCredentialProvider(providerType, command, timeout, usernameFile, passwordFile string) error {
+	switch providerType {
+	case "":
+		return nil
+	case CredentialProviderExec:
+		if command == "" {
+			return fmt.Errorf("%w: command is required for the %s provider", ErrInvalidCredentialProvider, providerType)
+		}
+		if timeout != "" {
+			if _, err := time.ParseDuration(timeout); err != nil {
+				return fmt.Errorf("%w: invalid timeout %q: %v", ErrInvalidCredentialProvider, timeout, err)
+			}
+		}
+	case CredentialProviderFileTemplate:
+		if usernameFile == "" || passwordFile == "" {
+			return fmt.Errorf("%w: usernameFile and passwordFile are required for the %s provider", ErrInvalidCredentialProvider, providerType)
+		}
+		for _, file := range []string{usernameFile, passwordFile} {
+			if _, err := template.New("").Parse(file); err != nil {
+				return fmt.Errorf("%w: invalid file template %q: %v", ErrInvalidCredentialProvider, file, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidCredentialProvider, providerType)
+	}
+	return nil
+}
+
+// validateRateLimit checks that the API rate limit and concurrency cap are not negative
+func validateRateLimit(qps float64, burst, maxConcurrentRequests int) error {
+	if qps < 0 {
+		return fmt.Errorf("%w: apiQPS must not be negative", ErrInvalidRateLimit)
+	}
+	if burst < 0 {
+		return fmt.Errorf("%w: apiBurst must not be negative", ErrInvalidRateLimit)
+	}
+	if maxConcurrentRequests < 0 {
+		return fmt.Errorf("%w: maxConcurrentRequests must not be negative", ErrInvalidRateLimit)
+	}
+	return nil
+}
+
 // FromEnv initializes the provided configuratoin object with values
 // obtained from environment variables. If an environment variable is set
 // for a property that's already initialized, the environment variable's value
 // takes precedence.
 func (cfg *Config) FromEnv() error {
+	return cfg.FromEnvSource(OSEnv)
+}
+
+// FromEnvSource initializes the config like FromEnv, with the variables of env.
+func (cfg *Config) FromEnvSource(env Env) error {
 
 	//Init
 	if cfg.VirtualCenter == nil {
@@ -69,29 +121,29 @@ func (cfg *Config) FromEnv() error {
 	}
 
 	//Globals
-	if v := os.Getenv("VSPHERE_VCENTER"); v != "" {
+	if v := env.Getenv("VSPHERE_VCENTER"); v != "" {
 		cfg.Global.VCenterIP = v
 	}
-	if v := os.Getenv("VSPHERE_VCENTER_PORT"); v != "" {
+	if v := env.Getenv("VSPHERE_VCENTER_PORT"); v != "" {
 		cfg.Global.VCenterPort = v
 	}
-	if v := os.Getenv("VSPHERE_USER"); v != "" {
+	if v := env.Getenv("VSPHERE_USER"); v != "" {
 		cfg.Global.User = v
 	}
-	if v := os.Getenv("VSPHERE_PASSWORD"); v != "" {
+	if v := env.Getenv("VSPHERE_PASSWORD"); v != "" {
 		cfg.Global.Password = v
 	}
-	if v := os.Getenv("VSPHERE_DATACENTER"); v != "" {
+	if v := env.Getenv("VSPHERE_DATACENTER"); v != "" {
 		cfg.Global.Datacenters = v
 	}
-	if v := os.Getenv("VSPHERE_SECRET_NAME"); v != "" {
+	if v := env.Getenv("VSPHERE_SECRET_NAME"); v != "" {
 		cfg.Global.SecretName = v
 	}
-	if v := os.Getenv("VSPHERE_SECRET_NAMESPACE"); v != "" {
+	if v := env.Getenv("VSPHERE_SECRET_NAMESPACE"); v != "" {
 		cfg.Global.SecretNamespace = v
 	}
 
-	if v := os.Getenv("VSPHERE_ROUNDTRIP_COUNT"); v != "" {
+	if v := env.Getenv("VSPHERE_ROUNDTRIP_COUNT"); v != "" {
 		tmp, err := strconv.ParseUint(v, 10, 32)
 		if err != nil {
 			klog.Errorf("Failed to parse VSPHERE_ROUNDTRIP_COUNT: %s", err)
@@ -100,7 +152,32 @@ func (cfg *Config) FromEnv() error {
 		}
 	}
 
-	if v := os.Getenv("VSPHERE_INSECURE"); v != "" {
+	if v := env.Getenv("VSPHERE_API_QPS"); v != "" {
+		tmp, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			klog.Errorf("Failed to parse VSPHERE_API_QPS: %s", err)
+		} else {
+			cfg.Global.APIQPS = tmp
+		}
+	}
+	if v := env.Getenv("VSPHERE_API_BURST"); v != "" {
+		tmp, err := strconv.Atoi(v)
+		if err != nil {
+			klog.Errorf("Failed to parse VSPHERE_API_BURST: %s", err)
+		} else {
+			cfg.Global.APIBurst = tmp
+		}
+	}
+	if v := env.Getenv("VSPHERE_MAX_CONCURRENT_REQUESTS"); v != "" {
+		tmp, err := strconv.Atoi(v)
+		if err != nil {
+			klog.Errorf("Failed to parse VSPHERE_MAX_CONCURRENT_REQUESTS: %s", err)
+		} else {
+			cfg.Global.MaxConcurrentRequests = tmp
+		}
+	}
+
+	if v := env.Getenv("VSPHERE_INSECURE"); v != "" {
 		InsecureFlag, err := strconv.ParseBool(v)
 		if err != nil {
 			klog.Errorf("Failed to parse VSPHERE_INSECURE: %s", err)
@@ -109,7 +186,7 @@ func (cfg *Config) FromEnv() error {
 		}
 	}
 
-	if v := os.Getenv("VSPHERE_SECRETS_DIRECTORY"); v != "" {
+	if v := env.Getenv("VSPHERE_SECRETS_DIRECTORY"); v != "" {
 		cfg.Global.SecretsDirectory = v
 	}
 	if cfg.Global.SecretsDirectory == "" {
@@ -119,21 +196,21 @@ func (cfg *Config) FromEnv() error {
 		cfg.Global.SecretsDirectory = "" //Dir does not exist, set to empty string
 	}
 
-	if v := os.Getenv("VSPHERE_CAFILE"); v != "" {
+	if v := env.Getenv("VSPHERE_CAFILE"); v != "" {
 		cfg.Global.CAFile = v
 	}
-	if v := os.Getenv("VSPHERE_THUMBPRINT"); v != "" {
+	if v := env.Getenv("VSPHERE_THUMBPRINT"); v != "" {
 		cfg.Global.Thumbprint = v
 	}
-	if v := os.Getenv("VSPHERE_LABEL_REGION"); v != "" {
+	if v := env.Getenv("VSPHERE_LABEL_REGION"); v != "" {
 		cfg.Labels.Region = v
 	}
-	if v := os.Getenv("VSPHERE_LABEL_ZONE"); v != "" {
+	if v := env.Getenv("VSPHERE_LABEL_ZONE"); v != "" {
 		cfg.Labels.Zone = v
 	}
 
 	//Build VirtualCenter from ENVs
-	for _, e := range os.Environ() {
+	for _, e := range env.Environ() {
 		pair := strings.Split(e, "=")
 
 		if len(pair) != 2 {
@@ -143,57 +220,82 @@ func (cfg *Config) FromEnv() error {
 		key := pair[0]
 		value := pair[1]
 
-		if strings.HasPrefix(key, "VSPHERE_VCENTER_") && len(value) > 0 {
+		// VSPHERE_VCENTER_PORT and the keys of the YAML mapping are not vCenters
+		if strings.HasPrefix(key, "VSPHERE_VCENTER_") && len(value) > 0 &&
+			key != "VSPHERE_VCENTER_PORT" && !isVCenterEnvVar(key) {
 			id := strings.TrimPrefix(key, "VSPHERE_VCENTER_")
 			vcenter := value
 
-			_, username, errUsername := getEnvKeyValue("VCENTER_"+id+"_USERNAME", false)
+			_, username, errUsername := getEnvKeyValue(env, "VCENTER_"+id+"_USERNAME", false)
 			if errUsername != nil {
 				username = cfg.Global.User
 			}
-			_, password, errPassword := getEnvKeyValue("VCENTER_"+id+"_PASSWORD", false)
+			_, password, errPassword := getEnvKeyValue(env, "VCENTER_"+id+"_PASSWORD", false)
 			if errPassword != nil {
 				password = cfg.Global.Password
 			}
-			_, server, errServer := getEnvKeyValue("VCENTER_"+id+"_SERVER", false)
+			_, server, errServer := getEnvKeyValue(env, "VCENTER_"+id+"_SERVER", false)
 			if errServer != nil {
 				server = ""
 			}
-			_, port, errPort := getEnvKeyValue("VCENTER_"+id+"_PORT", false)
+			_, port, errPort := getEnvKeyValue(env, "VCENTER_"+id+"_PORT", false)
 			if errPort != nil {
 				port = cfg.Global.VCenterPort
 			}
 			insecureFlag := false
-			_, insecureTmp, errInsecure := getEnvKeyValue("VCENTER_"+id+"_INSECURE", false)
+			_, insecureTmp, errInsecure := getEnvKeyValue(env, "VCENTER_"+id+"_INSECURE", false)
 			if errInsecure != nil {
 				insecureFlagTmp, errTmp := strconv.ParseBool(insecureTmp)
 				if errTmp == nil {
 					insecureFlag = insecureFlagTmp
 				}
 			}
-			_, datacenters, errDatacenters := getEnvKeyValue("VCENTER_"+id+"_DATACENTERS", false)
+			_, datacenters, errDatacenters := getEnvKeyValue(env, "VCENTER_"+id+"_DATACENTERS", false)
 			if errDatacenters != nil {
 				datacenters = cfg.Global.Datacenters
 			}
 			roundtrip := DefaultRoundTripperCount
-			_, roundtripTmp, errRoundtrip := getEnvKeyValue("VCENTER_"+id+"_ROUNDTRIP", false)
+			_, roundtripTmp, errRoundtrip := getEnvKeyValue(env, "VCENTER_"+id+"_ROUNDTRIP", false)
 			if errRoundtrip != nil {
 				roundtripFlagTmp, errTmp := strconv.ParseUint(roundtripTmp, 10, 32)
 				if errTmp == nil {
 					roundtrip = uint(roundtripFlagTmp)
 				}
 			}
-			_, caFile, errCaFile := getEnvKeyValue("VCENTER_"+id+"_CAFILE", false)
+			apiQPS := cfg.Global.APIQPS
+			if _, v, err := getEnvKeyValue(env, "VCENTER_"+id+"_API_QPS", false); err == nil {
+				if tmp, errTmp := strconv.ParseFloat(v, 64); errTmp == nil {
+					apiQPS = tmp
+				}
+			}
+			apiBurst := cfg.Global.APIBurst
+			if _, v, err := getEnvKeyValue(env, "VCENTER_"+id+"_API_BURST", false); err == nil {
+				if tmp, errTmp := strconv.Atoi(v); errTmp == nil {
+					apiBurst = tmp
+				}
+			}
+			maxConcurrentRequests := cfg.Global.MaxConcurrentRequests
+			if _, v, err := getEnvKeyValue(env, "VCENTER_"+id+"_MAX_CONCURRENT_REQUESTS", false); err == nil {
+				if tmp, errTmp := strconv.Atoi(v); errTmp == nil {
+					maxConcurrentRequests = tmp
+				}
+			}
+			_, caFile, errCaFile := getEnvKeyValue(env, "VCENTER_"+id+"_CAFILE", false)
 			if errCaFile != nil {
 				caFile = cfg.Global.CAFile
 			}
-			_, thumbprint, errThumbprint := getEnvKeyValue("VCENTER_"+id+"_THUMBPRINT", false)
+			_, thumbprint, errThumbprint := getEnvKeyValue(env, "VCENTER_"+id+"_THUMBPRINT", false)
 			if errThumbprint != nil {
 				thumbprint = cfg.Global.Thumbprint
 			}
 
-			_, secretName, secretNameErr := getEnvKeyValue("VCENTER_"+id+"_SECRET_NAME", false)
-			_, secretNamespace, secretNamespaceErr := getEnvKeyValue("VCENTER_"+id+"_SECRET_NAMESPACE", false)
+			_, secretKeyPrefix, errSecretKeyPrefix := getEnvKeyValue(env, "VCENTER_"+id+"_SECRET_KEY_PREFIX", false)
+			if errSecretKeyPrefix != nil {
+				secretKeyPrefix = ""
+			}
+
+			_, secretName, secretNameErr := getEnvKeyValue(env, "VCENTER_"+id+"_SECRET_NAME", false)
+			_, secretNamespace, secretNamespaceErr := getEnvKeyValue(env, "VCENTER_"+id+"_SECRET_NAMESPACE", false)
 
 			if secretNameErr != nil || secretNamespaceErr != nil {
 				secretName = ""
@@ -205,7 +307,7 @@ func (cfg *Config) FromEnv() error {
 			}
 
 			iPFamilyPriority := []string{DefaultIPFamily}
-			_, ipFamily, errIPFamily := getEnvKeyValue("VCENTER_"+id+"_IP_FAMILY", false)
+			_, ipFamily, errIPFamily := getEnvKeyValue(env, "VCENTER_"+id+"_IP_FAMILY", false)
 			if errIPFamily != nil {
 				iPFamilyPriority = []string{ipFamily}
 			}
@@ -234,11 +336,15 @@ func (cfg *Config) FromEnv() error {
 			vcc.InsecureFlag = insecureFlag
 			vcc.Datacenters = datacenters
 			vcc.RoundTripperCount = roundtrip
+			vcc.APIQPS = apiQPS
+			vcc.APIBurst = apiBurst
+			vcc.MaxConcurrentRequests = maxConcurrentRequests
 			vcc.CAFile = caFile
 			vcc.Thumbprint = thumbprint
 			vcc.SecretRef = secretRef
 			vcc.SecretName = secretName
 			vcc.SecretNamespace = secretNamespace
+			vcc.SecretKeyPrefix = secretKeyPrefix
 			vcc.IPFamilyPriority = iPFamilyPriority
 		}
 	}
@@ -283,3 +389,12 @@ func ReadConfig(byConfig []byte) (*Config, error) {
 	klog.Info("Config initialized")
 	return cfg, nil
 }
+
+// CredentialKey returns the name under which the credentials of the vCenter
+// are found in the secret holding them.
+func (vcc *VirtualCenterConfig) CredentialKey() string {
+	if vcc.SecretKeyPrefix != "" {
+		return vcc.SecretKeyPrefix
+	}
+	return vcc.VCenterIP
+}
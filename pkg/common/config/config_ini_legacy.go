@@ -42,7 +42,12 @@ func (cci *CommonConfigINI) CreateConfig() *Config {
 	cfg.Global.VCenterPort = cci.Global.VCenterPort
 	cfg.Global.InsecureFlag = cci.Global.InsecureFlag
 	cfg.Global.Datacenters = cci.Global.Datacenters
+	cfg.Global.VMFolders = cci.Global.VMFolders
+	cfg.Global.ResourcePools = cci.Global.ResourcePools
 	cfg.Global.RoundTripperCount = cci.Global.RoundTripperCount
+	cfg.Global.APIQPS = cci.Global.APIQPS
+	cfg.Global.APIBurst = cci.Global.APIBurst
+	cfg.Global.MaxConcurrentRequests = cci.Global.MaxConcurrentRequests
 	cfg.Global.CAFile = cci.Global.CAFile
 	cfg.Global.Thumbprint = cci.Global.Thumbprint
 	cfg.Global.SecretName = cci.Global.SecretName
@@ -51,26 +56,41 @@ func (cci *CommonConfigINI) CreateConfig() *Config {
 
 	for keyVcConfig, valVcConfig := range cci.VirtualCenter {
 		cfg.VirtualCenter[keyVcConfig] = &VirtualCenterConfig{
-			User:              valVcConfig.User,
-			Password:          valVcConfig.Password,
-			TenantRef:         valVcConfig.TenantRef,
-			VCenterIP:         valVcConfig.VCenterIP,
-			VCenterPort:       valVcConfig.VCenterPort,
-			InsecureFlag:      valVcConfig.InsecureFlag,
-			Datacenters:       valVcConfig.Datacenters,
-			RoundTripperCount: valVcConfig.RoundTripperCount,
-			CAFile:            valVcConfig.CAFile,
-			Thumbprint:        valVcConfig.Thumbprint,
-			SecretRef:         valVcConfig.SecretRef,
-			SecretName:        valVcConfig.SecretName,
-			SecretNamespace:   valVcConfig.SecretNamespace,
-			IPFamilyPriority:  valVcConfig.IPFamilyPriority,
+			User:                  valVcConfig.User,
+			Password:              valVcConfig.Password,
+			TenantRef:             valVcConfig.TenantRef,
+			VCenterIP:             valVcConfig.VCenterIP,
+			VCenterPort:           valVcConfig.VCenterPort,
+			InsecureFlag:          valVcConfig.InsecureFlag,
+			Datacenters:           valVcConfig.Datacenters,
+			VMFolders:             valVcConfig.VMFolders,
+			ResourcePools:         valVcConfig.ResourcePools,
+			RoundTripperCount:     valVcConfig.RoundTripperCount,
+			APIQPS:                valVcConfig.APIQPS,
+			APIBurst:              valVcConfig.APIBurst,
+			MaxConcurrentRequests: valVcConfig.MaxConcurrentRequests,
+			CAFile:                valVcConfig.CAFile,
+			Thumbprint:            valVcConfig.Thumbprint,
+			SecretRef:             valVcConfig.SecretRef,
+			SecretName:            valVcConfig.SecretName,
+			SecretNamespace:       valVcConfig.SecretNamespace,
+			SecretKeyPrefix:       valVcConfig.SecretKeyPrefix,
+			IPFamilyPriority:      valVcConfig.IPFamilyPriority,
 		}
 	}
 
 	cfg.Labels.Region = cci.Labels.Region
 	cfg.Labels.Zone = cci.Labels.Zone
 
+	cfg.CredentialProvider = CredentialProvider{
+		Type:         cci.CredentialProvider.Type,
+		Command:      cci.CredentialProvider.Command,
+		Args:         cci.CredentialProvider.Args,
+		Timeout:      cci.CredentialProvider.Timeout,
+		UsernameFile: cci.CredentialProvider.UsernameFile,
+		PasswordFile: cci.CredentialProvider.PasswordFile,
+	}
+
 	return cfg
 }
 
@@ -101,8 +121,10 @@ func (vcci *VirtualCenterConfigINI) validateIPFamily() error {
 
 // isSecretInfoProvided returns true if k8s secret is set or using generic CO secret method.
 // If both k8s secret and generic CO both are true, we don't know which to use, so return false.
+// An external credential provider takes precedence over both.
 func (cci *CommonConfigINI) isSecretInfoProvided() bool {
-	return (cci.Global.SecretName != "" && cci.Global.SecretNamespace != "" && cci.Global.SecretsDirectory == "") ||
+	return cci.CredentialProvider.Type != "" ||
+		(cci.Global.SecretName != "" && cci.Global.SecretNamespace != "" && cci.Global.SecretsDirectory == "") ||
 		(cci.Global.SecretName == "" && cci.Global.SecretNamespace == "" && cci.Global.SecretsDirectory != "")
 }
 
@@ -130,23 +152,34 @@ func (cci *CommonConfigINI) validateConfig() error {
 	// VirtualCenter does not already exist in the map
 	if cci.Global.VCenterIP != "" && cci.VirtualCenter[cci.Global.VCenterIP] == nil {
 		cci.VirtualCenter[cci.Global.VCenterIP] = &VirtualCenterConfigINI{
-			User:              cci.Global.User,
-			Password:          cci.Global.Password,
-			TenantRef:         cci.Global.VCenterIP,
-			VCenterIP:         cci.Global.VCenterIP,
-			VCenterPort:       cci.Global.VCenterPort,
-			InsecureFlag:      cci.Global.InsecureFlag,
-			Datacenters:       cci.Global.Datacenters,
-			RoundTripperCount: cci.Global.RoundTripperCount,
-			CAFile:            cci.Global.CAFile,
-			Thumbprint:        cci.Global.Thumbprint,
-			SecretRef:         DefaultCredentialManager,
-			SecretName:        cci.Global.SecretName,
-			SecretNamespace:   cci.Global.SecretNamespace,
-			IPFamily:          cci.Global.IPFamily,
+			User:                  cci.Global.User,
+			Password:              cci.Global.Password,
+			TenantRef:             cci.Global.VCenterIP,
+			VCenterIP:             cci.Global.VCenterIP,
+			VCenterPort:           cci.Global.VCenterPort,
+			InsecureFlag:          cci.Global.InsecureFlag,
+			Datacenters:           cci.Global.Datacenters,
+			VMFolders:             cci.Global.VMFolders,
+			ResourcePools:         cci.Global.ResourcePools,
+			RoundTripperCount:     cci.Global.RoundTripperCount,
+			APIQPS:                cci.Global.APIQPS,
+			APIBurst:              cci.Global.APIBurst,
+			MaxConcurrentRequests: cci.Global.MaxConcurrentRequests,
+			CAFile:                cci.Global.CAFile,
+			Thumbprint:            cci.Global.Thumbprint,
+			SecretRef:             DefaultCredentialManager,
+			SecretName:            cci.Global.SecretName,
+			SecretNamespace:       cci.Global.SecretNamespace,
+			IPFamily:              cci.Global.IPFamily,
 		}
 	}
 
+	if err := validateCredentialProvider(cci.CredentialProvider.Type, cci.CredentialProvider.Command,
+		cci.CredentialProvider.Timeout, cci.CredentialProvider.UsernameFile, cci.CredentialProvider.PasswordFile); err != nil {
+		klog.Error(err)
+		return err
+	}
+
 	// Must have at least one vCenter defined
 	if len(cci.VirtualCenter) == 0 {
 		klog.Error(ErrMissingVCenter)
@@ -201,9 +234,27 @@ func (cci *CommonConfigINI) validateConfig() error {
 				vcConfig.Datacenters = cci.Global.Datacenters
 			}
 		}
+		// the search scope is inherited as a whole
+		if vcConfig.VMFolders == "" && vcConfig.ResourcePools == "" {
+			vcConfig.VMFolders = cci.Global.VMFolders
+			vcConfig.ResourcePools = cci.Global.ResourcePools
+		}
 		if vcConfig.RoundTripperCount == 0 {
 			vcConfig.RoundTripperCount = cci.Global.RoundTripperCount
 		}
+		if vcConfig.APIQPS == 0 {
+			vcConfig.APIQPS = cci.Global.APIQPS
+		}
+		if vcConfig.APIBurst == 0 {
+			vcConfig.APIBurst = cci.Global.APIBurst
+		}
+		if vcConfig.MaxConcurrentRequests == 0 {
+			vcConfig.MaxConcurrentRequests = cci.Global.MaxConcurrentRequests
+		}
+		if err := validateRateLimit(vcConfig.APIQPS, vcConfig.APIBurst, vcConfig.MaxConcurrentRequests); err != nil {
+			klog.Errorf("Invalid rate limit for vc %s: %s", vcServer, err)
+			return err
+		}
 		if vcConfig.CAFile == "" {
 			vcConfig.CAFile = cci.Global.CAFile
 		}
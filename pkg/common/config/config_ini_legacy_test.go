@@ -218,3 +218,55 @@ func TestTenantRefsINI(t *testing.T) {
 		t.Errorf("vcConfig3 SecretRef should be kube-system/eu-secret but actual=%s", vcConfig3.SecretRef)
 	}
 }
+
+const credentialProviderConfigINI = `
+[Global]
+port = 443
+insecure-flag = true
+
+[VirtualCenter "10.0.0.1"]
+datacenters = "vic0dc"
+
+[CredentialProvider]
+type = fileTemplate
+username-file = "/vault/secrets/{{.Server}}/username"
+password-file = "/vault/secrets/{{.Server}}/password"
+`
+
+func TestCredentialProviderINI(t *testing.T) {
+	cfg, err := ReadConfigINI([]byte(credentialProviderConfigINI))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	if cfg.CredentialProvider.Type != CredentialProviderFileTemplate {
+		t.Errorf("incorrect credential provider type: %s", cfg.CredentialProvider.Type)
+	}
+	if cfg.CredentialProvider.PasswordFile != "/vault/secrets/{{.Server}}/password" {
+		t.Errorf("incorrect credential provider password file: %s", cfg.CredentialProvider.PasswordFile)
+	}
+	if cfg.VirtualCenter["10.0.0.1"].SecretRef != DefaultCredentialManager {
+		t.Errorf("vcConfig SecretRef should be %s but actual=%s", DefaultCredentialManager, cfg.VirtualCenter["10.0.0.1"].SecretRef)
+	}
+}
+
+func TestRateLimitINI(t *testing.T) {
+	cfg, err := ReadConfigINI([]byte(`
+[Global]
+user = user
+password = password
+api-qps = 2.5
+max-concurrent-requests = 4
+
+[VirtualCenter "10.0.0.1"]
+api-burst = 10
+`))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	vcConfig := cfg.VirtualCenter["10.0.0.1"]
+	if vcConfig.APIQPS != 2.5 || vcConfig.APIBurst != 10 || vcConfig.MaxConcurrentRequests != 4 {
+		t.Errorf("incorrect rate limit: %v/%d/%d", vcConfig.APIQPS, vcConfig.APIBurst, vcConfig.MaxConcurrentRequests)
+	}
+}
@@ -43,7 +43,12 @@ func (ccy *CommonConfigYAML) CreateConfig() *Config {
 	cfg.Global.VCenterPort = fmt.Sprint(ccy.Global.VCenterPort)
 	cfg.Global.InsecureFlag = ccy.Global.InsecureFlag
 	cfg.Global.Datacenters = strings.Join(ccy.Global.Datacenters, ",")
+	cfg.Global.VMFolders = strings.Join(ccy.Global.VMFolders, ",")
+	cfg.Global.ResourcePools = strings.Join(ccy.Global.ResourcePools, ",")
 	cfg.Global.RoundTripperCount = ccy.Global.RoundTripperCount
+	cfg.Global.APIQPS = ccy.Global.APIQPS
+	cfg.Global.APIBurst = ccy.Global.APIBurst
+	cfg.Global.MaxConcurrentRequests = ccy.Global.MaxConcurrentRequests
 	cfg.Global.CAFile = ccy.Global.CAFile
 	cfg.Global.Thumbprint = ccy.Global.Thumbprint
 	cfg.Global.SecretName = ccy.Global.SecretName
@@ -52,33 +57,51 @@ func (ccy *CommonConfigYAML) CreateConfig() *Config {
 
 	for keyVcConfig, valVcConfig := range ccy.Vcenter {
 		cfg.VirtualCenter[keyVcConfig] = &VirtualCenterConfig{
-			User:              valVcConfig.User,
-			Password:          valVcConfig.Password,
-			TenantRef:         valVcConfig.TenantRef,
-			VCenterIP:         valVcConfig.VCenterIP,
-			VCenterPort:       fmt.Sprint(valVcConfig.VCenterPort),
-			InsecureFlag:      valVcConfig.InsecureFlag,
-			Datacenters:       strings.Join(valVcConfig.Datacenters, ","),
-			RoundTripperCount: valVcConfig.RoundTripperCount,
-			CAFile:            valVcConfig.CAFile,
-			Thumbprint:        valVcConfig.Thumbprint,
-			SecretRef:         valVcConfig.SecretRef,
-			SecretName:        valVcConfig.SecretName,
-			SecretNamespace:   valVcConfig.SecretNamespace,
-			IPFamilyPriority:  valVcConfig.IPFamilyPriority,
+			User:                  valVcConfig.User,
+			Password:              valVcConfig.Password,
+			TenantRef:             valVcConfig.TenantRef,
+			VCenterIP:             valVcConfig.VCenterIP,
+			VCenterPort:           fmt.Sprint(valVcConfig.VCenterPort),
+			InsecureFlag:          valVcConfig.InsecureFlag,
+			Datacenters:           strings.Join(valVcConfig.Datacenters, ","),
+			VMFolders:             strings.Join(valVcConfig.VMFolders, ","),
+			ResourcePools:         strings.Join(valVcConfig.ResourcePools, ","),
+			RoundTripperCount:     valVcConfig.RoundTripperCount,
+			APIQPS:                valVcConfig.APIQPS,
+			APIBurst:              valVcConfig.APIBurst,
+			MaxConcurrentRequests: valVcConfig.MaxConcurrentRequests,
+			CAFile:                valVcConfig.CAFile,
+			Thumbprint:            valVcConfig.Thumbprint,
+			SecretRef:             valVcConfig.SecretRef,
+			SecretName:            valVcConfig.SecretName,
+			SecretNamespace:       valVcConfig.SecretNamespace,
+			SecretKeyPrefix:       valVcConfig.SecretKeyPrefix,
+			IPFamilyPriority:      valVcConfig.IPFamilyPriority,
+			Nodes:                 Nodes(valVcConfig.Nodes),
 		}
 	}
 
 	cfg.Labels.Region = ccy.Labels.Region
 	cfg.Labels.Zone = ccy.Labels.Zone
 
+	cfg.CredentialProvider = CredentialProvider{
+		Type:         ccy.CredentialProvider.Type,
+		Command:      ccy.CredentialProvider.Command,
+		Args:         ccy.CredentialProvider.Args,
+		Timeout:      ccy.CredentialProvider.Timeout,
+		UsernameFile: ccy.CredentialProvider.UsernameFile,
+		PasswordFile: ccy.CredentialProvider.PasswordFile,
+	}
+
 	return cfg
 }
 
 // isSecretInfoProvided returns true if k8s secret is set or using generic CO secret method.
 // If both k8s secret and generic CO both are true, we don't know which to use, so return false.
+// An external credential provider takes precedence over both.
 func (ccy *CommonConfigYAML) isSecretInfoProvided() bool {
-	return (ccy.Global.SecretName != "" && ccy.Global.SecretNamespace != "" && ccy.Global.SecretsDirectory == "") ||
+	return ccy.CredentialProvider.Type != "" ||
+		(ccy.Global.SecretName != "" && ccy.Global.SecretNamespace != "" && ccy.Global.SecretsDirectory == "") ||
 		(ccy.Global.SecretName == "" && ccy.Global.SecretNamespace == "" && ccy.Global.SecretsDirectory != "")
 }
 
@@ -106,23 +129,34 @@ func (ccy *CommonConfigYAML) validateConfig() error {
 	// VirtualCenter does not already exist in the map
 	if ccy.Global.VCenterIP != "" && ccy.Vcenter[ccy.Global.VCenterIP] == nil {
 		ccy.Vcenter[ccy.Global.VCenterIP] = &VirtualCenterConfigYAML{
-			User:              ccy.Global.User,
-			Password:          ccy.Global.Password,
-			TenantRef:         ccy.Global.VCenterIP,
-			VCenterIP:         ccy.Global.VCenterIP,
-			VCenterPort:       ccy.Global.VCenterPort,
-			InsecureFlag:      ccy.Global.InsecureFlag,
-			Datacenters:       ccy.Global.Datacenters,
-			RoundTripperCount: ccy.Global.RoundTripperCount,
-			CAFile:            ccy.Global.CAFile,
-			Thumbprint:        ccy.Global.Thumbprint,
-			SecretRef:         DefaultCredentialManager,
-			SecretName:        ccy.Global.SecretName,
-			SecretNamespace:   ccy.Global.SecretNamespace,
-			IPFamilyPriority:  ccy.Global.IPFamilyPriority,
+			User:                  ccy.Global.User,
+			Password:              ccy.Global.Password,
+			TenantRef:             ccy.Global.VCenterIP,
+			VCenterIP:             ccy.Global.VCenterIP,
+			VCenterPort:           ccy.Global.VCenterPort,
+			InsecureFlag:          ccy.Global.InsecureFlag,
+			Datacenters:           ccy.Global.Datacenters,
+			VMFolders:             ccy.Global.VMFolders,
+			ResourcePools:         ccy.Global.ResourcePools,
+			RoundTripperCount:     ccy.Global.RoundTripperCount,
+			APIQPS:                ccy.Global.APIQPS,
+			APIBurst:              ccy.Global.APIBurst,
+			MaxConcurrentRequests: ccy.Global.MaxConcurrentRequests,
+			CAFile:                ccy.Global.CAFile,
+			Thumbprint:            ccy.Global.Thumbprint,
+			SecretRef:             DefaultCredentialManager,
+			SecretName:            ccy.Global.SecretName,
+			SecretNamespace:       ccy.Global.SecretNamespace,
+			IPFamilyPriority:      ccy.Global.IPFamilyPriority,
 		}
 	}
 
+	if err := validateCredentialProvider(ccy.CredentialProvider.Type, ccy.CredentialProvider.Command,
+		ccy.CredentialProvider.Timeout, ccy.CredentialProvider.UsernameFile, ccy.CredentialProvider.PasswordFile); err != nil {
+		klog.Error(err)
+		return err
+	}
+
 	// Must have at least one vCenter defined
 	if len(ccy.Vcenter) == 0 {
 		klog.Error(ErrMissingVCenter)
@@ -170,9 +204,27 @@ func (ccy *CommonConfigYAML) validateConfig() error {
 				vcConfig.Datacenters = ccy.Global.Datacenters
 			}
 		}
+		// the search scope is inherited as a whole
+		if len(vcConfig.VMFolders) == 0 && len(vcConfig.ResourcePools) == 0 {
+			vcConfig.VMFolders = ccy.Global.VMFolders
+			vcConfig.ResourcePools = ccy.Global.ResourcePools
+		}
 		if vcConfig.RoundTripperCount == 0 {
 			vcConfig.RoundTripperCount = ccy.Global.RoundTripperCount
 		}
+		if vcConfig.APIQPS == 0 {
+			vcConfig.APIQPS = ccy.Global.APIQPS
+		}
+		if vcConfig.APIBurst == 0 {
+			vcConfig.APIBurst = ccy.Global.APIBurst
+		}
+		if vcConfig.MaxConcurrentRequests == 0 {
+			vcConfig.MaxConcurrentRequests = ccy.Global.MaxConcurrentRequests
+		}
+		if err := validateRateLimit(vcConfig.APIQPS, vcConfig.APIBurst, vcConfig.MaxConcurrentRequests); err != nil {
+			klog.Errorf("Invalid rate limit for vc %s: %s", tenantRef, err)
+			return err
+		}
 		if vcConfig.CAFile == "" {
 			vcConfig.CAFile = ccy.Global.CAFile
 		}
@@ -195,6 +247,12 @@ func (ccy *CommonConfigYAML) validateConfig() error {
 
 // ReadRawConfigYAML parses vSphere cloud config file and stores it into ConfigYAML
 func ReadRawConfigYAML(byConfig []byte) (*CommonConfigYAML, error) {
+	return ReadRawConfigYAMLWithEnv(byConfig, OSEnv)
+}
+
+// ReadRawConfigYAMLWithEnv parses the config like ReadRawConfigYAML, with the
+// variables of env overriding its keys.
+func ReadRawConfigYAMLWithEnv(byConfig []byte, env Env) (*CommonConfigYAML, error) {
 	if len(byConfig) == 0 {
 		klog.Errorf("Invalid YAML file")
 		return nil, fmt.Errorf("Invalid YAML file")
@@ -209,6 +267,12 @@ func ReadRawConfigYAML(byConfig []byte) (*CommonConfigYAML, error) {
 		return nil, err
 	}
 
+	// Env Vars override the keys of the file before the vCenters inherit from Global
+	if err := env.Apply(EnvPrefix, &cfg); err != nil {
+		klog.Errorf("ApplyEnv failed: %s", err)
+		return nil, err
+	}
+
 	err := cfg.validateConfig()
 	if err != nil {
 		klog.Errorf("validateConfig failed: %s", err)
@@ -17,6 +17,7 @@ limitations under the License.
 package config
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -137,3 +138,129 @@ func TestTenantRefsYAML(t *testing.T) {
 		t.Errorf("vcConfig3 SecretRef should be kube-system/eu-secret but actual=%s", vcConfig3.SecretRef)
 	}
 }
+
+const credentialProviderConfigYAML = `
+global:
+  port: 443
+  insecureFlag: true
+
+vcenter:
+  tenant1:
+    server: 10.0.0.1
+    datacenters:
+      - vic0dc
+
+credentialProvider:
+  type: exec
+  command: /usr/local/bin/vault-creds
+  args:
+    - --role
+    - ccm
+  timeout: 10s
+`
+
+func TestCredentialProviderYAML(t *testing.T) {
+	cfg, err := ReadConfigYAML([]byte(credentialProviderConfigYAML))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	if cfg.CredentialProvider.Type != CredentialProviderExec {
+		t.Errorf("incorrect credential provider type: %s", cfg.CredentialProvider.Type)
+	}
+	if cfg.CredentialProvider.Command != "/usr/local/bin/vault-creds" {
+		t.Errorf("incorrect credential provider command: %s", cfg.CredentialProvider.Command)
+	}
+	if len(cfg.CredentialProvider.Args) != 2 || cfg.CredentialProvider.Args[1] != "ccm" {
+		t.Errorf("incorrect credential provider args: %v", cfg.CredentialProvider.Args)
+	}
+	if cfg.VirtualCenter["tenant1"].SecretRef != DefaultCredentialManager {
+		t.Errorf("vcConfig SecretRef should be %s but actual=%s", DefaultCredentialManager, cfg.VirtualCenter["tenant1"].SecretRef)
+	}
+
+	invalid := strings.Replace(credentialProviderConfigYAML, "type: exec", "type: vault", 1)
+	if _, err := ReadConfigYAML([]byte(invalid)); err == nil {
+		t.Error("Should fail when an unknown credential provider type is provided")
+	}
+
+	missingFiles := strings.Replace(credentialProviderConfigYAML, "type: exec", "type: fileTemplate", 1)
+	if _, err := ReadConfigYAML([]byte(missingFiles)); err == nil {
+		t.Error("Should fail when the fileTemplate provider has no usernameFile and passwordFile")
+	}
+
+	invalidTemplate := strings.Replace(credentialProviderConfigYAML, "type: exec", `type: fileTemplate
+  usernameFile: /etc/creds/{{.Server}}/username
+  passwordFile: /etc/creds/{{.Server/password`, 1)
+	if _, err := ReadConfigYAML([]byte(invalidTemplate)); err == nil {
+		t.Error("Should fail when the fileTemplate provider has an invalid passwordFile template")
+	}
+}
+
+func TestSecretKeyPrefixYAML(t *testing.T) {
+	cfg, err := ReadConfigYAML([]byte(`
+global:
+  secretName: vsphere-creds
+  secretNamespace: kube-system
+
+vcenter:
+  site-a:
+    server: 10.0.0.1
+    secretKeyPrefix: site-a
+  site-b:
+    server: 10.0.0.2
+`))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	if key := cfg.VirtualCenter["site-a"].CredentialKey(); key != "site-a" {
+		t.Errorf("incorrect credential key for site-a: %s", key)
+	}
+	if key := cfg.VirtualCenter["site-b"].CredentialKey(); key != "10.0.0.2" {
+		t.Errorf("incorrect credential key for site-b: %s", key)
+	}
+}
+
+func TestRateLimitYAML(t *testing.T) {
+	cfg, err := ReadConfigYAML([]byte(`
+global:
+  user: user
+  password: password
+  apiQPS: 20
+  apiBurst: 40
+
+vcenter:
+  tenant1:
+    server: 10.0.0.1
+    maxConcurrentRequests: 8
+  tenant2:
+    server: 10.0.0.2
+    apiQPS: 5
+`))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	vcConfig := cfg.VirtualCenter["tenant1"]
+	if vcConfig.APIQPS != 20 || vcConfig.APIBurst != 40 || vcConfig.MaxConcurrentRequests != 8 {
+		t.Errorf("incorrect rate limit for tenant1: %v/%d/%d", vcConfig.APIQPS, vcConfig.APIBurst, vcConfig.MaxConcurrentRequests)
+	}
+	vcConfig = cfg.VirtualCenter["tenant2"]
+	if vcConfig.APIQPS != 5 || vcConfig.APIBurst != 40 || vcConfig.MaxConcurrentRequests != 0 {
+		t.Errorf("incorrect rate limit for tenant2: %v/%d/%d", vcConfig.APIQPS, vcConfig.APIBurst, vcConfig.MaxConcurrentRequests)
+	}
+
+	_, err = ReadConfigYAML([]byte(`
+global:
+  user: user
+  password: password
+
+vcenter:
+  tenant1:
+    server: 10.0.0.1
+    maxConcurrentRequests: -1
+`))
+	if !errors.Is(err, ErrInvalidRateLimit) {
+		t.Errorf("Should fail with ErrInvalidRateLimit when maxConcurrentRequests is negative, got %v", err)
+	}
+}
@@ -47,6 +47,11 @@ const (
 
 	// DefaultCredentialManager used for the Global CredMgr/Lister
 	DefaultCredentialManager string = "Global"
+
+	// CredentialProviderExec runs a command to obtain vCenter credentials
+	CredentialProviderExec = "exec"
+	// CredentialProviderFileTemplate reads vCenter credentials from templated file paths
+	CredentialProviderFileTemplate = "fileTemplate"
 )
 
 var (
@@ -66,4 +71,12 @@ var (
 
 	// ErrInvalidIPFamilyType is returned when an invalid IPFamily type is encountered
 	ErrInvalidIPFamilyType = errors.New("Invalid IP Family type")
+
+	// ErrInvalidCredentialProvider is returned when the credential provider type
+	// is unknown or misses its required settings.
+	ErrInvalidCredentialProvider = errors.New("Invalid credential provider")
+
+	// ErrInvalidRateLimit is returned when the API rate limit or the concurrency
+	// cap of a vCenter is negative.
+	ErrInvalidRateLimit = errors.New("Invalid vCenter API rate limit")
 )
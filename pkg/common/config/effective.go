@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Redacted replaces the values of the secret keys in MarshalRedacted
+const Redacted = "<redacted>"
+
+// secretKeys are the YAML keys holding credentials
+var secretKeys = map[string]bool{
+	"password":       true,
+	"vmcAccessToken": true,
+}
+
+// MarshalRedacted marshals in, a YAML config struct, with the non-empty values
+// of the secret keys replaced by Redacted. Only the keys listed by EnvVars are
+// written, the fields derived by the config packages are left out.
+func MarshalRedacted(in interface{}) ([]byte, error) {
+	v := reflect.ValueOf(in)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return yaml.Marshal(redactedValue(v))
+}
+
+func redactedValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return redactedValue(v.Elem())
+	case reflect.Struct:
+		return redactedStruct(v, yaml.MapSlice{})
+	case reflect.Map:
+		if _, ok := entryType(v.Type()); !ok {
+			return v.Interface()
+		}
+		if v.IsNil() {
+			return nil
+		}
+		keys := v.MapKeys()
+		names := make([]string, 0, len(keys))
+		for _, key := range keys {
+			names = append(names, key.String())
+		}
+		sort.Strings(names)
+		entries := yaml.MapSlice{}
+		for _, name := range names {
+			entries = append(entries, yaml.MapItem{Key: name, Value: redactedValue(v.MapIndex(reflect.ValueOf(name)))})
+		}
+		return entries
+	}
+	return v.Interface()
+}
+
+func redactedStruct(v reflect.Value, out yaml.MapSlice) yaml.MapSlice {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, inline := yamlField(t.Field(i))
+		if inline {
+			out = redactedStruct(v.Field(i), out)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		var value interface{}
+		if secretKeys[key] && v.Field(i).Kind() == reflect.String && v.Field(i).String() != "" {
+			value = Redacted
+		} else {
+			value = redactedValue(v.Field(i))
+		}
+		out = append(out, yaml.MapItem{Key: key, Value: value})
+	}
+	return out
+}
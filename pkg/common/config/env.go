@@ -0,0 +1,394 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// EnvPrefix is the prefix of the environment variables that override the keys
+// of the YAML cloud-config. A key is mapped to <EnvPrefix>_<SECTION>_<KEY>, and
+// the key of a map entry to <EnvPrefix>_<SECTION>_<NAME>_<KEY>, all in upper
+// snake case, e.g. VSPHERE_GLOBAL_INSECURE_FLAG or VSPHERE_VCENTER_TENANT_A_DATACENTERS.
+const EnvPrefix = "VSPHERE"
+
+// EnvEntryName is the placeholder of the map entry name in EnvVar.Name
+const EnvEntryName = "<NAME>"
+
+// EnvVar describes the environment variable overriding a YAML key
+type EnvVar struct {
+	// Name of the variable
+	Name string
+	// Path of the YAML key, dot separated
+	Path string
+	// Type of the value
+	Type string
+}
+
+// Env is the source of the environment variables overriding the keys of the
+// cloud-config. The config readers without an Env read OSEnv.
+type Env struct {
+	// Getenv returns the value of a variable, empty if it is not set
+	Getenv func(string) string
+	// Environ returns all variables as key=value pairs
+	Environ func() []string
+}
+
+// OSEnv reads the environment of the process
+var OSEnv = Env{Getenv: os.Getenv, Environ: os.Environ}
+
+// NoEnv has no variables, a cloud-config is read as written
+var NoEnv = Env{
+	Getenv:  func(string) string { return "" },
+	Environ: func() []string { return nil },
+}
+
+// EnvName converts a YAML key to upper snake case, e.g. lbServiceId to LB_SERVICE_ID
+func EnvName(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// envEntryName converts the name of a map entry to its form in variable names
+func envEntryName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// yamlField returns the YAML key of a struct field, and whether the field is
+// inlined. Untagged scalar fields are derived by the config packages, they are
+// not settings and have no key.
+func yamlField(field reflect.StructField) (string, bool) {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")
+	if tag[0] == "-" || field.PkgPath != "" && !field.Anonymous {
+		return "", false
+	}
+	if field.Anonymous || (len(tag) > 1 && tag[1] == "inline") {
+		return "", true
+	}
+	if tag[0] != "" {
+		return tag[0], false
+	}
+	switch field.Type.Kind() {
+	case reflect.Struct, reflect.Map:
+		return strings.ToLower(field.Name), false
+	}
+	return "", false
+}
+
+// EnvVars lists the environment variables of the YAML keys of out, a pointer to
+// a YAML config struct, sorted by name.
+func EnvVars(prefix string, out interface{}) []EnvVar {
+	vars := envVars(prefix, "", reflect.TypeOf(out).Elem())
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+func envVars(prefix, path string, t reflect.Type) []EnvVar {
+	var vars []EnvVar
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, inline := yamlField(field)
+		if inline {
+			vars = append(vars, envVars(prefix, path, field.Type)...)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		name := prefix + "_" + EnvName(key)
+		keyPath := strings.TrimPrefix(path+"."+key, ".")
+		if elem, ok := entryType(field.Type); ok {
+			vars = append(vars, envVars(name+"_"+EnvEntryName, keyPath+"."+EnvEntryName, elem)...)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			vars = append(vars, envVars(name, keyPath, field.Type)...)
+			continue
+		}
+		vars = append(vars, EnvVar{Name: name, Path: keyPath, Type: envType(field.Type)})
+	}
+	return vars
+}
+
+// entryType returns the struct type of the entries of a map of structs
+func entryType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil, false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem, elem.Kind() == reflect.Struct
+}
+
+func envType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return envType(t.Elem())
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "comma separated list"
+	case reflect.Map:
+		return "comma separated key=value list"
+	}
+	return "string"
+}
+
+// ApplyEnv overrides the keys of out, a pointer to a YAML config struct, with
+// the environment variables listed by EnvVars. Empty variables are ignored. The
+// entry name of a map is matched with the existing entries in upper snake case,
+// an entry is added with the name in lower case and dashes otherwise, e.g.
+// VSPHERE_VCENTER_TENANT_A_SERVER adds the vcenter tenant-a.
+func ApplyEnv(prefix string, out interface{}) error {
+	return OSEnv.Apply(prefix, out)
+}
+
+// Apply overrides the keys of out like ApplyEnv, with the variables of env.
+func (env Env) Apply(prefix string, out interface{}) error {
+	environ := map[string]string{}
+	for _, e := range env.Environ() {
+		pair := strings.SplitN(e, "=", 2)
+		if len(pair) == 2 && pair[1] != "" && strings.HasPrefix(pair[0], prefix+"_") {
+			environ[pair[0]] = pair[1]
+		}
+	}
+	if len(environ) == 0 {
+		return nil
+	}
+	return utilerrors.NewAggregate(applyEnv(prefix, reflect.ValueOf(out).Elem(), environ))
+}
+
+func applyEnv(prefix string, v reflect.Value, environ map[string]string) []error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, inline := yamlField(field)
+		if inline {
+			errs = append(errs, applyEnv(prefix, v.Field(i), environ)...)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		name := prefix + "_" + EnvName(key)
+		if _, ok := entryType(field.Type); ok {
+			errs = append(errs, applyEnvEntries(name, v.Field(i), environ)...)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, applyEnv(name, v.Field(i), environ)...)
+			continue
+		}
+		if value, ok := environ[name]; ok {
+			if err := setEnvValue(v.Field(i), value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %v", name, err))
+			}
+		}
+	}
+	return errs
+}
+
+// applyEnvEntries overrides the entries of a map of structs
+func applyEnvEntries(prefix string, m reflect.Value, environ map[string]string) []error {
+	elem, _ := entryType(m.Type())
+	var suffixes []string
+	for _, envVar := range envVars("", "", elem) {
+		suffixes = append(suffixes, envVar.Name)
+	}
+	// longest suffix first so that a key is not taken for the end of another
+	sort.Slice(suffixes, func(i, j int) bool { return len(suffixes[i]) > len(suffixes[j]) })
+
+	entries := map[string]bool{}
+	for name := range environ {
+		rest := strings.TrimPrefix(name, prefix+"_")
+		if rest == name {
+			continue
+		}
+		for _, suffix := range suffixes {
+			if len(rest) > len(suffix) && strings.HasSuffix(rest, suffix) {
+				entries[strings.TrimSuffix(rest, suffix)] = true
+				break
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	var errs []error
+	for entry := range entries {
+		key := strings.ToLower(strings.ReplaceAll(entry, "_", "-"))
+		for _, existing := range m.MapKeys() {
+			if envEntryName(existing.String()) == entry {
+				key = existing.String()
+				break
+			}
+		}
+
+		value := reflect.New(elem)
+		if current := m.MapIndex(reflect.ValueOf(key)); current.IsValid() && !current.IsNil() {
+			value = current
+		}
+		errs = append(errs, applyEnv(prefix+"_"+entry, value.Elem(), environ)...)
+		if m.Type().Elem().Kind() == reflect.Ptr {
+			m.SetMapIndex(reflect.ValueOf(key), value)
+		} else {
+			m.SetMapIndex(reflect.ValueOf(key), value.Elem())
+		}
+	}
+	return errs
+}
+
+// isVCenterEnvVar returns true if name is the variable of a key of a vcenter
+// entry, e.g. VSPHERE_VCENTER_TENANT_A_SERVER.
+func isVCenterEnvVar(name string) bool {
+	rest := strings.TrimPrefix(name, EnvPrefix+"_VCENTER_")
+	if rest == name {
+		return false
+	}
+	for _, envVar := range envVars("", "", reflect.TypeOf(VirtualCenterConfigYAML{})) {
+		if len(rest) > len(envVar.Name) && strings.HasSuffix(rest, envVar.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func setEnvValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setEnvValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		m := map[string]string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			pair := strings.SplitN(item, "=", 2)
+			if len(pair) != 2 {
+				return fmt.Errorf("%q is not key=value", item)
+			}
+			m[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// legacyEnvVars are the variables read by FromEnv, applied to the Config
+// after the YAML cloud-config and its mapped variables.
+var legacyEnvVars = []string{
+	"VSPHERE_VCENTER", "VSPHERE_VCENTER_PORT", "VSPHERE_USER", "VSPHERE_PASSWORD",
+	"VSPHERE_DATACENTER", "VSPHERE_SECRET_NAME", "VSPHERE_SECRET_NAMESPACE",
+	"VSPHERE_ROUNDTRIP_COUNT", "VSPHERE_API_QPS", "VSPHERE_API_BURST",
+	"VSPHERE_MAX_CONCURRENT_REQUESTS", "VSPHERE_INSECURE", "VSPHERE_SECRETS_DIRECTORY",
+	"VSPHERE_CAFILE", "VSPHERE_THUMBPRINT", "VSPHERE_LABEL_REGION", "VSPHERE_LABEL_ZONE",
+}
+
+// LegacyEnvVars returns the legacy variables read by FromEnv that are set,
+// sorted by name.
+func LegacyEnvVars() []string {
+	var set []string
+	for _, name := range legacyEnvVars {
+		if os.Getenv(name) != "" {
+			set = append(set, name)
+		}
+	}
+	for _, e := range os.Environ() {
+		pair := strings.SplitN(e, "=", 2)
+		if len(pair) == 2 && pair[1] != "" && strings.HasPrefix(pair[0], "VSPHERE_VCENTER_") &&
+			pair[0] != "VSPHERE_VCENTER_PORT" && !isVCenterEnvVar(pair[0]) {
+			set = append(set, pair[0])
+		}
+	}
+	sort.Strings(set)
+	return set
+}
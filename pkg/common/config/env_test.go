@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const envConfigYAML = `
+global:
+  port: 443
+  user: user
+  password: password
+  insecureFlag: false
+  datacenters:
+    - dc1
+vcenter:
+  tenant-a:
+    server: 10.0.0.1
+`
+
+func TestEnvName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"lbServiceId":               "LB_SERVICE_ID",
+		"apiQPS":                    "API_QPS",
+		"tier1GatewayPath":          "TIER1_GATEWAY_PATH",
+		"internalNetworkSubnetCidr": "INTERNAL_NETWORK_SUBNET_CIDR",
+		"loadBalancerClass":         "LOAD_BALANCER_CLASS",
+		"server":                    "SERVER",
+	} {
+		if name := EnvName(key); name != expected {
+			t.Errorf("EnvName(%q) = %q, expected %q", key, name, expected)
+		}
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	names := map[string]string{}
+	for _, envVar := range EnvVars(EnvPrefix, &CommonConfigYAML{}) {
+		names[envVar.Name] = envVar.Path
+	}
+	for name, path := range map[string]string{
+		"VSPHERE_GLOBAL_INSECURE_FLAG":       "global.insecureFlag",
+		"VSPHERE_VCENTER_<NAME>_DATACENTERS": "vcenter.<NAME>.datacenters",
+		"VSPHERE_LABELS_ZONE":                "labels.zone",
+		"VSPHERE_CREDENTIAL_PROVIDER_TYPE":   "credentialProvider.type",
+	} {
+		if names[name] != path {
+			t.Errorf("%s maps to %q, expected %q", name, names[name], path)
+		}
+	}
+	// the fields derived from the vcenter key are not settings
+	if _, ok := names["VSPHERE_VCENTER_<NAME>_TENANTREF"]; ok {
+		t.Error("tenantref must not have a variable")
+	}
+}
+
+func TestApplyEnvYAML(t *testing.T) {
+	t.Setenv("VSPHERE_GLOBAL_INSECURE_FLAG", "true")
+	t.Setenv("VSPHERE_GLOBAL_USER", "env-user")
+	t.Setenv("VSPHERE_VCENTER_TENANT_A_DATACENTERS", "dc2, dc3")
+	t.Setenv("VSPHERE_VCENTER_TENANT_B_SERVER", "10.0.0.2")
+	t.Setenv("VSPHERE_VCENTER_TENANT_B_USER", "tenant-b-user")
+
+	cfg, err := ReadRawConfigYAML([]byte(envConfigYAML))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+
+	if !cfg.Global.InsecureFlag || cfg.Global.User != "env-user" {
+		t.Errorf("global not overridden: %+v", cfg.Global)
+	}
+	a := cfg.Vcenter["tenant-a"]
+	if a == nil {
+		t.Fatal("tenant-a is missing")
+	}
+	if !reflect.DeepEqual(a.Datacenters, []string{"dc2", "dc3"}) {
+		t.Errorf("tenant-a datacenters = %v, expected the variable", a.Datacenters)
+	}
+	// the vcenter inherits from the global keys overridden by the variables
+	if a.User != "env-user" || !a.InsecureFlag {
+		t.Errorf("tenant-a did not inherit the global variables: %+v", a)
+	}
+	b := cfg.Vcenter["tenant-b"]
+	if b == nil {
+		t.Fatal("tenant-b should be added by its variables")
+	}
+	if b.VCenterIP != "10.0.0.2" || b.User != "tenant-b-user" || b.TenantRef != "tenant-b" {
+		t.Errorf("tenant-b = %+v", b)
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	t.Setenv("VSPHERE_GLOBAL_INSECURE_FLAG", "maybe")
+	t.Setenv("VSPHERE_VCENTER_TENANT_A_PORT", "https")
+
+	_, err := ReadRawConfigYAML([]byte(envConfigYAML))
+	if err == nil {
+		t.Fatal("Should fail when a variable is invalid")
+	}
+	for _, name := range []string{"VSPHERE_GLOBAL_INSECURE_FLAG", "VSPHERE_VCENTER_TENANT_A_PORT"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+}
+
+func TestFromEnvSkipsYAMLVariables(t *testing.T) {
+	t.Setenv("VSPHERE_VCENTER_PORT", "8443")
+	t.Setenv("VSPHERE_VCENTER_TENANT_A_DATACENTERS", "dc2")
+
+	cfg, err := ReadConfigYAML([]byte(envConfigYAML))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+	if err := cfg.FromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.VirtualCenter) != 1 || cfg.VirtualCenter["tenant-a"] == nil {
+		t.Errorf("FromEnv added vCenters for the YAML variables: %v", cfg.VirtualCenter)
+	}
+	if LegacyEnvVars()[0] != "VSPHERE_VCENTER_PORT" || len(LegacyEnvVars()) != 1 {
+		t.Errorf("LegacyEnvVars() = %v", LegacyEnvVars())
+	}
+}
+
+func TestMarshalRedacted(t *testing.T) {
+	cfg, err := ReadRawConfigYAML([]byte(envConfigYAML))
+	if err != nil {
+		t.Fatalf("Should succeed when a valid config is provided: %s", err)
+	}
+	data, err := MarshalRedacted(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if strings.Contains(out, "password: password") || !strings.Contains(out, "password: "+Redacted) {
+		t.Errorf("password not redacted:\n%s", out)
+	}
+	if strings.Contains(out, "tenantref") || strings.Contains(out, "secretref") {
+		t.Errorf("derived fields written:\n%s", out)
+	}
+	if !strings.Contains(out, "tenant-a:") {
+		t.Errorf("vcenter missing:\n%s", out)
+	}
+}
@@ -35,10 +35,24 @@ type Global struct {
 	VCenterPort string
 	// True if vCenter uses self-signed cert.
 	InsecureFlag bool
-	// Datacenter in which VMs are located.
+	// Datacenter in which VMs are located, comma-separated names, inventory
+	// paths or glob patterns such as "dc-*".
 	Datacenters string
+	// VMFolders are the comma-separated inventory paths or glob patterns of the
+	// VM folders the node VMs are searched in. Default: the whole datacenters.
+	VMFolders string
+	// ResourcePools are the comma-separated inventory paths or glob patterns of
+	// the resource pools the node VMs are searched in, along with VMFolders.
+	ResourcePools string
 	// Soap round tripper count (retries = RoundTripper - 1)
 	RoundTripperCount uint
+	// APIQPS is the sustained rate of SOAP and REST requests per second sent to the
+	// vCenter, with bursts of up to APIBurst requests. 0 means unlimited.
+	APIQPS   float64
+	APIBurst int
+	// MaxConcurrentRequests caps the number of requests in flight to the vCenter.
+	// Further requests are queued. 0 means unlimited.
+	MaxConcurrentRequests int
 	// Specifies the path to a CA certificate in PEM format. Optional; if not
 	// configured, the system's CA certificates will be used.
 	CAFile string
@@ -73,10 +87,24 @@ type VirtualCenterConfig struct {
 	VCenterPort string
 	// True if vCenter uses self-signed cert.
 	InsecureFlag bool
-	// Datacenter in which VMs are located.
+	// Datacenter in which VMs are located, comma-separated names, inventory
+	// paths or glob patterns such as "dc-*".
 	Datacenters string
+	// VMFolders are the comma-separated inventory paths or glob patterns of the
+	// VM folders the node VMs are searched in. Default: the whole datacenters.
+	VMFolders string
+	// ResourcePools are the comma-separated inventory paths or glob patterns of
+	// the resource pools the node VMs are searched in, along with VMFolders.
+	ResourcePools string
 	// Soap round tripper count (retries = RoundTripper - 1)
 	RoundTripperCount uint
+	// APIQPS is the sustained rate of SOAP and REST requests per second sent to the
+	// vCenter, with bursts of up to APIBurst requests. 0 means unlimited.
+	APIQPS   float64
+	APIBurst int
+	// MaxConcurrentRequests caps the number of requests in flight to the vCenter.
+	// Further requests are queued. 0 means unlimited.
+	MaxConcurrentRequests int
 	// Specifies the path to a CA certificate in PEM format. Optional; if not
 	// configured, the system's CA certificates will be used.
 	CAFile string
@@ -89,11 +117,40 @@ type VirtualCenterConfig struct {
 	SecretName string
 	// Namespace where the secret will be present containing vCenter credentials.
 	SecretNamespace string
+	// SecretKeyPrefix selects the credentials of this vCenter in the secret, which
+	// are then read from the "<prefix>.username" and "<prefix>.password" keys, or
+	// from the username_/password_ keys matching a "server_" key set to the prefix.
+	// This allows several vCenters to share one secret. Default: the vCenter IP/FQDN.
+	SecretKeyPrefix string
 	// IP Family enables the ability to support IPv4 or IPv6
 	// Supported values are:
 	// ipv4 - IPv4 addresses only (Default)
 	// ipv6 - IPv6 addresses only
 	IPFamilyPriority []string
+	// Nodes selects the addresses of the nodes found in this vCenter, in place
+	// of the global nodes section of the cloud provider. Not set if zero. A
+	// block with any field set replaces the global section as a whole, the
+	// fields it leaves unset are not taken from the global section.
+	Nodes Nodes
+}
+
+// Nodes captures internal/external networks
+type Nodes struct {
+	// IP address on VirtualMachine's network interfaces included in the fields' CIDRs
+	// that will be used in respective status.addresses fields.
+	InternalNetworkSubnetCIDR string
+	ExternalNetworkSubnetCIDR string
+	// IP address on VirtualMachine's VM Network names that will be used to when searching
+	// for status.addresses fields. Note that if InternalNetworkSubnetCIDR and
+	// ExternalNetworkSubnetCIDR are not set, then the vNIC associated to this network must
+	// only have a single IP address assigned to it.
+	InternalVMNetworkName string
+	ExternalVMNetworkName string
+	// IP addresses in these subnet ranges will be excluded when selecting
+	// the IP address from the VirtualMachine's VM for use in the
+	// status.addresses fields.
+	ExcludeInternalNetworkSubnetCIDR string
+	ExcludeExternalNetworkSubnetCIDR string
 }
 
 // Labels struct
@@ -104,6 +161,26 @@ type Labels struct {
 	Region string
 }
 
+// CredentialProvider configures an external source of vCenter credentials
+type CredentialProvider struct {
+	// Type of the credential provider.
+	// Supported values are:
+	// exec - run Command and read the credentials as JSON from its output
+	// fileTemplate - read the credentials from UsernameFile and PasswordFile
+	Type string
+	// Command run by the exec provider. The vCenter Server is passed in the
+	// VSPHERE_SERVER environment variable.
+	Command string
+	// Args passed to Command.
+	Args []string
+	// Timeout of Command, for example "30s".
+	Timeout string
+	// UsernameFile and PasswordFile are path templates read by the fileTemplate
+	// provider, for example "/vault/secrets/{{.Server}}/username".
+	UsernameFile string
+	PasswordFile string
+}
+
 // Config is used to read and store information from the cloud configuration file
 type Config struct {
 	// Global settings
@@ -114,4 +191,7 @@ type Config struct {
 
 	// Tag categories and tags which correspond to "built-in node labels: zones and region"
 	Labels Labels
+
+	// External source of vCenter credentials
+	CredentialProvider CredentialProvider
 }
@@ -36,8 +36,18 @@ type GlobalINI struct {
 	InsecureFlag bool `gcfg:"insecure-flag"`
 	// Datacenter in which VMs are located.
 	Datacenters string `gcfg:"datacenters"`
+	// VM folders and resource pools the node VMs are searched in.
+	VMFolders     string `gcfg:"vm-folders"`
+	ResourcePools string `gcfg:"resource-pools"`
 	// Soap round tripper count (retries = RoundTripper - 1)
 	RoundTripperCount uint `gcfg:"soap-roundtrip-count"`
+	// APIQPS is the sustained rate of SOAP and REST requests per second sent to the
+	// vCenter, with bursts of up to APIBurst requests. 0 means unlimited.
+	APIQPS   float64 `gcfg:"api-qps"`
+	APIBurst int     `gcfg:"api-burst"`
+	// MaxConcurrentRequests caps the number of requests in flight to the vCenter.
+	// Further requests are queued. 0 means unlimited.
+	MaxConcurrentRequests int `gcfg:"max-concurrent-requests"`
 	// Specifies the path to a CA certificate in PEM format. Optional; if not
 	// configured, the system's CA certificates will be used.
 	CAFile string `gcfg:"ca-file"`
@@ -86,8 +96,18 @@ type VirtualCenterConfigINI struct {
 	InsecureFlag bool `gcfg:"insecure-flag"`
 	// Datacenter in which VMs are located.
 	Datacenters string `gcfg:"datacenters"`
+	// VM folders and resource pools the node VMs are searched in.
+	VMFolders     string `gcfg:"vm-folders"`
+	ResourcePools string `gcfg:"resource-pools"`
 	// Soap round tripper count (retries = RoundTripper - 1)
 	RoundTripperCount uint `gcfg:"soap-roundtrip-count"`
+	// APIQPS is the sustained rate of SOAP and REST requests per second sent to the
+	// vCenter, with bursts of up to APIBurst requests. 0 means unlimited.
+	APIQPS   float64 `gcfg:"api-qps"`
+	APIBurst int     `gcfg:"api-burst"`
+	// MaxConcurrentRequests caps the number of requests in flight to the vCenter.
+	// Further requests are queued. 0 means unlimited.
+	MaxConcurrentRequests int `gcfg:"max-concurrent-requests"`
 	// Specifies the path to a CA certificate in PEM format. Optional; if not
 	// configured, the system's CA certificates will be used.
 	CAFile string `gcfg:"ca-file"`
@@ -100,6 +120,11 @@ type VirtualCenterConfigINI struct {
 	SecretName string `gcfg:"secret-name"`
 	// Namespace where the secret will be present containing vCenter credentials.
 	SecretNamespace string `gcfg:"secret-namespace"`
+	// SecretKeyPrefix selects the credentials of this vCenter in the secret, which
+	// are then read from the "<prefix>.username" and "<prefix>.password" keys, or
+	// from the username_/password_ keys matching a "server_" key set to the prefix.
+	// This allows several vCenters to share one secret. Default: the vCenter IP/FQDN.
+	SecretKeyPrefix string `gcfg:"secret-key-prefix"`
 	// IP Family enables the ability to support IPv4 or IPv6
 	// Supported values are:
 	// ipv4 - IPv4 addresses only (Default)
@@ -115,6 +140,26 @@ type LabelsINI struct {
 	Region string `gcfg:"region"`
 }
 
+// CredentialProviderINI configures an external source of vCenter credentials
+type CredentialProviderINI struct {
+	// Type of the credential provider.
+	// Supported values are:
+	// exec - run Command and read the credentials as JSON from its output
+	// fileTemplate - read the credentials from UsernameFile and PasswordFile
+	Type string `gcfg:"type"`
+	// Command run by the exec provider. The vCenter Server is passed in the
+	// VSPHERE_SERVER environment variable.
+	Command string `gcfg:"command"`
+	// Args passed to Command, one per arg entry.
+	Args []string `gcfg:"arg"`
+	// Timeout of Command, for example "30s".
+	Timeout string `gcfg:"timeout"`
+	// UsernameFile and PasswordFile are path templates read by the fileTemplate
+	// provider, for example "/vault/secrets/{{.Server}}/username".
+	UsernameFile string `gcfg:"username-file"`
+	PasswordFile string `gcfg:"password-file"`
+}
+
 // CommonConfigINI is used to read and store information from the cloud configuration file
 type CommonConfigINI struct {
 	// Global values...
@@ -125,4 +170,7 @@ type CommonConfigINI struct {
 
 	// Tag categories and tags which correspond to "built-in node labels: zones and region"
 	Labels LabelsINI
+
+	// External source of vCenter credentials
+	CredentialProvider CredentialProviderINI
 }
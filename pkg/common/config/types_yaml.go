@@ -24,6 +24,7 @@ package config
 	GlobalYAML -> Global
 	VirtualCenterConfigYAML -> VirtualCenterConfig
 	LabelsYAML -> Labels
+	CredentialProviderYAML -> CredentialProvider
 	ConfigYAML -> Config
 */
 
@@ -42,8 +43,18 @@ type GlobalYAML struct {
 	InsecureFlag bool `yaml:"insecureFlag"`
 	// Datacenter in which VMs are located.
 	Datacenters []string `yaml:"datacenters"`
+	// VM folders and resource pools the node VMs are searched in.
+	VMFolders     []string `yaml:"vmFolders"`
+	ResourcePools []string `yaml:"resourcePools"`
 	// Soap round tripper count (retries = RoundTripper - 1)
 	RoundTripperCount uint `yaml:"soapRoundtripCount"`
+	// APIQPS is the sustained rate of SOAP and REST requests per second sent to the
+	// vCenter, with bursts of up to APIBurst requests. 0 means unlimited.
+	APIQPS   float64 `yaml:"apiQPS"`
+	APIBurst int     `yaml:"apiBurst"`
+	// MaxConcurrentRequests caps the number of requests in flight to the vCenter.
+	// Further requests are queued. 0 means unlimited.
+	MaxConcurrentRequests int `yaml:"maxConcurrentRequests"`
 	// Specifies the path to a CA certificate in PEM format. Optional; if not
 	// configured, the system's CA certificates will be used.
 	CAFile string `yaml:"caFile"`
@@ -92,8 +103,18 @@ type VirtualCenterConfigYAML struct {
 	InsecureFlag bool `yaml:"insecureFlag"`
 	// Datacenter in which VMs are located.
 	Datacenters []string `yaml:"datacenters"`
+	// VM folders and resource pools the node VMs are searched in.
+	VMFolders     []string `yaml:"vmFolders"`
+	ResourcePools []string `yaml:"resourcePools"`
 	// Soap round tripper count (retries = RoundTripper - 1)
 	RoundTripperCount uint `yaml:"soapRoundtripCount"`
+	// APIQPS is the sustained rate of SOAP and REST requests per second sent to the
+	// vCenter, with bursts of up to APIBurst requests. 0 means unlimited.
+	APIQPS   float64 `yaml:"apiQPS"`
+	APIBurst int     `yaml:"apiBurst"`
+	// MaxConcurrentRequests caps the number of requests in flight to the vCenter.
+	// Further requests are queued. 0 means unlimited.
+	MaxConcurrentRequests int `yaml:"maxConcurrentRequests"`
 	// Specifies the path to a CA certificate in PEM format. Optional; if not
 	// configured, the system's CA certificates will be used.
 	CAFile string `yaml:"caFile"`
@@ -106,11 +127,29 @@ type VirtualCenterConfigYAML struct {
 	SecretName string `yaml:"secretName"`
 	// Namespace where the secret will be present containing vCenter credentials.
 	SecretNamespace string `yaml:"secretNamespace"`
+	// SecretKeyPrefix selects the credentials of this vCenter in the secret, which
+	// are then read from the "<prefix>.username" and "<prefix>.password" keys, or
+	// from the username_/password_ keys matching a "server_" key set to the prefix.
+	// This allows several vCenters to share one secret. Default: the vCenter IP/FQDN.
+	SecretKeyPrefix string `yaml:"secretKeyPrefix"`
 	// IP Family enables the ability to support IPv4 or IPv6
 	// Supported values are:
 	// ipv4 - IPv4 addresses only (Default)
 	// ipv6 - IPv6 addresses only
 	IPFamilyPriority []string `yaml:"ipFamily"`
+	// Nodes selects the addresses of the nodes found in this vCenter, in place
+	// of the global nodes section of the cloud provider.
+	Nodes NodesYAML `yaml:"nodes"`
+}
+
+// NodesYAML captures the internal/external networks of the nodes of a vCenter
+type NodesYAML struct {
+	InternalNetworkSubnetCIDR        string `yaml:"internalNetworkSubnetCidr"`
+	ExternalNetworkSubnetCIDR        string `yaml:"externalNetworkSubnetCidr"`
+	InternalVMNetworkName            string `yaml:"internalVmNetworkName"`
+	ExternalVMNetworkName            string `yaml:"externalVmNetworkName"`
+	ExcludeInternalNetworkSubnetCIDR string `yaml:"excludeInternalNetworkSubnetCidr"`
+	ExcludeExternalNetworkSubnetCIDR string `yaml:"excludeExternalNetworkSubnetCidr"`
 }
 
 // LabelsYAML tags categories and tags which correspond to "built-in node labels: zones and region"
@@ -119,6 +158,26 @@ type LabelsYAML struct {
 	Region string `yaml:"region"`
 }
 
+// CredentialProviderYAML configures an external source of vCenter credentials
+type CredentialProviderYAML struct {
+	// Type of the credential provider.
+	// Supported values are:
+	// exec - run Command and read the credentials as JSON from its output
+	// fileTemplate - read the credentials from UsernameFile and PasswordFile
+	Type string `yaml:"type"`
+	// Command run by the exec provider. The vCenter Server is passed in the
+	// VSPHERE_SERVER environment variable.
+	Command string `yaml:"command"`
+	// Args passed to Command.
+	Args []string `yaml:"args"`
+	// Timeout of Command, for example "30s".
+	Timeout string `yaml:"timeout"`
+	// UsernameFile and PasswordFile are path templates read by the fileTemplate
+	// provider, for example "/vault/secrets/{{.Server}}/username".
+	UsernameFile string `yaml:"usernameFile"`
+	PasswordFile string `yaml:"passwordFile"`
+}
+
 // CommonConfigYAML is used to read and store information from the cloud configuration file
 type CommonConfigYAML struct {
 	// Global values...
@@ -129,4 +188,7 @@ type CommonConfigYAML struct {
 
 	// Tag categories and tags which correspond to "built-in node labels: zones and region"
 	Labels LabelsYAML
+
+	// External source of vCenter credentials
+	CredentialProvider CredentialProviderYAML `yaml:"credentialProvider"`
 }
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// VCenterCheck is the result of CheckVCenter, a nil error is a passed check
+type VCenterCheck struct {
+	// Connect is the error connecting to the vCenter
+	Connect error
+	// Datacenters is the error finding the configured datacenters, nil if
+	// the vCenter is not connected
+	Datacenters error
+	// TagCategories is the error resolving the tag categories, nil if the
+	// vCenter is not connected
+	TagCategories error
+}
+
+// CheckVCenter connects to the vCenter of tenantRef, finds its configured
+// datacenters and resolves the given tag categories. The datacenters and tag
+// categories are only checked once the vCenter is connected.
+func (cm *ConnectionManager) CheckVCenter(ctx context.Context, tenantRef string, categories ...string) (*VCenterCheck, error) {
+	vsi := cm.VSphereInstances()[tenantRef]
+	if vsi == nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	check := &VCenterCheck{}
+	if check.Connect = cm.Connect(ctx, vsi); check.Connect != nil {
+		return check, nil
+	}
+
+	if len(splitList(vsi.Cfg.Datacenters)) > 0 {
+		_, check.Datacenters = datacenters(ctx, vsi)
+	}
+
+	if len(categories) > 0 {
+		check.TagCategories = withTagsClient(ctx, vsi.Conn, func(c *rest.Client) error {
+			m := tags.NewManager(c)
+			var errs []error
+			for _, category := range categories {
+				if _, err := m.GetCategory(ctx, category); err != nil {
+					errs = append(errs, fmt.Errorf("tag category %s: %v", category, err))
+				}
+			}
+			return utilerrors.NewAggregate(errs)
+		})
+	}
+	return check, nil
+}
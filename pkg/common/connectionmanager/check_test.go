@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+)
+
+func TestCheckVCenter(t *testing.T) {
+	config, cleanup := configFromEnvOrSim(true)
+	defer cleanup()
+
+	connMgr := NewConnectionManager(config, nil, nil)
+	defer connMgr.Logout()
+
+	ctx := context.Background()
+	tenantRef := config.Global.VCenterIP
+
+	if _, err := connMgr.CheckVCenter(ctx, "unknown"); err != ErrConnectionNotFound {
+		t.Errorf("expected ErrConnectionNotFound, got %v", err)
+	}
+
+	check, err := connMgr.CheckVCenter(ctx, tenantRef, config.Labels.Zone)
+	if err != nil {
+		t.Fatalf("CheckVCenter failed: %v", err)
+	}
+	if check.Connect != nil || check.Datacenters != nil {
+		t.Errorf("expected the vCenter and its datacenters to be found, got %+v", check)
+	}
+	if check.TagCategories == nil || !strings.Contains(check.TagCategories.Error(), config.Labels.Zone) {
+		t.Errorf("expected the missing %s category to be reported, got %v", config.Labels.Zone, check.TagCategories)
+	}
+
+	vsi := connMgr.VsphereInstanceMap[tenantRef]
+	restClient := rest.NewClient(vsi.Conn.Client)
+	if err := restClient.Login(ctx, url.UserPassword(vsi.Conn.Username, vsi.Conn.Password)); err != nil {
+		t.Fatalf("Rest login failed. err=%v", err)
+	}
+	if _, err := tags.NewManager(restClient).CreateCategory(ctx, &tags.Category{Name: config.Labels.Zone}); err != nil {
+		t.Fatal(err)
+	}
+	vsi.Cfg.Datacenters = "DC0,DC9"
+
+	check, err = connMgr.CheckVCenter(ctx, tenantRef, config.Labels.Zone)
+	if err != nil {
+		t.Fatalf("CheckVCenter failed: %v", err)
+	}
+	if check.TagCategories != nil {
+		t.Errorf("expected the %s category to be resolved, got %v", config.Labels.Zone, check.TagCategories)
+	}
+	if check.Datacenters == nil || !strings.Contains(check.Datacenters.Error(), "DC9") {
+		t.Errorf("expected the missing DC9 datacenter to be reported, got %v", check.Datacenters)
+	}
+}
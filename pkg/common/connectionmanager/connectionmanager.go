@@ -18,10 +18,18 @@ package connectionmanager
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	klog "k8s.io/klog/v2"
 
 	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
@@ -40,11 +48,26 @@ func NewConnectionManager(cfg *vcfg.Config, informMgr *k8s.InformerManager, clie
 		VsphereInstanceMap: generateInstanceMap(cfg),
 		credentialManagers: make(map[string]*cm.CredentialManager),
 		informerManagers:   make(map[string]*k8s.InformerManager),
+		reLogins:           workqueue.NewWithConfig(workqueue.QueueConfig{Name: "ReLogin"}),
 	}
 
+	if cfg.CredentialProvider.Type != "" {
+		klog.V(2).Infof("Initializing with %s credential provider", cfg.CredentialProvider.Type)
+		// the settings of the provider are checked when the config is read
+		provider, err := newCredentialProvider(&cfg.CredentialProvider)
+		if err != nil {
+			klog.Errorf("Failed to create %s credential provider: %v", cfg.CredentialProvider.Type, err)
+		} else {
+			credMgr := cm.NewProviderCredentialManager(provider)
+			credMgr.AddCredentialListener(connMgr.credentialsUpdated(vcfg.DefaultCredentialManager))
+			connMgr.credentialManagers[vcfg.DefaultCredentialManager] = credMgr
+
+			return connMgr
+		}
+	}
 	if cfg.Global.SecretsDirectory != "" {
 		klog.V(2).Info("Initializing for generic CO with secrets")
-		credMgr, _ := connMgr.createManagersPerTenant("", "", cfg.Global.SecretsDirectory, nil)
+		credMgr, _ := connMgr.createManagersPerTenant(vcfg.DefaultCredentialManager, "", "", cfg.Global.SecretsDirectory, nil)
 		connMgr.credentialManagers[vcfg.DefaultCredentialManager] = credMgr
 
 		return connMgr
@@ -52,6 +75,10 @@ func NewConnectionManager(cfg *vcfg.Config, informMgr *k8s.InformerManager, clie
 	if informMgr != nil {
 		klog.V(2).Info("Initializing with K8s SecretLister")
 		credMgr := cm.NewCredentialManager(cfg.Global.SecretName, cfg.Global.SecretNamespace, "", informMgr.GetSecretLister())
+		credMgr.AddCredentialListener(connMgr.credentialsUpdated(vcfg.DefaultCredentialManager))
+		if err := credMgr.AddSecretListener(informMgr.GetSecretInformer()); err != nil {
+			klog.Warningf("Adding secret listener failed, credentials will be read on demand: %v", err)
+		}
 		connMgr.credentialManagers[vcfg.DefaultCredentialManager] = credMgr
 		connMgr.informerManagers[vcfg.DefaultCredentialManager] = informMgr
 
@@ -65,6 +92,25 @@ func NewConnectionManager(cfg *vcfg.Config, informMgr *k8s.InformerManager, clie
 	return connMgr
 }
 
+// newCredentialProvider creates the external credential provider described by the config
+func newCredentialProvider(cfg *vcfg.CredentialProvider) (cm.CredentialProvider, error) {
+	switch cfg.Type {
+	case vcfg.CredentialProviderExec:
+		var timeout time.Duration
+		if cfg.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+				return nil, err
+			}
+		}
+		return cm.NewExecCredentialProvider(cfg.Command, cfg.Args, timeout), nil
+	case vcfg.CredentialProviderFileTemplate:
+		return cm.NewFileTemplateCredentialProvider(cfg.UsernameFile, cfg.PasswordFile)
+	default:
+		return nil, vcfg.ErrInvalidCredentialProvider
+	}
+}
+
 // generateInstanceMap creates a map of vCenter connection objects that can be
 // use to create a connection to a vCenter using vclib package
 func generateInstanceMap(cfg *vcfg.Config) map[string]*VSphereInstance {
@@ -80,6 +126,8 @@ func generateInstanceMap(cfg *vcfg.Config) map[string]*VSphereInstance {
 			Port:              vcConfig.VCenterPort,
 			CACert:            vcConfig.CAFile,
 			Thumbprint:        vcConfig.Thumbprint,
+			Limiter: vclib.NewLimiter(vcConfig.VCenterIP, vcConfig.APIQPS, vcConfig.APIBurst,
+				vcConfig.MaxConcurrentRequests),
 		}
 		vsphereIns := VSphereInstance{
 			Conn: &vSphereConn,
@@ -91,44 +139,228 @@ func generateInstanceMap(cfg *vcfg.Config) map[string]*VSphereInstance {
 	return vsphereInstanceMap
 }
 
+// VSphereInstances returns the vCenter instances keyed by tenant. The returned
+// map must not be modified.
+func (connMgr *ConnectionManager) VSphereInstances() map[string]*VSphereInstance {
+	connMgr.instancesLock.RLock()
+	defer connMgr.instancesLock.RUnlock()
+	return connMgr.VsphereInstanceMap
+}
+
+// Reload replaces the vCenter instances by the ones of cfg in one step.
+// Instances with an unchanged config are kept along with their session, the
+// sessions of removed or changed instances are logged out. Credential managers
+// are created for new secrets, the global credential source is not reloaded.
+func (connMgr *ConnectionManager) Reload(cfg *vcfg.Config) {
+	instances := generateInstanceMap(cfg)
+
+	connMgr.instancesLock.Lock()
+	previous := connMgr.VsphereInstanceMap
+	for tenantRef, vcInstance := range instances {
+		if current, ok := previous[tenantRef]; ok && reflect.DeepEqual(current.Cfg, vcInstance.Cfg) {
+			instances[tenantRef] = current
+		}
+	}
+	connMgr.VsphereInstanceMap = instances
+	connMgr.instancesLock.Unlock()
+
+	for tenantRef, vcInstance := range previous {
+		if instances[tenantRef] == vcInstance {
+			continue
+		}
+		klog.V(2).Infof("vCenter %s was removed or changed, ending its session", tenantRef)
+		if vcInstance.Conn.GetClient() != nil {
+			go vcInstance.Conn.Logout(context.Background())
+		}
+	}
+
+	connMgr.InitializeSecretLister()
+}
+
 // InitializeSecretLister initializes the individual secret listers that are NOT
 // handled through the Default/Global lister tied to the default service account.
 func (connMgr *ConnectionManager) InitializeSecretLister() {
 	// For each vsi that has a Secret set createManagersPerTenant
-	for _, vInstance := range connMgr.VsphereInstanceMap {
+	for _, vInstance := range connMgr.VSphereInstances() {
 		klog.V(3).Infof("Checking vcServer=%s SecretRef=%s", vInstance.Cfg.VCenterIP, vInstance.Cfg.SecretRef)
 		if strings.EqualFold(vInstance.Cfg.SecretRef, vcfg.DefaultCredentialManager) {
 			klog.V(3).Infof("Skipping. vCenter %s is configured using global service account/secret.", vInstance.Cfg.VCenterIP)
 			continue
 		}
 
+		connMgr.Lock()
+		_, ok := connMgr.credentialManagers[vInstance.Cfg.SecretRef]
+		connMgr.Unlock()
+		if ok {
+			klog.V(3).Infof("Skipping. vCenter %s shares secret %s with another vCenter.", vInstance.Cfg.VCenterIP, vInstance.Cfg.SecretRef)
+			continue
+		}
+
 		klog.V(3).Infof("Adding credMgr/informMgr for vcServer=%s", vInstance.Cfg.VCenterIP)
-		credsMgr, informMgr := connMgr.createManagersPerTenant(vInstance.Cfg.SecretName,
+		credsMgr, informMgr := connMgr.createManagersPerTenant(vInstance.Cfg.SecretRef, vInstance.Cfg.SecretName,
 			vInstance.Cfg.SecretNamespace, "", connMgr.client)
+		connMgr.Lock()
 		connMgr.credentialManagers[vInstance.Cfg.SecretRef] = credsMgr
 		connMgr.informerManagers[vInstance.Cfg.SecretRef] = informMgr
+		connMgr.Unlock()
 	}
 }
 
-func (connMgr *ConnectionManager) createManagersPerTenant(secretName string, secretNamespace string,
+func (connMgr *ConnectionManager) createManagersPerTenant(secretRef string, secretName string, secretNamespace string,
 	secretsDirectory string, client clientset.Interface) (*cm.CredentialManager, *k8s.InformerManager) {
 
 	var informMgr *k8s.InformerManager
 	var lister listerv1.SecretLister
 	if client != nil && secretsDirectory == "" {
-		informMgr = k8s.NewInformer(client, true)
+		// only watch the secret of the tenant so that the service account does
+		// not need access to every secret in the cluster
+		informMgr = k8s.NewSecretScopedInformer(client, secretNamespace, secretName)
 		lister = informMgr.GetSecretLister()
 	}
 
 	credMgr := cm.NewCredentialManager(secretName, secretNamespace, secretsDirectory, lister)
+	credMgr.AddCredentialListener(connMgr.credentialsUpdated(secretRef))
 
 	if lister != nil {
+		if err := credMgr.AddSecretListener(informMgr.GetSecretInformer()); err != nil {
+			klog.Warningf("Adding secret listener for %s failed, credentials will be read on demand: %v", secretRef, err)
+		}
 		informMgr.Listen()
 	}
+	if secretsDirectory != "" {
+		if err := credMgr.WatchSecretsDirectory(wait.NeverStop); err != nil {
+			klog.Warningf("Watching secrets directory %s failed, credentials will be read once: %v", secretsDirectory, err)
+		}
+	}
 
 	return credMgr, informMgr
 }
 
+// credentialsUpdated returns a CredentialListener that pushes rotated credentials
+// to the vCenter connections using the given credential holder. Connections
+// with an active session re-login right away so the old credentials are not
+// needed anymore.
+func (connMgr *ConnectionManager) credentialsUpdated(secretRef string) cm.CredentialListener {
+	return func(server string, credential cm.Credential) {
+		for _, vcInstance := range connMgr.VSphereInstances() {
+			if vcInstance.Cfg.CredentialKey() != server || !strings.EqualFold(vcInstance.Cfg.SecretRef, secretRef) {
+				continue
+			}
+			klog.V(2).Infof("Updating credentials. vcServer=%s credentialHolder=%s", vcInstance.Cfg.VCenterIP, secretRef)
+			connMgr.updateCredentials(vcInstance, credential)
+		}
+	}
+}
+
+// updateCredentials updates the credentials of a vCenter connection and
+// re-logins if the connection has already been established. The re-login is
+// queued, so that the secret event handler does not wait for it, and every
+// vCenter is queued once however many secret events arrive meanwhile.
+func (connMgr *ConnectionManager) updateCredentials(vcInstance *VSphereInstance, credential cm.Credential) {
+	vcInstance.Conn.UpdateCredentials(credential.User, credential.Password)
+	if vcInstance.Conn.GetClient() == nil {
+		return
+	}
+	connMgr.startReLogins.Do(func() {
+		go wait.Until(func() {
+			for connMgr.processNextReLogin() {
+			}
+		}, time.Second, wait.NeverStop)
+	})
+	connMgr.reLogins.Add(vcInstance)
+}
+
+// processNextReLogin re-logins the next queued vCenter connection with its
+// current credentials
+func (connMgr *ConnectionManager) processNextReLogin() bool {
+	obj, shutdown := connMgr.reLogins.Get()
+	if shutdown {
+		return false
+	}
+	defer connMgr.reLogins.Done(obj)
+
+	vcInstance := obj.(*VSphereInstance)
+	if err := vcInstance.Conn.ReLogin(context.Background()); err != nil {
+		klog.Errorf("Re-login with updated credentials failed. vcServer=%s err=%v", vcInstance.Cfg.VCenterIP, err)
+	}
+	return true
+}
+
+// ValidateCredentials checks that the secret of every vCenter holds its
+// username and password. Instead of stopping at the first problem, every
+// vCenter with missing keys is reported in the returned aggregate error.
+// It waits for the secret informers to sync until stopCh is closed.
+func (connMgr *ConnectionManager) ValidateCredentials(stopCh <-chan struct{}) error {
+	// the informers are waited for without the lock, Reload adds managers under it
+	credentialManagers, informerManagers := connMgr.managers()
+	for secretRef, informMgr := range informerManagers {
+		if informMgr == nil {
+			continue
+		}
+		if !cache.WaitForCacheSync(stopCh, informMgr.IsSecretInformerSynced()) {
+			return fmt.Errorf("timed out waiting for secret informer %s to sync", secretRef)
+		}
+	}
+
+	instances := connMgr.VSphereInstances()
+	tenantRefs := make([]string, 0, len(instances))
+	for tenantRef := range instances {
+		tenantRefs = append(tenantRefs, tenantRef)
+	}
+	sort.Strings(tenantRefs)
+
+	var errs []error
+	for _, tenantRef := range tenantRefs {
+		vcInstance := instances[tenantRef]
+		credMgr := credentialManagers[vcInstance.Cfg.SecretRef]
+		if credMgr == nil || !credMgr.HasSource() {
+			// credentials are set in the cloud config
+			continue
+		}
+		if err := credMgr.CheckCredential(vcInstance.Cfg.CredentialKey()); err != nil {
+			klog.Errorf("Invalid credentials for vCenter %s in %s: %v", tenantRef, vcInstance.Cfg.SecretRef, err)
+			errs = append(errs, fmt.Errorf("vCenter %s: %w", tenantRef, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// managers returns copies of the credential and informer managers keyed by
+// credential holder, taken under the lock.
+func (connMgr *ConnectionManager) managers() (map[string]*cm.CredentialManager, map[string]*k8s.InformerManager) {
+	connMgr.Lock()
+	defer connMgr.Unlock()
+
+	credentialManagers := make(map[string]*cm.CredentialManager, len(connMgr.credentialManagers))
+	for secretRef, credMgr := range connMgr.credentialManagers {
+		credentialManagers[secretRef] = credMgr
+	}
+	informerManagers := make(map[string]*k8s.InformerManager, len(connMgr.informerManagers))
+	for secretRef, informMgr := range connMgr.informerManagers {
+		informerManagers[secretRef] = informMgr
+	}
+	return credentialManagers, informerManagers
+}
+
+// CredentialReports returns the ValidationReport of every secret or secrets
+// directory holding vCenter credentials, keyed by credential holder.
+func (connMgr *ConnectionManager) CredentialReports() map[string]*cm.ValidationReport {
+	reports := make(map[string]*cm.ValidationReport)
+	credentialManagers, _ := connMgr.managers()
+	for secretRef, credMgr := range credentialManagers {
+		if credMgr == nil || !credMgr.HasSource() || credMgr.Provider != nil {
+			continue
+		}
+		report, err := credMgr.Validate()
+		if err != nil {
+			klog.Errorf("Failed to validate credentials of %s: %v", secretRef, err)
+			continue
+		}
+		reports[secretRef] = report
+	}
+	return reports
+}
+
 // Connect connects to vCenter with existing credentials
 // If credentials are invalid:
 //  1. It will fetch credentials from credentialManager
@@ -156,7 +388,9 @@ func (connMgr *ConnectionManager) Connect(ctx context.Context, vcInstance *VSphe
 		klog.Errorf("Unable to find credential manager for vcServer=%s credentialHolder=%s", vcInstance.Cfg.VCenterIP, vcInstance.Cfg.SecretRef)
 		return ErrUnableToFindCredentialManager
 	}
-	credentials, err := credMgr.GetCredential(vcInstance.Cfg.VCenterIP)
+	// the rejected credentials must not be served from the provider cache
+	credMgr.InvalidateCredential(vcInstance.Cfg.CredentialKey())
+	credentials, err := credMgr.GetCredential(vcInstance.Cfg.CredentialKey())
 	if err != nil {
 		klog.Error("Failed to get credentials from Secret Credential Manager with err:", err)
 		return err
@@ -167,11 +401,8 @@ func (connMgr *ConnectionManager) Connect(ctx context.Context, vcInstance *VSphe
 
 // Logout closes existing connections to remote vCenter endpoints.
 func (connMgr *ConnectionManager) Logout() {
-	for _, vsphereIns := range connMgr.VsphereInstanceMap {
-		connMgr.Lock()
-		c := vsphereIns.Conn.Client
-		connMgr.Unlock()
-		if c != nil {
+	for _, vsphereIns := range connMgr.VSphereInstances() {
+		if vsphereIns.Conn.GetClient() != nil {
 			vsphereIns.Conn.Logout(context.TODO())
 		}
 	}
@@ -180,7 +411,7 @@ func (connMgr *ConnectionManager) Logout() {
 // Verify validates the configuration by attempting to connect to the
 // configured, remote vCenter endpoints.
 func (connMgr *ConnectionManager) Verify() error {
-	for _, vcInstance := range connMgr.VsphereInstanceMap {
+	for _, vcInstance := range connMgr.VSphereInstances() {
 		err := connMgr.Connect(context.Background(), vcInstance)
 		if err == nil {
 			klog.V(3).Infof("vCenter connect %s succeeded.", vcInstance.Cfg.VCenterIP)
@@ -195,7 +426,7 @@ func (connMgr *ConnectionManager) Verify() error {
 // VerifyWithContext is the same as Verify but allows a Go Context
 // to control the lifecycle of the connection event.
 func (connMgr *ConnectionManager) VerifyWithContext(ctx context.Context) error {
-	for _, vcInstance := range connMgr.VsphereInstanceMap {
+	for _, vcInstance := range connMgr.VSphereInstances() {
 		err := connMgr.Connect(ctx, vcInstance)
 		if err == nil {
 			klog.V(3).Infof("vCenter connect %s succeeded.", vcInstance.Cfg.VCenterIP)
@@ -213,5 +444,5 @@ func (connMgr *ConnectionManager) APIVersion(vcInstance *VSphereInstance) (strin
 		return "", err
 	}
 
-	return vcInstance.Conn.Client.ServiceContent.About.ApiVersion, nil
+	return vcInstance.Conn.GetClient().ServiceContent.About.ApiVersion, nil
 }
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/session"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
+	cm "k8s.io/cloud-provider-vsphere/pkg/common/credentialmanager"
+	"k8s.io/cloud-provider-vsphere/pkg/common/vclib"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	secretInformer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Core().V1().Secrets()
+	err := secretInformer.Informer().GetIndexer().Add(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vsphere-creds", Namespace: "kube-system"},
+		Data: map[string][]byte{
+			"site-a.username": []byte("user"),
+			"site-a.password": []byte("password"),
+			"site-b.username": []byte("user"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add secret: %v", err)
+	}
+
+	newInstance := func(tenantRef, server, prefix string) *VSphereInstance {
+		return &VSphereInstance{
+			Conn: &vclib.VSphereConnection{Hostname: server},
+			Cfg: &vcfg.VirtualCenterConfig{
+				TenantRef:       tenantRef,
+				VCenterIP:       server,
+				SecretRef:       vcfg.DefaultCredentialManager,
+				SecretKeyPrefix: prefix,
+			},
+		}
+	}
+
+	connMgr := &ConnectionManager{
+		VsphereInstanceMap: map[string]*VSphereInstance{
+			"tenant-a": newInstance("tenant-a", "10.0.0.1", "site-a"),
+			"tenant-b": newInstance("tenant-b", "10.0.0.2", "site-b"),
+			"tenant-c": newInstance("tenant-c", "10.0.0.3", ""),
+		},
+		credentialManagers: map[string]*cm.CredentialManager{
+			vcfg.DefaultCredentialManager: cm.NewCredentialManager("vsphere-creds", "kube-system", "", secretInformer.Lister()),
+		},
+	}
+
+	err = connMgr.ValidateCredentials(nil)
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, expected := range []string{"tenant-b: Username/Password is missing: site-b.password", "tenant-c: Username/Password is missing: 10.0.0.3.username, 10.0.0.3.password"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in error %q", expected, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), "tenant-a") {
+		t.Errorf("did not expect tenant-a in error %q", err.Error())
+	}
+
+	credential, err := connMgr.credentialManagers[vcfg.DefaultCredentialManager].GetCredential(connMgr.VsphereInstanceMap["tenant-a"].Cfg.CredentialKey())
+	if err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if credential.User != "user" || credential.Password != "password" {
+		t.Fatalf("unexpected credential %+v", credential)
+	}
+}
+
+func TestConnectWithCredentialProvider(t *testing.T) {
+	config, cleanup := configFromSim(false)
+	defer cleanup()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "username"), []byte(config.Global.User), 0600); err != nil {
+		t.Fatalf("Failed to write username: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "password"), []byte(config.Global.Password), 0600); err != nil {
+		t.Fatalf("Failed to write password: %v", err)
+	}
+	config.CredentialProvider = vcfg.CredentialProvider{
+		Type:         vcfg.CredentialProviderFileTemplate,
+		UsernameFile: dir + "/username",
+		PasswordFile: dir + "/password",
+	}
+	vcConfig := config.VirtualCenter[config.Global.VCenterIP]
+	// vcsim rejects logins without password
+	vcConfig.Password = ""
+	vcConfig.SecretRef = vcfg.DefaultCredentialManager
+
+	connMgr := NewConnectionManager(config, nil, nil)
+	done := make(chan error)
+	go func() {
+		done <- connMgr.Connect(context.Background(), connMgr.VSphereInstances()[config.Global.VCenterIP])
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		connMgr.Logout()
+	case <-time.After(30 * time.Second):
+		t.Fatal("Connect did not return, the credential provider deadlocked")
+	}
+}
+
+func TestCredentialsUpdated(t *testing.T) {
+	config, cleanup := configFromSim(false)
+	defer cleanup()
+
+	connMgr := NewConnectionManager(config, nil, nil)
+	defer connMgr.Logout()
+	vcInstance := connMgr.VSphereInstances()[config.Global.VCenterIP]
+	if err := connMgr.Connect(context.Background(), vcInstance); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	previous := session.NewManager(vcInstance.Conn.GetClient())
+
+	connMgr.credentialsUpdated(vcInstance.Cfg.SecretRef)(vcInstance.Cfg.CredentialKey(), cm.Credential{User: "rotated", Password: "secret"})
+
+	// the re-login ends the previous session without the connection manager lock
+	connMgr.Lock()
+	defer connMgr.Unlock()
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		userSession, err := previous.UserSession(ctx)
+		return err == nil && userSession == nil, nil
+	})
+	if err != nil {
+		t.Errorf("expected re-login with the updated credentials: %v", err)
+	}
+	if vcInstance.Conn.Username != "rotated" || vcInstance.Conn.Password != "secret" {
+		t.Errorf("expected credentials to be updated, got %s", vcInstance.Conn.Username)
+	}
+}
@@ -23,8 +23,6 @@ import (
 	"time"
 
 	klog "k8s.io/klog/v2"
-
-	vclib "k8s.io/cloud-provider-vsphere/pkg/common/vclib"
 )
 
 // ListAllVCandDCPairs returns all VC/DC pairs
@@ -33,9 +31,7 @@ func (cm *ConnectionManager) ListAllVCandDCPairs(ctx context.Context) ([]*ListDi
 
 	listOfVCAndDCPairs := make([]*ListDiscoveryInfo, 0)
 
-	for _, vsi := range cm.VsphereInstanceMap {
-		var datacenterObjs []*vclib.Datacenter
-
+	for _, vsi := range cm.VSphereInstances() {
 		var err error
 		for i := 0; i < NumConnectionAttempts; i++ {
 			err = cm.Connect(ctx, vsi)
@@ -50,33 +46,26 @@ func (cm *ConnectionManager) ListAllVCandDCPairs(ctx context.Context) ([]*ListDi
 			continue
 		}
 
-		if vsi.Cfg.Datacenters == "" {
-			datacenterObjs, err = vclib.GetAllDatacenter(ctx, vsi.Conn)
+		datacenterObjs, err := datacenters(ctx, vsi)
+		if err != nil {
+			klog.Error("ListAllVCandDCPairs error dc:", err)
+		}
+
+		for _, datacenterObj := range datacenterObjs {
+			scopes, scoped, err := searchScopes(ctx, vsi, datacenterObj)
 			if err != nil {
-				klog.Error("GetAllDatacenter error dc:", err)
+				klog.Error("ListAllVCandDCPairs error scope:", err)
 				continue
 			}
-		} else {
-			datacenters := strings.Split(vsi.Cfg.Datacenters, ",")
-			for _, dc := range datacenters {
-				dc = strings.TrimSpace(dc)
-				if dc == "" {
-					continue
-				}
-				datacenterObj, err := vclib.GetDatacenter(ctx, vsi.Conn, dc)
-				if err != nil {
-					klog.Error("GetDatacenter error dc:", err)
-					continue
-				}
-				datacenterObjs = append(datacenterObjs, datacenterObj)
+			if scoped && len(scopes) == 0 {
+				klog.V(4).Infof("No VM folder or resource pool to search in vc=%s and datacenter=%s", vsi.Cfg.VCenterIP, datacenterObj.Name())
+				continue
 			}
-		}
-
-		for _, datacenterObj := range datacenterObjs {
 			listOfVCAndDCPairs = append(listOfVCAndDCPairs, &ListDiscoveryInfo{
 				TenantRef:  vsi.Cfg.TenantRef,
 				VcServer:   vsi.Cfg.VCenterIP,
 				DataCenter: datacenterObj,
+				Scopes:     scopes,
 			})
 		}
 	}
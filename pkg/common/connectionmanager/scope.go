@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	vclib "k8s.io/cloud-provider-vsphere/pkg/common/vclib"
+)
+
+// datacenters returns the datacenters of a connected vSphere instance, all of
+// them unless datacenters are configured by name, path or glob pattern. The
+// datacenters found are returned along with the error of the others.
+func datacenters(ctx context.Context, vsi *VSphereInstance) ([]*vclib.Datacenter, error) {
+	if strings.TrimSpace(vsi.Cfg.Datacenters) == "" {
+		return vclib.GetAllDatacenter(ctx, vsi.Conn)
+	}
+
+	var datacenterObjs []*vclib.Datacenter
+	var errs []error
+	seen := make(map[string]bool)
+	for _, dc := range splitList(vsi.Cfg.Datacenters) {
+		dcs, err := vclib.GetDatacenterList(ctx, vsi.Conn, dc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("datacenter %s: %v", dc, err))
+			continue
+		}
+		for _, datacenterObj := range dcs {
+			if ref := datacenterObj.Reference().Value; !seen[ref] {
+				seen[ref] = true
+				datacenterObjs = append(datacenterObjs, datacenterObj)
+			}
+		}
+	}
+	return datacenterObjs, utilerrors.NewAggregate(errs)
+}
+
+// searchScopes returns the VM folders and resource pools of the datacenter the
+// nodes of a vSphere instance are searched in. scoped is false when no VM
+// folders or resource pools are configured and the whole datacenter is searched.
+func searchScopes(ctx context.Context, vsi *VSphereInstance, dc *vclib.Datacenter) (scopes []types.ManagedObjectReference, scoped bool, err error) {
+	folders := splitList(vsi.Cfg.VMFolders)
+	pools := splitList(vsi.Cfg.ResourcePools)
+	if len(folders) == 0 && len(pools) == 0 {
+		return nil, false, nil
+	}
+	scopes, err = dc.GetVMSearchScopes(ctx, folders, pools)
+	return scopes, true, err
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
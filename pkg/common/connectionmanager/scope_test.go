@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectionmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+
+	"k8s.io/cloud-provider-vsphere/pkg/common/vclib"
+)
+
+func simVMInDatacenter(t *testing.T, dc string) *simulator.VirtualMachine {
+	t.Helper()
+	for _, obj := range simulator.Map.All("VirtualMachine") {
+		if vm := obj.(*simulator.VirtualMachine); strings.HasPrefix(vm.Name, dc+"_") {
+			return vm
+		}
+	}
+	t.Fatalf("no VM in %s", dc)
+	return nil
+}
+
+func TestSearchScopes(t *testing.T) {
+	config, cleanup := configFromEnvOrSim(true)
+	defer cleanup()
+
+	connMgr := NewConnectionManager(config, nil, nil)
+	defer connMgr.Logout()
+
+	ctx := context.Background()
+	vsi := connMgr.VsphereInstanceMap[config.Global.VCenterIP]
+	dc0 := simVMInDatacenter(t, "DC0")
+	dc1 := simVMInDatacenter(t, "DC1")
+
+	vsi.Cfg.Datacenters = "DC*, /DC0"
+	items, err := connMgr.ListAllVCandDCPairs(ctx)
+	if err != nil {
+		t.Fatalf("ListAllVCandDCPairs err=%v", err)
+	}
+	if len(items) != 2 || len(items[0].Scopes) != 0 {
+		t.Fatalf("expected DC0 and DC1 to be matched once and unscoped, got %+v", items)
+	}
+
+	vsi.Cfg.ResourcePools = "DC0_C*/Resources"
+	items, err = connMgr.ListAllVCandDCPairs(ctx)
+	if err != nil {
+		t.Fatalf("ListAllVCandDCPairs err=%v", err)
+	}
+	if len(items) != 1 || items[0].DataCenter.Name() != "DC0" || len(items[0].Scopes) != 1 {
+		t.Fatalf("expected DC0 to be scoped to its resource pool, got %+v", items)
+	}
+
+	info, err := connMgr.WhichVCandDCByNodeID(ctx, dc0.Config.Uuid, FindVMByUUID)
+	if err != nil {
+		t.Fatalf("WhichVCandDCByNodeID err=%v", err)
+	}
+	if info.DataCenter.Name() != "DC0" || !strings.EqualFold(info.UUID, dc0.Config.Uuid) {
+		t.Errorf("expected %s in DC0, got %+v", dc0.Name, info)
+	}
+	if _, err := connMgr.WhichVCandDCByNodeID(ctx, dc1.Config.Uuid, FindVMByUUID); err != vclib.ErrNoVMFound {
+		t.Errorf("expected %s out of the search scope, got %v", dc1.Name, err)
+	}
+
+	vsi.Cfg.ResourcePools = ""
+	vsi.Cfg.VMFolders = "/DC1/vm"
+	dc1.Guest.HostName = strings.ToLower(dc1.Name)
+	info, err = connMgr.WhichVCandDCByNodeID(ctx, dc1.Name, FindVMByName)
+	if err != nil {
+		t.Fatalf("WhichVCandDCByNodeID err=%v", err)
+	}
+	if info.DataCenter.Name() != "DC1" || !strings.EqualFold(info.UUID, dc1.Config.Uuid) {
+		t.Errorf("expected %s in DC1, got %+v", dc1.Name, info)
+	}
+
+	vsi.Cfg.VMFolders = "k8s"
+	items, err = connMgr.ListAllVCandDCPairs(ctx)
+	if err != nil {
+		t.Fatalf("ListAllVCandDCPairs err=%v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected no datacenter with a k8s folder, got %+v", items)
+	}
+}
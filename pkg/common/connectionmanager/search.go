@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
 	klog "k8s.io/klog/v2"
 
 	vclib "k8s.io/cloud-provider-vsphere/pkg/common/vclib"
@@ -53,6 +54,8 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 		tenantRef  string
 		vc         string
 		datacenter *vclib.Datacenter
+		scopes     []types.ManagedObjectReference
+		scoped     bool
 	}
 
 	var mutex = &sync.Mutex{}
@@ -98,9 +101,7 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 	}
 
 	go func() {
-		for _, vsi := range cm.VsphereInstanceMap {
-			var datacenterObjs []*vclib.Datacenter
-
+		for _, vsi := range cm.VSphereInstances() {
 			if getVMFound() {
 				break
 			}
@@ -120,28 +121,10 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 				continue
 			}
 
-			if vsi.Cfg.Datacenters == "" {
-				datacenterObjs, err = vclib.GetAllDatacenter(ctx, vsi.Conn)
-				if err != nil {
-					klog.Error("WhichVCandDCByNodeID error dc:", err)
-					setGlobalErr(err)
-					continue
-				}
-			} else {
-				datacenters := strings.Split(vsi.Cfg.Datacenters, ",")
-				for _, dc := range datacenters {
-					dc = strings.TrimSpace(dc)
-					if dc == "" {
-						continue
-					}
-					datacenterObj, err := vclib.GetDatacenter(ctx, vsi.Conn, dc)
-					if err != nil {
-						klog.Error("WhichVCandDCByNodeID error dc:", err)
-						setGlobalErr(err)
-						continue
-					}
-					datacenterObjs = append(datacenterObjs, datacenterObj)
-				}
+			datacenterObjs, err := datacenters(ctx, vsi)
+			if err != nil {
+				klog.Error("WhichVCandDCByNodeID error dc:", err)
+				setGlobalErr(err)
 			}
 
 			for _, datacenterObj := range datacenterObjs {
@@ -149,11 +132,24 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 					break
 				}
 
+				scopes, scoped, err := searchScopes(ctx, vsi, datacenterObj)
+				if err != nil {
+					klog.Error("WhichVCandDCByNodeID error scope:", err)
+					setGlobalErr(err)
+					continue
+				}
+				if scoped && len(scopes) == 0 {
+					klog.V(4).Infof("No VM folder or resource pool to search in vc=%s and datacenter=%s", vsi.Cfg.VCenterIP, datacenterObj.Name())
+					continue
+				}
+
 				klog.V(4).Infof("Finding node %s in vc=%s and datacenter=%s", myNodeID, vsi.Cfg.VCenterIP, datacenterObj.Name())
 				queueChannel <- &vmSearch{
 					tenantRef:  vsi.Cfg.TenantRef,
 					vc:         vsi.Cfg.VCenterIP,
 					datacenter: datacenterObj,
+					scopes:     scopes,
+					scoped:     scoped,
 				}
 			}
 		}
@@ -168,11 +164,17 @@ func (cm *ConnectionManager) WhichVCandDCByNodeID(ctx context.Context, nodeID st
 				var vm *vclib.VirtualMachine
 				var err error
 
-				switch searchBy {
-				case FindVMByUUID:
+				switch {
+				case searchBy == FindVMByUUID && res.scoped:
+					vm, err = res.datacenter.GetVMByUUIDInScopes(ctx, res.scopes, myNodeID)
+				case searchBy == FindVMByUUID:
 					vm, err = res.datacenter.GetVMByUUID(ctx, myNodeID)
-				case FindVMByIP:
+				case searchBy == FindVMByIP && res.scoped:
+					vm, err = res.datacenter.GetVMByIPInScopes(ctx, res.scopes, myNodeID)
+				case searchBy == FindVMByIP:
 					vm, err = res.datacenter.GetVMByIP(ctx, myNodeID)
+				case res.scoped:
+					vm, err = res.datacenter.GetVMByDNSNameInScopes(ctx, res.scopes, myNodeID)
 				default:
 					vm, err = res.datacenter.GetVMByDNSName(ctx, myNodeID)
 				}
@@ -274,9 +276,7 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 	}
 
 	go func() {
-		for _, vsi := range cm.VsphereInstanceMap {
-			var datacenterObjs []*vclib.Datacenter
-
+		for _, vsi := range cm.VSphereInstances() {
 			if getFCDFound() {
 				break
 			}
@@ -296,28 +296,10 @@ func (cm *ConnectionManager) WhichVCandDCByFCDId(ctx context.Context, fcdID stri
 				continue
 			}
 
-			if vsi.Cfg.Datacenters == "" {
-				datacenterObjs, err = vclib.GetAllDatacenter(ctx, vsi.Conn)
-				if err != nil {
-					klog.Error("WhichVCandDCByFCDId error dc:", err)
-					setGlobalErr(err)
-					continue
-				}
-			} else {
-				datacenters := strings.Split(vsi.Cfg.Datacenters, ",")
-				for _, dc := range datacenters {
-					dc = strings.TrimSpace(dc)
-					if dc == "" {
-						continue
-					}
-					datacenterObj, err := vclib.GetDatacenter(ctx, vsi.Conn, dc)
-					if err != nil {
-						klog.Error("WhichVCandDCByFCDId error dc:", err)
-						setGlobalErr(err)
-						continue
-					}
-					datacenterObjs = append(datacenterObjs, datacenterObj)
-				}
+			datacenterObjs, err := datacenters(ctx, vsi)
+			if err != nil {
+				klog.Error("WhichVCandDCByFCDId error dc:", err)
+				setGlobalErr(err)
 			}
 
 			for _, datacenterObj := range datacenterObjs {
@@ -19,7 +19,9 @@ package connectionmanager
 import (
 	"sync"
 
+	"github.com/vmware/govmomi/vim25/types"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
 	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 	cm "k8s.io/cloud-provider-vsphere/pkg/common/credentialmanager"
 	k8s "k8s.io/cloud-provider-vsphere/pkg/common/kubernetes"
@@ -33,14 +35,21 @@ type ConnectionManager struct {
 	// The k8s client init from the cloud provider service account
 	client clientset.Interface
 
-	// Maps the VC server to VSphereInstance
+	// Maps the VC server to VSphereInstance. The map is replaced as a whole when
+	// the config is reloaded, use VSphereInstances to read it.
 	VsphereInstanceMap map[string]*VSphereInstance
+	instancesLock      sync.RWMutex
 	// CredentialManager per VC
 	// The global CredentialManager will have an entry in this map with the key of "Global"
 	credentialManagers map[string]*cm.CredentialManager
 	// InformerManagers per VC
 	// The global InformerManager will have an entry in this map with the key of "Global"
 	informerManagers map[string]*k8s.InformerManager
+
+	// reLogins are the vCenter connections waiting for a re-login with
+	// updated credentials, handled by a single worker
+	reLogins      workqueue.Interface
+	startReLogins sync.Once
 }
 
 // VSphereInstance represents a vSphere instance where one or more kubernetes nodes are running.
@@ -72,6 +81,9 @@ type ListDiscoveryInfo struct {
 	TenantRef  string
 	VcServer   string
 	DataCenter *vclib.Datacenter
+	// Scopes are the VM folders and resource pools the nodes are searched
+	// in, empty when the whole datacenter is searched
+	Scopes []types.ManagedObjectReference
 }
 
 // ZoneDiscoveryInfo contains VC+DC info based on a given zone
@@ -52,7 +52,7 @@ func (cm *ConnectionManager) WhichVCandDCByZone(ctx context.Context,
 	klog.V(4).Infof("WhichVCandDCByZone called with zone: %s and region: %s", zoneLooking, regionLooking)
 
 	// Need at least one VC
-	numOfVCs := len(cm.VsphereInstanceMap)
+	numOfVCs := len(cm.VSphereInstances())
 	if numOfVCs == 0 {
 		err := ErrMustHaveAtLeastOneVCDC
 		klog.Errorf("%v", err)
@@ -72,7 +72,8 @@ func (cm *ConnectionManager) getDIFromSingleVC(ctx context.Context,
 	zoneLabel string, regionLabel string, zoneLooking string, regionLooking string) (*ZoneDiscoveryInfo, error) {
 	klog.V(4).Infof("getDIFromSingleVC called with zone: %s and region: %s", zoneLooking, regionLooking)
 
-	if len(cm.VsphereInstanceMap) != 1 {
+	instances := cm.VSphereInstances()
+	if len(instances) != 1 {
 		err := ErrUnsupportedConfiguration
 		klog.Errorf("%v", err)
 		return nil, err
@@ -82,7 +83,7 @@ func (cm *ConnectionManager) getDIFromSingleVC(ctx context.Context,
 
 	// Get first vSphere Instance
 	var tmpVsi *VSphereInstance
-	for _, tmpVsi = range cm.VsphereInstanceMap {
+	for _, tmpVsi = range instances {
 		break //Grab the first one because there is only one
 	}
 
@@ -172,9 +173,7 @@ func (cm *ConnectionManager) getDIFromMultiVCorDC(ctx context.Context,
 	}
 
 	go func() {
-		for _, vsi := range cm.VsphereInstanceMap {
-			var datacenterObjs []*vclib.Datacenter
-
+		for _, vsi := range cm.VSphereInstances() {
 			if getZoneFound() {
 				break
 			}
@@ -194,28 +193,10 @@ func (cm *ConnectionManager) getDIFromMultiVCorDC(ctx context.Context,
 				continue
 			}
 
-			if vsi.Cfg.Datacenters == "" {
-				datacenterObjs, err = vclib.GetAllDatacenter(ctx, vsi.Conn)
-				if err != nil {
-					klog.Error("getDIFromMultiVCorDC error dc:", err)
-					setGlobalErr(err)
-					continue
-				}
-			} else {
-				datacenters := strings.Split(vsi.Cfg.Datacenters, ",")
-				for _, dc := range datacenters {
-					dc = strings.TrimSpace(dc)
-					if dc == "" {
-						continue
-					}
-					datacenterObj, err := vclib.GetDatacenter(ctx, vsi.Conn, dc)
-					if err != nil {
-						klog.Error("getDIFromMultiVCorDC error dc:", err)
-						setGlobalErr(err)
-						continue
-					}
-					datacenterObjs = append(datacenterObjs, datacenterObj)
-				}
+			datacenterObjs, err := datacenters(ctx, vsi)
+			if err != nil {
+				klog.Error("getDIFromMultiVCorDC error dc:", err)
+				setGlobalErr(err)
 			}
 
 			for _, datacenterObj := range datacenterObjs {
@@ -291,8 +272,10 @@ func (cm *ConnectionManager) getDIFromMultiVCorDC(ctx context.Context,
 }
 
 func withTagsClient(ctx context.Context, connection *vclib.VSphereConnection, f func(c *rest.Client) error) error {
-	c := rest.NewClient(connection.Client)
-	signer, err := connection.Signer(ctx, connection.Client)
+	vimClient := connection.GetClient()
+	c := rest.NewClient(vimClient)
+	c.Transport = connection.Limiter.Transport(c.Transport)
+	signer, err := connection.Signer(ctx, vimClient)
 	if err != nil {
 		return err
 	}
@@ -320,7 +303,7 @@ func (cm *ConnectionManager) LookupZoneByMoref(ctx context.Context, tenantRef st
 
 	result := make(map[string]string)
 
-	vsi := cm.VsphereInstanceMap[tenantRef]
+	vsi := cm.VSphereInstances()[tenantRef]
 	if vsi == nil {
 		err := ErrConnectionNotFound
 		klog.Errorf("Unable to find Connection for tenantRef=%s", tenantRef)
@@ -330,9 +313,10 @@ func (cm *ConnectionManager) LookupZoneByMoref(ctx context.Context, tenantRef st
 	err := withTagsClient(ctx, vsi.Conn, func(c *rest.Client) error {
 		client := tags.NewManager(c)
 
-		pc := vsi.Conn.Client.ServiceContent.PropertyCollector
+		vimClient := vsi.Conn.GetClient()
+		pc := vimClient.ServiceContent.PropertyCollector
 		// example result: ["Folder", "Datacenter", "Cluster", "Host"]
-		objects, err := mo.Ancestors(ctx, vsi.Conn.Client, pc, moRef)
+		objects, err := mo.Ancestors(ctx, vimClient, pc, moRef)
 		if err != nil {
 			klog.Errorf("Ancestors failed for %s with err %v", moRef, err)
 			return err
@@ -32,11 +32,10 @@ func NewCredentialManager(secretName string, secretNamespace string, secretsDire
 	secretLister v1.SecretLister) *CredentialManager {
 
 	return &CredentialManager{
-		SecretName:             secretName,
-		SecretNamespace:        secretNamespace,
-		SecretsDirectory:       secretsDirectory,
-		SecretLister:           secretLister,
-		secretsDirectoryParsed: false,
+		SecretName:       secretName,
+		SecretNamespace:  secretNamespace,
+		SecretsDirectory: secretsDirectory,
+		SecretLister:     secretLister,
 		Cache: &SecretCache{
 			VirtualCenter: make(map[string]*Credential),
 		},
@@ -45,7 +44,26 @@ func NewCredentialManager(secretName string, secretNamespace string, secretsDire
 
 // GetCredential returns credentials for the given vCenter Server.
 // GetCredential returns error if Secret is not added or SecretDirectory is not set (ie No Creds).
+// If a CredentialProvider is configured, it is the only source of credentials.
+// Once the cache is kept up to date by AddSecretListener or WatchSecretsDirectory,
+// credentials are served from the cache and the secret is only read on a cache miss.
 func (credentialManager *CredentialManager) GetCredential(server string) (*Credential, error) {
+	if credentialManager.Provider != nil {
+		return credentialManager.getProviderCredential(server)
+	}
+
+	if credentialManager.isWatching() {
+		if credential, found := credentialManager.Cache.GetCredential(server); found {
+			return &credential, nil
+		}
+		klog.V(4).Infof("credentials for server %s not cached yet, reading secrets", server)
+	}
+
+	// parseErr is returned if the secret is incomplete and the requested server
+	// is not among the complete entries, so that a single incomplete vCenter
+	// entry in a shared secret does not break the other vCenters
+	var parseErr error
+
 	//get the creds using the K8s listener if it exists
 	if credentialManager.SecretLister != nil {
 		klog.V(4).Info("SecretLister is valid. Retrieving secrets.")
@@ -53,11 +71,15 @@ func (credentialManager *CredentialManager) GetCredential(server string) (*Crede
 		if err != nil {
 			klog.Errorf("updateCredentialsMapK8s failed. err=%s", err)
 			statusErr, ok := err.(*apierrors.StatusError)
-			if (ok && statusErr.ErrStatus.Code != http.StatusNotFound) || !ok {
+			if ok && statusErr.ErrStatus.Code != http.StatusNotFound {
 				return nil, err
 			}
-			// Handle secrets deletion by finding credentials from cache
-			klog.Warningf("secret %q not found in namespace %q", credentialManager.SecretName, credentialManager.SecretNamespace)
+			if ok {
+				// Handle secrets deletion by finding credentials from cache
+				klog.Warningf("secret %q not found in namespace %q", credentialManager.SecretName, credentialManager.SecretNamespace)
+			} else {
+				parseErr = err
+			}
 		}
 	}
 
@@ -71,11 +93,14 @@ func (credentialManager *CredentialManager) GetCredential(server string) (*Crede
 	}
 
 	credential, found := credentialManager.Cache.GetCredential(server)
-	if !found {
-		klog.Errorf("credentials not found for server %s", server)
-		return nil, ErrCredentialsNotFound
+	if found && (parseErr == nil || (credential.User != "" && credential.Password != "")) {
+		return &credential, nil
+	}
+	if parseErr != nil {
+		return nil, parseErr
 	}
-	return &credential, nil
+	klog.Errorf("credentials not found for server %s", server)
+	return nil, ErrCredentialsNotFound
 }
 
 func (credentialManager *CredentialManager) updateCredentialsMapK8s() error {
@@ -102,18 +127,29 @@ func (credentialManager *CredentialManager) updateCredentialsMapK8s() error {
 
 func (credentialManager *CredentialManager) updateCredentialsMapFile() error {
 	//Secretsdirectory was parsed before, no need to do it again
-	if credentialManager.secretsDirectoryParsed {
+	if credentialManager.secretsDirectoryParsed.Swap(true) {
 		return nil
 	}
 
 	//take the mounted secrets in the form of files and make it looks like we
 	//parsed it from a k8s secret so we can reuse the SecretCache.parseSecret() func
+	data, err := credentialManager.readSecretsDirectory()
+	if err != nil {
+		return err
+	}
+
+	credentialManager.Cache.UpdateSecretFile(data)
+	return credentialManager.Cache.parseSecret()
+}
+
+// readSecretsDirectory returns the content of the files in the SecretsDirectory
+// keyed by file name, the same way they would be in a Kubernetes secret.
+func (credentialManager *CredentialManager) readSecretsDirectory() (map[string][]byte, error) {
 	data := make(map[string][]byte)
 	entries, err := os.ReadDir(credentialManager.SecretsDirectory)
 	if err != nil {
-		credentialManager.secretsDirectoryParsed = true
 		klog.Warningf("Failed to find secrets directory %s. error: %q", credentialManager.SecretsDirectory, err)
-		return err
+		return nil, err
 	}
 
 	for _, f := range entries {
@@ -132,9 +168,7 @@ func (credentialManager *CredentialManager) updateCredentialsMapFile() error {
 		data[f.Name()] = contents
 	}
 
-	credentialManager.secretsDirectoryParsed = true
-	credentialManager.Cache.UpdateSecretFile(data)
-	return credentialManager.Cache.parseSecret()
+	return data, nil
 }
 
 // GetSecret returns a Kubernetes secret.
@@ -187,110 +221,23 @@ func (cache *SecretCache) parseSecret() error {
 }
 
 // parseConfig returns vCenter ip/fdqn mapping to its credentials viz. Username and Password.
+// The keys are classified like in ValidateSecretData, both the "<server>.username"
+// format and the alternative server_/username_/password_ format are supported.
+// The latter is needed because IPv6 addresses have colons, making the original
+// Secret format unusable. The credentials found are added to config even if the
+// secret is invalid, the returned *ValidationError reports all problems.
 func parseConfig(data map[string][]byte, config map[string]*Credential) error {
-	if len(data) == 0 {
-		return ErrCredentialMissing
-	}
-	unknownKeys := map[string][]byte{}
-	for credentialKey, credentialValue := range data {
-		if strings.HasSuffix(credentialKey, "password") {
-			vcServer := strings.Split(credentialKey, ".password")[0]
-			if _, ok := config[vcServer]; !ok {
-				config[vcServer] = &Credential{}
-			}
-			config[vcServer].Password = strings.TrimSuffix(string(credentialValue), "\n")
-		} else if strings.HasSuffix(credentialKey, "username") {
-			vcServer := strings.Split(credentialKey, ".username")[0]
-			if _, ok := config[vcServer]; !ok {
-				config[vcServer] = &Credential{}
-			}
-			config[vcServer].User = strings.TrimSuffix(string(credentialValue), "\n")
-		} else {
-			unknownKeys[credentialKey] = credentialValue
+	report := ValidateSecretData(data)
+	for _, server := range report.Servers {
+		if _, ok := config[server.Server]; !ok {
+			config[server.Server] = &Credential{}
 		}
-	}
-
-	// Attempt to parse server/username/password from keys in the
-	// alternative format. Iterate leftover key/values, looking for
-	// entries that look like this:
-	//   server_a: fd01::1
-	//   username_a: vcenter-user
-	//   password_a: vcenter-pass
-	// This alternative format is needed because IPv6 addresses have colons,
-	// making the original Secret format unusable.
-	potentialAltFormatKeys := unknownKeys
-	for credentialKey := range potentialAltFormatKeys {
-		if strings.HasPrefix(credentialKey, serverPrefix) {
-			parts := strings.Split(credentialKey, serverPrefix)
-			if parts[1] != "" {
-				serverKeySuffix := parts[1]
-				passwordKey := passwordPrefix + serverKeySuffix
-				usernameKey := usernamePrefix + serverKeySuffix
-				serverKey := serverPrefix + serverKeySuffix
-
-				var serverName, password, username []byte
-				var ok bool
-				serverName = data[serverKey]
-				if _, ok := config[string(serverName)]; !ok {
-					config[string(serverName)] = &Credential{}
-				}
-
-				if username, ok = data[usernameKey]; !ok {
-					klog.Errorf("%s is missing for server %s", usernameKey, serverName)
-					return ErrCredentialMissing
-				}
-				config[string(serverName)].User = string(username)
-				if password, ok = data[passwordKey]; !ok {
-					klog.Errorf("%s is missing for server %s", passwordKey, serverName)
-					return ErrCredentialMissing
-				}
-				config[string(serverName)].Password = string(password)
-				delete(unknownKeys, passwordKey)
-				delete(unknownKeys, usernameKey)
-				delete(unknownKeys, serverKey)
-			} else {
-				klog.Error("server secret key missing suffix")
-				return ErrUnknownSecretKey
-			}
+		if server.HasUsername {
+			config[server.Server].User = strings.TrimSuffix(string(data[server.UsernameKey]), "\n")
 		}
-	}
-
-	// Return errors if there are incomplete secret sets found. Return an error
-	// when a username or password was found but no server address was found.
-	// Return an error if username or password keys have no identifier suffix.
-	for credentialKey := range unknownKeys {
-		if strings.HasPrefix(credentialKey, usernamePrefix) {
-			parts := strings.Split(credentialKey, usernamePrefix)
-			if parts[1] == "" {
-				klog.Errorf("Found username key with no suffix identifier.")
-				return ErrUnknownSecretKey
-			}
-			identifier := parts[1]
-			klog.Errorf("Found username key \"%s\" without a matching \"%s\" identifier", credentialKey, serverPrefix+identifier)
-			return ErrIncompleteCredentialSet
-		}
-		if strings.HasPrefix(credentialKey, passwordPrefix) {
-			parts := strings.Split(credentialKey, passwordPrefix)
-			if parts[1] == "" {
-				klog.Errorf("Found password key with no suffix identifier.")
-				return ErrUnknownSecretKey
-			}
-			identifier := parts[1]
-			klog.Errorf("Found password key \"%s\" without a matching \"%s\" identifier", credentialKey, serverPrefix+identifier)
-			return ErrIncompleteCredentialSet
-		}
-	}
-
-	for credentialKey := range unknownKeys {
-		klog.Errorf("Unknown secret key %s", credentialKey)
-		return ErrUnknownSecretKey
-	}
-
-	for vcServer, credential := range config {
-		if credential.User == "" || credential.Password == "" {
-			klog.Errorf("Username/Password is missing for server %s", vcServer)
-			return ErrCredentialMissing
+		if server.HasPassword {
+			config[server.Server].Password = strings.TrimSuffix(string(data[server.PasswordKey]), "\n")
 		}
 	}
-	return nil
+	return report.Err()
 }
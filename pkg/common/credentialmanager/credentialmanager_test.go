@@ -17,6 +17,7 @@ limitations under the License.
 package credentialmanager
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -254,7 +255,7 @@ func TestSecretCredentialManagerK8s_GetCredential(t *testing.T) {
 				expected := test.expectedValues[ntest].(GetCredentialsTest)
 				credential, err := secretCredentialManager.GetCredential(expected.server)
 				t.Logf("Retrieving credentials for server %s", expected.server)
-				if err != expected.err {
+				if !errors.Is(err, expected.err) {
 					t.Fatalf("Fail to get credentials with error: %v", err)
 				}
 				if expected.err == nil {
@@ -480,7 +481,7 @@ func TestParseSecretConfig(t *testing.T) {
 	for _, testcase := range testcases {
 		err := parseConfig(testcase.data, resultConfig)
 		t.Logf("Executing Testcase: %s", testcase.testName)
-		if err != testcase.expectedError {
+		if !errors.Is(err, testcase.expectedError) {
 			t.Fatalf("Parsing Secret failed for data %+v: %s", testcase.data, err)
 		}
 		if testcase.config != nil && !reflect.DeepEqual(testcase.config, resultConfig) {
@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// ServerEnvVar is the environment variable holding the vCenter Server
+	// for which the exec credential provider is run.
+	ServerEnvVar = "VSPHERE_SERVER"
+
+	// DefaultExecTimeout is the time the exec credential provider waits for
+	// the command to return before giving up.
+	DefaultExecTimeout = 30 * time.Second
+)
+
+// CredentialProvider sources vCenter credentials from outside of Kubernetes
+// secrets, for example from a Vault agent or a cloud secret manager.
+type CredentialProvider interface {
+	// GetCredential returns the credential for the given vCenter Server.
+	GetCredential(server string) (*Credential, error)
+	// InvalidateCredential drops a cached credential of the given vCenter
+	// Server, for example after vCenter rejected it.
+	InvalidateCredential(server string)
+}
+
+// NewProviderCredentialManager returns a new CredentialManager object that
+// reads credentials from the given CredentialProvider.
+func NewProviderCredentialManager(provider CredentialProvider) *CredentialManager {
+	credentialManager := NewCredentialManager("", "", "", nil)
+	credentialManager.Provider = provider
+	return credentialManager
+}
+
+// getProviderCredential reads the credential from the provider and updates the
+// cache. The listeners are not notified, as the caller uses the returned
+// credential and may hold locks the listeners need.
+func (credentialManager *CredentialManager) getProviderCredential(server string) (*Credential, error) {
+	credential, err := credentialManager.Provider.GetCredential(server)
+	if err != nil {
+		klog.Errorf("credential provider failed for server %s. err=%v", server, err)
+		return nil, err
+	}
+	credentialManager.Cache.updateCredential(server, *credential)
+	return credential, nil
+}
+
+// InvalidateCredential drops the credential of the given vCenter Server cached
+// by the CredentialProvider, so that the next request obtains a new one.
+func (credentialManager *CredentialManager) InvalidateCredential(server string) {
+	if credentialManager.Provider != nil {
+		credentialManager.Provider.InvalidateCredential(server)
+	}
+}
+
+// updateCredential sets the cached credential for the given vCenter Server
+func (cache *SecretCache) updateCredential(server string, credential Credential) {
+	cache.cacheLock.Lock()
+	defer cache.cacheLock.Unlock()
+	cache.VirtualCenter[server] = &credential
+}
+
+// ExecCredential is the JSON document written to stdout by the command run by
+// ExecCredentialProvider.
+type ExecCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Expiry is optional. When set, the credential is reused until it expires,
+	// otherwise until it is invalidated.
+	Expiry *time.Time `json:"expiry,omitempty"`
+}
+
+// ExecCredentialProvider runs a configured binary and reads the credentials
+// from its output, similar to kubectl exec credential plugins.
+type ExecCredentialProvider struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+
+	cacheLock sync.Mutex
+	cache     map[string]ExecCredential
+	// calls are the running commands by server, concurrent requests for the
+	// same server wait for the running command instead of starting another
+	calls map[string]*execCall
+}
+
+// execCall is a running command of the ExecCredentialProvider
+type execCall struct {
+	done       chan struct{}
+	credential ExecCredential
+	err        error
+}
+
+// NewExecCredentialProvider returns a new ExecCredentialProvider object.
+func NewExecCredentialProvider(command string, args []string, timeout time.Duration) *ExecCredentialProvider {
+	if timeout == 0 {
+		timeout = DefaultExecTimeout
+	}
+	return &ExecCredentialProvider{
+		Command: command,
+		Args:    args,
+		Timeout: timeout,
+		cache:   make(map[string]ExecCredential),
+		calls:   make(map[string]*execCall),
+	}
+}
+
+// GetCredential returns the cached credential if it has not expired, otherwise
+// runs the command to obtain a new one. The command runs once per server at a
+// time, concurrent requests share its result.
+func (p *ExecCredentialProvider) GetCredential(server string) (*Credential, error) {
+	p.cacheLock.Lock()
+	if cached, ok := p.cache[server]; ok && (cached.Expiry == nil || time.Now().Before(*cached.Expiry)) {
+		p.cacheLock.Unlock()
+		return &Credential{User: cached.Username, Password: cached.Password}, nil
+	}
+	call, running := p.calls[server]
+	if !running {
+		call = &execCall{done: make(chan struct{})}
+		p.calls[server] = call
+	}
+	p.cacheLock.Unlock()
+
+	if !running {
+		call.credential, call.err = p.run(server)
+
+		p.cacheLock.Lock()
+		if call.err == nil {
+			p.cache[server] = call.credential
+		}
+		delete(p.calls, server)
+		p.cacheLock.Unlock()
+		close(call.done)
+	}
+
+	<-call.done
+	if call.err != nil {
+		return nil, call.err
+	}
+	return &Credential{User: call.credential.Username, Password: call.credential.Password}, nil
+}
+
+// InvalidateCredential drops the cached credential of the server, so that the
+// command is run again on the next request.
+func (p *ExecCredentialProvider) InvalidateCredential(server string) {
+	p.cacheLock.Lock()
+	defer p.cacheLock.Unlock()
+	delete(p.cache, server)
+}
+
+// run runs the command for the server and parses its output
+func (p *ExecCredentialProvider) run(server string) (ExecCredential, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Env = append(os.Environ(), ServerEnvVar+"="+server)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	klog.V(4).Infof("Running credential provider %s for server %s", p.Command, server)
+	if err := cmd.Run(); err != nil {
+		return ExecCredential{}, fmt.Errorf("credential provider %s failed: %v: %s", p.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var execCredential ExecCredential
+	if err := json.Unmarshal(stdout.Bytes(), &execCredential); err != nil {
+		return ExecCredential{}, fmt.Errorf("credential provider %s returned invalid output: %v", p.Command, err)
+	}
+	if execCredential.Username == "" || execCredential.Password == "" {
+		return ExecCredential{}, ErrCredentialMissing
+	}
+	return execCredential, nil
+}
+
+// FileTemplateCredentialProvider reads the credentials from files whose paths
+// are rendered from templates, for example files written by a Vault agent.
+// The templates are executed with a struct holding the vCenter Server, so
+// "/vault/secrets/{{.Server}}/password" is a valid template.
+type FileTemplateCredentialProvider struct {
+	usernameFile *template.Template
+	passwordFile *template.Template
+}
+
+// NewFileTemplateCredentialProvider returns a new FileTemplateCredentialProvider
+// object or an error if the templates cannot be parsed.
+func NewFileTemplateCredentialProvider(usernameFile, passwordFile string) (*FileTemplateCredentialProvider, error) {
+	usernameTemplate, err := template.New("usernameFile").Option("missingkey=error").Parse(usernameFile)
+	if err != nil {
+		return nil, err
+	}
+	passwordTemplate, err := template.New("passwordFile").Option("missingkey=error").Parse(passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTemplateCredentialProvider{
+		usernameFile: usernameTemplate,
+		passwordFile: passwordTemplate,
+	}, nil
+}
+
+// GetCredential reads the username and password files for the given vCenter Server.
+func (p *FileTemplateCredentialProvider) GetCredential(server string) (*Credential, error) {
+	username, err := readTemplatedFile(p.usernameFile, server)
+	if err != nil {
+		return nil, err
+	}
+	password, err := readTemplatedFile(p.passwordFile, server)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" || password == "" {
+		return nil, ErrCredentialMissing
+	}
+	return &Credential{User: username, Password: password}, nil
+}
+
+// InvalidateCredential does nothing, the files are read on every request
+func (p *FileTemplateCredentialProvider) InvalidateCredential(server string) {}
+
+// readTemplatedFile renders the path for the given vCenter Server and returns
+// the content of the file without the trailing newline.
+func readTemplatedFile(pathTemplate *template.Template, server string) (string, error) {
+	var path strings.Builder
+	if err := pathTemplate.Execute(&path, struct{ Server string }{Server: server}); err != nil {
+		return "", err
+	}
+	contents, err := os.ReadFile(path.String())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(contents), "\n"), nil
+}
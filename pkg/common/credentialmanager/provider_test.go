@@ -0,0 +1,165 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecCredentialProvider(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "creds.sh")
+	content := `#!/bin/sh
+echo run >> ` + counter + `
+echo "{\"username\": \"user-$VSPHERE_SERVER\", \"password\": \"$1\", \"expiry\": \"$2\"}"
+`
+	if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	runs := func() int {
+		data, err := os.ReadFile(counter)
+		if err != nil {
+			return 0
+		}
+		return len(data) / len("run\n")
+	}
+
+	expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	credMgr := NewProviderCredentialManager(NewExecCredentialProvider(script, []string{"secret", expiry}, 0))
+	notified := map[string]Credential{}
+	credMgr.AddCredentialListener(func(server string, credential Credential) {
+		notified[server] = credential
+	})
+
+	credential, err := credMgr.GetCredential("10.0.0.1")
+	if err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if credential.User != "user-10.0.0.1" || credential.Password != "secret" {
+		t.Fatalf("unexpected credential %+v", credential)
+	}
+	if len(notified) != 0 {
+		t.Fatalf("expected listener not to be notified, got %+v", notified)
+	}
+
+	if _, err := credMgr.GetCredential("10.0.0.1"); err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if cached, _ := credMgr.Cache.GetCredential("10.0.0.1"); cached != *credential {
+		t.Fatalf("expected credential to be cached, got %+v", cached)
+	}
+	if runs() != 1 {
+		t.Fatalf("expected the command to run once before expiry, ran %d times", runs())
+	}
+
+	failing := NewExecCredentialProvider(filepath.Join(dir, "missing.sh"), nil, time.Second)
+	if _, err := failing.GetCredential("10.0.0.1"); err == nil {
+		t.Fatal("expected an error for a missing command")
+	}
+}
+
+func TestExecCredentialProviderWithoutExpiry(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "creds.sh")
+	content := `#!/bin/sh
+echo run >> ` + counter + `
+sleep 0.2
+echo "{\"username\": \"user\", \"password\": \"secret\"}"
+`
+	if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	runs := func() int {
+		data, err := os.ReadFile(counter)
+		if err != nil {
+			return 0
+		}
+		return len(data) / len("run\n")
+	}
+
+	provider := NewExecCredentialProvider(script, nil, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := provider.GetCredential("10.0.0.1"); err != nil {
+				t.Errorf("GetCredential failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if runs() != 1 {
+		t.Fatalf("expected concurrent requests to share one run, ran %d times", runs())
+	}
+
+	if _, err := provider.GetCredential("10.0.0.1"); err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if runs() != 1 {
+		t.Fatalf("expected the credential without expiry to be cached, ran %d times", runs())
+	}
+
+	provider.InvalidateCredential("10.0.0.1")
+	if _, err := provider.GetCredential("10.0.0.1"); err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if runs() != 2 {
+		t.Fatalf("expected the command to run again after invalidation, ran %d times", runs())
+	}
+}
+
+func TestFileTemplateCredentialProvider(t *testing.T) {
+	dir := t.TempDir()
+	serverDir := filepath.Join(dir, "10.0.0.1")
+	if err := os.Mkdir(serverDir, 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "username"), []byte("user\n"), 0600); err != nil {
+		t.Fatalf("Failed to write username: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(serverDir, "password"), []byte("password\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password: %v", err)
+	}
+
+	provider, err := NewFileTemplateCredentialProvider(dir+"/{{.Server}}/username", dir+"/{{.Server}}/password")
+	if err != nil {
+		t.Fatalf("NewFileTemplateCredentialProvider failed: %v", err)
+	}
+
+	credential, err := provider.GetCredential("10.0.0.1")
+	if err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if credential.User != "user" || credential.Password != "password" {
+		t.Fatalf("unexpected credential %+v", credential)
+	}
+
+	if _, err := provider.GetCredential("10.0.0.2"); err == nil {
+		t.Fatal("expected an error for a server without credential files")
+	}
+
+	if _, err := NewFileTemplateCredentialProvider("{{.Server", "password"); err == nil {
+		t.Fatal("expected an error for an invalid template")
+	}
+}
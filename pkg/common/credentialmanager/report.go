@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Redacted replaces secret values wherever credentials are printed.
+	Redacted = "<redacted>"
+
+	usernameSuffix = ".username"
+	passwordSuffix = ".password"
+)
+
+// String implements fmt.Stringer so that printing a Credential never reveals
+// the password.
+func (c Credential) String() string {
+	return fmt.Sprintf("{User:%s Password:%s}", c.User, Redacted)
+}
+
+// GoString implements fmt.GoStringer so that %#v does not reveal the password.
+func (c Credential) GoString() string {
+	return c.String()
+}
+
+// ServerReport describes the credentials found in a secret for one vCenter
+// Server. Only key names are reported, never values.
+type ServerReport struct {
+	// Server is the vCenter Server or secret key prefix
+	Server string `json:"server"`
+	// UsernameKey and PasswordKey are the keys holding the credentials
+	UsernameKey string `json:"usernameKey"`
+	PasswordKey string `json:"passwordKey"`
+	HasUsername bool   `json:"hasUsername"`
+	HasPassword bool   `json:"hasPassword"`
+}
+
+// MissingKeys returns the keys of the server entry that are not in the secret.
+func (r *ServerReport) MissingKeys() []string {
+	var missing []string
+	if !r.HasUsername {
+		missing = append(missing, r.UsernameKey)
+	}
+	if !r.HasPassword {
+		missing = append(missing, r.PasswordKey)
+	}
+	return missing
+}
+
+// MalformedTriplet describes an incomplete or invalid set of server_,
+// username_ and password_ keys.
+type MalformedTriplet struct {
+	// Suffix is the identifier shared by the keys of the triplet
+	Suffix string `json:"suffix"`
+	// Keys present in the secret for this suffix
+	Keys []string `json:"keys"`
+	// Reason explains what is wrong with the triplet
+	Reason string `json:"reason"`
+}
+
+// ValidationReport is the result of validating the content of a credentials
+// secret. It contains key names and server names only, values are never
+// included so the report is safe to log and to publish.
+type ValidationReport struct {
+	Servers           []ServerReport     `json:"servers"`
+	UnknownKeys       []string           `json:"unknownKeys,omitempty"`
+	MalformedTriplets []MalformedTriplet `json:"malformedTriplets,omitempty"`
+}
+
+// Valid returns true if every server entry is complete and the secret has no
+// unknown keys or malformed triplets.
+func (r *ValidationReport) Valid() bool {
+	if len(r.Servers) == 0 || len(r.UnknownKeys) > 0 || len(r.MalformedTriplets) > 0 {
+		return false
+	}
+	for i := range r.Servers {
+		if len(r.Servers[i].MissingKeys()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns nil if the report is valid and a *ValidationError otherwise.
+func (r *ValidationReport) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	return &ValidationError{Report: r}
+}
+
+// Server returns the report of the given vCenter Server or nil if the secret
+// has no entry for it.
+func (r *ValidationReport) Server(server string) *ServerReport {
+	for i := range r.Servers {
+		if r.Servers[i].Server == server {
+			return &r.Servers[i]
+		}
+	}
+	return nil
+}
+
+// String returns a human readable summary of the report.
+func (r *ValidationReport) String() string {
+	var b strings.Builder
+	if len(r.Servers) == 0 {
+		b.WriteString("no vCenter credentials found\n")
+	}
+	for i := range r.Servers {
+		s := &r.Servers[i]
+		if missing := s.MissingKeys(); len(missing) > 0 {
+			fmt.Fprintf(&b, "server %s: missing %s\n", s.Server, strings.Join(missing, ", "))
+		} else {
+			fmt.Fprintf(&b, "server %s: ok (%s, %s)\n", s.Server, s.UsernameKey, s.PasswordKey)
+		}
+	}
+	for _, t := range r.MalformedTriplets {
+		fmt.Fprintf(&b, "malformed keys %s: %s\n", strings.Join(t.Keys, ", "), t.Reason)
+	}
+	if len(r.UnknownKeys) > 0 {
+		fmt.Fprintf(&b, "unknown keys: %s\n", strings.Join(r.UnknownKeys, ", "))
+	}
+	return b.String()
+}
+
+// keyKind is the kind of a key of a credentials secret
+type keyKind int
+
+const (
+	keyUnknown keyKind = iota
+	// keyUsername and keyPassword are keys in the "<server>.username" format
+	keyUsername
+	keyPassword
+	// keyTriplet is a server_, username_ or password_ key
+	keyTriplet
+)
+
+// classifyKey returns the kind of a key of a credentials secret and the
+// server of a "<server>.username" key or the suffix identifier of a triplet key.
+func classifyKey(key string) (keyKind, string) {
+	switch {
+	case strings.HasSuffix(key, usernameSuffix) && key != usernameSuffix:
+		return keyUsername, strings.TrimSuffix(key, usernameSuffix)
+	case strings.HasSuffix(key, passwordSuffix) && key != passwordSuffix:
+		return keyPassword, strings.TrimSuffix(key, passwordSuffix)
+	}
+	for _, prefix := range []string{serverPrefix, usernamePrefix, passwordPrefix} {
+		if strings.HasPrefix(key, prefix) {
+			return keyTriplet, strings.TrimPrefix(key, prefix)
+		}
+	}
+	return keyUnknown, ""
+}
+
+// ValidateSecretData builds the ValidationReport of the content of a
+// credentials secret, in both the "<server>.username" format and the
+// alternative server_/username_/password_ format.
+func ValidateSecretData(data map[string][]byte) *ValidationReport {
+	report := &ValidationReport{}
+	servers := map[string]*ServerReport{}
+	triplets := map[string][]string{}
+
+	for key := range data {
+		switch kind, name := classifyKey(key); kind {
+		case keyUsername:
+			legacyServer(servers, name).HasUsername = true
+		case keyPassword:
+			legacyServer(servers, name).HasPassword = true
+		case keyTriplet:
+			triplets[name] = append(triplets[name], key)
+		default:
+			report.UnknownKeys = append(report.UnknownKeys, key)
+		}
+	}
+
+	for suffix, keys := range triplets {
+		sort.Strings(keys)
+		serverKey := serverPrefix + suffix
+		serverName := strings.TrimSuffix(string(data[serverKey]), "\n")
+		_, hasServer := data[serverKey]
+		switch {
+		case suffix == "":
+			report.MalformedTriplets = append(report.MalformedTriplets, MalformedTriplet{Suffix: suffix, Keys: keys, Reason: "key has no suffix identifier"})
+		case !hasServer:
+			report.MalformedTriplets = append(report.MalformedTriplets, MalformedTriplet{Suffix: suffix, Keys: keys, Reason: fmt.Sprintf("no matching %q key", serverKey)})
+		case serverName == "":
+			report.MalformedTriplets = append(report.MalformedTriplets, MalformedTriplet{Suffix: suffix, Keys: keys, Reason: fmt.Sprintf("%q is empty", serverKey)})
+		default:
+			_, hasUsername := data[usernamePrefix+suffix]
+			_, hasPassword := data[passwordPrefix+suffix]
+			servers[serverName] = &ServerReport{
+				Server:      serverName,
+				UsernameKey: usernamePrefix + suffix,
+				PasswordKey: passwordPrefix + suffix,
+				HasUsername: hasUsername,
+				HasPassword: hasPassword,
+			}
+		}
+	}
+
+	for _, s := range servers {
+		report.Servers = append(report.Servers, *s)
+	}
+	sort.Slice(report.Servers, func(i, j int) bool { return report.Servers[i].Server < report.Servers[j].Server })
+	sort.Slice(report.MalformedTriplets, func(i, j int) bool {
+		return report.MalformedTriplets[i].Suffix < report.MalformedTriplets[j].Suffix
+	})
+	sort.Strings(report.UnknownKeys)
+	return report
+}
+
+// ValidationError is returned for an invalid credentials secret. It wraps
+// ErrUnknownSecretKey for unknown keys or triplet keys without suffix,
+// ErrIncompleteCredentialSet for other malformed triplets and
+// ErrCredentialMissing otherwise.
+type ValidationError struct {
+	Report *ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s:\n%s", e.Unwrap(), strings.TrimSuffix(e.Report.String(), "\n"))
+}
+
+// Unwrap returns the sentinel error of the most severe problem of the report.
+func (e *ValidationError) Unwrap() error {
+	if len(e.Report.UnknownKeys) > 0 {
+		return ErrUnknownSecretKey
+	}
+	for _, t := range e.Report.MalformedTriplets {
+		if t.Suffix == "" {
+			return ErrUnknownSecretKey
+		}
+	}
+	if len(e.Report.MalformedTriplets) > 0 {
+		return ErrIncompleteCredentialSet
+	}
+	return ErrCredentialMissing
+}
+
+// legacyServer returns the report of a server in the "<server>.username" format
+func legacyServer(servers map[string]*ServerReport, server string) *ServerReport {
+	if s, ok := servers[server]; ok {
+		return s
+	}
+	s := &ServerReport{
+		Server:      server,
+		UsernameKey: server + usernameSuffix,
+		PasswordKey: server + passwordSuffix,
+	}
+	servers[server] = s
+	return s
+}
+
+// Validate returns the ValidationReport of the secret or SecretsDirectory
+// managed by the CredentialManager.
+func (credentialManager *CredentialManager) Validate() (*ValidationReport, error) {
+	data, err := credentialManager.secretData()
+	if err != nil {
+		return nil, err
+	}
+	return ValidateSecretData(data), nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateSecretData(t *testing.T) {
+	data := map[string][]byte{
+		"10.0.0.1.username": []byte("user"),
+		"10.0.0.1.password": []byte("secret-password"),
+		"10.0.0.2.username": []byte("user"),
+		"server_a":          []byte("vc-a"),
+		"username_a":        []byte("user"),
+		"password_a":        []byte("secret-password"),
+		"username_b":        []byte("user"),
+		"server_c":          []byte(""),
+		"password_c":        []byte("secret-password"),
+		"token":             []byte("secret-token"),
+	}
+
+	report := ValidateSecretData(data)
+
+	expectedServers := []ServerReport{
+		{Server: "10.0.0.1", UsernameKey: "10.0.0.1.username", PasswordKey: "10.0.0.1.password", HasUsername: true, HasPassword: true},
+		{Server: "10.0.0.2", UsernameKey: "10.0.0.2.username", PasswordKey: "10.0.0.2.password", HasUsername: true},
+		{Server: "vc-a", UsernameKey: "username_a", PasswordKey: "password_a", HasUsername: true, HasPassword: true},
+	}
+	if !reflect.DeepEqual(report.Servers, expectedServers) {
+		t.Errorf("Servers: expected %+v, got %+v", expectedServers, report.Servers)
+	}
+	if !reflect.DeepEqual(report.UnknownKeys, []string{"token"}) {
+		t.Errorf("UnknownKeys: expected [token], got %v", report.UnknownKeys)
+	}
+	if len(report.MalformedTriplets) != 2 || report.MalformedTriplets[0].Suffix != "b" || report.MalformedTriplets[1].Suffix != "c" {
+		t.Errorf("MalformedTriplets: expected suffixes b and c, got %+v", report.MalformedTriplets)
+	}
+	if report.Valid() {
+		t.Error("Expected report to be invalid")
+	}
+
+	out, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	err = parseConfig(data, map[string]*Credential{})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Report, report) {
+		t.Fatalf("Expected parseConfig to return the report, got %v", err)
+	}
+	if !errors.Is(err, ErrUnknownSecretKey) {
+		t.Errorf("Expected %v, got %v", ErrUnknownSecretKey, err)
+	}
+	for _, s := range []string{report.String(), string(out), err.Error()} {
+		if strings.Contains(s, "secret-") {
+			t.Errorf("Report reveals a secret value: %s", s)
+		}
+	}
+}
+
+func TestValidateSecretDataValid(t *testing.T) {
+	report := ValidateSecretData(map[string][]byte{
+		"10.0.0.1.username": []byte("user"),
+		"10.0.0.1.password": []byte("password"),
+	})
+	if !report.Valid() {
+		t.Errorf("Expected report to be valid, got %s", report)
+	}
+	if ValidateSecretData(map[string][]byte{}).Valid() {
+		t.Error("Expected empty secret to be invalid")
+	}
+}
+
+func TestCredentialRedaction(t *testing.T) {
+	credential := Credential{User: "user", Password: "secret-password"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if s := fmt.Sprintf(format, credential); strings.Contains(s, "secret-password") {
+			t.Errorf("%s reveals the password: %s", format, s)
+		}
+		if s := fmt.Sprintf(format, &credential); strings.Contains(s, "secret-password") {
+			t.Errorf("%s of pointer reveals the password: %s", format, s)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package credentialmanager
 
 import (
 	"sync"
+	"sync/atomic"
 
 	v1 "k8s.io/api/core/v1"
 	clientv1 "k8s.io/client-go/listers/core/v1"
@@ -38,6 +39,10 @@ type Credential struct {
 	Password string `gcfg:"password"`
 }
 
+// CredentialListener is notified with the new credentials of a vCenter
+// Server whenever they change in the underlying secret.
+type CredentialListener func(server string, credential Credential)
+
 // CredentialManager is used to manage vCenter credentials stored as
 // Kubernetes secrets.
 type CredentialManager struct {
@@ -45,6 +50,14 @@ type CredentialManager struct {
 	SecretNamespace        string
 	SecretLister           clientv1.SecretLister
 	SecretsDirectory       string
-	secretsDirectoryParsed bool // internal placeholder to identify we parsed the SecretsDirectory
+	secretsDirectoryParsed atomic.Bool // internal placeholder to identify we parsed the SecretsDirectory
 	Cache                  *SecretCache
+	// Provider, if set, is used instead of the secret and SecretsDirectory
+	Provider CredentialProvider
+
+	// watching is set once the cache is kept up to date by secret informer
+	// events or by a watch on the SecretsDirectory
+	watching      bool
+	listenersLock sync.Mutex
+	listeners     []CredentialListener
 }
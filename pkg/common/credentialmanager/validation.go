@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HasSource returns true if the CredentialManager reads credentials from a
+// secret, a SecretsDirectory or a CredentialProvider.
+func (credentialManager *CredentialManager) HasSource() bool {
+	return credentialManager.Provider != nil || credentialManager.SecretsDirectory != "" ||
+		(credentialManager.SecretLister != nil && credentialManager.SecretName != "")
+}
+
+// CheckCredential verifies that the secret holding the credentials contains a
+// username and a password for the given vCenter Server and returns an error
+// naming the missing keys otherwise. Credentials served by a CredentialProvider
+// are not checked.
+func (credentialManager *CredentialManager) CheckCredential(server string) error {
+	if credentialManager.Provider != nil {
+		return nil
+	}
+
+	data, err := credentialManager.secretData()
+	if err != nil {
+		return err
+	}
+
+	missing := missingKeys(data, server)
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrCredentialMissing, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// secretData returns the raw content of the secret or SecretsDirectory
+func (credentialManager *CredentialManager) secretData() (map[string][]byte, error) {
+	if credentialManager.SecretLister != nil && credentialManager.SecretName != "" {
+		secret, err := credentialManager.SecretLister.Secrets(credentialManager.SecretNamespace).Get(credentialManager.SecretName)
+		if err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	}
+	if credentialManager.SecretsDirectory != "" {
+		return credentialManager.readSecretsDirectory()
+	}
+	return nil, ErrCredentialsNotFound
+}
+
+// missingKeys returns the keys that are needed for the given vCenter Server but
+// are not present in data, using the same formats as parseConfig.
+func missingKeys(data map[string][]byte, server string) []string {
+	serverReport := ValidateSecretData(data).Server(server)
+	if serverReport == nil {
+		return []string{server + usernameSuffix, server + passwordSuffix}
+	}
+	return serverReport.MissingKeys()
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingKeys(t *testing.T) {
+	var testcases = []struct {
+		testName string
+		data     map[string][]byte
+		server   string
+		expected []string
+	}{
+		{
+			testName: "Complete legacy format",
+			data: map[string][]byte{
+				"10.0.0.1.username": []byte("user"),
+				"10.0.0.1.password": []byte("password"),
+			},
+			server: "10.0.0.1",
+		},
+		{
+			testName: "Legacy format missing password",
+			data: map[string][]byte{
+				"10.0.0.1.username": []byte("user"),
+			},
+			server:   "10.0.0.1",
+			expected: []string{"10.0.0.1.password"},
+		},
+		{
+			testName: "Key prefix shared with another vCenter",
+			data: map[string][]byte{
+				"site-a.username": []byte("user"),
+				"site-a.password": []byte("password"),
+				"site-b.username": []byte("user"),
+			},
+			server:   "site-b",
+			expected: []string{"site-b.password"},
+		},
+		{
+			testName: "Complete alternative format",
+			data: map[string][]byte{
+				"server_0":   []byte("fd01::1"),
+				"username_0": []byte("user"),
+				"password_0": []byte("password"),
+			},
+			server: "fd01::1",
+		},
+		{
+			testName: "Alternative format missing username",
+			data: map[string][]byte{
+				"server_0":   []byte("fd01::1"),
+				"password_0": []byte("password"),
+			},
+			server:   "fd01::1",
+			expected: []string{"username_0"},
+		},
+		{
+			testName: "Server not in secret",
+			data: map[string][]byte{
+				"10.0.0.1.username": []byte("user"),
+				"10.0.0.1.password": []byte("password"),
+			},
+			server:   "10.0.0.2",
+			expected: []string{"10.0.0.2.username", "10.0.0.2.password"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("Executing Testcase: %s", testcase.testName)
+		missing := missingKeys(testcase.data, testcase.server)
+		if !reflect.DeepEqual(missing, testcase.expected) {
+			t.Fatalf("expected missing keys %v, got %v", testcase.expected, missing)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/fsnotify/fsnotify"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+// AddCredentialListener registers a callback that is invoked for every
+// vCenter Server whose credentials change after a secret event.
+func (credentialManager *CredentialManager) AddCredentialListener(listener CredentialListener) {
+	credentialManager.listenersLock.Lock()
+	defer credentialManager.listenersLock.Unlock()
+	credentialManager.listeners = append(credentialManager.listeners, listener)
+}
+
+// AddSecretListener adds secret informer add, update, delete callbacks that
+// keep the credential cache up to date. Once registered, GetCredential is
+// served from the cache instead of reading the secret on every call.
+func (credentialManager *CredentialManager) AddSecretListener(secretInformer v1.SecretInformer) error {
+	if credentialManager.SecretName == "" || credentialManager.SecretNamespace == "" {
+		klog.V(4).Info("No need to add secret listener as secret is not provided")
+		return nil
+	}
+	if secretInformer == nil {
+		return errors.New("failed to add secret listener as secret informer is nil")
+	}
+
+	_, err := secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    credentialManager.secretAdded,
+		UpdateFunc: credentialManager.secretUpdated,
+		DeleteFunc: credentialManager.secretDeleted,
+	})
+	if err != nil {
+		return err
+	}
+
+	credentialManager.setWatching()
+	return nil
+}
+
+// WatchSecretsDirectory sets up a filesystem watcher on the SecretsDirectory
+// and re-parses it whenever one of the mounted files changes. The watcher is
+// closed when stopCh is closed.
+func (credentialManager *CredentialManager) WatchSecretsDirectory(stopCh <-chan struct{}) error {
+	if credentialManager.SecretsDirectory == "" {
+		klog.V(4).Info("No need to watch secrets directory as it is not provided")
+		return nil
+	}
+
+	watch, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watch.Add(credentialManager.SecretsDirectory); err != nil {
+		_ = watch.Close()
+		return err
+	}
+
+	go func() {
+		defer func() {
+			_ = watch.Close() // ignore explicitly when the watch closes
+		}()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case err := <-watch.Errors:
+				klog.Warningf("secrets directory watcher receives err: %v", err)
+			case event := <-watch.Events:
+				if event.Op == fsnotify.Chmod {
+					klog.V(5).Infof("secrets directory watcher receives %s on %s", event.Op.String(), event.Name)
+					continue
+				}
+				klog.V(2).Infof("Reloading secrets directory %s after event %v", credentialManager.SecretsDirectory, event)
+				credentialManager.refresh(credentialManager.reloadCredentialsMapFile)
+			}
+		}
+	}()
+
+	credentialManager.setWatching()
+	return nil
+}
+
+func (credentialManager *CredentialManager) setWatching() {
+	credentialManager.listenersLock.Lock()
+	defer credentialManager.listenersLock.Unlock()
+	credentialManager.watching = true
+}
+
+func (credentialManager *CredentialManager) isWatching() bool {
+	credentialManager.listenersLock.Lock()
+	defer credentialManager.listenersLock.Unlock()
+	return credentialManager.watching
+}
+
+// isForSecret checks if the secret is the one holding the vCenter credentials
+func (credentialManager *CredentialManager) isForSecret(secret *corev1.Secret) bool {
+	return secret.GetName() == credentialManager.SecretName && secret.GetNamespace() == credentialManager.SecretNamespace
+}
+
+// secretAdded handles secret added event
+func (credentialManager *CredentialManager) secretAdded(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if secret == nil || !ok {
+		return
+	}
+	if credentialManager.isForSecret(secret) {
+		credentialManager.refresh(func() error { return credentialManager.updateCredentialsFromSecret(secret) })
+	}
+}
+
+// secretUpdated handles secret updated event
+func (credentialManager *CredentialManager) secretUpdated(oldObj, newObj interface{}) {
+	oldSecret, ok := oldObj.(*corev1.Secret)
+	if oldSecret == nil || !ok {
+		return
+	}
+	newSecret, ok := newObj.(*corev1.Secret)
+	if newSecret == nil || !ok {
+		return
+	}
+	if credentialManager.isForSecret(newSecret) && !reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+		credentialManager.refresh(func() error { return credentialManager.updateCredentialsFromSecret(newSecret) })
+	}
+}
+
+// secretDeleted handles secret deleted event. The cached credentials are kept
+// so existing sessions can be re-established until a new secret is added.
+func (credentialManager *CredentialManager) secretDeleted(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if secret == nil || !ok {
+		return
+	}
+	if credentialManager.isForSecret(secret) {
+		klog.Warningf("secret %q deleted from namespace %q, keeping cached credentials", secret.GetName(), secret.GetNamespace())
+	}
+}
+
+// refresh runs the update function and notifies the listeners about every
+// vCenter Server whose credentials differ from the ones cached before.
+func (credentialManager *CredentialManager) refresh(update func() error) {
+	previous := credentialManager.Cache.snapshot()
+	if err := update(); err != nil {
+		klog.Errorf("Failed to refresh credentials: %v", err)
+		return
+	}
+
+	credentialManager.listenersLock.Lock()
+	listeners := append([]CredentialListener(nil), credentialManager.listeners...)
+	credentialManager.listenersLock.Unlock()
+
+	for server, credential := range credentialManager.Cache.snapshot() {
+		if old, ok := previous[server]; ok && old == credential {
+			continue
+		}
+		klog.V(2).Infof("Credentials changed for server %s", server)
+		for _, listener := range listeners {
+			listener(server, credential)
+		}
+	}
+}
+
+// updateCredentialsFromSecret parses the given secret into the cache
+func (credentialManager *CredentialManager) updateCredentialsFromSecret(secret *corev1.Secret) error {
+	cacheSecret := credentialManager.Cache.GetSecret()
+	if cacheSecret != nil &&
+		cacheSecret.GetResourceVersion() == secret.GetResourceVersion() &&
+		reflect.DeepEqual(cacheSecret.Data, secret.Data) {
+		return nil
+	}
+	credentialManager.Cache.UpdateSecret(secret)
+	return credentialManager.Cache.parseSecret()
+}
+
+// reloadCredentialsMapFile forces the SecretsDirectory to be parsed again
+func (credentialManager *CredentialManager) reloadCredentialsMapFile() error {
+	credentialManager.secretsDirectoryParsed.Store(false)
+	return credentialManager.updateCredentialsMapFile()
+}
+
+// snapshot returns a copy of the cached credentials
+func (cache *SecretCache) snapshot() map[string]Credential {
+	cache.cacheLock.Lock()
+	defer cache.cacheLock.Unlock()
+	credentials := make(map[string]Credential, len(cache.VirtualCenter))
+	for server, credential := range cache.VirtualCenter {
+		credentials[server] = *credential
+	}
+	return credentials
+}
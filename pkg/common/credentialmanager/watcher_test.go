@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentialmanager
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecretEventsNotifyListeners(t *testing.T) {
+	credMgr := NewCredentialManager("vsconf", "kube-system", "", nil)
+
+	notified := map[string]Credential{}
+	credMgr.AddCredentialListener(func(server string, credential Credential) {
+		notified[server] = credential
+	})
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vsconf", Namespace: "kube-system", ResourceVersion: "1"},
+		Data: map[string][]byte{
+			"0.0.0.0.username": []byte("user"),
+			"0.0.0.0.password": []byte("password"),
+		},
+	}
+	credMgr.secretAdded(secret)
+	if notified["0.0.0.0"].Password != "password" {
+		t.Fatalf("expected listener to be notified on add, got %+v", notified)
+	}
+
+	// unrelated secrets are ignored
+	delete(notified, "0.0.0.0")
+	other := secret.DeepCopy()
+	other.Name = "other"
+	other.Data["0.0.0.0.password"] = []byte("other")
+	credMgr.secretAdded(other)
+	if len(notified) != 0 {
+		t.Fatalf("expected no notification for unrelated secret, got %+v", notified)
+	}
+
+	rotated := secret.DeepCopy()
+	rotated.ResourceVersion = "2"
+	rotated.Data["0.0.0.0.password"] = []byte("rotated")
+	credMgr.secretUpdated(secret, rotated)
+	if notified["0.0.0.0"].Password != "rotated" {
+		t.Fatalf("expected listener to be notified with rotated password, got %+v", notified)
+	}
+
+	// deleting the secret keeps the cached credentials
+	credMgr.secretDeleted(rotated)
+	credential, err := credMgr.GetCredential("0.0.0.0")
+	if err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if credential.Password != "rotated" {
+		t.Fatalf("expected cached password %q, got %q", "rotated", credential.Password)
+	}
+}
+
+func TestWatchSecretsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	writeFile("0.0.0.0.username", "user")
+	writeFile("0.0.0.0.password", "password")
+
+	credMgr := NewCredentialManager("", "", dir, nil)
+	var lock sync.Mutex
+	notified := map[string]Credential{}
+	credMgr.AddCredentialListener(func(server string, credential Credential) {
+		lock.Lock()
+		defer lock.Unlock()
+		notified[server] = credential
+	})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := credMgr.WatchSecretsDirectory(stopCh); err != nil {
+		t.Fatalf("WatchSecretsDirectory failed: %v", err)
+	}
+
+	credential, err := credMgr.GetCredential("0.0.0.0")
+	if err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if credential.Password != "password" {
+		t.Fatalf("expected password %q, got %q", "password", credential.Password)
+	}
+
+	writeFile("0.0.0.0.password", "rotated")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lock.Lock()
+		password := notified["0.0.0.0"].Password
+		lock.Unlock()
+		if password == "rotated" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for rotated credentials, got %+v", notified)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	credential, err = credMgr.GetCredential("0.0.0.0")
+	if err != nil {
+		t.Fatalf("GetCredential failed: %v", err)
+	}
+	if credential.Password != "rotated" {
+		t.Fatalf("expected password %q, got %q", "rotated", credential.Password)
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"syscall"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/informers"
 	informerv1 "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
@@ -66,10 +68,7 @@ func setupSignalHandler() (stopCh <-chan struct{}) {
 
 // NewInformer creates a newk8s client based on a service account
 func NewInformer(client clientset.Interface, singleWatcher bool) *InformerManager {
-	onceForInformer.Do(func() {
-		signalHandler = setupSignalHandler()
-		informerFactory = informers.NewSharedInformerFactory(client, noResyncPeriodFunc())
-	})
+	initInformerFactory(client)
 
 	return &InformerManager{
 		client:          client,
@@ -78,6 +77,33 @@ func NewInformer(client clientset.Interface, singleWatcher bool) *InformerManage
 	}
 }
 
+// NewSecretScopedInformer creates an InformerManager whose informers only see the
+// named secret in the given namespace. The service account then only needs
+// get/list/watch on that secret instead of on all secrets in the cluster.
+func NewSecretScopedInformer(client clientset.Interface, secretNamespace string, secretName string) *InformerManager {
+	initInformerFactory(client)
+
+	scopedFactory := informers.NewSharedInformerFactoryWithOptions(client, noResyncPeriodFunc(),
+		informers.WithNamespace(secretNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", secretName).String()
+		}))
+
+	return &InformerManager{
+		client:          client,
+		stopCh:          signalHandler,
+		informerFactory: scopedFactory,
+	}
+}
+
+// initInformerFactory sets up the signal handler and the shared informer factory once
+func initInformerFactory(client clientset.Interface) {
+	onceForInformer.Do(func() {
+		signalHandler = setupSignalHandler()
+		informerFactory = informers.NewSharedInformerFactory(client, noResyncPeriodFunc())
+	})
+}
+
 // GetSecretLister creates a lister to use
 func (im *InformerManager) GetSecretLister() listerv1.SecretLister {
 	if im.secretInformer == nil {
@@ -95,6 +121,11 @@ func (im *InformerManager) GetSecretInformer() informerv1.SecretInformer {
 	return im.secretInformer
 }
 
+// IsSecretInformerSynced returns whether secret informer is synced
+func (im *InformerManager) IsSecretInformerSynced() cache.InformerSynced {
+	return im.GetSecretInformer().Informer().HasSynced
+}
+
 // AddNodeListener hooks up add, update, delete callbacks
 func (im *InformerManager) AddNodeListener(add, remove func(obj interface{}), update func(oldObj, newObj interface{})) {
 	if im.nodeInformer == nil {
@@ -37,6 +37,8 @@ const (
 
 // VSphereConnection contains information for connecting to vCenter
 type VSphereConnection struct {
+	// Client is the session of the connection, it is replaced by Connect and
+	// ReLogin. Read it with GetClient while the connection is in use.
 	Client            *vim25.Client
 	Username          string
 	Password          string
@@ -46,20 +48,20 @@ type VSphereConnection struct {
 	Thumbprint        string
 	Insecure          bool
 	RoundTripperCount uint
-	credentialsLock   sync.Mutex
+	// Limiter limits the rate and the concurrency of the requests sent to the
+	// vCenter, nil for no limit
+	Limiter         *Limiter
+	credentialsLock sync.Mutex
+	clientLock      sync.Mutex
 }
 
-var (
-	clientLock sync.Mutex
-)
-
 // Connect makes connection to vCenter and sets VSphereConnection.Client.
 // If connection.Client is already set, it obtains the existing user session.
 // if user session is not valid, connection.Client will be set to the new client.
 func (connection *VSphereConnection) Connect(ctx context.Context) error {
 	var err error
-	clientLock.Lock()
-	defer clientLock.Unlock()
+	connection.clientLock.Lock()
+	defer connection.clientLock.Unlock()
 
 	if connection.Client == nil {
 		connection.Client, err = connection.NewClient(ctx)
@@ -149,9 +151,17 @@ func (connection *VSphereConnection) login(ctx context.Context, client *vim25.Cl
 	return m.LoginByToken(client.WithHeader(ctx, header))
 }
 
+// GetClient returns the client of the current session, nil if the connection
+// has not been established yet.
+func (connection *VSphereConnection) GetClient() *vim25.Client {
+	connection.clientLock.Lock()
+	defer connection.clientLock.Unlock()
+	return connection.Client
+}
+
 // Logout calls SessionManager.Logout for the given connection.
 func (connection *VSphereConnection) Logout(ctx context.Context) {
-	m := session.NewManager(connection.Client)
+	m := session.NewManager(connection.GetClient())
 	if err := m.Logout(ctx); err != nil {
 		klog.Errorf("Logout failed: %s", err)
 	}
@@ -182,6 +192,7 @@ func (connection *VSphereConnection) NewClient(ctx context.Context) (*vim25.Clie
 		return nil, err
 	}
 	client.UserAgent = userAgentName
+	client.RoundTripper = connection.Limiter.RoundTripper(client.RoundTripper)
 	err = connection.login(ctx, client)
 	if err != nil {
 		return nil, err
@@ -194,6 +205,30 @@ func (connection *VSphereConnection) NewClient(ctx context.Context) (*vim25.Clie
 	return client, nil
 }
 
+// ReLogin creates a new client session with the current credentials and
+// replaces the existing client. The previous session is logged out on success
+// and kept in place on failure.
+func (connection *VSphereConnection) ReLogin(ctx context.Context) error {
+	connection.clientLock.Lock()
+	defer connection.clientLock.Unlock()
+
+	client, err := connection.NewClient(ctx)
+	if err != nil {
+		klog.Errorf("Failed to create govmomi client with updated credentials. err: %+v", err)
+		return err
+	}
+
+	previous := connection.Client
+	connection.Client = client
+	if previous != nil {
+		m := session.NewManager(previous)
+		if err := m.Logout(ctx); err != nil {
+			klog.V(3).Infof("Logout of previous session failed: %s", err)
+		}
+	}
+	return nil
+}
+
 // UpdateCredentials updates username and password.
 // Note: Updated username and password will be used when there is no session active
 func (connection *VSphereConnection) UpdateCredentials(username string, password string) {
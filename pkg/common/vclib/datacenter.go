@@ -40,7 +40,7 @@ type Datacenter struct {
 // GetDatacenter returns the DataCenter Object for the given datacenterPath
 // If datacenter is located in a folder, include full path to datacenter else just provide the datacenter name
 func GetDatacenter(ctx context.Context, connection *VSphereConnection, datacenterPath string) (*Datacenter, error) {
-	finder := find.NewFinder(connection.Client, false)
+	finder := find.NewFinder(connection.GetClient(), false)
 	datacenter, err := finder.Datacenter(ctx, datacenterPath)
 	if err != nil {
 		klog.Errorf("Failed to find the datacenter: %s. err: %+v", datacenterPath, err)
@@ -53,7 +53,7 @@ func GetDatacenter(ctx context.Context, connection *VSphereConnection, datacente
 // GetAllDatacenter returns all the DataCenter Objects
 func GetAllDatacenter(ctx context.Context, connection *VSphereConnection) ([]*Datacenter, error) {
 	var dc []*Datacenter
-	finder := find.NewFinder(connection.Client, false)
+	finder := find.NewFinder(connection.GetClient(), false)
 	datacenters, err := finder.DatacenterList(ctx, "*")
 	if err != nil {
 		klog.Errorf("Failed to find the datacenter. err: %+v", err)
@@ -66,9 +66,26 @@ func GetAllDatacenter(ctx context.Context, connection *VSphereConnection) ([]*Da
 	return dc, nil
 }
 
+// GetDatacenterList returns the DataCenter Objects matching datacenterPath, a
+// name, an inventory path or a glob pattern such as "dc-*"
+func GetDatacenterList(ctx context.Context, connection *VSphereConnection, datacenterPath string) ([]*Datacenter, error) {
+	var dc []*Datacenter
+	finder := find.NewFinder(connection.GetClient(), false)
+	datacenters, err := finder.DatacenterList(ctx, datacenterPath)
+	if err != nil {
+		klog.Errorf("Failed to find the datacenters: %s. err: %+v", datacenterPath, err)
+		return nil, err
+	}
+	for _, datacenter := range datacenters {
+		dc = append(dc, &(Datacenter{datacenter}))
+	}
+
+	return dc, nil
+}
+
 // GetNumberOfDatacenters returns the number of DataCenters in this vCenter
 func GetNumberOfDatacenters(ctx context.Context, connection *VSphereConnection) (int, error) {
-	finder := find.NewFinder(connection.Client, false)
+	finder := find.NewFinder(connection.GetClient(), false)
 	datacenters, err := finder.DatacenterList(ctx, "*")
 	if err != nil {
 		klog.Errorf("Failed to find the datacenter. err: %+v", err)
@@ -145,6 +162,141 @@ func (dc *Datacenter) GetVMByPath(ctx context.Context, vmPath string) (*VirtualM
 	return &virtualMachine, nil
 }
 
+// GetVMSearchScopes returns the VM folders and resource pools matching the given
+// inventory paths or glob patterns. Relative folder paths start at the vm folder
+// of the datacenter, relative resource pool paths at its host folder, such as
+// "cluster/Resources/pool". Paths matching nothing in the datacenter are skipped.
+func (dc *Datacenter) GetVMSearchScopes(ctx context.Context, folderPaths, poolPaths []string) ([]types.ManagedObjectReference, error) {
+	finder := getFinder(dc)
+	var scopes []types.ManagedObjectReference
+	for _, folderPath := range folderPaths {
+		if !strings.HasPrefix(folderPath, "/") {
+			folderPath = "vm/" + folderPath
+		}
+		folders, err := finder.FolderList(ctx, folderPath)
+		if _, ok := err.(*find.NotFoundError); ok {
+			klog.V(4).Infof("No folder %s in datacenter %s", folderPath, dc.Name())
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Failed to find the folders %s. err: %+v", folderPath, err)
+			return nil, err
+		}
+		for _, folder := range folders {
+			scopes = append(scopes, folder.Reference())
+		}
+	}
+	for _, poolPath := range poolPaths {
+		pools, err := finder.ResourcePoolList(ctx, poolPath)
+		if _, ok := err.(*find.NotFoundError); ok {
+			klog.V(4).Infof("No resource pool %s in datacenter %s", poolPath, dc.Name())
+			continue
+		}
+		if err != nil {
+			klog.Errorf("Failed to find the resource pools %s. err: %+v", poolPath, err)
+			return nil, err
+		}
+		for _, pool := range pools {
+			scopes = append(scopes, pool.Reference())
+		}
+	}
+	return scopes, nil
+}
+
+// GetVMByUUIDInScopes gets the VM object with the given vmUUID in the VM folders
+// and resource pools of scopes
+func (dc *Datacenter) GetVMByUUIDInScopes(ctx context.Context, scopes []types.ManagedObjectReference, vmUUID string) (*VirtualMachine, error) {
+	vmUUID = strings.ToLower(strings.TrimSpace(vmUUID))
+	return dc.getVMInScopes(ctx, scopes, func(s *object.SearchIndex) ([]object.Reference, error) {
+		svm, err := s.FindByUuid(ctx, dc.Datacenter, vmUUID, true, nil)
+		if err != nil || svm == nil {
+			return nil, err
+		}
+		return []object.Reference{svm}, nil
+	})
+}
+
+// GetVMByDNSNameInScopes gets the VM object with the given dns name in the VM
+// folders and resource pools of scopes
+func (dc *Datacenter) GetVMByDNSNameInScopes(ctx context.Context, scopes []types.ManagedObjectReference, dnsName string) (*VirtualMachine, error) {
+	dnsName = strings.ToLower(strings.TrimSpace(dnsName))
+	return dc.getVMInScopes(ctx, scopes, func(s *object.SearchIndex) ([]object.Reference, error) {
+		return s.FindAllByDnsName(ctx, dc.Datacenter, dnsName, true)
+	})
+}
+
+// GetVMByIPInScopes gets the VM object with the given IP address in the VM
+// folders and resource pools of scopes
+func (dc *Datacenter) GetVMByIPInScopes(ctx context.Context, scopes []types.ManagedObjectReference, ipAddy string) (*VirtualMachine, error) {
+	ipAddy = strings.ToLower(strings.TrimSpace(ipAddy))
+	return dc.getVMInScopes(ctx, scopes, func(s *object.SearchIndex) ([]object.Reference, error) {
+		return s.FindAllByIp(ctx, dc.Datacenter, ipAddy, true)
+	})
+}
+
+// getVMInScopes returns the only VM found by the search index of the
+// datacenter that is in the scopes.
+func (dc *Datacenter) getVMInScopes(ctx context.Context, scopes []types.ManagedObjectReference,
+	find func(s *object.SearchIndex) ([]object.Reference, error)) (*VirtualMachine, error) {
+	refs, err := find(object.NewSearchIndex(dc.Client()))
+	if err != nil {
+		klog.Errorf("Failed to search the VMs of datacenter %s. err: %+v", dc.Name(), err)
+		return nil, err
+	}
+
+	scopeSet := map[types.ManagedObjectReference]bool{}
+	for _, scope := range scopes {
+		scopeSet[scope] = true
+	}
+	var found []types.ManagedObjectReference
+	for _, ref := range refs {
+		inScopes, err := dc.inScopes(ctx, ref.Reference(), scopeSet)
+		if err != nil {
+			return nil, err
+		}
+		if inScopes {
+			found = append(found, ref.Reference())
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, ErrNoVMFound
+	case 1:
+		return &VirtualMachine{object.NewVirtualMachine(dc.Client(), found[0]), dc}, nil
+	}
+	klog.Errorf("Multiple vms found in the search scopes of datacenter %s", dc.Name())
+	return nil, ErrMultipleVMsFound
+}
+
+// inScopes checks whether one of the folders or resource pools the VM is
+// placed in is a scope
+func (dc *Datacenter) inScopes(ctx context.Context, vmRef types.ManagedObjectReference, scopes map[types.ManagedObjectReference]bool) (bool, error) {
+	pc := property.DefaultCollector(dc.Client())
+	var vm mo.VirtualMachine
+	if err := pc.RetrieveOne(ctx, vmRef, []string{"resourcePool"}, &vm); err != nil {
+		klog.Errorf("Failed to retrieve the resource pool of VM %s. err: %+v", vmRef, err)
+		return false, err
+	}
+	roots := []types.ManagedObjectReference{vmRef}
+	if vm.ResourcePool != nil {
+		roots = append(roots, *vm.ResourcePool)
+	}
+	for _, root := range roots {
+		ancestors, err := mo.Ancestors(ctx, dc.Client(), pc.Reference(), root)
+		if err != nil {
+			klog.Errorf("Failed to retrieve the ancestors of %s. err: %+v", root, err)
+			return false, err
+		}
+		for _, ancestor := range ancestors {
+			if scopes[ancestor.Self] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // GetAllDatastores gets the datastore URL to DatastoreInfo map for all the datastores in
 // the datacenter.
 func (dc *Datacenter) GetAllDatastores(ctx context.Context) (map[string]*DatastoreInfo, error) {
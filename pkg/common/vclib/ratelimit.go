@@ -0,0 +1,215 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vclib
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"golang.org/x/time/rate"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// Client types reported in the rate limiter metrics
+const (
+	ClientSOAP = "soap"
+	ClientREST = "rest"
+)
+
+var (
+	requestsQueuedMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "cloudprovider_vsphere_api_requests_queued",
+			Help:           "Number of vCenter API requests waiting for the rate limiter or the concurrency cap",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"vcenter", "client"},
+	)
+
+	requestsInFlightMetric = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Name:           "cloudprovider_vsphere_api_requests_in_flight",
+			Help:           "Number of vCenter API requests in flight",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"vcenter", "client"},
+	)
+
+	requestWaitMetric = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Name:           "cloudprovider_vsphere_api_request_wait_duration_seconds",
+			Help:           "Time vCenter API requests spent queued before being sent",
+			Buckets:        metrics.ExponentialBuckets(0.001, 4, 10),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"vcenter", "client"},
+	)
+
+	registerLimiterMetrics sync.Once
+)
+
+// Limiter limits the rate and the concurrency of the requests sent to one
+// vCenter. A nil Limiter does not limit anything.
+type Limiter struct {
+	server  string
+	limiter *rate.Limiter
+	slots   chan struct{}
+}
+
+// NewLimiter returns a Limiter allowing qps requests per second with bursts of
+// burst requests, and at most maxConcurrent requests in flight. A value of 0
+// disables the corresponding limit. nil is returned if both are disabled.
+func NewLimiter(server string, qps float64, burst int, maxConcurrent int) *Limiter {
+	if qps <= 0 && maxConcurrent <= 0 {
+		return nil
+	}
+
+	registerLimiterMetrics.Do(func() {
+		legacyregistry.MustRegister(requestsQueuedMetric, requestsInFlightMetric, requestWaitMetric)
+	})
+
+	l := &Limiter{server: server}
+	if qps > 0 {
+		if burst <= 0 {
+			burst = int(math.Max(1, math.Ceil(qps)))
+		}
+		l.limiter = rate.NewLimiter(rate.Limit(qps), burst)
+	}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	return l
+}
+
+// Wait blocks until the request may be sent and returns the function that
+// must be called once it completes.
+func (l *Limiter) Wait(ctx context.Context, client string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	queued := requestsQueuedMetric.WithLabelValues(l.server, client)
+	queued.Inc()
+	start := time.Now()
+	err := l.wait(ctx)
+	queued.Dec()
+	if err != nil {
+		return nil, err
+	}
+	requestWaitMetric.WithLabelValues(l.server, client).Observe(time.Since(start).Seconds())
+
+	inFlight := requestsInFlightMetric.WithLabelValues(l.server, client)
+	inFlight.Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			inFlight.Dec()
+			if l.slots != nil {
+				<-l.slots
+			}
+		})
+	}, nil
+}
+
+func (l *Limiter) wait(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if l.limiter != nil {
+		if err := l.limiter.Wait(ctx); err != nil {
+			if l.slots != nil {
+				<-l.slots
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// RoundTripper wraps a SOAP round tripper so that its calls go through the Limiter.
+func (l *Limiter) RoundTripper(rt soap.RoundTripper) soap.RoundTripper {
+	if l == nil {
+		return rt
+	}
+	return &limitedRoundTripper{roundTripper: rt, limiter: l}
+}
+
+// Transport wraps an HTTP transport so that its requests go through the Limiter.
+// The concurrency slot of a request is held until its response body is closed.
+func (l *Limiter) Transport(rt http.RoundTripper) http.RoundTripper {
+	if l == nil {
+		return rt
+	}
+	return &limitedTransport{transport: rt, limiter: l}
+}
+
+type limitedRoundTripper struct {
+	roundTripper soap.RoundTripper
+	limiter      *Limiter
+}
+
+// RoundTrip implements soap.RoundTripper
+func (rt *limitedRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	done, err := rt.limiter.Wait(ctx, ClientSOAP)
+	if err != nil {
+		return err
+	}
+	defer done()
+	return rt.roundTripper.RoundTrip(ctx, req, res)
+}
+
+type limitedTransport struct {
+	transport http.RoundTripper
+	limiter   *Limiter
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.limiter.Wait(req.Context(), ClientREST)
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.transport.RoundTrip(req)
+	if err != nil || res.Body == nil {
+		done()
+		return res, err
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, done: done}
+	return res, nil
+}
+
+// releasingBody releases the concurrency slot of a request when it is closed
+type releasingBody struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vclib_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/cloud-provider-vsphere/pkg/common/vclib"
+)
+
+func TestNewLimiterDisabled(t *testing.T) {
+	if l := vclib.NewLimiter("vc", 0, 10, 0); l != nil {
+		t.Fatal("Expected no limiter when rate and concurrency are unlimited")
+	}
+
+	var l *vclib.Limiter
+	done, err := l.Wait(context.Background(), vclib.ClientSOAP)
+	if err != nil {
+		t.Fatalf("Wait on nil limiter failed: %v", err)
+	}
+	done()
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := vclib.NewLimiter("vc-concurrency", 0, 0, 2)
+
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, err := l.Wait(context.Background(), vclib.ClientSOAP)
+			if err != nil {
+				t.Errorf("Wait failed: %v", err)
+				return
+			}
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			done()
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := vclib.NewLimiter("vc-rate", 1, 1, 0)
+
+	done, err := l.Wait(context.Background(), vclib.ClientSOAP)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	done()
+
+	// the bucket is empty, the next request must wait for about a second
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, vclib.ClientSOAP); err == nil {
+		t.Error("Expected Wait to fail once the burst is exhausted")
+	}
+}
+
+func TestLimiterTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	l := vclib.NewLimiter("vc-transport", 0, 0, 1)
+	client := &http.Client{Transport: l.Transport(http.DefaultTransport)}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	// the only slot is held until the body is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx, vclib.ClientREST); err == nil {
+		t.Error("Expected the slot to be held while the body is open")
+	}
+
+	res.Body.Close()
+	done, err := l.Wait(context.Background(), vclib.ClientREST)
+	if err != nil {
+		t.Fatalf("Expected the slot to be released once the body is closed: %v", err)
+	}
+	done()
+}
@@ -18,10 +18,11 @@ package config
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 
 	klog "k8s.io/klog/v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
 // FromEnv initializes the provided configuration object with values
@@ -29,16 +30,20 @@ import (
 // for a property that's already initialized, the environment variable's value
 // takes precedence.
 func (cfg *Config) FromEnv() error {
-	if v := os.Getenv("NSXT_MANAGER_HOST"); v != "" {
+	return cfg.fromEnv(vcfg.OSEnv)
+}
+
+func (cfg *Config) fromEnv(env vcfg.Env) error {
+	if v := env.Getenv("NSXT_MANAGER_HOST"); v != "" {
 		cfg.Host = v
 	}
-	if v := os.Getenv("NSXT_USERNAME"); v != "" {
+	if v := env.Getenv("NSXT_USERNAME"); v != "" {
 		cfg.User = v
 	}
-	if v := os.Getenv("NSXT_PASSWORD"); v != "" {
+	if v := env.Getenv("NSXT_PASSWORD"); v != "" {
 		cfg.Password = v
 	}
-	if v := os.Getenv("NSXT_ALLOW_UNVERIFIED_SSL"); v != "" {
+	if v := env.Getenv("NSXT_ALLOW_UNVERIFIED_SSL"); v != "" {
 		InsecureFlag, err := strconv.ParseBool(v)
 		if err != nil {
 			klog.Errorf("Failed to parse NSXT_ALLOW_UNVERIFIED_SSL: %s", err)
@@ -46,19 +51,19 @@ func (cfg *Config) FromEnv() error {
 		}
 		cfg.InsecureFlag = InsecureFlag
 	}
-	if v := os.Getenv("NSXT_CLIENT_AUTH_CERT_FILE"); v != "" {
+	if v := env.Getenv("NSXT_CLIENT_AUTH_CERT_FILE"); v != "" {
 		cfg.ClientAuthCertFile = v
 	}
-	if v := os.Getenv("NSXT_CLIENT_AUTH_KEY_FILE"); v != "" {
+	if v := env.Getenv("NSXT_CLIENT_AUTH_KEY_FILE"); v != "" {
 		cfg.ClientAuthKeyFile = v
 	}
-	if v := os.Getenv("NSXT_CA_FILE"); v != "" {
+	if v := env.Getenv("NSXT_CA_FILE"); v != "" {
 		cfg.CAFile = v
 	}
-	if v := os.Getenv("NSXT_SECRET_NAME"); v != "" {
+	if v := env.Getenv("NSXT_SECRET_NAME"); v != "" {
 		cfg.SecretName = v
 	}
-	if v := os.Getenv("NSXT_SECRET_NAMESPACE"); v != "" {
+	if v := env.Getenv("NSXT_SECRET_NAMESPACE"); v != "" {
 		cfg.SecretNamespace = v
 	}
 
@@ -74,11 +79,17 @@ func (cfg *Config) FromEnv() error {
 // ReadNsxtConfig parses vSphere cloud config file and stores it into VSphereConfig.
 // Environment variables are also checked
 func ReadNsxtConfig(configData []byte) (*Config, error) {
+	return ReadNsxtConfigWithEnv(configData, vcfg.OSEnv)
+}
+
+// ReadNsxtConfigWithEnv parses the config like ReadNsxtConfig, with the
+// variables of env overriding its keys.
+func ReadNsxtConfigWithEnv(configData []byte, env vcfg.Env) (*Config, error) {
 	if len(configData) == 0 {
 		return nil, fmt.Errorf("Invalid YAML/INI file")
 	}
 
-	cfg, err := ReadConfigYAML(configData)
+	cfg, err := readConfigYAML(configData, env)
 	if err != nil {
 		cfg, err = ReadConfigINI(configData)
 		if err != nil {
@@ -91,7 +102,7 @@ func ReadNsxtConfig(configData []byte) (*Config, error) {
 	}
 
 	// Env Vars should override config file entries if present
-	if err := cfg.FromEnv(); err != nil {
+	if err := cfg.fromEnv(env); err != nil {
 		return nil, err
 	}
 
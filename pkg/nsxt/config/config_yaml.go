@@ -21,6 +21,8 @@ import (
 	"fmt"
 
 	"gopkg.in/yaml.v2"
+
+	vcfg "k8s.io/cloud-provider-vsphere/pkg/common/config"
 )
 
 /*
@@ -91,6 +93,10 @@ func (ncy *NsxtConfigYAML) CompleteAndValidate() error {
 
 // ReadRawConfigYAML parses vSphere cloud config file and stores it into ConfigYAML
 func ReadRawConfigYAML(configData []byte) (*NsxtConfigYAML, error) {
+	return readRawConfigYAML(configData, vcfg.OSEnv)
+}
+
+func readRawConfigYAML(configData []byte, env vcfg.Env) (*NsxtConfigYAML, error) {
 	if len(configData) == 0 {
 		return nil, fmt.Errorf("Invalid YAML file")
 	}
@@ -100,6 +106,9 @@ func ReadRawConfigYAML(configData []byte) (*NsxtConfigYAML, error) {
 	if err := yaml.Unmarshal(configData, &cfg); err != nil {
 		return nil, err
 	}
+	if err := env.Apply(vcfg.EnvPrefix, &cfg); err != nil {
+		return nil, err
+	}
 
 	err := cfg.CompleteAndValidate()
 	if err != nil {
@@ -110,7 +119,11 @@ func ReadRawConfigYAML(configData []byte) (*NsxtConfigYAML, error) {
 
 // ReadConfigYAML parses vSphere cloud config file and stores it into Config
 func ReadConfigYAML(configData []byte) (*Config, error) {
-	cfg, err := ReadRawConfigYAML(configData)
+	return readConfigYAML(configData, vcfg.OSEnv)
+}
+
+func readConfigYAML(configData []byte, env vcfg.Env) (*Config, error) {
+	cfg, err := readRawConfigYAML(configData, env)
 	if err != nil {
 		return nil, err
 	}
@@ -26,8 +26,10 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
+	"github.com/vmware/vsphere-automation-sdk-go/runtime/bindings"
 	"github.com/vmware/vsphere-automation-sdk-go/runtime/core"
 	"github.com/vmware/vsphere-automation-sdk-go/runtime/protocol/client"
 	"github.com/vmware/vsphere-automation-sdk-go/runtime/security"
@@ -41,8 +43,9 @@ import (
 
 // ConnectorManager manages NSXT connection
 type ConnectorManager struct {
+	lock      sync.RWMutex
 	config    *config.Config
-	connector client.Connector
+	connector *reloadableConnector
 }
 
 type remoteBasicAuthHeaderProcessor struct {
@@ -65,7 +68,18 @@ func NewConnectorManager(nsxtConfig *config.Config) (*ConnectorManager, error) {
 	if nsxtConfig == nil {
 		return cm, nil
 	}
+	connector, err := newConnector(nsxtConfig)
+	if err != nil {
+		return nil, err
+	}
 	cm.config = nsxtConfig
+	cm.connector = &reloadableConnector{target: connector}
+
+	return cm, nil
+}
+
+// newConnector creates a connector to the NSXT manager of the config
+func newConnector(nsxtConfig *config.Config) (client.Connector, error) {
 	url := fmt.Sprintf("https://%s", nsxtConfig.Host)
 	var securityCtx *core.SecurityContextImpl
 	securityContextNeeded := true
@@ -112,9 +126,49 @@ func NewConnectorManager(nsxtConfig *config.Config) (*ConnectorManager, error) {
 	if nsxtConfig.RemoteAuth {
 		connector.AddRequestProcessor(newRemoteBasicAuthHeaderProcessor())
 	}
-	cm.connector = connector
 
-	return cm, nil
+	return connector, nil
+}
+
+// Reload switches the NSXT connection to the settings of nsxtConfig. The
+// connector returned by GetConnector keeps working and uses the new settings
+// from then on. The host cannot be changed, the load balancer and route
+// providers would refer to objects of the previous NSXT manager. The returned
+// function restores the previous settings.
+func (cm *ConnectorManager) Reload(nsxtConfig *config.Config) (func(), error) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+
+	if cm.config == nil || nsxtConfig == nil {
+		if cm.config != nsxtConfig {
+			return nil, fmt.Errorf("NSXT cannot be enabled or disabled without a restart")
+		}
+		return func() {}, nil
+	}
+	if nsxtConfig.Host != cm.config.Host {
+		return nil, fmt.Errorf("NSXT host cannot be changed from %s to %s without a restart", cm.config.Host, nsxtConfig.Host)
+	}
+
+	connector, err := newConnector(nsxtConfig)
+	if err != nil {
+		return nil, err
+	}
+	// credentials read from the secret are not part of the config
+	if nsxtConfig.SecretName == cm.config.SecretName && nsxtConfig.SecretNamespace == cm.config.SecretNamespace &&
+		cm.config.SecretName != "" {
+		connector.SetSecurityContext(cm.connector.SecurityContext())
+	}
+
+	oldConfig, oldConnector := cm.config, cm.connector.swap(connector)
+	cm.config = nsxtConfig
+	klog.V(2).Infof("NSXT connection settings reloaded")
+
+	return func() {
+		cm.lock.Lock()
+		defer cm.lock.Unlock()
+		cm.config = oldConfig
+		cm.connector.swap(oldConnector)
+	}, nil
 }
 
 // getConnectorTLSConfig loads certificates to build TLS configuration
@@ -190,15 +244,26 @@ func getAPIToken(vmcAuthHost string, vmcAccessToken string) (string, error) {
 
 // GetConnector gets NSXT connector
 func (cm *ConnectorManager) GetConnector() client.Connector {
+	if cm.connector == nil {
+		return nil
+	}
 	return cm.connector
 }
 
+// getConfig returns the current config
+func (cm *ConnectorManager) getConfig() *config.Config {
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	return cm.config
+}
+
 // AddSecretListener adds secret informer add, update, delete callbacks
 func (cm *ConnectorManager) AddSecretListener(secretInformer v1.SecretInformer) error {
-	if cm.config == nil {
+	cfg := cm.getConfig()
+	if cfg == nil {
 		return errors.New("config is not available for NSXT connector manager")
 	}
-	if cm.config.SecretName == "" || cm.config.SecretNamespace == "" {
+	if cfg.SecretName == "" || cfg.SecretNamespace == "" {
 		klog.V(6).Infof("No need to initialize NSXT secret manager as secret is not provided")
 		return nil
 	}
@@ -217,7 +282,8 @@ func (cm *ConnectorManager) AddSecretListener(secretInformer v1.SecretInformer)
 
 // isForNsxtSecret checks if secret is for nsxt config
 func (cm *ConnectorManager) isForNsxtSecret(secret *corev1.Secret) bool {
-	if secret.GetName() == cm.config.SecretName && secret.GetNamespace() == cm.config.SecretNamespace {
+	cfg := cm.getConfig()
+	if cfg != nil && secret.GetName() == cfg.SecretName && secret.GetNamespace() == cfg.SecretNamespace {
 		return true
 	}
 	return false
@@ -289,3 +355,55 @@ func (cm *ConnectorManager) resetConnectorContext() {
 	securityCtx := core.NewSecurityContextImpl()
 	cm.connector.SetSecurityContext(securityCtx)
 }
+
+// reloadableConnector forwards to a connector that is replaced when the NSXT
+// settings are reloaded, so that the brokers created from it are kept.
+type reloadableConnector struct {
+	lock   sync.RWMutex
+	target client.Connector
+}
+
+var _ client.Connector = &reloadableConnector{}
+
+func (c *reloadableConnector) get() client.Connector {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.target
+}
+
+// swap replaces the target connector and returns the previous one
+func (c *reloadableConnector) swap(target client.Connector) client.Connector {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	old := c.target
+	c.target = target
+	return old
+}
+
+func (c *reloadableConnector) Address() string {
+	return c.get().Address()
+}
+
+func (c *reloadableConnector) ApplicationContext() *core.ApplicationContext {
+	return c.get().ApplicationContext()
+}
+
+func (c *reloadableConnector) SecurityContext() core.SecurityContext {
+	return c.get().SecurityContext()
+}
+
+func (c *reloadableConnector) SetSecurityContext(securityCtx core.SecurityContext) {
+	c.get().SetSecurityContext(securityCtx)
+}
+
+func (c *reloadableConnector) NewExecutionContext() *core.ExecutionContext {
+	return c.get().NewExecutionContext()
+}
+
+func (c *reloadableConnector) GetApiProvider() core.APIProvider {
+	return c.get().GetApiProvider()
+}
+
+func (c *reloadableConnector) TypeConverter() *bindings.TypeConverter {
+	return c.get().TypeConverter()
+}